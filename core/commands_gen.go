@@ -263,6 +263,24 @@ var commandsJSON = `{
               }
             ]
           },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "cell",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "S2",
+            "arguments": [
+              {
+                "name": "token",
+                "type": "string"
+              }
+            ]
+          },
           {
             "name": "STRING",
             "arguments": [
@@ -430,6 +448,385 @@ var commandsJSON = `{
     "since": "1.3.0",
     "group": "keys"
   },
+  "DISTANCE": {
+    "summary": "Get the distance in meters between two objects",
+    "complexity": "O(N) where N is the number of points in the objects",
+    "arguments": [
+      {
+        "name": "key",
+        "type": "string"
+      },
+      {
+        "name": "id",
+        "type": "string"
+      },
+      {
+        "name": "target",
+        "enumargs": [
+          {
+            "name": "key",
+            "arguments": [
+              {
+                "name": "id",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "POINT",
+            "arguments": [
+              {
+                "name": "lat",
+                "type": "double"
+              },
+              {
+                "name": "lon",
+                "type": "double"
+              }
+            ]
+          }
+        ]
+      }
+    ],
+    "group": "keys"
+  },
+  "GEOMOP": {
+    "summary": "Perform a geometry operation on objects",
+    "complexity": "O(N*log(M)) where N and M are the number of points in the objects",
+    "arguments": [
+      {
+        "name": "operation",
+        "enumargs": [
+          {
+            "name": "UNION",
+            "arguments": [
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "INTERSECTION",
+            "arguments": [
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "DIFFERENCE",
+            "arguments": [
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "HULL",
+            "arguments": [
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "CENTROID",
+            "arguments": [
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "SIMPLIFY",
+            "arguments": [
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "meters",
+                "type": "double"
+              }
+            ]
+          },
+          {
+            "name": "BUFFER",
+            "arguments": [
+              {
+                "name": "area",
+                "enumargs": [
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "meters",
+                "type": "double"
+              }
+            ]
+          }
+        ]
+      },
+      {
+        "command": "STORE",
+        "name": [
+          "key",
+          "id"
+        ],
+        "type": [
+          "string",
+          "string"
+        ],
+        "optional": true
+      }
+    ],
+    "group": "keys"
+  },
   "GET": {
     "summary": "Get the object of an id",
     "complexity": "O(1)",
@@ -448,6 +845,19 @@ var commandsJSON = `{
         "type": [],
         "optional": true
       },
+      {
+        "command": "FIELDS",
+        "name": [
+          "count",
+          "field"
+        ],
+        "type": [
+          "integer",
+          "string"
+        ],
+        "optional": true,
+        "variadic": true
+      },
       {
         "name": "type",
         "optional": true,
@@ -469,6 +879,15 @@ var commandsJSON = `{
                 "type": "geohash"
               }
             ]
+          },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "resolution",
+                "type": "integer"
+              }
+            ]
           }
         ]
       }
@@ -567,6 +986,38 @@ var commandsJSON = `{
     "since": "1.0.0",
     "group": "keys"
   },
+  "SETINDEX": {
+    "summary": "Sets the spatial index of a key to 2D or 3D",
+    "complexity": "O(N) where N is the number of objects in the key",
+    "arguments": [
+      {
+        "name": "key",
+        "type": "string"
+      },
+      {
+        "name": "dims",
+        "type": "enum",
+        "enum": ["2D", "3D"]
+      }
+    ],
+    "group": "keys"
+  },
+  "SETMOTION": {
+    "summary": "Derives the speed, heading and acceleration of the objects in a key",
+    "complexity": "O(1)",
+    "arguments": [
+      {
+        "name": "key",
+        "type": "string"
+      },
+      {
+        "name": "motion",
+        "type": "enum",
+        "enum": ["ON", "OFF"]
+      }
+    ],
+    "group": "keys"
+  },
   "SEARCH": {
     "summary": "Search for string values in a key",
     "complexity": "O(N) where N is the number of values in the key",
@@ -577,8 +1028,8 @@ var commandsJSON = `{
       },
       {
         "command": "CURSOR",
-        "name": "start",
-        "type": "integer",
+        "name": "start|token",
+        "type": "string",
         "optional": true
       },
       {
@@ -636,12 +1087,37 @@ var commandsJSON = `{
         "multiple": true,
         "variadic": true
       },
+      {
+        "command": "FIELDS",
+        "name": [
+          "count",
+          "field"
+        ],
+        "type": [
+          "integer",
+          "string"
+        ],
+        "optional": true,
+        "variadic": true
+      },
       {
         "command": "NOFIELDS",
         "name": [],
         "type": [],
         "optional": true
       },
+      {
+        "command": "AREA",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
+      {
+        "command": "LENGTH",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
       {
         "name": "type",
         "optional": true,
@@ -668,8 +1144,8 @@ var commandsJSON = `{
       },
       {
         "command": "CURSOR",
-        "name": "start",
-        "type": "integer",
+        "name": "start|token",
+        "type": "string",
         "optional": true
       },
       {
@@ -727,12 +1203,64 @@ var commandsJSON = `{
         "multiple": true,
         "variadic": true
       },
+      {
+        "name": "orderby",
+        "optional": true,
+        "enumargs": [
+          {
+            "name": "ORDERBY",
+            "arguments": [
+              {
+                "name": "field",
+                "type": "string"
+              },
+              {
+                "name": "direction",
+                "optional": true,
+                "enumargs": [
+                  {
+                    "name": "ASC"
+                  },
+                  {
+                    "name": "DESC"
+                  }
+                ]
+              }
+            ]
+          }
+        ]
+      },
+      {
+        "command": "FIELDS",
+        "name": [
+          "count",
+          "field"
+        ],
+        "type": [
+          "integer",
+          "string"
+        ],
+        "optional": true,
+        "variadic": true
+      },
       {
         "command": "NOFIELDS",
         "name": [],
         "type": [],
         "optional": true
       },
+      {
+        "command": "AREA",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
+      {
+        "command": "LENGTH",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
       {
         "name": "type",
         "optional": true,
@@ -760,6 +1288,228 @@ var commandsJSON = `{
                 "type": "integer"
               }
             ]
+          },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "resolution",
+                "type": "integer"
+              }
+            ]
+          },
+          {
+            "name": "S2CELLS",
+            "arguments": [
+              {
+                "name": "level",
+                "type": "integer"
+              }
+            ]
+          },
+          {
+            "name": "AGGREGATE",
+            "arguments": [
+              {
+                "command": "GROUPBY",
+                "name": "field",
+                "type": "string",
+                "optional": true
+              },
+              {
+                "name": "function",
+                "multiple": true,
+                "enumargs": [
+                  {
+                    "name": "COUNT"
+                  },
+                  {
+                    "name": "SUM",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "AVG",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MIN",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MAX",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "PERCENTILE",
+                    "arguments": [
+                      {
+                        "name": "percentile",
+                        "type": "double"
+                      },
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "GRID",
+            "arguments": [
+              {
+                "name": "system",
+                "enumargs": [
+                  {
+                    "name": "HASH",
+                    "arguments": [
+                      {
+                        "name": "precision",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "QUADKEY",
+                    "arguments": [
+                      {
+                        "name": "level",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "TILE",
+                    "arguments": [
+                      {
+                        "name": "zoom",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "H3",
+                    "arguments": [
+                      {
+                        "name": "resolution",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "S2",
+                    "arguments": [
+                      {
+                        "name": "level",
+                        "type": "integer"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "function",
+                "optional": true,
+                "multiple": true,
+                "enumargs": [
+                  {
+                    "name": "COUNT"
+                  },
+                  {
+                    "name": "SUM",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "AVG",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MIN",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MAX",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "PERCENTILE",
+                    "arguments": [
+                      {
+                        "name": "percentile",
+                        "type": "double"
+                      },
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "CLUSTER",
+            "arguments": [
+              {
+                "name": "zoom",
+                "type": "integer"
+              },
+              {
+                "command": "RADIUS",
+                "name": "pixels",
+                "type": "double",
+                "optional": true
+              },
+              {
+                "command": "MEMBERS",
+                "name": [],
+                "type": [],
+                "optional": true
+              }
+            ]
           }
         ]
       }
@@ -777,8 +1527,8 @@ var commandsJSON = `{
       },
       {
         "command": "CURSOR",
-        "name": "start",
-        "type": "integer",
+        "name": "start|token",
+        "type": "string",
         "optional": true
       },
       {
@@ -831,16 +1581,68 @@ var commandsJSON = `{
         "variadic": true
       },
       {
-        "command": "NOFIELDS",
-        "name": [],
-        "type": [],
-        "optional": true
-      },
-      {
-        "command": "FENCE",
-        "name": [],
-        "type": [],
-        "optional": true
+        "name": "orderby",
+        "optional": true,
+        "enumargs": [
+          {
+            "name": "ORDERBY",
+            "arguments": [
+              {
+                "name": "field",
+                "type": "string"
+              },
+              {
+                "name": "direction",
+                "optional": true,
+                "enumargs": [
+                  {
+                    "name": "ASC"
+                  },
+                  {
+                    "name": "DESC"
+                  }
+                ]
+              }
+            ]
+          }
+        ]
+      },
+      {
+        "command": "FIELDS",
+        "name": [
+          "count",
+          "field"
+        ],
+        "type": [
+          "integer",
+          "string"
+        ],
+        "optional": true,
+        "variadic": true
+      },
+      {
+        "command": "NOFIELDS",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
+      {
+        "command": "AREA",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
+      {
+        "command": "LENGTH",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
+      {
+        "command": "FENCE",
+        "name": [],
+        "type": [],
+        "optional": true
       },
       {
         "command": "DETECT",
@@ -881,6 +1683,228 @@ var commandsJSON = `{
                 "type": "integer"
               }
             ]
+          },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "resolution",
+                "type": "integer"
+              }
+            ]
+          },
+          {
+            "name": "S2CELLS",
+            "arguments": [
+              {
+                "name": "level",
+                "type": "integer"
+              }
+            ]
+          },
+          {
+            "name": "AGGREGATE",
+            "arguments": [
+              {
+                "command": "GROUPBY",
+                "name": "field",
+                "type": "string",
+                "optional": true
+              },
+              {
+                "name": "function",
+                "multiple": true,
+                "enumargs": [
+                  {
+                    "name": "COUNT"
+                  },
+                  {
+                    "name": "SUM",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "AVG",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MIN",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MAX",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "PERCENTILE",
+                    "arguments": [
+                      {
+                        "name": "percentile",
+                        "type": "double"
+                      },
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "GRID",
+            "arguments": [
+              {
+                "name": "system",
+                "enumargs": [
+                  {
+                    "name": "HASH",
+                    "arguments": [
+                      {
+                        "name": "precision",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "QUADKEY",
+                    "arguments": [
+                      {
+                        "name": "level",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "TILE",
+                    "arguments": [
+                      {
+                        "name": "zoom",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "H3",
+                    "arguments": [
+                      {
+                        "name": "resolution",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "S2",
+                    "arguments": [
+                      {
+                        "name": "level",
+                        "type": "integer"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "function",
+                "optional": true,
+                "multiple": true,
+                "enumargs": [
+                  {
+                    "name": "COUNT"
+                  },
+                  {
+                    "name": "SUM",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "AVG",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MIN",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MAX",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "PERCENTILE",
+                    "arguments": [
+                      {
+                        "name": "percentile",
+                        "type": "double"
+                      },
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "CLUSTER",
+            "arguments": [
+              {
+                "name": "zoom",
+                "type": "integer"
+              },
+              {
+                "command": "RADIUS",
+                "name": "pixels",
+                "type": "double",
+                "optional": true
+              },
+              {
+                "command": "MEMBERS",
+                "name": [],
+                "type": [],
+                "optional": true
+              }
+            ]
           }
         ]
       },
@@ -904,6 +1928,27 @@ var commandsJSON = `{
               }
             ]
           },
+          {
+            "name": "POINTZ",
+            "arguments": [
+              {
+                "name": "lat",
+                "type": "double"
+              },
+              {
+                "name": "lon",
+                "type": "double"
+              },
+              {
+                "name": "z",
+                "type": "double"
+              },
+              {
+                "name": "meters",
+                "type": "double"
+              }
+            ]
+          },
           {
             "name": "ROAM",
             "arguments": [
@@ -922,6 +1967,12 @@ var commandsJSON = `{
             ]
           }
         ]
+      },
+      {
+        "command": "ZRANGE",
+        "name": ["min", "max"],
+        "type": ["double", "double"],
+        "optional": true
       }
     ],
     "since": "1.0.0",
@@ -937,8 +1988,8 @@ var commandsJSON = `{
       },
       {
         "command": "CURSOR",
-        "name": "start",
-        "type": "integer",
+        "name": "start|token",
+        "type": "string",
         "optional": true
       },
       {
@@ -984,12 +2035,64 @@ var commandsJSON = `{
         "multiple": true,
         "variadic": true
       },
+      {
+        "name": "orderby",
+        "optional": true,
+        "enumargs": [
+          {
+            "name": "ORDERBY",
+            "arguments": [
+              {
+                "name": "field",
+                "type": "string"
+              },
+              {
+                "name": "direction",
+                "optional": true,
+                "enumargs": [
+                  {
+                    "name": "ASC"
+                  },
+                  {
+                    "name": "DESC"
+                  }
+                ]
+              }
+            ]
+          }
+        ]
+      },
+      {
+        "command": "FIELDS",
+        "name": [
+          "count",
+          "field"
+        ],
+        "type": [
+          "integer",
+          "string"
+        ],
+        "optional": true,
+        "variadic": true
+      },
       {
         "command": "NOFIELDS",
         "name": [],
         "type": [],
         "optional": true
       },
+      {
+        "command": "AREA",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
+      {
+        "command": "LENGTH",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
       {
         "command": "FENCE",
         "name": [],
@@ -1035,6 +2138,228 @@ var commandsJSON = `{
                 "type": "integer"
               }
             ]
+          },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "resolution",
+                "type": "integer"
+              }
+            ]
+          },
+          {
+            "name": "S2CELLS",
+            "arguments": [
+              {
+                "name": "level",
+                "type": "integer"
+              }
+            ]
+          },
+          {
+            "name": "AGGREGATE",
+            "arguments": [
+              {
+                "command": "GROUPBY",
+                "name": "field",
+                "type": "string",
+                "optional": true
+              },
+              {
+                "name": "function",
+                "multiple": true,
+                "enumargs": [
+                  {
+                    "name": "COUNT"
+                  },
+                  {
+                    "name": "SUM",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "AVG",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MIN",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MAX",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "PERCENTILE",
+                    "arguments": [
+                      {
+                        "name": "percentile",
+                        "type": "double"
+                      },
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "GRID",
+            "arguments": [
+              {
+                "name": "system",
+                "enumargs": [
+                  {
+                    "name": "HASH",
+                    "arguments": [
+                      {
+                        "name": "precision",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "QUADKEY",
+                    "arguments": [
+                      {
+                        "name": "level",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "TILE",
+                    "arguments": [
+                      {
+                        "name": "zoom",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "H3",
+                    "arguments": [
+                      {
+                        "name": "resolution",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "S2",
+                    "arguments": [
+                      {
+                        "name": "level",
+                        "type": "integer"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "function",
+                "optional": true,
+                "multiple": true,
+                "enumargs": [
+                  {
+                    "name": "COUNT"
+                  },
+                  {
+                    "name": "SUM",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "AVG",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MIN",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MAX",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "PERCENTILE",
+                    "arguments": [
+                      {
+                        "name": "percentile",
+                        "type": "double"
+                      },
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "CLUSTER",
+            "arguments": [
+              {
+                "name": "zoom",
+                "type": "integer"
+              },
+              {
+                "command": "RADIUS",
+                "name": "pixels",
+                "type": "double",
+                "optional": true
+              },
+              {
+                "command": "MEMBERS",
+                "name": [],
+                "type": [],
+                "optional": true
+              }
+            ]
           }
         ]
       },
@@ -1136,6 +2461,60 @@ var commandsJSON = `{
               }
             ]
           },          
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "cell",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "S2",
+            "arguments": [
+              {
+                "name": "token",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "CORRIDOR",
+            "arguments": [
+              {
+                "name": "route",
+                "enumargs": [
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "meters",
+                "type": "double"
+              }
+            ]
+          },
           {
             "name": "SECTOR",
             "arguments": [
@@ -1162,6 +2541,12 @@ var commandsJSON = `{
             ]
           }
         ]
+      },
+      {
+        "command": "ZRANGE",
+        "name": ["min", "max"],
+        "type": ["double", "double"],
+        "optional": true
       }
     ],
     "since": "1.0.0",
@@ -1177,8 +2562,8 @@ var commandsJSON = `{
       },
       {
         "command": "CURSOR",
-        "name": "start",
-        "type": "integer",
+        "name": "start|token",
+        "type": "string",
         "optional": true
       },
       {
@@ -1230,12 +2615,64 @@ var commandsJSON = `{
         "type": [],
         "optional": true
       },
+      {
+        "name": "orderby",
+        "optional": true,
+        "enumargs": [
+          {
+            "name": "ORDERBY",
+            "arguments": [
+              {
+                "name": "field",
+                "type": "string"
+              },
+              {
+                "name": "direction",
+                "optional": true,
+                "enumargs": [
+                  {
+                    "name": "ASC"
+                  },
+                  {
+                    "name": "DESC"
+                  }
+                ]
+              }
+            ]
+          }
+        ]
+      },
+      {
+        "command": "FIELDS",
+        "name": [
+          "count",
+          "field"
+        ],
+        "type": [
+          "integer",
+          "string"
+        ],
+        "optional": true,
+        "variadic": true
+      },
       {
         "command": "NOFIELDS",
         "name": [],
         "type": [],
         "optional": true
       },
+      {
+        "command": "AREA",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
+      {
+        "command": "LENGTH",
+        "name": [],
+        "type": [],
+        "optional": true
+      },
       {
         "command": "FENCE",
         "name": [],
@@ -1281,27 +2718,249 @@ var commandsJSON = `{
                 "type": "integer"
               }
             ]
-          }
-        ]
-      },
-      {
-        "name": "area",
-        "enumargs": [
+          },
           {
-            "name": "GET",
+            "name": "H3",
             "arguments": [
               {
-                "name": "key",
-                "type": "string"
-              },
-              {
-                "name": "id",
-                "type": "string"
+                "name": "resolution",
+                "type": "integer"
               }
             ]
           },
           {
-            "name": "BOUNDS",
+            "name": "S2CELLS",
+            "arguments": [
+              {
+                "name": "level",
+                "type": "integer"
+              }
+            ]
+          },
+          {
+            "name": "AGGREGATE",
+            "arguments": [
+              {
+                "command": "GROUPBY",
+                "name": "field",
+                "type": "string",
+                "optional": true
+              },
+              {
+                "name": "function",
+                "multiple": true,
+                "enumargs": [
+                  {
+                    "name": "COUNT"
+                  },
+                  {
+                    "name": "SUM",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "AVG",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MIN",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MAX",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "PERCENTILE",
+                    "arguments": [
+                      {
+                        "name": "percentile",
+                        "type": "double"
+                      },
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "GRID",
+            "arguments": [
+              {
+                "name": "system",
+                "enumargs": [
+                  {
+                    "name": "HASH",
+                    "arguments": [
+                      {
+                        "name": "precision",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "QUADKEY",
+                    "arguments": [
+                      {
+                        "name": "level",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "TILE",
+                    "arguments": [
+                      {
+                        "name": "zoom",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "H3",
+                    "arguments": [
+                      {
+                        "name": "resolution",
+                        "type": "integer"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "S2",
+                    "arguments": [
+                      {
+                        "name": "level",
+                        "type": "integer"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "function",
+                "optional": true,
+                "multiple": true,
+                "enumargs": [
+                  {
+                    "name": "COUNT"
+                  },
+                  {
+                    "name": "SUM",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "AVG",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MIN",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "MAX",
+                    "arguments": [
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "PERCENTILE",
+                    "arguments": [
+                      {
+                        "name": "percentile",
+                        "type": "double"
+                      },
+                      {
+                        "name": "field",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          },
+          {
+            "name": "CLUSTER",
+            "arguments": [
+              {
+                "name": "zoom",
+                "type": "integer"
+              },
+              {
+                "command": "RADIUS",
+                "name": "pixels",
+                "type": "double",
+                "optional": true
+              },
+              {
+                "command": "MEMBERS",
+                "name": [],
+                "type": [],
+                "optional": true
+              }
+            ]
+          }
+        ]
+      },
+      {
+        "name": "area",
+        "enumargs": [
+          {
+            "name": "GET",
+            "arguments": [
+              {
+                "name": "key",
+                "type": "string"
+              },
+              {
+                "name": "id",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "BOUNDS",
             "arguments": [
               {
                 "name": "minlat",
@@ -1382,6 +3041,60 @@ var commandsJSON = `{
               }
             ]
           },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "cell",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "S2",
+            "arguments": [
+              {
+                "name": "token",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "CORRIDOR",
+            "arguments": [
+              {
+                "name": "route",
+                "enumargs": [
+                  {
+                    "name": "OBJECT",
+                    "arguments": [
+                      {
+                        "name": "geojson",
+                        "type": "geojson"
+                      }
+                    ]
+                  },
+                  {
+                    "name": "GET",
+                    "arguments": [
+                      {
+                        "name": "key",
+                        "type": "string"
+                      },
+                      {
+                        "name": "id",
+                        "type": "string"
+                      }
+                    ]
+                  }
+                ]
+              },
+              {
+                "name": "meters",
+                "type": "double"
+              }
+            ]
+          },
           {
             "name": "SECTOR",
             "arguments": [
@@ -1408,6 +3121,12 @@ var commandsJSON = `{
             ]
           }
         ]
+      },
+      {
+        "command": "ZRANGE",
+        "name": ["min", "max"],
+        "type": ["double", "double"],
+        "optional": true
       }
     ],
     "since": "1.0.0",
@@ -1469,12 +3188,38 @@ var commandsJSON = `{
     "group": "server"
   },
   "FLUSHDB": {
-    "summary": "Removes all keys",
+    "summary": "Removes all keys from the selected database",
     "complexity": "O(1)",
     "arguments": [],
     "since": "1.0.0",
     "group": "server"
   },
+  "SELECT": {
+    "summary": "Selects the logical database for the current connection",
+    "complexity": "O(1)",
+    "arguments": [
+      {
+        "name": "db",
+        "type": "string"
+      }
+    ],
+    "group": "server"
+  },
+  "SWAPDB": {
+    "summary": "Swaps the keys of two logical databases",
+    "complexity": "O(1)",
+    "arguments": [
+      {
+        "name": "db1",
+        "type": "string"
+      },
+      {
+        "name": "db2",
+        "type": "string"
+      }
+    ],
+    "group": "server"
+  },
   "FOLLOW": {
     "summary": "Follows a leader host",
     "complexity": "O(1)",
@@ -1604,6 +3349,83 @@ var commandsJSON = `{
         "optional": true,
         "multiple": false
       },
+      {
+        "command": "SCHEDULE",
+        "name": ["window"],
+        "type": ["string"],
+        "optional": true,
+        "multiple": true
+      },
+      {
+        "command": "ACTIVE",
+        "name": ["from", "to"],
+        "type": ["string", "string"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "RETRY",
+        "name": ["attempts"],
+        "type": ["integer"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "BACKOFF",
+        "name": ["seconds", "max_seconds"],
+        "type": ["double", "double"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "TTL",
+        "name": ["seconds"],
+        "type": ["double"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "DEADLETTER",
+        "name": ["max", "seconds"],
+        "type": ["integer", "double"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "TEMPLATE",
+        "name": ["template"],
+        "type": ["string"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "BATCH",
+        "name": ["max_events", "max_bytes", "max_latency"],
+        "type": ["integer", "integer", "double"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "SECRET",
+        "name": ["secret"],
+        "type": ["string"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "BEARER",
+        "name": ["token"],
+        "type": ["string"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "HEADER",
+        "name": ["name", "value"],
+        "type": ["string", "string"],
+        "optional": true,
+        "multiple": true
+      },
       {
         "enum": ["NEARBY", "WITHIN", "INTERSECTS"]
       },
@@ -1622,6 +3444,18 @@ var commandsJSON = `{
         "type": ["string"],
         "optional": true
       },
+      {
+        "command": "DWELL",
+        "name": ["seconds"],
+        "type": ["double"],
+        "optional": true
+      },
+      {
+        "command": "LOITER",
+        "name": ["seconds", "meters"],
+        "type": ["double", "double"],
+        "optional": true
+      },
       {
         "command": "COMMANDS",
         "name": ["which"],
@@ -1656,6 +3490,46 @@ var commandsJSON = `{
     ],
     "group": "webhook"
   },
+  "DEADLETTER LIST": {
+    "summary": "Lists the dead letters of the hooks matching a pattern",
+    "arguments": [
+      {
+        "name": "pattern",
+        "type": "pattern"
+      }
+    ],
+    "group": "webhook"
+  },
+  "DEADLETTER REPLAY": {
+    "summary": "Queues the dead letters of the hooks matching a pattern again",
+    "arguments": [
+      {
+        "name": "pattern",
+        "type": "pattern"
+      }
+    ],
+    "group": "webhook"
+  },
+  "DEADLETTER PURGE": {
+    "summary": "Removes the dead letters of the hooks matching a pattern",
+    "arguments": [
+      {
+        "name": "pattern",
+        "type": "pattern"
+      }
+    ],
+    "group": "webhook"
+  },
+  "HOOKSTATS": {
+    "summary": "Returns the delivery statistics of the hooks matching a pattern",
+    "arguments": [
+      {
+        "name": "pattern",
+        "type": "pattern"
+      }
+    ],
+    "group": "webhook"
+  },
   "PDELHOOK": {
     "summary": "Removes all hooks matching a pattern",
     "arguments": [
@@ -1688,6 +3562,27 @@ var commandsJSON = `{
         "optional": true,
         "multiple": false
       },
+      {
+        "command": "SCHEDULE",
+        "name": ["window"],
+        "type": ["string"],
+        "optional": true,
+        "multiple": true
+      },
+      {
+        "command": "ACTIVE",
+        "name": ["from", "to"],
+        "type": ["string", "string"],
+        "optional": true,
+        "multiple": false
+      },
+      {
+        "command": "TEMPLATE",
+        "name": ["template"],
+        "type": ["string"],
+        "optional": true,
+        "multiple": false
+      },
       {
         "enum": ["NEARBY", "WITHIN", "INTERSECTS"]
       },
@@ -1706,6 +3601,18 @@ var commandsJSON = `{
         "type": ["string"],
         "optional": true
       },
+      {
+        "command": "DWELL",
+        "name": ["seconds"],
+        "type": ["double"],
+        "optional": true
+      },
+      {
+        "command": "LOITER",
+        "name": ["seconds", "meters"],
+        "type": ["double", "double"],
+        "optional": true
+      },
       {
         "command": "COMMANDS",
         "name": ["which"],
@@ -1865,40 +3772,114 @@ var commandsJSON = `{
     ],
     "group": "keys"
   },
-  "EVAL": {
-    "summary": "Evaluates a Lua script",
-    "complexity": "Depends on the evaluated script",
+  "SETSCHEMA": {
+    "summary": "Sets the field schema for a key",
+    "complexity": "O(1)",
     "arguments": [
       {
-        "name": "script",
+        "name": "key",
         "type": "string"
       },
       {
-        "name": "numkeys",
-        "type": "integer"
+        "command": "FIELD",
+        "name": ["name", "kind"],
+        "type": ["string", "string"],
+        "multiple": true
       },
       {
-        "name": "key",
-        "type": "string",
-        "optional": true,
-        "multiple": true
+        "command": "REQUIRED",
+        "name": [],
+        "type": [],
+        "optional": true
       },
       {
-        "name": "arg",
-        "type": "string",
-        "optional": true,
-        "multiple": true
+        "command": "MIN",
+        "name": ["value"],
+        "type": ["double"],
+        "optional": true
+      },
+      {
+        "command": "MAX",
+        "name": ["value"],
+        "type": ["double"],
+        "optional": true
       }
     ],
-    "since": "1.10.0",
-    "group": "scripting"
+    "group": "keys"
   },
-  "EVALSHA": {
-    "summary": "Evaluates a Lua script cached on the server by its SHA1 digest",
-    "complexity": "Depends on the evaluated script",
+  "DELSCHEMA": {
+    "summary": "Removes the field schema for a key",
+    "complexity": "O(1)",
     "arguments": [
       {
-        "name": "sha1",
+        "name": "key",
+        "type": "string"
+      }
+    ],
+    "group": "keys"
+  },
+  "SCHEMAS": {
+    "summary": "Finds all schemas matching a pattern",
+    "arguments": [
+      {
+        "name": "pattern",
+        "type": "pattern"
+      }
+    ],
+    "group": "keys"
+  },
+  "VALIDATE": {
+    "summary": "Validates the objects of a key against a schema",
+    "complexity": "O(N) where N is the number of ids in the key",
+    "arguments": [
+      {
+        "name": "key",
+        "type": "string"
+      },
+      {
+        "command": "FIELD",
+        "name": ["name", "kind"],
+        "type": ["string", "string"],
+        "optional": true,
+        "multiple": true
+      }
+    ],
+    "group": "keys"
+  },
+  "EVAL": {
+    "summary": "Evaluates a Lua script",
+    "complexity": "Depends on the evaluated script",
+    "arguments": [
+      {
+        "name": "script",
+        "type": "string"
+      },
+      {
+        "name": "numkeys",
+        "type": "integer"
+      },
+      {
+        "name": "key",
+        "type": "string",
+        "optional": true,
+        "multiple": true
+      },
+      {
+        "name": "arg",
+        "type": "string",
+        "optional": true,
+        "multiple": true
+      }
+    ],
+    "since": "1.10.0",
+    "group": "scripting"
+  },
+  "EVALSHA": {
+    "summary": "Evaluates a Lua script cached on the server by its SHA1 digest",
+    "complexity": "Depends on the evaluated script",
+    "arguments": [
+      {
+        "name": "sha1",
         "type": "string"
       },
       {
@@ -2178,6 +4159,24 @@ var commandsJSON = `{
                 "type": "geohash"
               }
             ]
+          },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "cell",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "S2",
+            "arguments": [
+              {
+                "name": "token",
+                "type": "string"
+              }
+            ]
           }
         ]
       },
@@ -2308,11 +4307,318 @@ var commandsJSON = `{
                 "type": "geohash"
               }
             ]
+          },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "cell",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "S2",
+            "arguments": [
+              {
+                "name": "token",
+                "type": "string"
+              }
+            ]
           }
         ]
       }
     ],
     "since": "1.16.0",
     "group": "tests"
+  },
+  "COVER": {
+    "summary": "Returns the grid cells that cover an area",
+    "complexity": "O(N) where N is the number of cells in the covering",
+    "arguments": [
+      {
+        "name": "system",
+        "enumargs": [
+          {
+            "name": "S2",
+            "arguments": [
+              {
+                "name": "level",
+                "type": "integer"
+              }
+            ]
+          },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "resolution",
+                "type": "integer"
+              }
+            ]
+          },
+          {
+            "name": "HASH",
+            "arguments": [
+              {
+                "name": "precision",
+                "type": "integer"
+              }
+            ]
+          }
+        ]
+      },
+      {
+        "command": "MAXCELLS",
+        "name": "count",
+        "type": "integer",
+        "optional": true
+      },
+      {
+        "name": "area",
+        "enumargs": [
+          {
+            "name": "POINT",
+            "arguments": [
+              {
+                "name": "lat",
+                "type": "double"
+              },
+              {
+                "name": "lon",
+                "type": "double"
+              }
+            ]
+          },
+          {
+            "name": "GET",
+            "arguments": [
+              {
+                "name": "key",
+                "type": "string"
+              },
+              {
+                "name": "id",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "BOUNDS",
+            "arguments": [
+              {
+                "name": "minlat",
+                "type": "double"
+              },
+              {
+                "name": "minlon",
+                "type": "double"
+              },
+              {
+                "name": "maxlat",
+                "type": "double"
+              },
+              {
+                "name": "maxlon",
+                "type": "double"
+              }
+            ]
+          },
+          {
+            "name": "OBJECT",
+            "arguments": [
+              {
+                "name": "geojson",
+                "type": "geojson"
+              }
+            ]
+          },
+          {
+            "name": "CIRCLE",
+            "arguments": [
+              {
+                "name": "lat",
+                "type": "double"
+              },
+              {
+                "name": "lon",
+                "type": "double"
+              },
+              {
+                "name": "meters",
+                "type": "double"
+              }
+            ]
+          },
+          {
+            "name": "TILE",
+            "arguments": [
+              {
+                "name": "x",
+                "type": "double"
+              },
+              {
+                "name": "y",
+                "type": "double"
+              },
+              {
+                "name": "z",
+                "type": "double"
+              }
+            ]
+          },
+          {
+            "name": "QUADKEY",
+            "arguments": [
+              {
+                "name": "quadkey",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "HASH",
+            "arguments": [
+              {
+                "name": "geohash",
+                "type": "geohash"
+              }
+            ]
+          },
+          {
+            "name": "H3",
+            "arguments": [
+              {
+                "name": "cell",
+                "type": "string"
+              }
+            ]
+          },
+          {
+            "name": "S2",
+            "arguments": [
+              {
+                "name": "token",
+                "type": "string"
+              }
+            ]
+          }
+        ]
+      }
+    ],
+    "group": "search"
+  },
+  "JOIN": {
+    "summary": "Returns the objects in a key that match objects in another key, and the count of the matching pairs",
+    "complexity": "O(N+M+P) where N and M are the number of ids in the first and second key and P is the number of pairs whose bounding boxes intersect",
+    "arguments": [
+      {
+        "name": "keyA",
+        "type": "string"
+      },
+      {
+        "name": "keyB",
+        "type": "string"
+      },
+      {
+        "name": "relation",
+        "enumargs": [
+          {
+            "name": "INTERSECTS"
+          },
+          {
+            "name": "WITHIN"
+          },
+          {
+            "name": "NEARBY",
+            "arguments": [
+              {
+                "name": "meters",
+                "type": "double"
+              }
+            ]
+          }
+        ]
+      },
+      {
+        "command": "CURSOR",
+        "name": "start",
+        "type": "integer",
+        "optional": true
+      },
+      {
+        "command": "LIMIT",
+        "name": "count",
+        "type": "integer",
+        "optional": true
+      },
+      {
+        "command": "MATCH",
+        "name": "pattern",
+        "type": "pattern",
+        "optional": true
+      },
+      {
+        "command": "WHERE",
+        "name": ["field", "min", "max"],
+        "type": ["string", "double", "double"],
+        "optional": true,
+        "multiple": true
+      },
+      {
+        "command": "WHEREIN",
+        "name": ["field", "count", "value"],
+        "type": ["string", "integer", "double"],
+        "optional": true,
+        "multiple": true,
+        "variadic": true
+      },
+      {
+        "name": "type",
+        "optional": true,
+        "enumargs": [
+          {
+            "name": "COUNT"
+          },
+          {
+            "name": "IDS"
+          }
+        ]
+      }
+    ],
+    "group": "search"
+  },
+  "EXPLAIN": {
+    "summary": "Runs a search command and reports how it found its results",
+    "complexity": "The complexity of the search command",
+    "arguments": [
+      {
+        "name": "command",
+        "enumargs": [
+          {
+            "name": "SCAN"
+          },
+          {
+            "name": "SEARCH"
+          },
+          {
+            "name": "NEARBY"
+          },
+          {
+            "name": "WITHIN"
+          },
+          {
+            "name": "INTERSECTS"
+          }
+        ]
+      },
+      {
+        "command": "arg",
+        "type": "string",
+        "multiple": true,
+        "optional": true
+      }
+    ],
+    "group": "search"
   }
 }`
@@ -17,7 +17,8 @@ require (
 	github.com/tidwall/redbench v0.1.0
 	github.com/tidwall/redcon v1.6.2
 	github.com/tidwall/resp v0.1.1
-	github.com/tidwall/rtree v1.10.0
+	github.com/tidwall/rtred v0.1.2
+	github.com/tidwall/rtree v1.10.0 // indirect
 	github.com/tidwall/sjson v1.2.5
 	github.com/tidwall/tinylru v1.2.1
 )
@@ -29,6 +30,7 @@ require (
 	github.com/aws/aws-sdk-go v1.55.8
 	github.com/cloudflare/cloudflare-go/v4 v4.6.0
 	github.com/eclipse/paho.mqtt.golang v1.5.1
+	github.com/golang/geo v0.0.0-20230421003525-6adc56603217
 	github.com/golang/protobuf v1.5.4
 	github.com/gomodule/redigo v1.9.2
 	github.com/iwpnd/sectr v0.1.2
@@ -106,7 +108,6 @@ require (
 	github.com/tidwall/conv v0.1.0 // indirect
 	github.com/tidwall/geoindex v1.7.0 // indirect
 	github.com/tidwall/grect v0.1.4 // indirect
-	github.com/tidwall/rtred v0.1.2 // indirect
 	github.com/tidwall/tinyqueue v0.1.1 // indirect
 	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
 	github.com/xdg-go/stringprep v1.0.4 // indirect
@@ -5,7 +5,9 @@ import (
 	"github.com/tidwall/geojson/geometry"
 )
 
-// Clip clips the contents of a geojson object and return
+// Clip clips the contents of a geojson object and return. The clipper is a
+// rectangle, or a polygon or multipolygon, otherwise its bounding rectangle
+// is used.
 func Clip(
 	obj geojson.Object, clipper geojson.Object, opts *geometry.IndexOptions,
 ) (clipped geojson.Object) {
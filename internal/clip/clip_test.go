@@ -101,6 +101,144 @@ func TestClipPolygon2(t *testing.T) {
 	}
 }
 
+func polysArea(obj geojson.Object) float64 {
+	var area float64
+	switch obj := obj.(type) {
+	case *geojson.Polygon:
+		area += ringArea(ringPoints(obj.Base().Exterior))
+		for _, hole := range obj.Base().Holes {
+			area += ringArea(ringPoints(hole))
+		}
+	case *geojson.MultiPolygon:
+		for _, child := range obj.Children() {
+			area += polysArea(child)
+		}
+	}
+	return area
+}
+
+func ringPoints(ring geometry.Ring) []geometry.Point {
+	points := make([]geometry.Point, ring.NumPoints())
+	for i := range points {
+		points[i] = ring.PointAt(i)
+	}
+	return points
+}
+
+func TestClipPolygonByConcavePolygon(t *testing.T) {
+	square := PPO([]geometry.Point{
+		{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}, {X: 0, Y: 0},
+	}, nil)
+	// a U that covers the bottom and both sides of the square
+	u := PPO([]geometry.Point{
+		{X: -1, Y: -1}, {X: 5, Y: -1}, {X: 5, Y: 5}, {X: 3, Y: 5},
+		{X: 3, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 5}, {X: -1, Y: 5},
+		{X: -1, Y: -1},
+	}, nil)
+	clipped := Clip(square, u, nil)
+	cp, ok := clipped.(*geojson.Polygon)
+	if !ok {
+		t.Fatalf("wrong type %T", clipped)
+	}
+	if area := polysArea(cp); area != 10 {
+		t.Fatalf("expected area 10, got %v", area)
+	}
+	// a bar across the middle of the U leaves two pieces
+	bar := PPO([]geometry.Point{
+		{X: -2, Y: 2}, {X: 6, Y: 2}, {X: 6, Y: 3}, {X: -2, Y: 3},
+		{X: -2, Y: 2},
+	}, nil)
+	clipped = Clip(bar, u, nil)
+	mp, ok := clipped.(*geojson.MultiPolygon)
+	if !ok {
+		t.Fatalf("wrong type %T", clipped)
+	}
+	if len(mp.Children()) != 2 || polysArea(mp) != 4 {
+		t.Fatalf("expected two parts with area 4, got %s", mp)
+	}
+}
+
+func TestClipPolygonByPolygonWithHoles(t *testing.T) {
+	// a square with a square hole in the middle
+	frame := PPO([]geometry.Point{
+		{X: 0, Y: 0}, {X: 6, Y: 0}, {X: 6, Y: 6}, {X: 0, Y: 6}, {X: 0, Y: 0},
+	}, [][]geometry.Point{{
+		{X: 2, Y: 2}, {X: 2, Y: 4}, {X: 4, Y: 4}, {X: 4, Y: 2}, {X: 2, Y: 2},
+	}})
+	inner := PPO([]geometry.Point{
+		{X: 1, Y: 1}, {X: 5, Y: 1}, {X: 5, Y: 5}, {X: 1, Y: 5}, {X: 1, Y: 1},
+	}, nil)
+	clipped := Clip(inner, frame, nil)
+	cp, ok := clipped.(*geojson.Polygon)
+	if !ok {
+		t.Fatalf("wrong type %T", clipped)
+	}
+	if len(cp.Base().Holes) != 1 || polysArea(cp) != 12 {
+		t.Fatalf("expected a polygon with one hole and area 12, got %s", cp)
+	}
+	// shared edges
+	half := PPO([]geometry.Point{
+		{X: 3, Y: 0}, {X: 9, Y: 0}, {X: 9, Y: 6}, {X: 3, Y: 6}, {X: 3, Y: 0},
+	}, nil)
+	clipped = Clip(frame, half, nil)
+	if area := polysArea(clipped); area != 16 {
+		t.Fatalf("expected area 16, got %v for %s", area, clipped)
+	}
+	clipped = Clip(frame, frame, nil)
+	if area := polysArea(clipped); area != 32 {
+		t.Fatalf("expected area 32, got %v for %s", area, clipped)
+	}
+}
+
+func TestClipByMultiPolygon(t *testing.T) {
+	clipper := geojson.NewMultiPolygon([]*geometry.Poly{
+		geometry.NewPoly([]geometry.Point{
+			{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}, {X: 0, Y: 0},
+		}, nil, nil),
+		geometry.NewPoly([]geometry.Point{
+			{X: 2, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 0},
+		}, nil, nil),
+	})
+	clipped := Clip(RO(0.5, 0.5, 2.5, 2), clipper, nil)
+	if area := polysArea(clipped); area != 0.5 {
+		t.Fatalf("expected area 0.5, got %v for %s", area, clipped)
+	}
+	ls := LO([]geometry.Point{{X: -1, Y: 0.5}, {X: 4, Y: 0.5}})
+	clipped = Clip(ls, clipper, nil)
+	if clipped.String() != `{"type":"MultiLineString","coordinates":`+
+		`[[[0,0.5],[1,0.5]],[[2,0.5],[3,0.5]]]}` {
+		t.Fatalf("got %s", clipped)
+	}
+	if !Clip(geojson.NewPoint(geometry.Point{X: 2.5, Y: 0.5}), clipper,
+		nil).(*geojson.Point).Valid() {
+		t.Fatal("point must be kept")
+	}
+	if !Clip(geojson.NewPoint(geometry.Point{X: 1.5, Y: 0.5}), clipper,
+		nil).Empty() {
+		t.Fatal("point must be removed")
+	}
+}
+
+func TestClipLineStringByPolygonWithHole(t *testing.T) {
+	frame := PPO([]geometry.Point{
+		{X: 0, Y: 0}, {X: 6, Y: 0}, {X: 6, Y: 6}, {X: 0, Y: 6}, {X: 0, Y: 0},
+	}, [][]geometry.Point{{
+		{X: 2, Y: 2}, {X: 2, Y: 4}, {X: 4, Y: 4}, {X: 4, Y: 2}, {X: 2, Y: 2},
+	}})
+	ls := LO([]geometry.Point{{X: -1, Y: 3}, {X: 7, Y: 3}, {X: 7, Y: 5}})
+	clipped := Clip(ls, frame, nil)
+	if clipped.String() != `{"type":"MultiLineString","coordinates":`+
+		`[[[0,3],[2,3]],[[4,3],[6,3]]]}` {
+		t.Fatalf("got %s", clipped)
+	}
+	// along an edge
+	ls = LO([]geometry.Point{{X: 1, Y: 0}, {X: 5, Y: 0}, {X: 5, Y: -1}})
+	clipped = Clip(ls, frame, nil)
+	if clipped.String() != `{"type":"LineString","coordinates":[[1,0],[5,0]]}` {
+		t.Fatalf("got %s", clipped)
+	}
+}
+
 // func TestClipLineString(t *testing.T) {
 // 	featuresJSON := `
 // 		{"type": "FeatureCollection","features": [
@@ -0,0 +1,520 @@
+package clip
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geometry"
+	"github.com/tidwall/tile38/internal/rtree"
+)
+
+// Polygonal returns true when an object can be used as a clipper. That is a
+// rectangle, a polygon, a multipolygon, or a feature of one of them.
+func Polygonal(clipper geojson.Object) bool {
+	if _, ok := clipper.(*geojson.Rect); ok {
+		return true
+	}
+	return len(clipperPolys(clipper)) > 0
+}
+
+// clipperPolys returns the polygons of a clipper, or nil when the clipper
+// should be treated as a rectangle.
+func clipperPolys(clipper geojson.Object) []*geometry.Poly {
+	switch clipper := clipper.(type) {
+	case *geojson.Polygon:
+		return []*geometry.Poly{clipper.Base()}
+	case *geojson.MultiPolygon:
+		var polys []*geometry.Poly
+		for _, child := range clipper.Children() {
+			if child, ok := child.(*geojson.Polygon); ok {
+				polys = append(polys, child.Base())
+			}
+		}
+		return polys
+	case *geojson.Feature:
+		return clipperPolys(clipper.Base())
+	}
+	return nil
+}
+
+// region is the area of one or more polygons, with their holes.
+type region struct {
+	polys [][]geometry.Segment // the segments of all rings of each polygon
+	trees []*rtree.RTreeGN[float64, int]
+}
+
+func newRegion(polys []*geometry.Poly) *region {
+	r := &region{}
+	for _, poly := range polys {
+		segs := polySegments(poly)
+		r.polys = append(r.polys, segs)
+		r.trees = append(r.trees, segmentTree(segs))
+	}
+	return r
+}
+
+// segmentTree indexes segments by their position in segs.
+func segmentTree(segs []geometry.Segment) *rtree.RTreeGN[float64, int] {
+	tr := &rtree.RTreeGN[float64, int]{}
+	for i, seg := range segs {
+		rect := seg.Rect()
+		tr.Insert([2]float64{rect.Min.X, rect.Min.Y},
+			[2]float64{rect.Max.X, rect.Max.Y}, i)
+	}
+	return tr
+}
+
+func polySegments(poly *geometry.Poly) []geometry.Segment {
+	var segs []geometry.Segment
+	rings := append([]geometry.Ring{poly.Exterior}, poly.Holes...)
+	for _, ring := range rings {
+		for i := 0; i < ring.NumSegments(); i++ {
+			if seg := ring.SegmentAt(i); seg.A != seg.B {
+				segs = append(segs, seg)
+			}
+		}
+	}
+	return segs
+}
+
+// segments returns the segments of all polygons in the region.
+func (r *region) segments() []geometry.Segment {
+	var segs []geometry.Segment
+	for _, poly := range r.polys {
+		segs = append(segs, poly...)
+	}
+	return segs
+}
+
+// contains returns true when a point is in the interior of the region.
+// Points on an edge may go either way.
+func (r *region) contains(p geometry.Point) bool {
+	for i, segs := range r.polys {
+		min, max := r.trees[i].Bounds()
+		if p.X < min[0] || p.Y < min[1] || p.X > max[0] || p.Y > max[1] {
+			continue
+		}
+		// cast the ray towards the nearest side, and only the segments that
+		// are on the way can cross it
+		dir := rayRight
+		best := max[0] - p.X
+		if d := p.X - min[0]; d < best {
+			dir, best = rayLeft, d
+		}
+		if d := max[1] - p.Y; d < best {
+			dir, best = rayUp, d
+		}
+		if d := p.Y - min[1]; d < best {
+			dir = rayDown
+		}
+		switch dir {
+		case rayRight:
+			min = [2]float64{p.X, p.Y}
+			max[1] = p.Y
+		case rayLeft:
+			min[1] = p.Y
+			max = [2]float64{p.X, p.Y}
+		case rayUp:
+			min = [2]float64{p.X, p.Y}
+			max[0] = p.X
+		case rayDown:
+			min[0] = p.X
+			max = [2]float64{p.X, p.Y}
+		}
+		var in bool
+		r.trees[i].Search(min, max, func(_, _ [2]float64, j int) bool {
+			if crossesRay(segs[j], p, dir) {
+				in = !in
+			}
+			return true
+		})
+		if in {
+			return true
+		}
+	}
+	return false
+}
+
+// onEdge returns true when a point is on the boundary of the region.
+func (r *region) onEdge(p geometry.Point) bool {
+	for _, segs := range r.polys {
+		for _, seg := range segs {
+			if seg.ContainsPoint(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evenOdd is ray casting over the segments of all the rings of a polygon, so
+// points in holes are outside.
+func evenOdd(segs []geometry.Segment, p geometry.Point) bool {
+	var in bool
+	for _, seg := range segs {
+		if crossesRay(seg, p, rayRight) {
+			in = !in
+		}
+	}
+	return in
+}
+
+// The directions of a ray.
+const (
+	rayRight = iota
+	rayLeft
+	rayUp
+	rayDown
+)
+
+// crossesRay returns true when a segment crosses the ray that goes from a
+// point in a direction.
+func crossesRay(seg geometry.Segment, p geometry.Point, dir int) bool {
+	a, b := seg.A, seg.B
+	switch dir {
+	case rayUp, rayDown:
+		if (a.X > p.X) == (b.X > p.X) {
+			return false
+		}
+		y := (b.Y-a.Y)*(p.X-a.X)/(b.X-a.X) + a.Y
+		if dir == rayUp {
+			return p.Y < y
+		}
+		return p.Y > y
+	}
+	if (a.Y > p.Y) == (b.Y > p.Y) {
+		return false
+	}
+	x := (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y) + a.X
+	if dir == rayRight {
+		return p.X < x
+	}
+	return p.X > x
+}
+
+func cross(a, b geometry.Point) float64 {
+	return a.X*b.Y - a.Y*b.X
+}
+
+func sub(a, b geometry.Point) geometry.Point {
+	return geometry.Point{X: a.X - b.X, Y: a.Y - b.Y}
+}
+
+// crossings returns the points where two segments touch. Vertices are
+// returned as is, so that both segments are split at the exact same points.
+func crossings(s, o geometry.Segment) []geometry.Point {
+	if !s.Rect().IntersectsRect(o.Rect()) {
+		return nil
+	}
+	d1, d2 := sub(s.B, s.A), sub(o.B, o.A)
+	denom := cross(d1, d2)
+	if denom == 0 {
+		if cross(sub(o.A, s.A), d1) != 0 {
+			// parallel
+			return nil
+		}
+		// collinear, the segments split each other at their ends
+		var pts []geometry.Point
+		for _, p := range []geometry.Point{s.A, s.B} {
+			if o.ContainsPoint(p) {
+				pts = append(pts, p)
+			}
+		}
+		for _, p := range []geometry.Point{o.A, o.B} {
+			if s.ContainsPoint(p) {
+				pts = append(pts, p)
+			}
+		}
+		return pts
+	}
+	t := cross(sub(o.A, s.A), d2) / denom
+	u := cross(sub(o.A, s.A), d1) / denom
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return nil
+	}
+	switch {
+	case t == 0:
+		return []geometry.Point{s.A}
+	case t == 1:
+		return []geometry.Point{s.B}
+	case u == 0:
+		return []geometry.Point{o.A}
+	case u == 1:
+		return []geometry.Point{o.B}
+	}
+	return []geometry.Point{{X: s.A.X + d1.X*t, Y: s.A.Y + d1.Y*t}}
+}
+
+// splitAll splits every segment of a at the points where it touches a
+// segment of b, and the other way around.
+func splitAll(a, b []geometry.Segment) (as, bs []geometry.Segment) {
+	acuts := make([][]geometry.Point, len(a))
+	bcuts := make([][]geometry.Point, len(b))
+	tr := segmentTree(b)
+	for i := range a {
+		rect := a[i].Rect()
+		tr.Search([2]float64{rect.Min.X, rect.Min.Y},
+			[2]float64{rect.Max.X, rect.Max.Y},
+			func(_, _ [2]float64, j int) bool {
+				for _, p := range crossings(a[i], b[j]) {
+					acuts[i] = append(acuts[i], p)
+					bcuts[j] = append(bcuts[j], p)
+				}
+				return true
+			})
+	}
+	return splitSegments(a, acuts), splitSegments(b, bcuts)
+}
+
+func splitSegments(segs []geometry.Segment, cuts [][]geometry.Point,
+) []geometry.Segment {
+	var res []geometry.Segment
+	for i, seg := range segs {
+		d := sub(seg.B, seg.A)
+		pts := append([]geometry.Point{seg.A, seg.B}, cuts[i]...)
+		sort.Slice(pts, func(i, j int) bool {
+			pi, pj := sub(pts[i], seg.A), sub(pts[j], seg.A)
+			return pi.X*d.X+pi.Y*d.Y < pj.X*d.X+pj.Y*d.Y
+		})
+		for j := 1; j < len(pts); j++ {
+			if pts[j] != pts[j-1] {
+				res = append(res, geometry.Segment{A: pts[j-1], B: pts[j]})
+			}
+		}
+	}
+	return res
+}
+
+func midpoint(seg geometry.Segment) geometry.Point {
+	return geometry.Point{X: (seg.A.X + seg.B.X) / 2, Y: (seg.A.Y + seg.B.Y) / 2}
+}
+
+func clipPointByPolys(
+	point *geojson.Point, polys []*geometry.Poly,
+) geojson.Object {
+	for _, poly := range polys {
+		if poly.IntersectsPoint(point.Base()) {
+			return point
+		}
+	}
+	return geojson.NewMultiPoint(nil)
+}
+
+// clipLineStringByPolys keeps the parts of a line that are inside or on the
+// edge of the polygons.
+func clipLineStringByPolys(
+	lineString *geojson.LineString, polys []*geometry.Poly,
+	opts *geometry.IndexOptions,
+) geojson.Object {
+	r := newRegion(polys)
+	base := lineString.Base()
+	var segs []geometry.Segment
+	for i := 0; i < base.NumSegments(); i++ {
+		if seg := base.SegmentAt(i); seg.A != seg.B {
+			segs = append(segs, seg)
+		}
+	}
+	segs, _ = splitAll(segs, r.segments())
+	var newPoints [][]geometry.Point
+	var line []geometry.Point
+	for _, seg := range segs {
+		mid := midpoint(seg)
+		if !r.contains(mid) && !r.onEdge(mid) {
+			continue
+		}
+		if len(line) > 0 && line[len(line)-1] != seg.A {
+			newPoints = append(newPoints, line)
+			line = nil
+		}
+		if len(line) == 0 {
+			line = append(line, seg.A)
+		}
+		line = append(line, seg.B)
+	}
+	if len(line) > 0 {
+		newPoints = append(newPoints, line)
+	}
+	var children []*geometry.Line
+	for _, points := range newPoints {
+		children = append(children, geometry.NewLine(points, opts))
+	}
+	if len(children) == 1 {
+		return geojson.NewLineString(children[0])
+	}
+	return geojson.NewMultiLineString(children)
+}
+
+// clipPolygonByPolys returns the intersection of a polygon and the polygons.
+func clipPolygonByPolys(
+	polygon *geojson.Polygon, polys []*geometry.Poly,
+	opts *geometry.IndexOptions,
+) geojson.Object {
+	return Overlay([]*geometry.Poly{polygon.Base()}, polys, Intersection, opts)
+}
+
+// Op is a boolean operation on polygons.
+type Op int
+
+const (
+	Intersection Op = iota
+	Union
+	Difference
+)
+
+// Overlay returns the result of a boolean operation on two sets of polygons.
+// The polygons of a set must not overlap each other. The result is a
+// Polygon, or a MultiPolygon when it has zero or many parts.
+//
+// The edges of both are split where they cross, and an edge is kept when the
+// result is on exactly one side of it. The kept edges are directed so the
+// result is on their left, and are then joined into rings, which are
+// exteriors when counterclockwise and holes otherwise.
+func Overlay(a, b []*geometry.Poly, op Op, opts *geometry.IndexOptions,
+) geojson.Object {
+	ra, rb := newRegion(a), newRegion(b)
+	as, bs := splitAll(ra.segments(), rb.segments())
+	inside := func(p geometry.Point) bool {
+		switch op {
+		case Union:
+			return ra.contains(p) || rb.contains(p)
+		case Difference:
+			return ra.contains(p) && !rb.contains(p)
+		}
+		return ra.contains(p) && rb.contains(p)
+	}
+	edges := make(map[geometry.Point][]geometry.Point)
+	seen := make(map[geometry.Segment]bool)
+	for _, seg := range append(as, bs...) {
+		d := sub(seg.B, seg.A)
+		length := math.Hypot(d.X, d.Y)
+		eps := math.Max(length*1e-6, 1e-11) / length
+		mid := midpoint(seg)
+		left := geometry.Point{X: mid.X - d.Y*eps, Y: mid.Y + d.X*eps}
+		right := geometry.Point{X: mid.X + d.Y*eps, Y: mid.Y - d.X*eps}
+		inLeft, inRight := inside(left), inside(right)
+		if inLeft == inRight {
+			continue
+		}
+		if inRight {
+			seg = geometry.Segment{A: seg.B, B: seg.A}
+		}
+		if !seen[seg] {
+			seen[seg] = true
+			edges[seg.A] = append(edges[seg.A], seg.B)
+		}
+	}
+	rings := joinRings(edges)
+
+	var exteriors [][]geometry.Point
+	var holes [][]geometry.Point
+	for _, ring := range rings {
+		if ringArea(ring) > 0 {
+			exteriors = append(exteriors, ring)
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+	// smallest first, so a hole goes to the innermost exterior
+	sort.Slice(exteriors, func(i, j int) bool {
+		return ringArea(exteriors[i]) < ringArea(exteriors[j])
+	})
+	extHoles := make([][][]geometry.Point, len(exteriors))
+	for _, hole := range holes {
+		mid := midpoint(geometry.Segment{A: hole[0], B: hole[1]})
+		for i, ext := range exteriors {
+			if evenOdd(ringSegments(ext), mid) {
+				extHoles[i] = append(extHoles[i], hole)
+				break
+			}
+		}
+	}
+	var newPolys []*geometry.Poly
+	for i, ext := range exteriors {
+		newPolys = append(newPolys, geometry.NewPoly(ext, extHoles[i], opts))
+	}
+	if len(newPolys) == 1 {
+		return geojson.NewPolygon(newPolys[0])
+	}
+	return geojson.NewMultiPolygon(newPolys)
+}
+
+// joinRings joins directed edges into closed rings. Where a vertex has more
+// than one way out, the sharpest left turn is taken, which keeps polygons
+// that only touch at a vertex apart.
+func joinRings(edges map[geometry.Point][]geometry.Point) [][]geometry.Point {
+	// visit the vertices in a stable order
+	starts := make([]geometry.Point, 0, len(edges))
+	for p := range edges {
+		starts = append(starts, p)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		if starts[i].X != starts[j].X {
+			return starts[i].X < starts[j].X
+		}
+		return starts[i].Y < starts[j].Y
+	})
+	next := func(prev, at geometry.Point) (geometry.Point, bool) {
+		outs := edges[at]
+		if len(outs) == 0 {
+			return geometry.Point{}, false
+		}
+		best := 0
+		if len(outs) > 1 {
+			din := sub(at, prev)
+			bestTurn := math.Inf(-1)
+			for i, out := range outs {
+				dout := sub(out, at)
+				turn := math.Atan2(cross(din, dout),
+					din.X*dout.X+din.Y*dout.Y)
+				if turn > bestTurn {
+					best, bestTurn = i, turn
+				}
+			}
+		}
+		p := outs[best]
+		edges[at] = append(outs[:best:best], outs[best+1:]...)
+		return p, true
+	}
+	var rings [][]geometry.Point
+	for _, start := range starts {
+		for len(edges[start]) > 0 {
+			ring := []geometry.Point{start}
+			prev, at := start, edges[start][0]
+			edges[start] = edges[start][1:]
+			ring = append(ring, at)
+			closed := true
+			for at != start {
+				p, ok := next(prev, at)
+				if !ok {
+					closed = false
+					break
+				}
+				prev, at = at, p
+				ring = append(ring, at)
+			}
+			if closed && len(ring) >= 4 {
+				rings = append(rings, ring)
+			}
+		}
+	}
+	return rings
+}
+
+func ringSegments(ring []geometry.Point) []geometry.Segment {
+	segs := make([]geometry.Segment, 0, len(ring)-1)
+	for i := 1; i < len(ring); i++ {
+		segs = append(segs, geometry.Segment{A: ring[i-1], B: ring[i]})
+	}
+	return segs
+}
+
+// ringArea is the signed area of a closed ring, which is positive when the
+// ring is counterclockwise.
+func ringArea(ring []geometry.Point) float64 {
+	var area float64
+	for i := 1; i < len(ring); i++ {
+		area += cross(ring[i-1], ring[i])
+	}
+	return area / 2
+}
@@ -9,6 +9,9 @@ func clipLineString(
 	lineString *geojson.LineString, clipper geojson.Object,
 	opts *geometry.IndexOptions,
 ) geojson.Object {
+	if polys := clipperPolys(clipper); polys != nil {
+		return clipLineStringByPolys(lineString, polys, opts)
+	}
 	bbox := clipper.Rect()
 	var newPoints [][]geometry.Point
 	var clipped geometry.Segment
@@ -8,6 +8,9 @@ import (
 func clipPoint(
 	point *geojson.Point, clipper geojson.Object, opts *geometry.IndexOptions,
 ) geojson.Object {
+	if polys := clipperPolys(clipper); polys != nil {
+		return clipPointByPolys(point, polys)
+	}
 	if point.IntersectsRect(clipper.Rect()) {
 		return point
 	}
@@ -9,6 +9,9 @@ func clipPolygon(
 	polygon *geojson.Polygon, clipper geojson.Object,
 	opts *geometry.IndexOptions,
 ) geojson.Object {
+	if polys := clipperPolys(clipper); polys != nil {
+		return clipPolygonByPolys(polygon, polys, opts)
+	}
 	rect := clipper.Rect()
 	var newPoints [][]geometry.Point
 	base := polygon.Base()
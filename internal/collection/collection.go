@@ -7,10 +7,11 @@ import (
 	"github.com/tidwall/btree"
 	"github.com/tidwall/geojson"
 	"github.com/tidwall/geojson/geometry"
-	"github.com/tidwall/rtree"
+	"github.com/tidwall/rtred"
 	"github.com/tidwall/tile38/internal/deadline"
 	"github.com/tidwall/tile38/internal/field"
 	"github.com/tidwall/tile38/internal/object"
+	"github.com/tidwall/tile38/internal/rtree"
 )
 
 // yieldStep forces the iterator to yield goroutine every 256 steps.
@@ -22,6 +23,18 @@ type Cursor interface {
 	Step(count uint64)
 }
 
+// NodeCounter is a Cursor that is told how many nodes of the spatial index a
+// search visited, once the search is done.
+type NodeCounter interface {
+	CountNodes(count uint64)
+}
+
+func countNodes(cursor Cursor, nodes uint64) {
+	if nc, ok := cursor.(NodeCounter); ok {
+		nc.CountNodes(nodes)
+	}
+}
+
 func byID(a, b *object.Object) bool {
 	return a.ID() < b.ID()
 }
@@ -54,6 +67,7 @@ func byExpires(a, b *object.Object) bool {
 type Collection struct {
 	objs     btree.Map[string, *object.Object]      // sorted by id
 	spatial  rtree.RTreeGN[float32, *object.Object] // geospatially indexed
+	spatial3 *rtred.RTree                           // optional 3D index
 	values   *btree.BTreeG[*object.Object]          // sorted by value+id
 	expires  *btree.BTreeG[*object.Object]          // sorted by ex+id
 	weight   int
@@ -109,12 +123,18 @@ func (c *Collection) Bounds() (minX, minY, maxX, maxY float64) {
 func (c *Collection) indexDelete(item *object.Object) {
 	if !item.Geo().Empty() {
 		c.spatial.Delete(rtreeItem(item))
+		if c.spatial3 != nil {
+			c.spatial3.Remove(item3D{item})
+		}
 	}
 }
 
 func (c *Collection) indexInsert(item *object.Object) {
 	if !item.Geo().Empty() {
 		c.spatial.Insert(rtreeItem(item))
+		if c.spatial3 != nil {
+			c.spatial3.Insert(item3D{item})
+		}
 	}
 }
 
@@ -369,6 +389,37 @@ func (c *Collection) SearchValuesRange(start, end string, desc bool,
 	return keepon
 }
 
+// SearchValuesFrom iterates though the collection values starting with the
+// specified value and id. The iteration stops at the end value, unless the
+// end value is empty.
+func (c *Collection) SearchValuesFrom(value, id, end string, desc bool,
+	cursor Cursor,
+	deadline *deadline.Deadline,
+	iterator func(o *object.Object) bool,
+) bool {
+	var keepon = true
+	var count uint64
+	iter := func(o *object.Object) bool {
+		count++
+		nextStep(count, cursor, deadline)
+		keepon = iterator(o)
+		return keepon
+	}
+
+	pstart := object.New(id, String(value), 0, field.List{})
+	pend := object.New("", String(end), 0, field.List{})
+	if desc {
+		c.values.Descend(pstart, func(item *object.Object) bool {
+			return (end == "" || bGT(c.values, item, pend)) && iter(item)
+		})
+	} else {
+		c.values.Ascend(pstart, func(item *object.Object) bool {
+			return (end == "" || bLT(c.values, item, pend)) && iter(item)
+		})
+	}
+	return keepon
+}
+
 func bLT(tr *btree.BTreeG[*object.Object], a, b *object.Object) bool { return tr.Less(a, b) }
 func bGT(tr *btree.BTreeG[*object.Object], a, b *object.Object) bool { return tr.Less(b, a) }
 
@@ -404,6 +455,7 @@ func (c *Collection) ScanGreaterOrEqual(id string, desc bool,
 
 func (c *Collection) geoSearch(
 	rect geometry.Rect,
+	nodes *uint64,
 	iter func(o *object.Object) bool,
 ) bool {
 	alive := true
@@ -416,8 +468,8 @@ func (c *Collection) geoSearch(
 		return alive
 	}
 
-	c.spatial.Search(
-		min, max,
+	c.spatial.SearchNodes(
+		min, max, nodes,
 		func(_, _ [2]float32, o *object.Object) bool {
 			alive = iter(o)
 			return alive
@@ -427,12 +479,12 @@ func (c *Collection) geoSearch(
 }
 
 func (c *Collection) geoSparse(
-	obj geojson.Object, sparse uint8,
+	obj geojson.Object, sparse uint8, nodes *uint64,
 	iter func(o *object.Object) (match, ok bool),
 ) bool {
 	matches := make(map[string]bool)
 	alive := true
-	c.geoSparseInner(obj.Rect(), sparse, func(o *object.Object) (match, ok bool) {
+	c.geoSparseInner(obj.Rect(), sparse, nodes, func(o *object.Object) (match, ok bool) {
 		ok = true
 		if !matches[o.ID()] {
 			match, ok = iter(o)
@@ -445,7 +497,7 @@ func (c *Collection) geoSparse(
 	return alive
 }
 func (c *Collection) geoSparseInner(
-	rect geometry.Rect, sparse uint8,
+	rect geometry.Rect, sparse uint8, nodes *uint64,
 	iter func(o *object.Object) (match, ok bool),
 ) bool {
 	if sparse > 0 {
@@ -470,14 +522,14 @@ func (c *Collection) geoSparseInner(
 			},
 		}
 		for _, quad := range quads {
-			if !c.geoSparseInner(quad, sparse-1, iter) {
+			if !c.geoSparseInner(quad, sparse-1, nodes, iter) {
 				return false
 			}
 		}
 		return true
 	}
 	alive := true
-	c.geoSearch(rect, func(o *object.Object) bool {
+	c.geoSearch(rect, nodes, func(o *object.Object) bool {
 		match, ok := iter(o)
 		if !ok {
 			alive = false
@@ -503,8 +555,10 @@ func (c *Collection) Within(
 		offset = cursor.Offset()
 		cursor.Step(offset)
 	}
+	var nodes uint64
+	defer func() { countNodes(cursor, nodes) }()
 	if sparse > 0 {
-		return c.geoSparse(obj, sparse, func(o *object.Object) (match, ok bool) {
+		return c.geoSparse(obj, sparse, &nodes, func(o *object.Object) (match, ok bool) {
 			count++
 			if count <= offset {
 				return false, true
@@ -516,7 +570,7 @@ func (c *Collection) Within(
 			return match, ok
 		})
 	}
-	return c.geoSearch(obj.Rect(), func(o *object.Object) bool {
+	return c.geoSearch(obj.Rect(), &nodes, func(o *object.Object) bool {
 		count++
 		if count <= offset {
 			return true
@@ -544,8 +598,10 @@ func (c *Collection) Intersects(
 		offset = cursor.Offset()
 		cursor.Step(offset)
 	}
+	var nodes uint64
+	defer func() { countNodes(cursor, nodes) }()
 	if sparse > 0 {
-		return c.geoSparse(gobj, sparse, func(o *object.Object) (match, ok bool) {
+		return c.geoSparse(gobj, sparse, &nodes, func(o *object.Object) (match, ok bool) {
 			count++
 			if count <= offset {
 				return false, true
@@ -557,7 +613,7 @@ func (c *Collection) Intersects(
 			return match, ok
 		})
 	}
-	return c.geoSearch(gobj.Rect(), func(o *object.Object) bool {
+	return c.geoSearch(gobj.Rect(), &nodes, func(o *object.Object) bool {
 		count++
 		if count <= offset {
 			return true
@@ -577,6 +633,21 @@ func (c *Collection) Nearby(
 	cursor Cursor,
 	deadline *deadline.Deadline,
 	iter func(o *object.Object, dist float64) bool,
+) bool {
+	var nodes uint64
+	defer func() { countNodes(cursor, nodes) }()
+	return c.nearby(target, math.Inf(-1), cursor, &nodes, deadline, iter)
+}
+
+// nearby returns the nearest neighbors, starting with the ones that are from
+// meters away from the target.
+func (c *Collection) nearby(
+	target geojson.Object,
+	from float64,
+	cursor Cursor,
+	nodes *uint64,
+	deadline *deadline.Deadline,
+	iter func(o *object.Object, dist float64) bool,
 ) bool {
 	alive := true
 	center := target.Center()
@@ -587,7 +658,19 @@ func (c *Collection) Nearby(
 		cursor.Step(offset)
 	}
 	distFn := geodeticDistAlgo([2]float64{center.X, center.Y})
-	c.spatial.Nearby(
+	var skip func(min, max [2]float32, data *object.Object, item bool) bool
+	if !math.IsInf(from, -1) {
+		skip = func(min, max [2]float32, data *object.Object, item bool) bool {
+			fmin := [2]float64{float64(min[0]), float64(min[1])}
+			fmax := [2]float64{float64(max[0]), float64(max[1])}
+			if item {
+				return distFn(fmin, fmax, data, true) < from
+			}
+			return rectMaxDist([2]float64{center.X, center.Y}, fmin,
+				fmax) < from
+		}
+	}
+	c.spatial.NearbyFrom(
 		func(min, max [2]float32, data *object.Object, item bool) float64 {
 			return distFn(
 				[2]float64{float64(min[0]), float64(min[1])},
@@ -595,6 +678,8 @@ func (c *Collection) Nearby(
 				data, item,
 			)
 		},
+		skip,
+		nodes,
 		func(_, _ [2]float32, o *object.Object, dist float64) bool {
 			count++
 			if count <= offset {
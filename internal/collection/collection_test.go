@@ -2,6 +2,7 @@ package collection
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"reflect"
 	"strconv"
@@ -55,7 +56,7 @@ func TestCollectionNewCollection(t *testing.T) {
 		Min: geometry.Point{X: -180, Y: -90},
 		Max: geometry.Point{X: 180, Y: 90},
 	}
-	c.geoSearch(bbox, func(o *object.Object) bool {
+	c.geoSearch(bbox, nil, func(o *object.Object) bool {
 		count++
 		return true
 	})
@@ -347,6 +348,36 @@ func TestCollectionSearch(t *testing.T) {
 			return true
 		})
 	expect(t, n == 10)
+
+	// continue from a value and id
+	n = 0
+	min, _ := c.values.Min()
+	id := min.ID()
+	c.SearchValuesFrom("0000", id, "", false, nil, nil,
+		func(o *object.Object) bool {
+			expect(t, n > 0 || o.ID() == id)
+			n++
+			return true
+		})
+	expect(t, n == c.Count())
+
+	n = 0
+	c.SearchValuesFrom("0065", "", "0070", false, nil, nil,
+		func(o *object.Object) bool {
+			expect(t, o.Geo().String() >= "0065" && o.Geo().String() < "0070")
+			n++
+			return true
+		})
+	expect(t, n == 5)
+
+	n = 0
+	c.SearchValuesFrom("0065", "", "", true, nil, nil,
+		func(o *object.Object) bool {
+			expect(t, o.Geo().String() < "0065")
+			n++
+			return true
+		})
+	expect(t, n == 65)
 }
 
 func TestCollectionWeight(t *testing.T) {
@@ -553,6 +584,104 @@ func TestCollectionSparse(t *testing.T) {
 
 }
 
+func TestCollectionIndex3D(t *testing.T) {
+	rect := geojson.NewRect(geometry.Rect{
+		Min: geometry.Point{X: -71.598930, Y: 42.4586739},
+		Max: geometry.Point{X: -71.37302, Y: 42.607937},
+	})
+	N := 10000
+	r := rect.Rect()
+	c2 := New()
+	c3 := New()
+	c3.SetIndex3D(true)
+	expect(t, !c2.Index3D() && c3.Index3D())
+	for i := 0; i < N; i++ {
+		x := (r.Max.X-r.Min.X)*rand.Float64() + r.Min.X
+		y := (r.Max.Y-r.Min.Y)*rand.Float64() + r.Min.Y
+		z := rand.Float64() * 200
+		point := geojson.NewPointZ(geometry.Point{X: x, Y: y}, z)
+		id := fmt.Sprintf("%d", i)
+		c2.Set(object.New(id, point, 0, field.List{}))
+		c3.Set(object.New(id, point, 0, field.List{}))
+	}
+	// replace some objects to make sure that the 3d index stays in sync
+	for i := 0; i < N/10; i++ {
+		id := fmt.Sprintf("%d", rand.Intn(N))
+		point := geojson.NewPointZ(c2.Get(id).Geo().Center(), 150)
+		c2.Set(object.New(id, point, 0, field.List{}))
+		c3.Set(object.New(id, point, 0, field.List{}))
+	}
+	zr := ZRange{Min: 100, Max: 150}
+	var expected int
+	c2.Within(rect, 0, nil, nil, func(o *object.Object) bool {
+		if zr.Contains(Z(o.Geo())) {
+			expected++
+		}
+		return true
+	})
+	expect(t, expected > 0)
+	for _, c := range []*Collection{c2, c3} {
+		var n int
+		c.WithinZ(rect, zr, 0, nil, nil, func(o *object.Object) bool {
+			expect(t, zr.Contains(Z(o.Geo())))
+			n++
+			return true
+		})
+		expect(t, n == expected)
+		n = 0
+		c.IntersectsZ(rect, zr, 0, nil, nil, func(o *object.Object) bool {
+			n++
+			return true
+		})
+		expect(t, n == expected)
+	}
+	c3.SetIndex3D(false)
+	expect(t, !c3.Index3D())
+	c3.SetIndex3D(true)
+	var n int
+	c3.WithinZ(rect, zr, 0, nil, nil, func(o *object.Object) bool {
+		n++
+		return true
+	})
+	expect(t, n == expected)
+}
+
+func TestCollectionNearbyZ(t *testing.T) {
+	c := New()
+	center := geometry.Point{X: -112, Y: 33}
+	c.Set(object.New("1", geojson.NewPointZ(center, 1000), 0, field.List{}))
+	c.Set(object.New("2", geojson.NewPointZ(
+		geometry.Point{X: -112.001, Y: 33}, 0), 0, field.List{}))
+	c.Set(object.New("3", geojson.NewPointZ(
+		geometry.Point{X: -112.002, Y: 33}, 10), 0, field.List{}))
+	c.Set(object.New("4", PO(-112.003, 33), 0, field.List{}))
+
+	var ids []string
+	var dists []float64
+	c.NearbyZ(PO(center.X, center.Y), 0, nil, nil,
+		func(o *object.Object, dist float64) bool {
+			ids = append(ids, o.ID())
+			dists = append(dists, dist)
+			return true
+		},
+	)
+	expect(t, reflect.DeepEqual(ids, []string{"2", "3", "4", "1"}))
+	expect(t, dists[3] == 1000)
+	for i := 1; i < len(dists); i++ {
+		expect(t, dists[i-1] <= dists[i])
+	}
+
+	// stop early
+	ids = ids[:0]
+	c.NearbyZ(PO(center.X, center.Y), 1000, nil, nil,
+		func(o *object.Object, dist float64) bool {
+			ids = append(ids, o.ID())
+			return false
+		},
+	)
+	expect(t, reflect.DeepEqual(ids, []string{"1"}))
+}
+
 func testCollectionVerifyContents(t *testing.T, c *Collection, objs map[string]geojson.Object) {
 	for id, o2 := range objs {
 		o := c.Get(id)
@@ -567,6 +696,45 @@ func testCollectionVerifyContents(t *testing.T, c *Collection, objs map[string]g
 	}
 }
 
+func TestGeodesicMeasures(t *testing.T) {
+	parse := func(s string) geojson.Object {
+		g, err := geojson.Parse(s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) <= math.Abs(b)*1e-6
+	}
+	deg := earthRadius * math.Pi / 180 // meters in one degree of arc
+
+	line := parse(`{"type":"LineString","coordinates":[[0,0],[2,0]]}`)
+	expect(t, near(GeodesicLength(line), deg*2))
+	expect(t, GeodesicArea(line) == 0)
+
+	square := parse(`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`)
+	area := earthRadius * earthRadius * math.Pi / 180 * math.Sin(math.Pi/180)
+	expect(t, near(GeodesicArea(square), area))
+	expect(t, GeodesicLength(square) > deg*3.99 && GeodesicLength(square) < deg*4)
+	rect := geojson.NewRect(geometry.Rect{Max: geometry.Point{X: 1, Y: 1}})
+	expect(t, near(GeodesicArea(rect), area))
+	holed := parse(`{"type":"Polygon","coordinates":[` +
+		`[[0,0],[1,0],[1,1],[0,1],[0,0]],` +
+		`[[0.25,0.25],[0.75,0.25],[0.75,0.75],[0.25,0.75],[0.25,0.25]]]}`)
+	expect(t, GeodesicArea(holed) < area*0.76 && GeodesicArea(holed) > area*0.74)
+	expect(t, GeodesicArea(PO(0, 0)) == 0 && GeodesicLength(PO(0, 0)) == 0)
+
+	expect(t, near(GeodesicDistance(PO(0, 0), PO(0, 1)), deg))
+	expect(t, near(GeodesicDistance(PO(1, 1), line), deg))
+	expect(t, near(GeodesicDistance(line, PO(3, 0)), deg))
+	expect(t, GeodesicDistance(PO(0.5, 0.5), square) == 0)
+	// the great circle of the top edge bulges north of the 1st parallel
+	dist := GeodesicDistance(PO(0.5, 2), square)
+	expect(t, dist < deg && dist > deg-10)
+	expect(t, GeodesicDistance(line, square) == 0)
+}
+
 func TestManyCollections(t *testing.T) {
 	colsM := make(map[string]*Collection)
 	cols := 100
@@ -597,7 +765,7 @@ func TestManyCollections(t *testing.T) {
 		Min: geometry.Point{X: -180, Y: 30},
 		Max: geometry.Point{X: 34, Y: 100},
 	}
-	col.geoSearch(bbox, func(o *object.Object) bool {
+	col.geoSearch(bbox, nil, func(o *object.Object) bool {
 		//println(id)
 		return true
 	})
@@ -768,3 +936,89 @@ func benchmarkScan(t *testing.B, nFields int) {
 		})
 	}
 }
+
+type testNodeCounter struct{ nodes uint64 }
+
+func (nc *testNodeCounter) Offset() uint64          { return 0 }
+func (nc *testNodeCounter) Step(count uint64)       {}
+func (nc *testNodeCounter) CountNodes(count uint64) { nc.nodes += count }
+
+func TestCollectionFrom(t *testing.T) {
+	c := New()
+	for i := 0; i < 5000; i++ {
+		x, y := rand.Float64()*360-180, rand.Float64()*180-90
+		var g geojson.Object = PO(x, y)
+		if i%5 == 0 {
+			g = geojson.NewRect(geometry.Rect{
+				Min: geometry.Point{X: x, Y: y},
+				Max: geometry.Point{X: x + rand.Float64(), Y: y + rand.Float64()},
+			})
+		}
+		c.Set(object.New(strconv.Itoa(i), g, 0, field.List{}))
+	}
+	world := geometry.Rect{
+		Min: geometry.Point{X: -180, Y: -90},
+		Max: geometry.Point{X: 180, Y: 90},
+	}
+
+	// the objects are in the order of their west edges, and a search from
+	// a west edge has all of the objects from there
+	var ids []string
+	var wests []float64
+	all := &testNodeCounter{}
+	c.SearchFrom(world, math.Inf(-1), all, nil,
+		func(o *object.Object, west float64) bool {
+			ids = append(ids, o.ID())
+			wests = append(wests, west)
+			return true
+		})
+	expect(t, len(ids) == 5000)
+	for i := 1; i < len(wests); i++ {
+		expect(t, wests[i-1] <= wests[i])
+	}
+	from := wests[4000]
+	var count int
+	rest := &testNodeCounter{}
+	c.SearchFrom(world, from, rest, nil,
+		func(o *object.Object, west float64) bool {
+			expect(t, west >= from)
+			count++
+			return true
+		})
+	var expected int
+	for _, west := range wests {
+		if west >= from {
+			expected++
+		}
+	}
+	expect(t, count == expected)
+	expect(t, rest.nodes < all.nodes)
+
+	// a nearby search from a distance has all of the objects from there
+	target := PO(rand.Float64()*360-180, rand.Float64()*180-90)
+	var dists []float64
+	all = &testNodeCounter{}
+	c.NearbyFrom(target, math.Inf(-1), all, nil,
+		func(o *object.Object, dist float64) bool {
+			dists = append(dists, dist)
+			return true
+		})
+	expect(t, len(dists) == 5000)
+	from = dists[4000]
+	count = 0
+	rest = &testNodeCounter{}
+	c.NearbyFrom(target, from, rest, nil,
+		func(o *object.Object, dist float64) bool {
+			expect(t, dist >= from)
+			count++
+			return true
+		})
+	expected = 0
+	for _, dist := range dists {
+		if dist >= from {
+			expected++
+		}
+	}
+	expect(t, count == expected)
+	expect(t, rest.nodes < all.nodes)
+}
@@ -3,13 +3,17 @@ package collection
 import (
 	"math"
 
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geometry"
 	"github.com/tidwall/tile38/internal/object"
 )
 
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371e3
+
 func geodeticDistAlgo(center [2]float64) (
 	algo func(min, max [2]float64, obj *object.Object, item bool) (dist float64),
 ) {
-	const earthRadius = 6371e3
 	return func(min, max [2]float64, obj *object.Object, item bool) (dist float64) {
 		if item {
 			r := obj.Rect()
@@ -128,3 +132,183 @@ func pointRectDistGeodeticRad(φq, λq, φl, λl, φh, λh float64) float64 {
 	// valid since the track in question is a meridian.
 	return math.Asin(math.Cos(φq) * sinΔλ)
 }
+
+// geodesicShape is an object broken into the parts that are measured.
+type geodesicShape struct {
+	points []geometry.Point
+	series []geometry.Series // lines and the rings of polygons
+	polys  []*geometry.Poly
+}
+
+func (shape *geodesicShape) add(g geojson.Object) {
+	switch g := g.(type) {
+	case *geojson.Point:
+		shape.points = append(shape.points, g.Base())
+	case *geojson.SimplePoint:
+		shape.points = append(shape.points, g.Base())
+	case *geojson.LineString:
+		shape.series = append(shape.series, g.Base())
+	case *geojson.Rect:
+		shape.add(geojson.NewPolygon(&geometry.Poly{Exterior: g.Base()}))
+	case *geojson.Polygon:
+		poly := g.Base()
+		shape.polys = append(shape.polys, poly)
+		shape.series = append(shape.series, poly.Exterior)
+		for _, hole := range poly.Holes {
+			shape.series = append(shape.series, hole)
+		}
+	case *geojson.Circle:
+		shape.add(g.Polygon())
+	case *geojson.Feature:
+		shape.add(g.Base())
+	case geojson.Collection:
+		for _, g := range g.Children() {
+			shape.add(g)
+		}
+	default:
+		shape.points = append(shape.points, g.Center())
+	}
+}
+
+// pointDistRad returns the distance between two points on the unit sphere,
+// using the Haversine formula.
+func pointDistRad(a, b geometry.Point) float64 {
+	φa, λa := a.Y*math.Pi/180, a.X*math.Pi/180
+	φb, λb := b.Y*math.Pi/180, b.X*math.Pi/180
+	sinΔφ := math.Sin((φb - φa) / 2)
+	sinΔλ := math.Sin((λb - λa) / 2)
+	h := sinΔφ*sinΔφ + math.Cos(φa)*math.Cos(φb)*sinΔλ*sinΔλ
+	return 2 * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// bearingRad returns the initial bearing from a to b in radians.
+func bearingRad(a, b geometry.Point) float64 {
+	φa, λa := a.Y*math.Pi/180, a.X*math.Pi/180
+	φb, λb := b.Y*math.Pi/180, b.X*math.Pi/180
+	y := math.Sin(λb-λa) * math.Cos(φb)
+	x := math.Cos(φa)*math.Sin(φb) - math.Sin(φa)*math.Cos(φb)*math.Cos(λb-λa)
+	return math.Atan2(y, x)
+}
+
+// segmentDistRad returns the distance on the unit sphere from a point to the
+// great circle segment a-b, using the cross-track distance when the closest
+// point is between a and b.
+func segmentDistRad(p, a, b geometry.Point) float64 {
+	δap := pointDistRad(a, p)
+	δab := pointDistRad(a, b)
+	if δab == 0 || δap == 0 {
+		return δap
+	}
+	θ := bearingRad(a, p) - bearingRad(a, b)
+	if math.Cos(θ) > 0 {
+		xt := math.Asin(math.Max(-1, math.Min(1, math.Sin(δap)*math.Sin(θ))))
+		at := math.Acos(math.Max(-1, math.Min(1, math.Cos(δap)/math.Cos(xt))))
+		if at <= δab {
+			return math.Abs(xt)
+		}
+	}
+	return math.Min(δap, pointDistRad(b, p))
+}
+
+// distRad returns the shortest distance on the unit sphere from a point to
+// the points and series of a shape.
+func (shape *geodesicShape) distRad(p geometry.Point) float64 {
+	dist := math.Inf(1)
+	for _, q := range shape.points {
+		dist = math.Min(dist, pointDistRad(p, q))
+	}
+	for _, s := range shape.series {
+		for i := 0; i < s.NumSegments(); i++ {
+			seg := s.SegmentAt(i)
+			dist = math.Min(dist, segmentDistRad(p, seg.A, seg.B))
+		}
+	}
+	return dist
+}
+
+// vertices calls iter for every point of a shape.
+func (shape *geodesicShape) vertices(iter func(p geometry.Point)) {
+	for _, p := range shape.points {
+		iter(p)
+	}
+	for _, s := range shape.series {
+		for i := 0; i < s.NumPoints(); i++ {
+			iter(s.PointAt(i))
+		}
+	}
+}
+
+// GeodesicDistance returns the shortest distance in meters between two
+// objects on the surface of the earth, or zero when the objects intersect.
+func GeodesicDistance(a, b geojson.Object) float64 {
+	if a.Empty() || b.Empty() {
+		return 0
+	}
+	if a.Intersects(b) {
+		return 0
+	}
+	var sa, sb geodesicShape
+	sa.add(a)
+	sb.add(b)
+	dist := math.Inf(1)
+	sa.vertices(func(p geometry.Point) {
+		dist = math.Min(dist, sb.distRad(p))
+	})
+	sb.vertices(func(p geometry.Point) {
+		dist = math.Min(dist, sa.distRad(p))
+	})
+	if math.IsInf(dist, 0) {
+		return 0
+	}
+	return dist * earthRadius
+}
+
+// GeodesicLength returns the length in meters of the lines of an object, or
+// the perimeter of a polygon, along the surface of the earth.
+func GeodesicLength(g geojson.Object) float64 {
+	var shape geodesicShape
+	shape.add(g)
+	var length float64
+	for _, s := range shape.series {
+		for i := 0; i < s.NumSegments(); i++ {
+			seg := s.SegmentAt(i)
+			length += pointDistRad(seg.A, seg.B)
+		}
+	}
+	return length * earthRadius
+}
+
+// ringAreaRad returns the area of a ring on the unit sphere.
+// Algorithm from:
+// Chamberlain, R. G., & Duquette, W. H. (2007).
+// Some Algorithms for Polygons on a Sphere.
+// JPL Publication 07-03.
+func ringAreaRad(ring geometry.Ring) float64 {
+	n := ring.NumPoints()
+	if n < 3 {
+		return 0
+	}
+	var area float64
+	for i := 0; i < n; i++ {
+		a, b := ring.PointAt(i), ring.PointAt((i+1)%n)
+		area += (b.X - a.X) * math.Pi / 180 *
+			(2 + math.Sin(a.Y*math.Pi/180) + math.Sin(b.Y*math.Pi/180))
+	}
+	return math.Abs(area / 2)
+}
+
+// GeodesicArea returns the area in square meters of the polygons of an
+// object on the surface of the earth.
+func GeodesicArea(g geojson.Object) float64 {
+	var shape geodesicShape
+	shape.add(g)
+	var area float64
+	for _, poly := range shape.polys {
+		a := ringAreaRad(poly.Exterior)
+		for _, hole := range poly.Holes {
+			a -= ringAreaRad(hole)
+		}
+		area += math.Max(0, a)
+	}
+	return area * earthRadius * earthRadius
+}
@@ -0,0 +1,268 @@
+package collection
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/rtred"
+	"github.com/tidwall/tile38/internal/deadline"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// ZRange is an inclusive range of altitudes.
+type ZRange struct {
+	Min, Max float64
+}
+
+// Contains returns true if z is within the range.
+func (r ZRange) Contains(z float64) bool {
+	return z >= r.Min && z <= r.Max
+}
+
+// Z returns the altitude of a geometry. Only points, and features with a
+// point geometry, have an altitude. All other objects are at zero.
+func Z(g geojson.Object) float64 {
+	for {
+		switch v := g.(type) {
+		case *geojson.Point:
+			return v.Z()
+		case *geojson.Feature:
+			g = v.Base()
+		default:
+			return 0
+		}
+	}
+}
+
+// item3D is an object in the 3D index.
+type item3D struct {
+	obj *object.Object
+}
+
+func (item item3D) Rect(ctx interface{}) (min, max []float64) {
+	rect := item.obj.Rect()
+	z := Z(item.obj.Geo())
+	return []float64{rect.Min.X, rect.Min.Y, z},
+		[]float64{rect.Max.X, rect.Max.Y, z}
+}
+
+// box3D is a search area in the 3D index.
+type box3D struct {
+	min, max []float64
+}
+
+func (box box3D) Rect(ctx interface{}) (min, max []float64) {
+	return box.min, box.max
+}
+
+// SetIndex3D turns the 3D index on or off. The 3D index is used along side
+// the 2D index, and allows for searches that are limited to a range of
+// altitudes to skip the objects that are outside of that range.
+func (c *Collection) SetIndex3D(on bool) {
+	if on == (c.spatial3 != nil) {
+		return
+	}
+	if !on {
+		c.spatial3 = nil
+		return
+	}
+	c.spatial3 = rtred.New(nil)
+	c.objs.Scan(func(_ string, o *object.Object) bool {
+		if o.IsSpatial() && !o.Geo().Empty() {
+			c.spatial3.Insert(item3D{o})
+		}
+		return true
+	})
+}
+
+// Index3D returns true if the collection has a 3D index.
+func (c *Collection) Index3D() bool {
+	return c.spatial3 != nil
+}
+
+func (c *Collection) geoSearchZ(
+	obj geojson.Object, zr ZRange, nodes *uint64,
+	iter func(o *object.Object) bool,
+) bool {
+	if c.spatial3 == nil {
+		return c.geoSearch(obj.Rect(), nodes, func(o *object.Object) bool {
+			if !zr.Contains(Z(o.Geo())) {
+				return true
+			}
+			return iter(o)
+		})
+	}
+	alive := true
+	rect := obj.Rect()
+	c.spatial3.Search(box3D{
+		min: []float64{rect.Min.X, rect.Min.Y, zr.Min},
+		max: []float64{rect.Max.X, rect.Max.Y, zr.Max},
+	}, func(item rtred.Item) bool {
+		alive = iter(item.(item3D).obj)
+		return alive
+	})
+	return alive
+}
+
+// WithinZ returns all objects that are fully contained within an object
+// and have an altitude in the provided range.
+func (c *Collection) WithinZ(
+	obj geojson.Object,
+	zr ZRange,
+	sparse uint8,
+	cursor Cursor,
+	deadline *deadline.Deadline,
+	iter func(o *object.Object) bool,
+) bool {
+	var count uint64
+	var offset uint64
+	if cursor != nil {
+		offset = cursor.Offset()
+		cursor.Step(offset)
+	}
+	var nodes uint64
+	defer func() { countNodes(cursor, nodes) }()
+	if sparse > 0 {
+		return c.geoSparse(obj, sparse, &nodes, func(o *object.Object) (match, ok bool) {
+			count++
+			if count <= offset {
+				return false, true
+			}
+			nextStep(count, cursor, deadline)
+			ok = true
+			if match = zr.Contains(Z(o.Geo())) && o.Geo().Within(obj); match {
+				ok = iter(o)
+			}
+			return match, ok
+		})
+	}
+	return c.geoSearchZ(obj, zr, &nodes, func(o *object.Object) bool {
+		count++
+		if count <= offset {
+			return true
+		}
+		nextStep(count, cursor, deadline)
+		if o.Geo().Within(obj) {
+			return iter(o)
+		}
+		return true
+	})
+}
+
+// IntersectsZ returns all objects that intersect an object and have an
+// altitude in the provided range.
+func (c *Collection) IntersectsZ(
+	gobj geojson.Object,
+	zr ZRange,
+	sparse uint8,
+	cursor Cursor,
+	deadline *deadline.Deadline,
+	iter func(o *object.Object) bool,
+) bool {
+	var count uint64
+	var offset uint64
+	if cursor != nil {
+		offset = cursor.Offset()
+		cursor.Step(offset)
+	}
+	var nodes uint64
+	defer func() { countNodes(cursor, nodes) }()
+	if sparse > 0 {
+		return c.geoSparse(gobj, sparse, &nodes, func(o *object.Object) (match, ok bool) {
+			count++
+			if count <= offset {
+				return false, true
+			}
+			nextStep(count, cursor, deadline)
+			ok = true
+			if match = zr.Contains(Z(o.Geo())) && o.Geo().Intersects(gobj); match {
+				ok = iter(o)
+			}
+			return match, ok
+		})
+	}
+	return c.geoSearchZ(gobj, zr, &nodes, func(o *object.Object) bool {
+		count++
+		if count <= offset {
+			return true
+		}
+		nextStep(count, cursor, deadline)
+		if o.Geo().Intersects(gobj) {
+			return iter(o)
+		}
+		return true
+	})
+}
+
+// Distance3D returns the distance in meters between a surface distance and
+// two altitudes.
+func Distance3D(meters, z1, z2 float64) float64 {
+	dz := z2 - z1
+	return math.Sqrt(meters*meters + dz*dz)
+}
+
+type nearbyZItem struct {
+	obj  *object.Object
+	dist float64
+}
+
+type nearbyZQueue []nearbyZItem
+
+func (q nearbyZQueue) Len() int           { return len(q) }
+func (q nearbyZQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q nearbyZQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *nearbyZQueue) Push(x any)        { *q = append(*q, x.(nearbyZItem)) }
+func (q *nearbyZQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// NearbyZ returns the nearest neighbors to a target at an altitude. The
+// distance is the straight line distance in meters, which accounts for
+// the difference in altitude.
+func (c *Collection) NearbyZ(
+	target geojson.Object,
+	z float64,
+	cursor Cursor,
+	deadline *deadline.Deadline,
+	iter func(o *object.Object, dist float64) bool,
+) bool {
+	alive := true
+	var count uint64
+	var offset uint64
+	if cursor != nil {
+		offset = cursor.Offset()
+		cursor.Step(offset)
+	}
+	emit := func(item nearbyZItem) bool {
+		count++
+		if count <= offset {
+			return true
+		}
+		nextStep(count, cursor, deadline)
+		alive = iter(item.obj, item.dist)
+		return alive
+	}
+	// The surface distance is never greater than the 3D distance, so objects
+	// are queued in surface order and released once no other object can be
+	// closer.
+	var queue nearbyZQueue
+	var nodes uint64
+	defer func() { countNodes(cursor, nodes) }()
+	c.nearby(target, math.Inf(-1), nil, &nodes, deadline, func(o *object.Object, dist float64) bool {
+		for len(queue) > 0 && queue[0].dist <= dist {
+			if !emit(heap.Pop(&queue).(nearbyZItem)) {
+				return false
+			}
+		}
+		heap.Push(&queue, nearbyZItem{o, Distance3D(dist, z, Z(o.Geo()))})
+		return true
+	})
+	for alive && len(queue) > 0 {
+		emit(heap.Pop(&queue).(nearbyZItem))
+	}
+	return alive
+}
@@ -0,0 +1,57 @@
+package collection
+
+import (
+	"math"
+
+	"github.com/tidwall/tile38/internal/deadline"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// joinPad returns a rect that contains all of the points that are within
+// meters of a rect.
+func joinPad(min, max [2]float32, meters float64) (pmin, pmax [2]float32) {
+	if meters <= 0 {
+		return min, max
+	}
+	dlat := meters / earthRadius * 180 / math.Pi
+	minLat := float64(min[1]) - dlat
+	maxLat := float64(max[1]) + dlat
+	dlon := 360.0
+	if lat := math.Max(math.Abs(minLat), math.Abs(maxLat)); lat < 90 {
+		dlon = math.Min(dlat/math.Cos(lat*math.Pi/180), 360)
+	}
+	pmin = [2]float32{
+		rtreeValueDown(float64(min[0]) - dlon), rtreeValueDown(minLat),
+	}
+	pmax = [2]float32{
+		rtreeValueUp(float64(max[0]) + dlon), rtreeValueUp(maxLat),
+	}
+	return pmin, pmax
+}
+
+// Join calls iter for each pair of objects, one from each collection, whose
+// rects are within meters of each other, by a synchronized traversal of
+// both spatial indexes. The pairs are in no particular order, and the
+// objects may be in the same collection.
+func (c *Collection) Join(other *Collection, meters float64,
+	deadline *deadline.Deadline, iter func(a, b *object.Object) bool,
+) bool {
+	alive := true
+	var count uint64
+	c.spatial.Join(&other.spatial,
+		func(amin, amax, bmin, bmax [2]float32) bool {
+			amin, amax = joinPad(amin, amax, meters)
+			return !(bmin[0] > amax[0] || bmax[0] < amin[0] ||
+				bmin[1] > amax[1] || bmax[1] < amin[1])
+		},
+		func(_, _ [2]float32, a *object.Object,
+			_, _ [2]float32, b *object.Object,
+		) bool {
+			count++
+			nextStep(count, nil, deadline)
+			alive = iter(a, b)
+			return alive
+		},
+	)
+	return alive
+}
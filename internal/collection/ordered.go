@@ -0,0 +1,76 @@
+package collection
+
+import (
+	"math"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geometry"
+	"github.com/tidwall/tile38/internal/deadline"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// rectMaxDist returns a distance in meters that no point of a rect is
+// farther than from a point. Any point of the rect is no farther from a
+// corner than the height plus the width of the rect, and a meter is added
+// for the rounding of the distances.
+func rectMaxDist(center, min, max [2]float64) float64 {
+	dist := math.Inf(1)
+	for _, corner := range [4][2]float64{
+		{min[0], min[1]}, {min[0], max[1]}, {max[0], min[1]}, {max[0], max[1]},
+	} {
+		d := earthRadius * pointRectDistGeodeticDeg(center[1], center[0],
+			corner[1], corner[0], corner[1], corner[0])
+		dist = math.Min(dist, d)
+	}
+	span := (max[0] - min[0] + max[1] - min[1]) * math.Pi / 180
+	return dist + span*earthRadius + 1
+}
+
+// SearchFrom calls iter for the objects whose rects intersect a rect, in the
+// order of the west edges of their rects, starting with the objects whose
+// west edge is at from. The west edge of an object is passed to iter, which
+// a later search can continue from without visiting the nodes of the
+// spatial index that only have earlier objects. Objects with the same west
+// edge are in no particular order.
+func (c *Collection) SearchFrom(
+	rect geometry.Rect,
+	from float64,
+	cursor Cursor,
+	deadline *deadline.Deadline,
+	iter func(o *object.Object, west float64) bool,
+) bool {
+	alive := true
+	min, max := rtreeRect(rect)
+	if math.IsNaN(float64(min[0])) && math.IsNaN(float64(min[1])) &&
+		math.IsNaN(float64(max[0])) && math.IsNaN(float64(max[1])) {
+		return alive
+	}
+	var nodes uint64
+	defer func() { countNodes(cursor, nodes) }()
+	var count uint64
+	c.spatial.SearchFrom(min, max, rtreeValueDown(from), &nodes,
+		func(min, _ [2]float32, o *object.Object) bool {
+			count++
+			nextStep(count, cursor, deadline)
+			alive = iter(o, float64(min[0]))
+			return alive
+		},
+	)
+	return alive
+}
+
+// NearbyFrom is like Nearby, but it starts with the objects that are from
+// meters away from the target, and it doesn't visit the nodes of the spatial
+// index that only have closer objects. So a search can continue from the
+// distance of the last object of an earlier search.
+func (c *Collection) NearbyFrom(
+	target geojson.Object,
+	from float64,
+	cursor Cursor,
+	deadline *deadline.Deadline,
+	iter func(o *object.Object, dist float64) bool,
+) bool {
+	var nodes uint64
+	defer func() { countNodes(cursor, nodes) }()
+	return c.nearby(target, from, cursor, &nodes, deadline, iter)
+}
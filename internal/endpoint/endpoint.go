@@ -153,6 +153,15 @@ type Conn interface {
 	Send(val string) error
 }
 
+// SendOptions are how the messages of a hook are sent to its HTTP endpoints.
+// They come from the SETHOOK command, so they are stored in plaintext in the
+// aof and are sent to the followers.
+type SendOptions struct {
+	Secret  string      // signs each request, when set
+	Token   string      // the bearer token, when set
+	Headers [][2]string // static headers, as names and values
+}
+
 // Manager manages all endpoints
 type Manager struct {
 	mu        sync.RWMutex
@@ -210,8 +219,34 @@ func (epc *Manager) Validate(url string) error {
 	return err
 }
 
+// Batchable returns true when the endpoint can receive an array of messages
+// as a single message.
+func (epc *Manager) Batchable(url string) bool {
+	ep, err := parseEndpoint(url)
+	if err != nil {
+		return false
+	}
+	switch ep.Protocol {
+	case HTTP, Kafka, SQS, NATS, AMQP:
+		return true
+	}
+	return false
+}
+
+// IsHTTP returns true when the endpoint is HTTP, which are the ones that use
+// SendOptions.
+func (epc *Manager) IsHTTP(url string) bool {
+	ep, err := parseEndpoint(url)
+	return err == nil && ep.Protocol == HTTP
+}
+
 // Send send a message to an endpoint
 func (epc *Manager) Send(endpoint, msg string) error {
+	return epc.SendWith(endpoint, msg, nil)
+}
+
+// SendWith sends a message to an endpoint with the options, which may be nil.
+func (epc *Manager) SendWith(endpoint, msg string, opts *SendOptions) error {
 	for {
 		epc.mu.Lock()
 		conn, exists := epc.conns[endpoint]
@@ -254,7 +289,12 @@ func (epc *Manager) Send(endpoint, msg string) error {
 			epc.conns[endpoint] = conn
 		}
 		epc.mu.Unlock()
-		err := conn.Send(msg)
+		var err error
+		if hconn, ok := conn.(*HTTPConn); ok && opts != nil {
+			err = hconn.SendWith(msg, opts)
+		} else {
+			err = conn.Send(msg)
+		}
 		if err != nil {
 			if err == errExpired {
 				// it's possible that the connection has expired in-between
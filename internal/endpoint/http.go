@@ -2,9 +2,13 @@ package endpoint
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -14,6 +18,23 @@ const (
 	httpMaxIdleConnections = 20
 )
 
+// The headers of a signed request. The signature is the HMAC-SHA256 of the
+// timestamp, a '.', and the body, as "sha256=" and the hex of the hash.
+const (
+	HTTPTimestampHeader = "X-Tile38-Timestamp"
+	HTTPSignatureHeader = "X-Tile38-Signature"
+)
+
+// HTTPSignature returns the signature of a request body that was sent at the
+// timestamp, in unix seconds.
+func HTTPSignature(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
 // HTTPConn is an endpoint connection
 type HTTPConn struct {
 	ep     Endpoint
@@ -44,12 +65,31 @@ func (conn *HTTPConn) ExpireNow() {
 
 // Send sends a message
 func (conn *HTTPConn) Send(msg string) error {
+	return conn.SendWith(msg, nil)
+}
+
+// SendWith sends a message with the headers of the options, which may be nil.
+func (conn *HTTPConn) SendWith(msg string, opts *SendOptions) error {
 	req, err := http.NewRequest("POST", conn.ep.Original, bytes.NewBufferString(msg))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if opts != nil {
+		for _, header := range opts.Headers {
+			req.Header.Set(header[0], header[1])
+		}
+		if opts.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+opts.Token)
+		}
+		if opts.Secret != "" {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set(HTTPTimestampHeader, ts)
+			req.Header.Set(HTTPSignatureHeader,
+				HTTPSignature(opts.Secret, ts, msg))
+		}
+	}
 	resp, err := conn.client.Do(req)
 	if err != nil {
 		return err
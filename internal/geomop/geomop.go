@@ -0,0 +1,534 @@
+// Package geomop provides operations on geometries, such as union,
+// intersection and difference of polygons, convex hulls, centroids, line
+// simplification and geodesic buffers.
+package geomop
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geo"
+	"github.com/tidwall/geojson/geometry"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/tile38/internal/clip"
+)
+
+const (
+	earthRadius = 6371e3
+	// metersPerDegree is the length of one degree of latitude
+	metersPerDegree = earthRadius * math.Pi / 180
+	// bufferSteps is the number of points in the circles of a buffer
+	bufferSteps = 64
+)
+
+var (
+	errNotPolygon    = errors.New("not a polygon")
+	errEmpty         = errors.New("empty geometry")
+	errInvalidMeters = errors.New("invalid meters")
+)
+
+// Polys returns the polygons of a polygonal object. That is a rectangle,
+// circle, polygon, multipolygon, or a feature of one of them.
+func Polys(g geojson.Object) ([]*geometry.Poly, bool) {
+	switch g := g.(type) {
+	case *geojson.Rect:
+		return []*geometry.Poly{rectPoly(g.Base())}, true
+	case *geojson.Circle:
+		return Polys(g.Polygon())
+	case *geojson.Polygon:
+		return []*geometry.Poly{g.Base()}, true
+	case *geojson.MultiPolygon:
+		var polys []*geometry.Poly
+		for _, child := range g.Children() {
+			child, ok := child.(*geojson.Polygon)
+			if !ok {
+				return nil, false
+			}
+			polys = append(polys, child.Base())
+		}
+		return polys, true
+	case *geojson.Feature:
+		return Polys(g.Base())
+	}
+	return nil, false
+}
+
+func rectPoly(rect geometry.Rect) *geometry.Poly {
+	return geometry.NewPoly([]geometry.Point{
+		rect.Min, {X: rect.Max.X, Y: rect.Min.Y}, rect.Max,
+		{X: rect.Min.X, Y: rect.Max.Y}, rect.Min,
+	}, nil, nil)
+}
+
+// Union returns the union of two polygonal objects.
+func Union(a, b geojson.Object, opts *geometry.IndexOptions,
+) (geojson.Object, error) {
+	return overlay(a, b, clip.Union, opts)
+}
+
+// Difference returns the part of a that is not in b. Both are polygonal.
+func Difference(a, b geojson.Object, opts *geometry.IndexOptions,
+) (geojson.Object, error) {
+	return overlay(a, b, clip.Difference, opts)
+}
+
+// Intersection returns the part of a that is in b. The b object must be
+// polygonal, while a may also be a point or line.
+func Intersection(a, b geojson.Object, opts *geometry.IndexOptions,
+) (geojson.Object, error) {
+	if _, ok := Polys(a); ok {
+		return overlay(a, b, clip.Intersection, opts)
+	}
+	bpolys, ok := Polys(b)
+	if !ok {
+		return nil, errNotPolygon
+	}
+	return clip.Clip(a, geojson.NewMultiPolygon(bpolys), opts), nil
+}
+
+func overlay(a, b geojson.Object, op clip.Op, opts *geometry.IndexOptions,
+) (geojson.Object, error) {
+	apolys, ok := Polys(a)
+	if !ok {
+		return nil, errNotPolygon
+	}
+	bpolys, ok := Polys(b)
+	if !ok {
+		return nil, errNotPolygon
+	}
+	return clip.Overlay(apolys, bpolys, op, opts), nil
+}
+
+// appendPoints appends all of the points of an object to dst.
+func appendPoints(dst []geometry.Point, g geojson.Object) []geometry.Point {
+	switch g := g.(type) {
+	case *geojson.Point:
+		dst = append(dst, g.Base())
+	case *geojson.SimplePoint:
+		dst = append(dst, g.Base())
+	case *geojson.LineString:
+		dst = appendSeries(dst, g.Base())
+	case *geojson.Polygon:
+		dst = appendSeries(dst, g.Base().Exterior)
+	case *geojson.Rect:
+		dst = appendSeries(dst, rectPoly(g.Base()).Exterior)
+	case *geojson.Circle:
+		dst = appendPoints(dst, g.Polygon())
+	case *geojson.Feature:
+		dst = appendPoints(dst, g.Base())
+	case geojson.Collection:
+		for _, child := range g.Children() {
+			dst = appendPoints(dst, child)
+		}
+	}
+	return dst
+}
+
+func appendSeries(dst []geometry.Point, s geometry.Series) []geometry.Point {
+	for i := 0; i < s.NumPoints(); i++ {
+		dst = append(dst, s.PointAt(i))
+	}
+	return dst
+}
+
+func cross(o, a, b geometry.Point) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
+
+// ConvexHull returns the smallest convex polygon that contains all of the
+// points of an object. It's a Point or LineString when the points do not
+// make an area.
+func ConvexHull(g geojson.Object, opts *geometry.IndexOptions,
+) (geojson.Object, error) {
+	points := appendPoints(nil, g)
+	if len(points) == 0 {
+		return nil, errEmpty
+	}
+	// Andrew's monotone chain
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].X != points[j].X {
+			return points[i].X < points[j].X
+		}
+		return points[i].Y < points[j].Y
+	})
+	n := 1
+	for i := 1; i < len(points); i++ {
+		if points[i] != points[n-1] {
+			points[n] = points[i]
+			n++
+		}
+	}
+	points = points[:n]
+	if len(points) == 1 {
+		return geojson.NewPoint(points[0]), nil
+	}
+	hull := make([]geometry.Point, 0, len(points)+1)
+	for _, p := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		p := points[i]
+		for len(hull) >= lower &&
+			cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	if len(hull) < 4 {
+		// all of the points are on a line
+		line := []geometry.Point{points[0], points[len(points)-1]}
+		return geojson.NewLineString(geometry.NewLine(line, opts)), nil
+	}
+	return geojson.NewPolygon(geometry.NewPoly(hull, nil, opts)), nil
+}
+
+// centroid accumulates the weighted centers of the parts of an object. The
+// parts with the highest dimension win.
+type centroid struct {
+	area, ax, ay   float64
+	length, lx, ly float64
+	count, px, py  float64
+}
+
+func (c *centroid) add(g geojson.Object) {
+	switch g := g.(type) {
+	case *geojson.Point:
+		c.addPoint(g.Base())
+	case *geojson.SimplePoint:
+		c.addPoint(g.Base())
+	case *geojson.LineString:
+		c.addLine(g.Base())
+	case *geojson.Polygon, *geojson.Rect, *geojson.Circle:
+		polys, _ := Polys(g)
+		for _, poly := range polys {
+			c.addRing(poly.Exterior, 1)
+			for _, hole := range poly.Holes {
+				c.addRing(hole, -1)
+			}
+		}
+	case *geojson.Feature:
+		c.add(g.Base())
+	case geojson.Collection:
+		for _, child := range g.Children() {
+			c.add(child)
+		}
+	}
+}
+
+func (c *centroid) addPoint(p geometry.Point) {
+	c.count++
+	c.px += p.X
+	c.py += p.Y
+}
+
+func (c *centroid) addLine(s geometry.Series) {
+	for i := 0; i < s.NumSegments(); i++ {
+		seg := s.SegmentAt(i)
+		l := math.Hypot(seg.B.X-seg.A.X, seg.B.Y-seg.A.Y)
+		c.length += l
+		c.lx += l * (seg.A.X + seg.B.X) / 2
+		c.ly += l * (seg.A.Y + seg.B.Y) / 2
+	}
+	if s.NumPoints() > 0 {
+		c.addPoint(s.PointAt(0))
+	}
+}
+
+// addRing adds the area of a ring, or removes it for a hole.
+func (c *centroid) addRing(s geometry.Series, sign float64) {
+	if s.NumPoints() == 0 {
+		return
+	}
+	// relative to the first point, for precision
+	o := s.PointAt(0)
+	var a, cx, cy float64
+	for i := 0; i < s.NumSegments(); i++ {
+		seg := s.SegmentAt(i)
+		ax, ay := seg.A.X-o.X, seg.A.Y-o.Y
+		bx, by := seg.B.X-o.X, seg.B.Y-o.Y
+		f := ax*by - bx*ay
+		a += f
+		cx += (ax + bx) * f
+		cy += (ay + by) * f
+	}
+	if a == 0 {
+		c.addLine(s)
+		return
+	}
+	// a is twice the signed area, and the center is (cx/3a, cy/3a)
+	w := sign * math.Abs(a) / 2
+	c.area += w
+	c.ax += w * (o.X + cx/(3*a))
+	c.ay += w * (o.Y + cy/(3*a))
+}
+
+// Centroid returns the center of mass of an object as a Point.
+func Centroid(g geojson.Object) (geojson.Object, error) {
+	var c centroid
+	c.add(g)
+	switch {
+	case c.area > 0:
+		return geojson.NewPoint(geometry.Point{
+			X: c.ax / c.area, Y: c.ay / c.area}), nil
+	case c.length > 0:
+		return geojson.NewPoint(geometry.Point{
+			X: c.lx / c.length, Y: c.ly / c.length}), nil
+	case c.count > 0:
+		return geojson.NewPoint(geometry.Point{
+			X: c.px / c.count, Y: c.py / c.count}), nil
+	}
+	return nil, errEmpty
+}
+
+// segmentMeters returns the distance in meters from p to the segment a b,
+// on a plane around the segment.
+func segmentMeters(p, a, b geometry.Point) float64 {
+	k := math.Cos((a.Y + b.Y) / 2 * math.Pi / 180)
+	px, py := (p.X-a.X)*k, p.Y-a.Y
+	bx, by := (b.X-a.X)*k, b.Y-a.Y
+	var t float64
+	if l := bx*bx + by*by; l > 0 {
+		t = math.Max(0, math.Min(1, (px*bx+py*by)/l))
+	}
+	return math.Hypot(px-bx*t, py-by*t) * metersPerDegree
+}
+
+// simplifyPoints is Douglas-Peucker line simplification.
+func simplifyPoints(points []geometry.Point, meters float64) []geometry.Point {
+	if len(points) < 3 {
+		return points
+	}
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	var simplify func(i, j int)
+	simplify = func(i, j int) {
+		var max float64
+		var index int
+		for k := i + 1; k < j; k++ {
+			if d := segmentMeters(points[k], points[i], points[j]); d > max {
+				max, index = d, k
+			}
+		}
+		if max > meters {
+			keep[index] = true
+			simplify(i, index)
+			simplify(index, j)
+		}
+	}
+	simplify(0, len(points)-1)
+	var res []geometry.Point
+	for i, p := range points {
+		if keep[i] {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// simplifyRing simplifies a closed ring, or returns nil when it collapses.
+func simplifyRing(s geometry.Series, meters float64) []geometry.Point {
+	points := simplifyPoints(appendSeries(nil, s), meters)
+	if len(points) < 4 {
+		return nil
+	}
+	return points
+}
+
+func simplifyPoly(poly *geometry.Poly, meters float64,
+	opts *geometry.IndexOptions,
+) *geometry.Poly {
+	exterior := simplifyRing(poly.Exterior, meters)
+	if exterior == nil {
+		// too small to simplify
+		return poly
+	}
+	var holes [][]geometry.Point
+	for _, hole := range poly.Holes {
+		if hole := simplifyRing(hole, meters); hole != nil {
+			holes = append(holes, hole)
+		}
+	}
+	return geometry.NewPoly(exterior, holes, opts)
+}
+
+// Simplify removes the points of the lines and polygons of an object that
+// are closer than meters to the simplified shape.
+func Simplify(g geojson.Object, meters float64, opts *geometry.IndexOptions,
+) (geojson.Object, error) {
+	if meters < 0 || math.IsInf(meters, 0) || math.IsNaN(meters) {
+		return nil, errInvalidMeters
+	}
+	switch g := g.(type) {
+	case *geojson.LineString:
+		points := simplifyPoints(appendSeries(nil, g.Base()), meters)
+		return geojson.NewLineString(geometry.NewLine(points, opts)), nil
+	case *geojson.MultiLineString:
+		var lines []*geometry.Line
+		for _, child := range g.Children() {
+			if child, ok := child.(*geojson.LineString); ok {
+				points := simplifyPoints(appendSeries(nil, child.Base()), meters)
+				lines = append(lines, geometry.NewLine(points, opts))
+			}
+		}
+		return geojson.NewMultiLineString(lines), nil
+	case *geojson.Polygon:
+		return geojson.NewPolygon(simplifyPoly(g.Base(), meters, opts)), nil
+	case *geojson.MultiPolygon:
+		polys, _ := Polys(g)
+		for i := range polys {
+			polys[i] = simplifyPoly(polys[i], meters, opts)
+		}
+		return geojson.NewMultiPolygon(polys), nil
+	case *geojson.Feature:
+		base, err := Simplify(g.Base(), meters, opts)
+		if err != nil {
+			return nil, err
+		}
+		return geojson.NewFeature(base, g.Members()), nil
+	case *geojson.FeatureCollection, *geojson.GeometryCollection:
+		var children []geojson.Object
+		for _, child := range g.(geojson.Collection).Children() {
+			child, err := Simplify(child, meters, opts)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+		if _, ok := g.(*geojson.FeatureCollection); ok {
+			return geojson.NewFeatureCollection(children), nil
+		}
+		return geojson.NewGeometryCollection(children), nil
+	}
+	// points have nothing to simplify
+	return g, nil
+}
+
+// geodesicCircle returns a polygon of the points that are meters away from
+// the center along a great circle.
+func geodesicCircle(center geometry.Point, meters float64) *geometry.Poly {
+	points := make([]geometry.Point, 0, bufferSteps+1)
+	for i := 0; i < bufferSteps; i++ {
+		lat, lon := geo.DestinationPoint(center.Y, center.X, meters,
+			float64(i)*360/bufferSteps)
+		points = append(points, geometry.Point{X: lon, Y: lat})
+	}
+	points = append(points, points[0])
+	return geometry.NewPoly(points, nil, nil)
+}
+
+// appendSeriesPieces appends the circles around the points of a series, and
+// the quads around its segments, to dst.
+func appendSeriesPieces(dst []*geometry.Poly, s geometry.Series,
+	meters float64,
+) []*geometry.Poly {
+	for i := 0; i < s.NumPoints(); i++ {
+		dst = append(dst, geodesicCircle(s.PointAt(i), meters))
+	}
+	for i := 0; i < s.NumSegments(); i++ {
+		seg := s.SegmentAt(i)
+		if seg.A == seg.B {
+			continue
+		}
+		bear1 := geo.BearingTo(seg.A.Y, seg.A.X, seg.B.Y, seg.B.X)
+		bear2 := geo.BearingTo(seg.B.Y, seg.B.X, seg.A.Y, seg.A.X)
+		lat1, lon1 := geo.DestinationPoint(seg.A.Y, seg.A.X, meters, bear1-90)
+		lat2, lon2 := geo.DestinationPoint(seg.A.Y, seg.A.X, meters, bear1+90)
+		lat3, lon3 := geo.DestinationPoint(seg.B.Y, seg.B.X, meters, bear2-90)
+		lat4, lon4 := geo.DestinationPoint(seg.B.Y, seg.B.X, meters, bear2+90)
+		dst = append(dst, geometry.NewPoly([]geometry.Point{
+			{X: lon1, Y: lat1}, {X: lon2, Y: lat2},
+			{X: lon3, Y: lat3}, {X: lon4, Y: lat4},
+			{X: lon1, Y: lat1},
+		}, nil, nil))
+	}
+	return dst
+}
+
+// appendBufferPieces appends the polygons that together cover everything
+// within meters of an object to dst.
+func appendBufferPieces(dst []*geometry.Poly, g geojson.Object,
+	meters float64,
+) ([]*geometry.Poly, error) {
+	switch g := g.(type) {
+	case *geojson.Point:
+		dst = append(dst, geodesicCircle(g.Base(), meters))
+	case *geojson.SimplePoint:
+		dst = append(dst, geodesicCircle(g.Base(), meters))
+	case *geojson.LineString:
+		dst = appendSeriesPieces(dst, g.Base(), meters)
+	case *geojson.Polygon, *geojson.Rect, *geojson.Circle:
+		polys, _ := Polys(g)
+		for _, poly := range polys {
+			dst = append(dst, poly)
+			dst = appendSeriesPieces(dst, poly.Exterior, meters)
+			for _, hole := range poly.Holes {
+				dst = appendSeriesPieces(dst, hole, meters)
+			}
+		}
+	case *geojson.Feature:
+		return appendBufferPieces(dst, g.Base(), meters)
+	case geojson.Collection:
+		for _, child := range g.Children() {
+			var err error
+			if dst, err = appendBufferPieces(dst, child, meters); err != nil {
+				return nil, err
+			}
+		}
+	case nil:
+		return nil, errors.New("cannot buffer nil object")
+	default:
+		typ := gjson.Get(g.JSON(), "type").String()
+		return nil, errors.New("cannot buffer " + typ + " type")
+	}
+	return dst, nil
+}
+
+// unionAll returns the union of the polygons, which may overlap. They are
+// merged in pairs to keep the operands of each union small.
+func unionAll(polys [][]*geometry.Poly, opts *geometry.IndexOptions,
+) []*geometry.Poly {
+	if len(polys) == 0 {
+		return nil
+	}
+	for len(polys) > 1 {
+		var next [][]*geometry.Poly
+		for i := 0; i < len(polys); i += 2 {
+			if i+1 == len(polys) {
+				next = append(next, polys[i])
+				continue
+			}
+			res, _ := Polys(clip.Overlay(polys[i], polys[i+1], clip.Union, opts))
+			next = append(next, res)
+		}
+		polys = next
+	}
+	return polys[0]
+}
+
+// Buffer returns the area that is within meters of an object, measured
+// along great circles.
+func Buffer(g geojson.Object, meters float64, opts *geometry.IndexOptions,
+) (geojson.Object, error) {
+	if meters <= 0 || math.IsInf(meters, 0) || math.IsNaN(meters) {
+		return nil, errInvalidMeters
+	}
+	pieces, err := appendBufferPieces(nil, g, meters)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([][]*geometry.Poly, len(pieces))
+	for i, piece := range pieces {
+		parts[i] = []*geometry.Poly{piece}
+	}
+	polys := unionAll(parts, opts)
+	if len(polys) == 1 {
+		return geojson.NewPolygon(polys[0]), nil
+	}
+	return geojson.NewMultiPolygon(polys), nil
+}
@@ -0,0 +1,137 @@
+package geomop
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geometry"
+)
+
+func parse(t *testing.T, s string) geojson.Object {
+	t.Helper()
+	g, err := geojson.Parse(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func area(g geojson.Object) float64 {
+	var c centroid
+	c.add(g)
+	return c.area
+}
+
+const (
+	square1 = `{"type":"Polygon","coordinates":[[[0,0],[2,0],[2,2],[0,2],[0,0]]]}`
+	square2 = `{"type":"Polygon","coordinates":[[[1,1],[3,1],[3,3],[1,3],[1,1]]]}`
+)
+
+func TestBooleans(t *testing.T) {
+	a, b := parse(t, square1), parse(t, square2)
+	u, err := Union(a, b, nil)
+	if err != nil || area(u) != 7 {
+		t.Fatalf("union: %v %v", u, err)
+	}
+	i, err := Intersection(a, b, nil)
+	if err != nil || i.String() !=
+		`{"type":"Polygon","coordinates":[[[1,1],[2,1],[2,2],[1,2],[1,1]]]}` {
+		t.Fatalf("intersection: %v %v", i, err)
+	}
+	d, err := Difference(a, b, nil)
+	if err != nil || area(d) != 3 {
+		t.Fatalf("difference: %v %v", d, err)
+	}
+	line := parse(t, `{"type":"LineString","coordinates":[[-1,1],[3,1]]}`)
+	i, err = Intersection(line, a, nil)
+	if err != nil || i.String() !=
+		`{"type":"LineString","coordinates":[[0,1],[2,1]]}` {
+		t.Fatalf("intersection: %v %v", i, err)
+	}
+	if _, err := Union(a, line, nil); err != errNotPolygon {
+		t.Fatalf("expected %v, got %v", errNotPolygon, err)
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	g := parse(t, `{"type":"MultiPoint","coordinates":[[0,0],[2,0],[1,1],[2,2],[0,2],[1,0]]}`)
+	h, err := ConvexHull(g, nil)
+	if err != nil || h.String() !=
+		`{"type":"Polygon","coordinates":[[[0,0],[2,0],[2,2],[0,2],[0,0]]]}` {
+		t.Fatalf("got %v %v", h, err)
+	}
+	g = parse(t, `{"type":"MultiPoint","coordinates":[[0,0],[1,1],[2,2]]}`)
+	if h, _ = ConvexHull(g, nil); h.String() !=
+		`{"type":"LineString","coordinates":[[0,0],[2,2]]}` {
+		t.Fatalf("got %v", h)
+	}
+	g = parse(t, `{"type":"MultiPoint","coordinates":[[1,1],[1,1]]}`)
+	if h, _ = ConvexHull(g, nil); h.String() !=
+		`{"type":"Point","coordinates":[1,1]}` {
+		t.Fatalf("got %v", h)
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	g := parse(t, `{"type":"Polygon","coordinates":[[[0,0],[4,0],[4,4],[0,4],[0,0]],[[0,0],[2,0],[2,2],[0,2],[0,0]]]}`)
+	c, err := Centroid(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := c.(*geojson.Point).Base()
+	if math.Abs(p.X-7.0/3) > 1e-9 || math.Abs(p.Y-7.0/3) > 1e-9 {
+		t.Fatalf("got %v", p)
+	}
+	g = parse(t, `{"type":"LineString","coordinates":[[0,0],[3,0],[3,1]]}`)
+	if c, _ = Centroid(g); c.String() !=
+		`{"type":"Point","coordinates":[1.875,0.125]}` {
+		t.Fatalf("got %v", c)
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	// the middle point is about 11 meters off the line
+	g := parse(t, `{"type":"LineString","coordinates":[[0,0],[0.001,0.0001],[0.002,0]]}`)
+	s, err := Simplify(g, 20, nil)
+	if err != nil || s.String() !=
+		`{"type":"LineString","coordinates":[[0,0],[0.002,0]]}` {
+		t.Fatalf("got %v %v", s, err)
+	}
+	if s, _ = Simplify(g, 5, nil); s.String() != g.String() {
+		t.Fatalf("got %v", s)
+	}
+	if _, err := Simplify(g, -1, nil); err != errInvalidMeters {
+		t.Fatalf("expected %v, got %v", errInvalidMeters, err)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	g := parse(t, `{"type":"LineString","coordinates":[[0,0],[0.01,0],[0.01,0.01]]}`)
+	b, err := Buffer(g, 100, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poly, ok := b.(*geojson.Polygon)
+	if !ok {
+		t.Fatalf("expected a polygon, got %v", b)
+	}
+	// every point of the buffer is about 100 meters from the line
+	for i := 0; i < poly.Base().Exterior.NumPoints(); i++ {
+		p := poly.Base().Exterior.PointAt(i)
+		d := math.Min(
+			segmentMeters(p, geometry.Point{X: 0, Y: 0},
+				geometry.Point{X: 0.01, Y: 0}),
+			segmentMeters(p, geometry.Point{X: 0.01, Y: 0},
+				geometry.Point{X: 0.01, Y: 0.01}))
+		if d < 99 || d > 101 {
+			t.Fatalf("point %v is %v meters away", p, d)
+		}
+	}
+	if !b.Contains(g) {
+		t.Fatal("buffer must contain the line")
+	}
+	if _, err := Buffer(g, 0, nil); err != errInvalidMeters {
+		t.Fatalf("expected %v, got %v", errInvalidMeters, err)
+	}
+}
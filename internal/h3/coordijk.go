@@ -0,0 +1,276 @@
+package h3
+
+import "math"
+
+// coordIJK is a hexagon coordinate using three 120 degree axes.
+type coordIJK struct {
+	i, j, k int
+}
+
+// direction is a digit of an index, which is a direction on the ijk axes.
+type direction int
+
+const (
+	centerDigit  direction = 0
+	kAxesDigit   direction = 1
+	jAxesDigit   direction = 2
+	jkAxesDigit  direction = 3
+	iAxesDigit   direction = 4
+	ikAxesDigit  direction = 5
+	ijAxesDigit  direction = 6
+	invalidDigit direction = 7
+	numDigits              = invalidDigit
+)
+
+// unitVecs are the unit vectors of each direction.
+var unitVecs = [...]coordIJK{
+	{0, 0, 0}, // center
+	{0, 0, 1}, // k
+	{0, 1, 0}, // j
+	{0, 1, 1}, // jk
+	{1, 0, 0}, // i
+	{1, 0, 1}, // ik
+	{1, 1, 0}, // ij
+}
+
+// hex2dToCoordIJK returns the hexagon that contains a 2D cartesian point.
+func hex2dToCoordIJK(v vec2d) coordIJK {
+	var h coordIJK
+
+	// quantize into the ij system and then normalize
+	a1 := math.Abs(v.x)
+	a2 := math.Abs(v.y)
+
+	// first do a reverse conversion
+	x2 := a2 * rsin60
+	x1 := a1 + x2/2.0
+
+	// check if we have the center of a hex
+	m1 := int(x1)
+	m2 := int(x2)
+
+	// otherwise round correctly
+	r1 := x1 - float64(m1)
+	r2 := x2 - float64(m2)
+
+	if r1 < 0.5 {
+		if r1 < 1.0/3.0 {
+			if r2 < (1.0+r1)/2.0 {
+				h.i, h.j = m1, m2
+			} else {
+				h.i, h.j = m1, m2+1
+			}
+		} else {
+			if r2 < (1.0 - r1) {
+				h.j = m2
+			} else {
+				h.j = m2 + 1
+			}
+			if (1.0-r1) <= r2 && r2 < (2.0*r1) {
+				h.i = m1 + 1
+			} else {
+				h.i = m1
+			}
+		}
+	} else {
+		if r1 < 2.0/3.0 {
+			if r2 < (1.0 - r1) {
+				h.j = m2
+			} else {
+				h.j = m2 + 1
+			}
+			if (2.0*r1-1.0) < r2 && r2 < (1.0-r1) {
+				h.i = m1
+			} else {
+				h.i = m1 + 1
+			}
+		} else {
+			if r2 < (r1 / 2.0) {
+				h.i, h.j = m1+1, m2
+			} else {
+				h.i, h.j = m1+1, m2+1
+			}
+		}
+	}
+
+	// now fold across the axes if necessary
+	if v.x < 0.0 {
+		if h.j%2 == 0 {
+			axisi := h.j / 2
+			diff := h.i - axisi
+			h.i = h.i - 2*diff
+		} else {
+			axisi := (h.j + 1) / 2
+			diff := h.i - axisi
+			h.i = h.i - (2*diff + 1)
+		}
+	}
+	if v.y < 0.0 {
+		h.i = h.i - (2*h.j+1)/2
+		h.j = -1 * h.j
+	}
+	h.normalize()
+	return h
+}
+
+// hex2d returns the center point of a hexagon in 2D cartesian coordinates.
+func (h coordIJK) hex2d() vec2d {
+	i := h.i - h.k
+	j := h.j - h.k
+	return vec2d{float64(i) - 0.5*float64(j), float64(j) * sqrt3_2}
+}
+
+func (h coordIJK) add(o coordIJK) coordIJK {
+	return coordIJK{h.i + o.i, h.j + o.j, h.k + o.k}
+}
+
+func (h coordIJK) sub(o coordIJK) coordIJK {
+	return coordIJK{h.i - o.i, h.j - o.j, h.k - o.k}
+}
+
+func (h coordIJK) scale(factor int) coordIJK {
+	return coordIJK{h.i * factor, h.j * factor, h.k * factor}
+}
+
+// normalize makes all of the components positive with at least one zero.
+func (h *coordIJK) normalize() {
+	// remove any negative values
+	if h.i < 0 {
+		h.j -= h.i
+		h.k -= h.i
+		h.i = 0
+	}
+	if h.j < 0 {
+		h.i -= h.j
+		h.k -= h.j
+		h.j = 0
+	}
+	if h.k < 0 {
+		h.i -= h.k
+		h.j -= h.k
+		h.k = 0
+	}
+	// remove the min value if needed
+	min := h.i
+	if h.j < min {
+		min = h.j
+	}
+	if h.k < min {
+		min = h.k
+	}
+	if min > 0 {
+		h.i -= min
+		h.j -= min
+		h.k -= min
+	}
+}
+
+// unitDigit returns the digit of a unit vector, or invalidDigit.
+func (h coordIJK) unitDigit() direction {
+	h.normalize()
+	for d := centerDigit; d < numDigits; d++ {
+		if h == unitVecs[d] {
+			return d
+		}
+	}
+	return invalidDigit
+}
+
+// upAp7 moves to the parent of an aperture 7 grid, counter-clockwise.
+func (h *coordIJK) upAp7() {
+	i := h.i - h.k
+	j := h.j - h.k
+	h.i = int(math.Round(float64(3*i-j) * oneSeventh))
+	h.j = int(math.Round(float64(i+2*j) * oneSeventh))
+	h.k = 0
+	h.normalize()
+}
+
+// upAp7r moves to the parent of an aperture 7 grid, clockwise.
+func (h *coordIJK) upAp7r() {
+	i := h.i - h.k
+	j := h.j - h.k
+	h.i = int(math.Round(float64(2*i+j) * oneSeventh))
+	h.j = int(math.Round(float64(3*j-i) * oneSeventh))
+	h.k = 0
+	h.normalize()
+}
+
+func (h *coordIJK) down(iVec, jVec, kVec coordIJK) {
+	*h = iVec.scale(h.i).add(jVec.scale(h.j)).add(kVec.scale(h.k))
+	h.normalize()
+}
+
+// downAp7 moves to the center child of an aperture 7 grid,
+// counter-clockwise.
+func (h *coordIJK) downAp7() {
+	h.down(coordIJK{3, 0, 1}, coordIJK{1, 3, 0}, coordIJK{0, 1, 3})
+}
+
+// downAp7r moves to the center child of an aperture 7 grid, clockwise.
+func (h *coordIJK) downAp7r() {
+	h.down(coordIJK{3, 1, 0}, coordIJK{0, 3, 1}, coordIJK{1, 0, 3})
+}
+
+// downAp3 moves to the center child of an aperture 3 grid,
+// counter-clockwise.
+func (h *coordIJK) downAp3() {
+	h.down(coordIJK{2, 0, 1}, coordIJK{1, 2, 0}, coordIJK{0, 1, 2})
+}
+
+// downAp3r moves to the center child of an aperture 3 grid, clockwise.
+func (h *coordIJK) downAp3r() {
+	h.down(coordIJK{2, 1, 0}, coordIJK{0, 2, 1}, coordIJK{1, 0, 2})
+}
+
+// neighbor moves to the neighboring hexagon in the direction of a digit.
+func (h *coordIJK) neighbor(digit direction) {
+	if digit > centerDigit && digit < numDigits {
+		*h = h.add(unitVecs[digit])
+		h.normalize()
+	}
+}
+
+func (h *coordIJK) rotate60ccw() {
+	h.down(coordIJK{1, 1, 0}, coordIJK{0, 1, 1}, coordIJK{1, 0, 1})
+}
+
+func (h *coordIJK) rotate60cw() {
+	h.down(coordIJK{1, 0, 1}, coordIJK{1, 1, 0}, coordIJK{0, 1, 1})
+}
+
+func (d direction) rotate60ccw() direction {
+	switch d {
+	case kAxesDigit:
+		return ikAxesDigit
+	case ikAxesDigit:
+		return iAxesDigit
+	case iAxesDigit:
+		return ijAxesDigit
+	case ijAxesDigit:
+		return jAxesDigit
+	case jAxesDigit:
+		return jkAxesDigit
+	case jkAxesDigit:
+		return kAxesDigit
+	}
+	return d
+}
+
+func (d direction) rotate60cw() direction {
+	switch d {
+	case kAxesDigit:
+		return jkAxesDigit
+	case jkAxesDigit:
+		return jAxesDigit
+	case jAxesDigit:
+		return ijAxesDigit
+	case ijAxesDigit:
+		return iAxesDigit
+	case iAxesDigit:
+		return ikAxesDigit
+	case ikAxesDigit:
+		return kAxesDigit
+	}
+	return d
+}
@@ -0,0 +1,532 @@
+package h3
+
+import "math"
+
+const (
+	numIcosaFaces = 20
+	numHexVerts   = 6
+	numPentVerts  = 5
+	maxRes        = 15
+)
+
+// face quadrants
+const (
+	ij = 1
+	ki = 2
+	jk = 3
+)
+
+// overage is the result of adjusting a coordinate that may be off its face.
+type overage int
+
+const (
+	noOverage overage = iota // on the original face
+	faceEdge                 // on a face edge, substrate grids only
+	newFace                  // moved to a new face
+)
+
+// faceIJK is a hexagon coordinate on an icosahedron face.
+type faceIJK struct {
+	face  int
+	coord coordIJK
+}
+
+// faceOrientIJK is the orientation of a neighboring face.
+type faceOrientIJK struct {
+	face      int
+	translate coordIJK
+	ccwRot60  int
+}
+
+// faceCenterGeo are the icosahedron face centers in lat/lng radians.
+var faceCenterGeo = [numIcosaFaces]latLng{
+	{0.803582649718989942, 1.248397419617396099},   // face  0
+	{1.307747883455638156, 2.536945009877921159},   // face  1
+	{1.054751253523952054, -1.347517358900396623},  // face  2
+	{0.600191595538186799, -0.450603909469755746},  // face  3
+	{0.491715428198773866, 0.401988202911306943},   // face  4
+	{0.172745327415618701, 1.678146885280433686},   // face  5
+	{0.605929321571350690, 2.953923329812411617},   // face  6
+	{0.427370518328979641, -1.888876200336285401},  // face  7
+	{-0.079066118549212831, -0.733429513380867741}, // face  8
+	{-0.230961644455383637, 0.506495587332349035},  // face  9
+	{0.079066118549212831, 2.408163140208925497},   // face 10
+	{0.230961644455383637, -2.635097066257444203},  // face 11
+	{-0.172745327415618701, -1.463445768309359553}, // face 12
+	{-0.605929321571350690, -0.187669323777381622}, // face 13
+	{-0.427370518328979641, 1.252716453253507838},  // face 14
+	{-0.600191595538186799, 2.690988744120037492},  // face 15
+	{-0.491715428198773866, -2.739604450678486295}, // face 16
+	{-0.803582649718989942, -1.893195233972397139}, // face 17
+	{-1.307747883455638156, -0.604647643711872080}, // face 18
+	{-1.054751253523952054, 1.794075294689396615},  // face 19
+}
+
+// faceCenterPoint are the icosahedron face centers on the unit sphere.
+var faceCenterPoint = [numIcosaFaces]vec3d{
+	{0.2199307791404606, 0.6583691780274996, 0.7198475378926182},    // face  0
+	{-0.2139234834501421, 0.1478171829550703, 0.9656017935214205},   // face  1
+	{0.1092625278784797, -0.4811951572873210, 0.8697775121287253},   // face  2
+	{0.7428567301586791, -0.3593941678278028, 0.5648005936517033},   // face  3
+	{0.8112534709140969, 0.3448953237639384, 0.4721387736413930},    // face  4
+	{-0.1055498149613921, 0.9794457296411413, 0.1718874610009365},   // face  5
+	{-0.8075407579970092, 0.1533552485898818, 0.5695261994882688},   // face  6
+	{-0.2846148069787907, -0.8644080972654206, 0.4144792552473539},  // face  7
+	{0.7405621473854482, -0.6673299564565524, -0.0789837646326737},  // face  8
+	{0.8512303986474293, 0.4722343788582681, -0.2289137388687808},   // face  9
+	{-0.7405621473854481, 0.6673299564565524, 0.0789837646326737},   // face 10
+	{-0.8512303986474292, -0.4722343788582682, 0.2289137388687808},  // face 11
+	{0.1055498149613919, -0.9794457296411413, -0.1718874610009365},  // face 12
+	{0.8075407579970092, -0.1533552485898819, -0.5695261994882688},  // face 13
+	{0.2846148069787908, 0.8644080972654204, -0.4144792552473539},   // face 14
+	{-0.7428567301586791, 0.3593941678278027, -0.5648005936517033},  // face 15
+	{-0.8112534709140971, -0.3448953237639382, -0.4721387736413930}, // face 16
+	{-0.2199307791404607, -0.6583691780274996, -0.7198475378926182}, // face 17
+	{0.2139234834501420, -0.1478171829550704, -0.9656017935214205},  // face 18
+	{-0.1092625278784796, 0.4811951572873210, -0.8697775121287253},  // face 19
+}
+
+// faceAxesAzRadsCII are the face ijk axes as azimuths in radians from the
+// face center to vertex 0/1/2.
+var faceAxesAzRadsCII = [numIcosaFaces][3]float64{
+	{5.619958268523939882, 3.525563166130744542, 1.431168063737548730}, // face  0
+	{5.760339081714187279, 3.665943979320991689, 1.571548876927796127}, // face  1
+	{0.780213654393430055, 4.969003859179821079, 2.874608756786625655}, // face  2
+	{0.430469363979999913, 4.619259568766391033, 2.524864466373195467}, // face  3
+	{6.130269123335111400, 4.035874020941915804, 1.941478918548720291}, // face  4
+	{2.692877706530642877, 0.598482604137447119, 4.787272808923838195}, // face  5
+	{2.982963003477243874, 0.888567901084048369, 5.077358105870439581}, // face  6
+	{3.532912002790141181, 1.438516900396945656, 5.627307105183336758}, // face  7
+	{3.494305004259568154, 1.399909901866372864, 5.588700106652763840}, // face  8
+	{3.003214169499538391, 0.908819067106342928, 5.097609271892733906}, // face  9
+	{5.930472956509811562, 3.836077854116615875, 1.741682751723420374}, // face 10
+	{0.138378484090254847, 4.327168688876645809, 2.232773586483450311}, // face 11
+	{0.448714947059150361, 4.637505151845541521, 2.543110049452346120}, // face 12
+	{0.158629650112549365, 4.347419854898940135, 2.253024752505744869}, // face 13
+	{5.891865957979238535, 3.797470855586042958, 1.703075753192847583}, // face 14
+	{2.711123289609793325, 0.616728187216597771, 4.805518392002988683}, // face 15
+	{3.294508837434268316, 1.200113735041072948, 5.388903939827463911}, // face 16
+	{3.804819692245439833, 1.710424589852244509, 5.899214794638635174}, // face 17
+	{3.664438879055192436, 1.570043776661997111, 5.758833981448388027}, // face 18
+	{2.361378999196363184, 0.266983896803167583, 4.455774101589558636}, // face 19
+}
+
+// faceNeighbors are the neighboring faces of each face, for the center,
+// ij, ki and jk quadrants.
+var faceNeighbors = [numIcosaFaces][4]faceOrientIJK{
+	{ // face 0
+		{0, coordIJK{0, 0, 0}, 0},
+		{4, coordIJK{2, 0, 2}, 1},
+		{1, coordIJK{2, 2, 0}, 5},
+		{5, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 1
+		{1, coordIJK{0, 0, 0}, 0},
+		{0, coordIJK{2, 0, 2}, 1},
+		{2, coordIJK{2, 2, 0}, 5},
+		{6, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 2
+		{2, coordIJK{0, 0, 0}, 0},
+		{1, coordIJK{2, 0, 2}, 1},
+		{3, coordIJK{2, 2, 0}, 5},
+		{7, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 3
+		{3, coordIJK{0, 0, 0}, 0},
+		{2, coordIJK{2, 0, 2}, 1},
+		{4, coordIJK{2, 2, 0}, 5},
+		{8, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 4
+		{4, coordIJK{0, 0, 0}, 0},
+		{3, coordIJK{2, 0, 2}, 1},
+		{0, coordIJK{2, 2, 0}, 5},
+		{9, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 5
+		{5, coordIJK{0, 0, 0}, 0},
+		{10, coordIJK{2, 2, 0}, 3},
+		{14, coordIJK{2, 0, 2}, 3},
+		{0, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 6
+		{6, coordIJK{0, 0, 0}, 0},
+		{11, coordIJK{2, 2, 0}, 3},
+		{10, coordIJK{2, 0, 2}, 3},
+		{1, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 7
+		{7, coordIJK{0, 0, 0}, 0},
+		{12, coordIJK{2, 2, 0}, 3},
+		{11, coordIJK{2, 0, 2}, 3},
+		{2, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 8
+		{8, coordIJK{0, 0, 0}, 0},
+		{13, coordIJK{2, 2, 0}, 3},
+		{12, coordIJK{2, 0, 2}, 3},
+		{3, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 9
+		{9, coordIJK{0, 0, 0}, 0},
+		{14, coordIJK{2, 2, 0}, 3},
+		{13, coordIJK{2, 0, 2}, 3},
+		{4, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 10
+		{10, coordIJK{0, 0, 0}, 0},
+		{5, coordIJK{2, 2, 0}, 3},
+		{6, coordIJK{2, 0, 2}, 3},
+		{15, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 11
+		{11, coordIJK{0, 0, 0}, 0},
+		{6, coordIJK{2, 2, 0}, 3},
+		{7, coordIJK{2, 0, 2}, 3},
+		{16, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 12
+		{12, coordIJK{0, 0, 0}, 0},
+		{7, coordIJK{2, 2, 0}, 3},
+		{8, coordIJK{2, 0, 2}, 3},
+		{17, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 13
+		{13, coordIJK{0, 0, 0}, 0},
+		{8, coordIJK{2, 2, 0}, 3},
+		{9, coordIJK{2, 0, 2}, 3},
+		{18, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 14
+		{14, coordIJK{0, 0, 0}, 0},
+		{9, coordIJK{2, 2, 0}, 3},
+		{5, coordIJK{2, 0, 2}, 3},
+		{19, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 15
+		{15, coordIJK{0, 0, 0}, 0},
+		{16, coordIJK{2, 0, 2}, 1},
+		{19, coordIJK{2, 2, 0}, 5},
+		{10, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 16
+		{16, coordIJK{0, 0, 0}, 0},
+		{17, coordIJK{2, 0, 2}, 1},
+		{15, coordIJK{2, 2, 0}, 5},
+		{11, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 17
+		{17, coordIJK{0, 0, 0}, 0},
+		{18, coordIJK{2, 0, 2}, 1},
+		{16, coordIJK{2, 2, 0}, 5},
+		{12, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 18
+		{18, coordIJK{0, 0, 0}, 0},
+		{19, coordIJK{2, 0, 2}, 1},
+		{17, coordIJK{2, 2, 0}, 5},
+		{13, coordIJK{0, 2, 2}, 3},
+	},
+	{ // face 19
+		{19, coordIJK{0, 0, 0}, 0},
+		{15, coordIJK{2, 0, 2}, 1},
+		{18, coordIJK{2, 2, 0}, 5},
+		{14, coordIJK{0, 2, 2}, 3},
+	},
+}
+
+// adjacentFaceDir is the quadrant of a face that borders another face, or
+// -1 when the faces are not adjacent.
+var adjacentFaceDir = [numIcosaFaces][numIcosaFaces]int{
+	{0, ki, -1, -1, ij, jk, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1}, // face 0
+	{ij, 0, ki, -1, -1, -1, jk, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1}, // face 1
+	{-1, ij, 0, ki, -1, -1, -1, jk, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1}, // face 2
+	{-1, -1, ij, 0, ki, -1, -1, -1, jk, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1}, // face 3
+	{ki, -1, -1, ij, 0, -1, -1, -1, -1, jk, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1}, // face 4
+	{jk, -1, -1, -1, -1, 0, -1, -1, -1, -1, ij, -1, -1, -1, ki, -1, -1, -1, -1, -1}, // face 5
+	{-1, jk, -1, -1, -1, -1, 0, -1, -1, -1, ki, ij, -1, -1, -1, -1, -1, -1, -1, -1}, // face 6
+	{-1, -1, jk, -1, -1, -1, -1, 0, -1, -1, -1, ki, ij, -1, -1, -1, -1, -1, -1, -1}, // face 7
+	{-1, -1, -1, jk, -1, -1, -1, -1, 0, -1, -1, -1, ki, ij, -1, -1, -1, -1, -1, -1}, // face 8
+	{-1, -1, -1, -1, jk, -1, -1, -1, -1, 0, -1, -1, -1, ki, ij, -1, -1, -1, -1, -1}, // face 9
+	{-1, -1, -1, -1, -1, ij, ki, -1, -1, -1, 0, -1, -1, -1, -1, jk, -1, -1, -1, -1}, // face 10
+	{-1, -1, -1, -1, -1, -1, ij, ki, -1, -1, -1, 0, -1, -1, -1, -1, jk, -1, -1, -1}, // face 11
+	{-1, -1, -1, -1, -1, -1, -1, ij, ki, -1, -1, -1, 0, -1, -1, -1, -1, jk, -1, -1}, // face 12
+	{-1, -1, -1, -1, -1, -1, -1, -1, ij, ki, -1, -1, -1, 0, -1, -1, -1, -1, jk, -1}, // face 13
+	{-1, -1, -1, -1, -1, ki, -1, -1, -1, ij, -1, -1, -1, -1, 0, -1, -1, -1, -1, jk}, // face 14
+	{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, jk, -1, -1, -1, -1, 0, ij, -1, -1, ki}, // face 15
+	{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, jk, -1, -1, -1, ki, 0, ij, -1, -1}, // face 16
+	{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, jk, -1, -1, -1, ki, 0, ij, -1}, // face 17
+	{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, jk, -1, -1, -1, ki, 0, ij}, // face 18
+	{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, jk, ij, -1, -1, ki, 0}, // face 19
+}
+var maxDimByCIIres = [...]int{
+	2, -1, 14, -1, 98, -1, 686, -1, 4802, -1, 33614, -1, 235298, -1,
+	1647086, -1, 11529602,
+}
+
+var unitScaleByCIIres = [...]int{
+	1, -1, 7, -1, 49, -1, 343, -1, 2401, -1, 16807, -1, 117649, -1,
+	823543, -1, 5764801,
+}
+
+func isClassIII(res int) bool {
+	return res%2 == 1
+}
+
+// geoToFaceIJK returns the face coordinate of a point at a resolution.
+func geoToFaceIJK(g latLng, res int) faceIJK {
+	face, v := geoToHex2d(g, res)
+	return faceIJK{face, hex2dToCoordIJK(v)}
+}
+
+// geoToHex2d returns the face and 2D coordinate of a point at a resolution.
+func geoToHex2d(g latLng, res int) (int, vec2d) {
+	face, sqd := geoToClosestFace(g)
+	// cos(r) = 1 - 2 * sin^2(r/2) = 1 - 2 * (sqd / 4) = 1 - sqd/2
+	r := math.Acos(1 - sqd*0.5)
+	if r < epsilon {
+		return face, vec2d{}
+	}
+	// find the ccw theta from the class II i-axis
+	theta := posAngleRads(faceAxesAzRadsCII[face][0] -
+		posAngleRads(azimuth(faceCenterGeo[face], g)))
+	if isClassIII(res) {
+		theta = posAngleRads(theta - ap7RotRads)
+	}
+	// gnomonic scaling of r, then scale for the resolution
+	r = math.Tan(r) * invRes0UGnomonic
+	for i := 0; i < res; i++ {
+		r *= sqrt7
+	}
+	return face, vec2d{r * math.Cos(theta), r * math.Sin(theta)}
+}
+
+// hex2dToGeo returns the point of a 2D coordinate on a face.
+func hex2dToGeo(v vec2d, face, res int, substrate bool) latLng {
+	r := v.mag()
+	if r < epsilon {
+		return faceCenterGeo[face]
+	}
+	theta := math.Atan2(v.y, v.x)
+	for i := 0; i < res; i++ {
+		r *= rsqrt7
+	}
+	if substrate {
+		r *= oneThird
+		if isClassIII(res) {
+			r *= rsqrt7
+		}
+	}
+	r = math.Atan(r * res0UGnomonic)
+	if !substrate && isClassIII(res) {
+		theta = posAngleRads(theta + ap7RotRads)
+	}
+	theta = posAngleRads(faceAxesAzRadsCII[face][0] - theta)
+	return azDistance(faceCenterGeo[face], theta, r)
+}
+
+// geoToClosestFace returns the face whose center is nearest to a point,
+// and the square of the distance to that center on the unit sphere.
+func geoToClosestFace(g latLng) (face int, sqd float64) {
+	v := g.vec3d()
+	sqd = 5.0
+	for f := 0; f < numIcosaFaces; f++ {
+		if d := faceCenterPoint[f].squareDist(v); d < sqd {
+			face, sqd = f, d
+		}
+	}
+	return face, sqd
+}
+
+// geo returns the center point of a face coordinate.
+func (h faceIJK) geo(res int) latLng {
+	return hex2dToGeo(h.coord.hex2d(), h.face, res, false)
+}
+
+var (
+	vertsCII = [numHexVerts]coordIJK{
+		{2, 1, 0}, {1, 2, 0}, {0, 2, 1}, {0, 1, 2}, {1, 0, 2}, {2, 0, 1},
+	}
+	vertsCIII = [numHexVerts]coordIJK{
+		{5, 4, 0}, {1, 5, 0}, {0, 5, 4}, {0, 1, 5}, {4, 0, 5}, {5, 0, 1},
+	}
+)
+
+// verts returns the vertices of a cell as substrate face coordinates,
+// along with the resolution of the substrate grid.
+func (h faceIJK) verts(res, n int) ([]faceIJK, int) {
+	verts := vertsCII[:]
+	if isClassIII(res) {
+		verts = vertsCIII[:]
+	}
+	// move to the aperture 33r substrate grid, and then to the class II
+	// grid when needed
+	h.coord.downAp3()
+	h.coord.downAp3r()
+	if isClassIII(res) {
+		h.coord.downAp7r()
+		res++
+	}
+	fijkVerts := make([]faceIJK, n)
+	for v := 0; v < n; v++ {
+		fijkVerts[v].face = h.face
+		fijkVerts[v].coord = h.coord.add(verts[v])
+		fijkVerts[v].coord.normalize()
+	}
+	return fijkVerts, res
+}
+
+// faceEdges returns the edges of a face quadrant in substrate coordinates.
+func faceEdges(dir, maxDim int) (vec2d, vec2d) {
+	v0 := vec2d{3.0 * float64(maxDim), 0.0}
+	v1 := vec2d{-1.5 * float64(maxDim), 3.0 * sqrt3_2 * float64(maxDim)}
+	v2 := vec2d{-1.5 * float64(maxDim), -3.0 * sqrt3_2 * float64(maxDim)}
+	switch dir {
+	case ij:
+		return v0, v1
+	case jk:
+		return v1, v2
+	default:
+		return v2, v0
+	}
+}
+
+// boundary returns the vertices of a hexagon cell.
+func (h faceIJK) boundary(res int) []latLng {
+	fijkVerts, adjRes := h.verts(res, numHexVerts)
+	var verts []latLng
+	lastFace := -1
+	lastOverage := noOverage
+	for vert := 0; vert < numHexVerts+1; vert++ {
+		v := vert % numHexVerts
+		fijk := fijkVerts[v]
+		ovr := fijk.adjustOverageClassII(adjRes, false, true)
+
+		// Each face of the icosahedron is a different projection plane, so
+		// when an edge of a class III cell crosses a face edge an extra
+		// vertex is needed at the intersection.
+		if isClassIII(res) && vert > 0 && fijk.face != lastFace &&
+			lastOverage != faceEdge {
+			lastV := (v + 5) % numHexVerts
+			orig2d0 := fijkVerts[lastV].coord.hex2d()
+			orig2d1 := fijkVerts[v].coord.hex2d()
+			face2 := lastFace
+			if lastFace == h.face {
+				face2 = fijk.face
+			}
+			edge0, edge1 := faceEdges(adjacentFaceDir[h.face][face2],
+				maxDimByCIIres[adjRes])
+			inter := intersect(orig2d0, orig2d1, edge0, edge1)
+			// an intersection at a vertex needs no extra vertex
+			if !orig2d0.almostEquals(inter) && !orig2d1.almostEquals(inter) {
+				verts = append(verts, hex2dToGeo(inter, h.face, adjRes, true))
+			}
+		}
+		if vert < numHexVerts {
+			verts = append(verts,
+				hex2dToGeo(fijk.coord.hex2d(), fijk.face, adjRes, true))
+		}
+		lastFace = fijk.face
+		lastOverage = ovr
+	}
+	return verts
+}
+
+// pentBoundary returns the vertices of a pentagon cell.
+func (h faceIJK) pentBoundary(res int) []latLng {
+	fijkVerts, adjRes := h.verts(res, numPentVerts)
+	var verts []latLng
+	var lastFijk faceIJK
+	for vert := 0; vert < numPentVerts+1; vert++ {
+		v := vert % numPentVerts
+		fijk := fijkVerts[v]
+		fijk.adjustPentVertOverage(adjRes)
+
+		// all class III pentagon edges cross icosahedron edges
+		if isClassIII(res) && vert > 0 {
+			tmpFijk := fijk
+			orig2d0 := lastFijk.coord.hex2d()
+			currentToLastDir := adjacentFaceDir[tmpFijk.face][lastFijk.face]
+			orient := faceNeighbors[tmpFijk.face][currentToLastDir]
+			tmpFijk.face = orient.face
+			for i := 0; i < orient.ccwRot60; i++ {
+				tmpFijk.coord.rotate60ccw()
+			}
+			trans := orient.translate.scale(unitScaleByCIIres[adjRes] * 3)
+			tmpFijk.coord = tmpFijk.coord.add(trans)
+			tmpFijk.coord.normalize()
+			orig2d1 := tmpFijk.coord.hex2d()
+			edge0, edge1 := faceEdges(adjacentFaceDir[tmpFijk.face][fijk.face],
+				maxDimByCIIres[adjRes])
+			inter := intersect(orig2d0, orig2d1, edge0, edge1)
+			verts = append(verts, hex2dToGeo(inter, tmpFijk.face, adjRes, true))
+		}
+		if vert < numPentVerts {
+			verts = append(verts,
+				hex2dToGeo(fijk.coord.hex2d(), fijk.face, adjRes, true))
+		}
+		lastFijk = fijk
+	}
+	return verts
+}
+
+// adjustOverageClassII moves a class II coordinate that is off its face onto
+// the neighboring face.
+func (h *faceIJK) adjustOverageClassII(res int, pentLeading4, substrate bool,
+) overage {
+	ovr := noOverage
+	ijk := &h.coord
+	maxDim := maxDimByCIIres[res]
+	if substrate {
+		maxDim *= 3
+	}
+	sum := ijk.i + ijk.j + ijk.k
+	if substrate && sum == maxDim {
+		return faceEdge
+	}
+	if sum <= maxDim {
+		return ovr
+	}
+	ovr = newFace
+	var orient faceOrientIJK
+	if ijk.k > 0 {
+		if ijk.j > 0 {
+			orient = faceNeighbors[h.face][jk]
+		} else {
+			orient = faceNeighbors[h.face][ki]
+			// adjust for the pentagonal missing sequence
+			if pentLeading4 {
+				origin := coordIJK{maxDim, 0, 0}
+				tmp := ijk.sub(origin)
+				tmp.rotate60cw()
+				*ijk = tmp.add(origin)
+			}
+		}
+	} else {
+		orient = faceNeighbors[h.face][ij]
+	}
+	h.face = orient.face
+	for i := 0; i < orient.ccwRot60; i++ {
+		ijk.rotate60ccw()
+	}
+	unitScale := unitScaleByCIIres[res]
+	if substrate {
+		unitScale *= 3
+	}
+	*ijk = ijk.add(orient.translate.scale(unitScale))
+	ijk.normalize()
+	if substrate && ijk.i+ijk.j+ijk.k == maxDim {
+		ovr = faceEdge
+	}
+	return ovr
+}
+
+// adjustPentVertOverage moves a pentagon vertex onto the correct face.
+func (h *faceIJK) adjustPentVertOverage(res int) overage {
+	for {
+		if ovr := h.adjustOverageClassII(res, false, true); ovr != newFace {
+			return ovr
+		}
+	}
+}
@@ -0,0 +1,346 @@
+// Package h3 is a pure Go implementation of the parts of the H3 hexagonal
+// hierarchical geospatial indexing system that are needed for converting
+// between points and cells.
+//
+// The algorithms and tables are ported from the H3 C library, Copyright Uber
+// Technologies, Inc., licensed under the Apache License, Version 2.0. The
+// H3 bindings for Go need cgo, which the static builds of Tile38 do not use.
+// The port is tested against vectors that were written by the C library, in
+// testdata.
+//
+// https://github.com/uber/h3
+// https://h3geo.org/docs/core-library/h3Indexing
+package h3
+
+import (
+	"math"
+	"strconv"
+)
+
+// MaxResolution is the finest resolution of a cell.
+const MaxResolution = maxRes
+
+// bit layout of an index
+const (
+	modeOffset     = 59
+	baseCellOffset = 45
+	resOffset      = 52
+	perDigitOffset = 3
+	cellMode       = 1
+	digitMask      = 7
+	initIndex      = 35184372088831 // all digits set to 7
+)
+
+// Cell is an H3 cell index.
+type Cell uint64
+
+// LatLng is a point in degrees.
+type LatLng struct {
+	Lat, Lng float64
+}
+
+// LatLngToCell returns the cell that contains a point at a resolution. The
+// result is zero when the resolution or point are not valid.
+func LatLngToCell(lat, lng float64, res int) Cell {
+	if res < 0 || res > maxRes {
+		return 0
+	}
+	if math.IsNaN(lat) || math.IsInf(lat, 0) ||
+		math.IsNaN(lng) || math.IsInf(lng, 0) {
+		return 0
+	}
+	g := latLng{lat * math.Pi / 180, lng * math.Pi / 180}
+	return faceIJKToCell(geoToFaceIJK(g, res), res)
+}
+
+// ParseCell parses a cell from its hexadecimal string representation.
+func ParseCell(s string) (Cell, bool) {
+	n, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, false
+	}
+	c := Cell(n)
+	return c, c.IsValid()
+}
+
+// String returns the hexadecimal representation of a cell.
+func (c Cell) String() string {
+	return strconv.FormatUint(uint64(c), 16)
+}
+
+// Resolution returns the resolution of a cell.
+func (c Cell) Resolution() int {
+	return int(c>>resOffset) & 15
+}
+
+func (c Cell) baseCell() int {
+	return int(c>>baseCellOffset) & 127
+}
+
+func (c Cell) digit(res int) direction {
+	return direction(c>>((maxRes-res)*perDigitOffset)) & digitMask
+}
+
+func (c *Cell) setDigit(res int, digit direction) {
+	shift := (maxRes - res) * perDigitOffset
+	*c = *c&^(digitMask<<shift) | Cell(digit)<<shift
+}
+
+// IsValid returns true if the value is a valid cell.
+func (c Cell) IsValid() bool {
+	// the high bit is zero, the mode is a cell, and the reserved bits are
+	// zero
+	if c>>56 != cellMode<<3 {
+		return false
+	}
+	res := c.Resolution()
+	baseCell := c.baseCell()
+	if baseCell >= numBaseCells {
+		return false
+	}
+	leading := centerDigit
+	for r := 1; r <= maxRes; r++ {
+		d := c.digit(r)
+		if r > res {
+			if d != invalidDigit {
+				return false
+			}
+			continue
+		}
+		if d == invalidDigit {
+			return false
+		}
+		if leading == centerDigit && d != centerDigit {
+			leading = d
+			// pentagons have no cells in the k axes direction
+			if isBaseCellPentagon(baseCell) && d == kAxesDigit {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsPentagon returns true if the cell is one of the twelve pentagons at its
+// resolution.
+func (c Cell) IsPentagon() bool {
+	return isBaseCellPentagon(c.baseCell()) &&
+		c.leadingNonZeroDigit() == centerDigit
+}
+
+// Parent returns the parent of a cell at a coarser resolution. The result is
+// zero when the resolution is not valid for the cell.
+func (c Cell) Parent(res int) Cell {
+	childRes := c.Resolution()
+	if res < 0 || res > childRes {
+		return 0
+	}
+	parent := c&^(15<<resOffset) | Cell(res)<<resOffset
+	for r := res + 1; r <= childRes; r++ {
+		parent.setDigit(r, invalidDigit)
+	}
+	return parent
+}
+
+// Children returns the cells at the next finer resolution whose parent is
+// the cell.
+func (c Cell) Children() []Cell {
+	res := c.Resolution()
+	if res == maxRes {
+		return nil
+	}
+	child := c&^(15<<resOffset) | Cell(res+1)<<resOffset
+	children := make([]Cell, 0, 7)
+	pent := c.IsPentagon()
+	for d := centerDigit; d < numDigits; d++ {
+		if pent && d == kAxesDigit {
+			// pentagons have no k axes child
+			continue
+		}
+		child.setDigit(res+1, d)
+		children = append(children, child)
+	}
+	return children
+}
+
+// BaseCells returns the 122 cells at resolution zero.
+func BaseCells() []Cell {
+	cells := make([]Cell, numBaseCells)
+	for i := range cells {
+		cells[i] = Cell(initIndex) | cellMode<<modeOffset |
+			Cell(i)<<baseCellOffset
+	}
+	return cells
+}
+
+// LatLng returns the center point of a cell.
+func (c Cell) LatLng() LatLng {
+	g := c.faceIJK().geo(c.Resolution())
+	return LatLng{g.lat * 180 / math.Pi, g.lng * 180 / math.Pi}
+}
+
+// Boundary returns the vertices of a cell in counter-clockwise order.
+func (c Cell) Boundary() []LatLng {
+	fijk := c.faceIJK()
+	var verts []latLng
+	if c.IsPentagon() {
+		verts = fijk.pentBoundary(c.Resolution())
+	} else {
+		verts = fijk.boundary(c.Resolution())
+	}
+	points := make([]LatLng, len(verts))
+	for i, g := range verts {
+		points[i] = LatLng{g.lat * 180 / math.Pi, g.lng * 180 / math.Pi}
+	}
+	return points
+}
+
+func (c Cell) leadingNonZeroDigit() direction {
+	for r := 1; r <= c.Resolution(); r++ {
+		if d := c.digit(r); d != centerDigit {
+			return d
+		}
+	}
+	return centerDigit
+}
+
+func (c Cell) rotate60ccw() Cell {
+	for r := 1; r <= c.Resolution(); r++ {
+		c.setDigit(r, c.digit(r).rotate60ccw())
+	}
+	return c
+}
+
+func (c Cell) rotate60cw() Cell {
+	for r := 1; r <= c.Resolution(); r++ {
+		c.setDigit(r, c.digit(r).rotate60cw())
+	}
+	return c
+}
+
+// rotatePent60ccw rotates a pentagon cell, skipping the deleted k axes
+// sequence.
+func (c Cell) rotatePent60ccw() Cell {
+	found := false
+	for r := 1; r <= c.Resolution(); r++ {
+		c.setDigit(r, c.digit(r).rotate60ccw())
+		if !found && c.digit(r) != centerDigit {
+			found = true
+			if c.leadingNonZeroDigit() == kAxesDigit {
+				c = c.rotate60ccw()
+			}
+		}
+	}
+	return c
+}
+
+// faceIJKToCell returns the cell of a face coordinate at a resolution.
+func faceIJKToCell(fijk faceIJK, res int) Cell {
+	c := Cell(initIndex) | cellMode<<modeOffset | Cell(res)<<resOffset
+	if res == 0 {
+		if fijk.coord.i > maxFaceCoord || fijk.coord.j > maxFaceCoord ||
+			fijk.coord.k > maxFaceCoord {
+			return 0
+		}
+		return c | Cell(fijk.baseCell().baseCell)<<baseCellOffset
+	}
+
+	// build the index from the finest resolution up
+	fijkBC := fijk
+	ijk := &fijkBC.coord
+	for r := res - 1; r >= 0; r-- {
+		lastIJK := *ijk
+		var lastCenter coordIJK
+		if isClassIII(r + 1) {
+			ijk.upAp7()
+			lastCenter = *ijk
+			lastCenter.downAp7()
+		} else {
+			ijk.upAp7r()
+			lastCenter = *ijk
+			lastCenter.downAp7r()
+		}
+		diff := lastIJK.sub(lastCenter)
+		diff.normalize()
+		c.setDigit(r+1, diff.unitDigit())
+	}
+
+	// fijkBC is now the base cell in the coordinate system of the face
+	if ijk.i > maxFaceCoord || ijk.j > maxFaceCoord || ijk.k > maxFaceCoord {
+		return 0
+	}
+	bc := fijkBC.baseCell()
+	c |= Cell(bc.baseCell) << baseCellOffset
+
+	// rotate to the canonical orientation of the base cell
+	if isBaseCellPentagon(bc.baseCell) {
+		// force rotation out of the missing k axes sequence
+		if c.leadingNonZeroDigit() == kAxesDigit {
+			if baseCellIsCwOffset(bc.baseCell, fijkBC.face) {
+				c = c.rotate60cw()
+			} else {
+				c = c.rotate60ccw()
+			}
+		}
+		for i := 0; i < bc.ccwRot60; i++ {
+			c = c.rotatePent60ccw()
+		}
+	} else {
+		for i := 0; i < bc.ccwRot60; i++ {
+			c = c.rotate60ccw()
+		}
+	}
+	return c
+}
+
+// faceIJK returns the face coordinate of a cell.
+func (c Cell) faceIJK() faceIJK {
+	baseCell := c.baseCell()
+	if baseCell >= numBaseCells {
+		return faceIJK{}
+	}
+	// adjust for the pentagonal missing sequence
+	if isBaseCellPentagon(baseCell) && c.leadingNonZeroDigit() == ikAxesDigit {
+		c = c.rotate60cw()
+	}
+
+	// start with the home face of the base cell
+	fijk := baseCellData[baseCell].homeFijk
+	res := c.Resolution()
+	possibleOverage := isBaseCellPentagon(baseCell) ||
+		(res != 0 && fijk.coord != coordIJK{})
+	for r := 1; r <= res; r++ {
+		if isClassIII(r) {
+			fijk.coord.downAp7()
+		} else {
+			fijk.coord.downAp7r()
+		}
+		fijk.coord.neighbor(c.digit(r))
+	}
+	if !possibleOverage {
+		return fijk
+	}
+
+	// the cell may lie on an adjacent face
+	origIJK := fijk.coord
+	adjRes := res
+	if isClassIII(res) {
+		fijk.coord.downAp7r()
+		adjRes++
+	}
+	pentLeading4 := isBaseCellPentagon(baseCell) &&
+		c.leadingNonZeroDigit() == iAxesDigit
+	if fijk.adjustOverageClassII(adjRes, pentLeading4, false) != noOverage {
+		// pentagons may have secondary overages
+		if isBaseCellPentagon(baseCell) {
+			for fijk.adjustOverageClassII(adjRes, false, false) != noOverage {
+			}
+		}
+		if adjRes != res {
+			fijk.coord.upAp7r()
+		}
+	} else if adjRes != res {
+		fijk.coord = origIJK
+	}
+	return fijk
+}
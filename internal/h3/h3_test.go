@@ -0,0 +1,181 @@
+package h3
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// readVectors returns the fields of each line of a file in testdata, which
+// were written by the H3 C library.
+func readVectors(t *testing.T, name string) [][]string {
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines [][]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.Fields(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func parseFloats(t *testing.T, ss []string) []float64 {
+	fs := make([]float64, len(ss))
+	for i, s := range ss {
+		var err error
+		if fs[i], err = strconv.ParseFloat(s, 64); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+func sameLatLng(a, b LatLng) bool {
+	dlng := math.Abs(a.Lng - b.Lng)
+	if dlng > 180 {
+		dlng = 360 - dlng
+	}
+	return math.Abs(a.Lat-b.Lat) < 1e-8 && dlng < 1e-8
+}
+
+// TestVectors checks the cells of points, and the centers and boundaries of
+// cells, against the H3 C library. The points are at every resolution, many
+// of them near the pentagons. The cells are the base cells, the distorted
+// cells around the pentagons, and cells that cross the edges of the faces of
+// the icosahedron.
+func TestVectors(t *testing.T) {
+	for _, v := range readVectors(t, "cells.txt") {
+		ll := parseFloats(t, v[:2])
+		res, _ := strconv.Atoi(v[2])
+		if c := LatLngToCell(ll[0], ll[1], res); c.String() != v[3] {
+			t.Fatalf("%v: expected '%s', got '%s'", ll, v[3], c)
+		}
+	}
+	for _, v := range readVectors(t, "boundaries.txt") {
+		c, ok := ParseCell(v[0])
+		if !ok {
+			t.Fatalf("expected valid '%s'", v[0])
+		}
+		fs := parseFloats(t, v[1:])
+		if center := c.LatLng(); !sameLatLng(center, LatLng{fs[0], fs[1]}) {
+			t.Fatalf("%s: expected center %v, got %v", c, fs[:2], center)
+		}
+		verts := c.Boundary()
+		if len(verts) != len(fs)/2-1 {
+			t.Fatalf("%s: expected %d vertices, got %d", c, len(fs)/2-1,
+				len(verts))
+		}
+		for i, vert := range verts {
+			expect := LatLng{fs[2+i*2], fs[3+i*2]}
+			if !sameLatLng(vert, expect) {
+				t.Fatalf("%s: expected %v, got %v", c, expect, vert)
+			}
+		}
+	}
+}
+
+func TestLatLngToCell(t *testing.T) {
+	tests := []struct {
+		lat, lng float64
+		res      int
+		cell     string
+	}{
+		{37.775938728915946, -122.41795063018799, 9, "8928308280fffff"},
+		{67.1509268640, -168.3908885810, 5, "850dab63fffffff"},
+		{37.775705522929044, -122.41812765598296, 0, "8029fffffffffff"},
+	}
+	for _, tt := range tests {
+		c := LatLngToCell(tt.lat, tt.lng, tt.res)
+		if c.String() != tt.cell {
+			t.Fatalf("expected '%s', got '%s'", tt.cell, c)
+		}
+		if c.Resolution() != tt.res {
+			t.Fatalf("expected %d, got %d", tt.res, c.Resolution())
+		}
+		ll := c.LatLng()
+		if LatLngToCell(ll.Lat, ll.Lng, tt.res) != c {
+			t.Fatalf("center of '%s' is not in the cell", c)
+		}
+	}
+	if LatLngToCell(0, 0, 16) != 0 || LatLngToCell(math.NaN(), 0, 1) != 0 {
+		t.Fatal("expected zero")
+	}
+}
+
+func TestCellBoundary(t *testing.T) {
+	c, ok := ParseCell("850dab63fffffff")
+	if !ok {
+		t.Fatal("expected valid")
+	}
+	expect := []LatLng{
+		{67.224749856, -168.523006585},
+		{67.140938355, -168.626914333},
+		{67.067252558, -168.494913285},
+		{67.077062918, -168.259695931},
+		{67.160561948, -168.154801171},
+		{67.234563187, -168.286102782},
+	}
+	verts := c.Boundary()
+	if len(verts) != len(expect) {
+		t.Fatalf("expected %d, got %d", len(expect), len(verts))
+	}
+	for i := range verts {
+		if math.Abs(verts[i].Lat-expect[i].Lat) > 1e-8 ||
+			math.Abs(verts[i].Lng-expect[i].Lng) > 1e-8 {
+			t.Fatalf("expected %v, got %v", expect[i], verts[i])
+		}
+	}
+	p, _ := ParseCell("821c07fffffffff")
+	if !p.IsPentagon() || len(p.Boundary()) != 5 {
+		t.Fatal("expected pentagon")
+	}
+}
+
+func TestCellParent(t *testing.T) {
+	c, _ := ParseCell("8928308280fffff")
+	if p := c.Parent(5); p.String() != "85283083fffffff" {
+		t.Fatalf("expected '85283083fffffff', got '%s'", p)
+	}
+	if c.Parent(9) != c || c.Parent(10) != 0 {
+		t.Fatal("invalid parent")
+	}
+}
+
+func TestParseCell(t *testing.T) {
+	for _, s := range []string{"", "hello", "8928308280fffff0", "0",
+		"8f28308280fffff", "821c07fffffffff0"} {
+		if _, ok := ParseCell(s); ok {
+			t.Fatalf("expected invalid '%s'", s)
+		}
+	}
+}
+
+func TestCellChildren(t *testing.T) {
+	var count int
+	for _, c := range BaseCells() {
+		if !c.IsValid() {
+			t.Fatalf("invalid base cell '%s'", c)
+		}
+		for _, child := range c.Children() {
+			if !child.IsValid() || child.Parent(0) != c {
+				t.Fatalf("invalid child '%s'", child)
+			}
+			count++
+		}
+	}
+	// 110 hexagons and 12 pentagons
+	if count != 110*7+12*6 {
+		t.Fatalf("expected %d, got %d", 110*7+12*6, count)
+	}
+}
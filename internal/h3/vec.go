@@ -0,0 +1,129 @@
+package h3
+
+import "math"
+
+const (
+	epsilon          = 1e-16
+	fltEpsilon       = 1.1920928955078125e-7
+	sqrt3_2          = 0.8660254037844386467637231707529361834714
+	rsin60           = 1.1547005383792515290182975610039149112953
+	oneSeventh       = 1.0 / 7.0
+	oneThird         = 1.0 / 3.0
+	sqrt7            = 2.6457513110645905905016157536392604257102
+	rsqrt7           = 0.37796447300922722721451653623418006081576
+	ap7RotRads       = 0.333473172251832115336090755351601070065900389
+	res0UGnomonic    = 0.38196601125010500003
+	invRes0UGnomonic = 2.61803398874989588842
+)
+
+// vec2d is a 2D cartesian coordinate.
+type vec2d struct {
+	x, y float64
+}
+
+func (v vec2d) mag() float64 {
+	return math.Sqrt(v.x*v.x + v.y*v.y)
+}
+
+func (v vec2d) almostEquals(o vec2d) bool {
+	return math.Abs(v.x-o.x) < fltEpsilon && math.Abs(v.y-o.y) < fltEpsilon
+}
+
+// intersect returns the point where the line p0,p1 crosses the line p2,p3.
+func intersect(p0, p1, p2, p3 vec2d) vec2d {
+	s1 := vec2d{p1.x - p0.x, p1.y - p0.y}
+	s2 := vec2d{p3.x - p2.x, p3.y - p2.y}
+	t := (s2.x*(p0.y-p2.y) - s2.y*(p0.x-p2.x)) / (-s2.x*s1.y + s1.x*s2.y)
+	return vec2d{p0.x + t*s1.x, p0.y + t*s1.y}
+}
+
+// vec3d is a 3D cartesian coordinate.
+type vec3d struct {
+	x, y, z float64
+}
+
+func (v vec3d) squareDist(o vec3d) float64 {
+	dx, dy, dz := v.x-o.x, v.y-o.y, v.z-o.z
+	return dx*dx + dy*dy + dz*dz
+}
+
+// latLng is a spherical coordinate in radians.
+type latLng struct {
+	lat, lng float64
+}
+
+func (g latLng) vec3d() vec3d {
+	r := math.Cos(g.lat)
+	return vec3d{math.Cos(g.lng) * r, math.Sin(g.lng) * r, math.Sin(g.lat)}
+}
+
+func posAngleRads(rads float64) float64 {
+	tmp := rads
+	if rads < 0 {
+		tmp = rads + 2*math.Pi
+	}
+	if rads >= 2*math.Pi {
+		tmp -= 2 * math.Pi
+	}
+	return tmp
+}
+
+func constrainLng(lng float64) float64 {
+	for lng > math.Pi {
+		lng -= 2 * math.Pi
+	}
+	for lng < -math.Pi {
+		lng += 2 * math.Pi
+	}
+	return lng
+}
+
+// azimuth returns the azimuth from p1 to p2 in radians.
+func azimuth(p1, p2 latLng) float64 {
+	return math.Atan2(math.Cos(p2.lat)*math.Sin(p2.lng-p1.lng),
+		math.Cos(p1.lat)*math.Sin(p2.lat)-
+			math.Sin(p1.lat)*math.Cos(p2.lat)*math.Cos(p2.lng-p1.lng))
+}
+
+// azDistance returns the point at an azimuth and distance from p1.
+func azDistance(p1 latLng, az, distance float64) latLng {
+	if distance < epsilon {
+		return p1
+	}
+	var p2 latLng
+	az = posAngleRads(az)
+	if az < epsilon || math.Abs(az-math.Pi) < epsilon {
+		// due north or south
+		if az < epsilon {
+			p2.lat = p1.lat + distance
+		} else {
+			p2.lat = p1.lat - distance
+		}
+		if math.Abs(p2.lat-math.Pi/2) < epsilon {
+			p2.lat, p2.lng = math.Pi/2, 0
+		} else if math.Abs(p2.lat+math.Pi/2) < epsilon {
+			p2.lat, p2.lng = -math.Pi/2, 0
+		} else {
+			p2.lng = constrainLng(p1.lng)
+		}
+		return p2
+	}
+	sinlat := math.Sin(p1.lat)*math.Cos(distance) +
+		math.Cos(p1.lat)*math.Sin(distance)*math.Cos(az)
+	sinlat = math.Max(-1, math.Min(1, sinlat))
+	p2.lat = math.Asin(sinlat)
+	if math.Abs(p2.lat-math.Pi/2) < epsilon {
+		p2.lat, p2.lng = math.Pi/2, 0
+	} else if math.Abs(p2.lat+math.Pi/2) < epsilon {
+		p2.lat, p2.lng = -math.Pi/2, 0
+	} else {
+		invcosp2lat := 1.0 / math.Cos(p2.lat)
+		sinlng := math.Sin(az) * math.Sin(distance) * invcosp2lat
+		coslng := (math.Cos(distance) - math.Sin(p1.lat)*math.Sin(p2.lat)) /
+			math.Cos(p1.lat) * invcosp2lat
+		sinlng = math.Max(-1, math.Min(1, sinlng))
+		coslng = math.Max(-1, math.Min(1, coslng))
+		p2.lng = constrainLng(p1.lng + math.Atan2(sinlng, coslng))
+	}
+	return p2
+}
@@ -0,0 +1,75 @@
+package rtree
+
+// Join calls iter for each pair of items, one from each tree, whose rects
+// pass the test. Both trees are traversed at once, and only the pairs of
+// nodes whose rects pass the test are descended into, so the test must also
+// pass for the rects of the nodes that contain a passing pair of items.
+func (tr *RTreeGN[N, T]) Join(other *RTreeGN[N, T],
+	test func(amin, amax, bmin, bmax [2]N) bool,
+	iter func(amin, amax [2]N, a T, bmin, bmax [2]N, b T) bool,
+) {
+	if tr.root == nil || other.root == nil {
+		return
+	}
+	if test(tr.rect.min, tr.rect.max, other.rect.min, other.rect.max) {
+		join(tr.root, &tr.rect, other.root, &other.rect, test, iter)
+	}
+}
+
+func join[N numeric, T any](a *node[N, T], ar *rect[N], b *node[N, T],
+	br *rect[N], test func(amin, amax, bmin, bmax [2]N) bool,
+	iter func(amin, amax [2]N, a T, bmin, bmax [2]N, b T) bool,
+) bool {
+	arects := a.rects[:a.count]
+	brects := b.rects[:b.count]
+	switch {
+	case a.leaf() && b.leaf():
+		aitems := a.items()
+		bitems := b.items()
+		for i := range arects {
+			for j := range brects {
+				if test(arects[i].min, arects[i].max,
+					brects[j].min, brects[j].max) {
+					if !iter(arects[i].min, arects[i].max, aitems[i],
+						brects[j].min, brects[j].max, bitems[j]) {
+						return false
+					}
+				}
+			}
+		}
+	case a.leaf():
+		// the other tree is deeper, descend it alone
+		children := b.children()
+		for j := range brects {
+			if test(ar.min, ar.max, brects[j].min, brects[j].max) {
+				if !join(a, ar, children[j], &brects[j], test, iter) {
+					return false
+				}
+			}
+		}
+	case b.leaf():
+		children := a.children()
+		for i := range arects {
+			if test(arects[i].min, arects[i].max, br.min, br.max) {
+				if !join(children[i], &arects[i], b, br, test, iter) {
+					return false
+				}
+			}
+		}
+	default:
+		achildren := a.children()
+		bchildren := b.children()
+		for i := range arects {
+			for j := range brects {
+				if test(arects[i].min, arects[i].max,
+					brects[j].min, brects[j].max) {
+					if !join(achildren[i], &arects[i], bchildren[j],
+						&brects[j], test, iter) {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package rtree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randRects(rng *rand.Rand, n int, size float64) [][2][2]float64 {
+	rects := make([][2][2]float64, n)
+	for i := range rects {
+		x := rng.Float64()*360 - 180
+		y := rng.Float64()*180 - 90
+		w := rng.Float64() * size
+		h := rng.Float64() * size
+		rects[i] = [2][2]float64{{x, y}, {x + w, y + h}}
+	}
+	return rects
+}
+
+func TestJoin(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	intersects := func(amin, amax, bmin, bmax [2]float64) bool {
+		return !(bmin[0] > amax[0] || bmax[0] < amin[0] ||
+			bmin[1] > amax[1] || bmax[1] < amin[1])
+	}
+	for _, n := range [][2]int{{0, 10}, {1, 1}, {10, 5000}, {3000, 2000}} {
+		arects := randRects(rng, n[0], 10)
+		brects := randRects(rng, n[1], 5)
+		var a, b RTreeGN[float64, int]
+		for i, r := range arects {
+			a.Insert(r[0], r[1], i)
+		}
+		for i, r := range brects {
+			b.Insert(r[0], r[1], i)
+		}
+		expect := make(map[[2]int]bool)
+		for i, ar := range arects {
+			for j, br := range brects {
+				if intersects(ar[0], ar[1], br[0], br[1]) {
+					expect[[2]int{i, j}] = true
+				}
+			}
+		}
+		got := make(map[[2]int]bool)
+		a.Join(&b, intersects, func(_, _ [2]float64, i int, _, _ [2]float64,
+			j int,
+		) bool {
+			if got[[2]int{i, j}] {
+				t.Fatalf("%v: pair %d %d is joined twice", n, i, j)
+			}
+			got[[2]int{i, j}] = true
+			return true
+		})
+		if len(got) != len(expect) {
+			t.Fatalf("%v: expected %d pairs, got %d", n, len(expect), len(got))
+		}
+		for pair := range expect {
+			if !got[pair] {
+				t.Fatalf("%v: missing pair %v", n, pair)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package rtree
+
+// SearchFrom is like SearchNodes, but the items are in the order of the west
+// edges of their rects, min[0], starting with the items whose west edge is
+// not before from. Items with the same west edge are in no particular order.
+// The nodes that only have items before from are not visited, so a search
+// can continue where an earlier one stopped.
+func (tr *RTreeGN[N, T]) SearchFrom(min, max [2]N, from N, nodes *uint64,
+	iter func(min, max [2]N, data T) bool,
+) {
+	target := rect[N]{min, max}
+	if tr.root == nil || !target.intersects(&tr.rect) ||
+		tr.rect.max[0] < from {
+		return
+	}
+	q := tr.qpool.Get().(*queue[N, T])
+	defer func() {
+		*q = (*q)[:0]
+		tr.qpool.Put(q)
+	}()
+	// The west edge of a node is never after the west edges of its items,
+	// so the queue releases the items from the west.
+	q.push(qnode[N, T]{
+		dist: float64(tr.rect.min[0]),
+		rect: tr.rect,
+		node: tr.root,
+	})
+	for {
+		qn, ok := q.pop()
+		if !ok {
+			return
+		}
+		if qn.node == nil {
+			if !iter(qn.rect.min, qn.rect.max, qn.data) {
+				return
+			}
+			continue
+		}
+		if nodes != nil {
+			*nodes++
+		}
+		rects := qn.node.rects[:qn.node.count]
+		if qn.node.leaf() {
+			items := qn.node.items()
+			for i := range rects {
+				if rects[i].min[0] >= from && target.intersects(&rects[i]) {
+					q.push(qnode[N, T]{
+						dist: float64(rects[i].min[0]),
+						rect: rects[i],
+						data: items[i],
+					})
+				}
+			}
+		} else {
+			children := qn.node.children()
+			for i := range rects {
+				if rects[i].max[0] >= from && target.intersects(&rects[i]) {
+					q.push(qnode[N, T]{
+						dist: float64(rects[i].min[0]),
+						rect: rects[i],
+						node: children[i],
+					})
+				}
+			}
+		}
+	}
+}
+
+// NearbyFrom is like NearbyNodes, but it skips the nodes and items for which
+// skip returns true, such as the ones that were returned by an earlier
+// search. A node is only skipped when all of its items would be.
+func (tr *RTreeGN[N, T]) NearbyFrom(
+	dist func(min, max [2]N, data T, item bool) float64,
+	skip func(min, max [2]N, data T, item bool) bool,
+	nodes *uint64,
+	iter func(min, max [2]N, data T, dist float64) bool,
+) {
+	tr.nearby(dist, skip, nodes, iter)
+}
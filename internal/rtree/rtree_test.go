@@ -0,0 +1,70 @@
+package rtree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func (n *node[N, T]) deepNodes() uint64 {
+	count := uint64(1)
+	if n.leaf() {
+		return count
+	}
+	for _, child := range n.children()[:n.count] {
+		count += child.deepNodes()
+	}
+	return count
+}
+
+func TestNodes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var tr RTreeGN[float64, int]
+	var nodes uint64
+	tr.SearchNodes([2]float64{-180, -90}, [2]float64{180, 90}, &nodes,
+		func(min, max [2]float64, data int) bool { return true })
+	if nodes != 0 {
+		t.Fatalf("expected 0 nodes, got %d", nodes)
+	}
+	for i, r := range randRects(rng, 10000, 0) {
+		tr.Insert(r[0], r[1], i)
+	}
+	total := tr.root.deepNodes()
+	if total < 100 {
+		t.Fatalf("expected a deep tree, got %d nodes", total)
+	}
+
+	// all of the nodes
+	nodes = 0
+	tr.SearchNodes([2]float64{-180, -90}, [2]float64{180, 90}, &nodes,
+		func(min, max [2]float64, data int) bool { return true })
+	if nodes != total {
+		t.Fatalf("expected %d nodes, got %d", total, nodes)
+	}
+
+	// a small area only visits the nodes on its way down
+	nodes = 0
+	var count int
+	tr.SearchNodes([2]float64{0, 0}, [2]float64{10, 10}, &nodes,
+		func(min, max [2]float64, data int) bool {
+			count++
+			return true
+		})
+	if count == 0 || nodes < 2 || nodes > total/4 {
+		t.Fatalf("expected a few nodes for %d items, got %d of %d", count,
+			nodes, total)
+	}
+
+	// the nearest items only visit the nodes that are closest
+	nodes = 0
+	count = 0
+	tr.NearbyNodes(
+		BoxDist[float64, int]([2]float64{0, 0}, [2]float64{0, 0}, nil),
+		&nodes,
+		func(min, max [2]float64, data int, dist float64) bool {
+			count++
+			return count < 10
+		})
+	if nodes < 2 || nodes > total/4 {
+		t.Fatalf("expected a few nodes, got %d of %d", nodes, total)
+	}
+}
@@ -0,0 +1,355 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/field"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+var errTooManyValues = errors.New("too many values for percentile")
+
+// maxPercentileValues is the most values that the percentiles of a search
+// keep, for all of the groups together.
+const maxPercentileValues = 1000000
+
+type aggregateOp int
+
+const (
+	aggregateCount aggregateOp = iota
+	aggregateSum
+	aggregateAvg
+	aggregateMin
+	aggregateMax
+	aggregatePercentile
+)
+
+// aggregateT is a single aggregate function, such as "AVG speed".
+type aggregateT struct {
+	op         aggregateOp
+	field      string
+	percentile float64
+}
+
+// name returns the name of the aggregate in the response, such as
+// "avg(speed)" or "p95(speed)".
+func (agg aggregateT) name() string {
+	var fn string
+	switch agg.op {
+	case aggregateCount:
+		return "count"
+	case aggregateSum:
+		fn = "sum"
+	case aggregateAvg:
+		fn = "avg"
+	case aggregateMin:
+		fn = "min"
+	case aggregateMax:
+		fn = "max"
+	case aggregatePercentile:
+		fn = "p" + strconv.FormatFloat(agg.percentile, 'f', -1, 64)
+	}
+	return fn + "(" + agg.field + ")"
+}
+
+// aggregateState is the running state of one aggregate function.
+type aggregateState struct {
+	count    uint64
+	sum      float64
+	min, max float64
+	values   []float64 // only for percentiles
+}
+
+func (st *aggregateState) push(agg aggregateT, v float64) {
+	if st.count == 0 || v < st.min {
+		st.min = v
+	}
+	if st.count == 0 || v > st.max {
+		st.max = v
+	}
+	st.count++
+	st.sum += v
+	if agg.op == aggregatePercentile {
+		st.values = append(st.values, v)
+	}
+}
+
+// result returns the result of an aggregate. Returns false when there were
+// no values to aggregate.
+func (st *aggregateState) result(agg aggregateT) (float64, bool) {
+	if st.count == 0 {
+		return 0, false
+	}
+	switch agg.op {
+	case aggregateCount:
+		return float64(st.count), true
+	case aggregateSum:
+		return st.sum, true
+	case aggregateAvg:
+		return st.sum / float64(st.count), true
+	case aggregateMin:
+		return st.min, true
+	case aggregateMax:
+		return st.max, true
+	default:
+		// linear interpolation between the closest ranks
+		sort.Float64s(st.values)
+		rank := agg.percentile / 100 * float64(len(st.values)-1)
+		i := int(rank)
+		if i == len(st.values)-1 {
+			return st.values[i], true
+		}
+		frac := rank - float64(i)
+		return st.values[i] + (st.values[i+1]-st.values[i])*frac, true
+	}
+}
+
+// aggregateGroup is the state of all of the aggregates for a single group.
+type aggregateGroup struct {
+	key    string
+	count  uint64
+	states []aggregateState
+}
+
+// aggregator computes the aggregates of the objects that are written to a
+// scanWriter. The objects are grouped by a field for GROUPBY, or by the cell
+// that contains the object for GRID.
+type aggregator struct {
+	aggs    []aggregateT
+	groupBy string
+	grid    string // grid system for GRID
+	level   int    // grid level for GRID
+	groups  map[string]*aggregateGroup
+	values  int // values kept for percentiles
+}
+
+// parseAggregate parses the arguments that follow the AGGREGATE keyword.
+//
+//	AGGREGATE [GROUPBY field] COUNT|SUM field|AVG field|MIN field|MAX field|
+//	  PERCENTILE p field ...
+//
+// The arguments end at the first token that is not an aggregate function.
+func parseAggregate(vs []string) ([]string, *aggregator, error) {
+	ag := &aggregator{groups: make(map[string]*aggregateGroup)}
+	vs, err := ag.parseFuncs(vs, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ag.aggs) == 0 {
+		if len(vs) == 0 {
+			return nil, nil, errInvalidNumberOfArguments
+		}
+		return nil, nil, errInvalidArgument(vs[0])
+	}
+	return vs, ag, nil
+}
+
+// parseFuncs parses aggregate functions until the first token that is not
+// an aggregate function.
+func (ag *aggregator) parseFuncs(vs []string, allowGroupBy bool,
+) ([]string, error) {
+	var groupBy bool
+	for len(vs) > 0 {
+		var op aggregateOp
+		switch strings.ToLower(vs[0]) {
+		case "groupby":
+			if !allowGroupBy {
+				return vs, nil
+			}
+			if groupBy {
+				return nil, errDuplicateArgument(strings.ToUpper(vs[0]))
+			}
+			if len(vs) < 2 || vs[1] == "" {
+				return nil, errInvalidNumberOfArguments
+			}
+			ag.groupBy = vs[1]
+			groupBy = true
+			vs = vs[2:]
+			continue
+		case "count":
+			ag.aggs = append(ag.aggs, aggregateT{op: aggregateCount})
+			vs = vs[1:]
+			continue
+		case "sum":
+			op = aggregateSum
+		case "avg":
+			op = aggregateAvg
+		case "min":
+			op = aggregateMin
+		case "max":
+			op = aggregateMax
+		case "percentile":
+			if len(vs) < 3 || vs[1] == "" || vs[2] == "" {
+				return nil, errInvalidNumberOfArguments
+			}
+			p, err := strconv.ParseFloat(vs[1], 64)
+			if err != nil || !(p >= 0 && p <= 100) {
+				return nil, errInvalidArgument(vs[1])
+			}
+			ag.aggs = append(ag.aggs, aggregateT{
+				op: aggregatePercentile, field: vs[2], percentile: p,
+			})
+			vs = vs[3:]
+			continue
+		default:
+			return vs, nil
+		}
+		if len(vs) < 2 || vs[1] == "" {
+			return nil, errInvalidNumberOfArguments
+		}
+		ag.aggs = append(ag.aggs, aggregateT{op: op, field: vs[1]})
+		vs = vs[2:]
+	}
+	return vs, nil
+}
+
+// binnable returns false for an object that has no cell in the GRID, which
+// is not aggregated.
+func (ag *aggregator) binnable(o *object.Object) bool {
+	return ag.grid == "" || (objIsSpatial(o.Geo()) && !o.Geo().Empty())
+}
+
+// push adds an object to the aggregates. Only number values are aggregated.
+// It fails when the percentiles have too many values.
+func (ag *aggregator) push(o *object.Object) error {
+	var key string
+	if ag.grid != "" {
+		var ok bool
+		if key, ok = gridCellOf(o.Geo(), ag.grid, ag.level); !ok {
+			return nil
+		}
+	} else if ag.groupBy != "" {
+		key = getFieldValue(o, ag.groupBy).Data()
+	}
+	g := ag.groups[key]
+	if g == nil {
+		g = &aggregateGroup{
+			key:    key,
+			states: make([]aggregateState, len(ag.aggs)),
+		}
+		ag.groups[key] = g
+	}
+	g.count++
+	for i, agg := range ag.aggs {
+		if agg.op == aggregateCount {
+			g.states[i].push(agg, 0)
+			continue
+		}
+		v := getFieldValue(o, agg.field)
+		if v.Kind() == field.Number && !math.IsNaN(v.Num()) {
+			if agg.op == aggregatePercentile {
+				if ag.values == maxPercentileValues {
+					return errTooManyValues
+				}
+				ag.values++
+			}
+			g.states[i].push(agg, v.Num())
+		}
+	}
+	return nil
+}
+
+// sortedGroups returns the groups ordered by key.
+func (ag *aggregator) sortedGroups() []*aggregateGroup {
+	groups := make([]*aggregateGroup, 0, len(ag.groups))
+	for _, g := range ag.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].key < groups[j].key
+	})
+	return groups
+}
+
+func (ag *aggregator) appendJSONResults(wr *bytes.Buffer, g *aggregateGroup) {
+	wr.WriteByte('{')
+	for i, agg := range ag.aggs {
+		if i > 0 {
+			wr.WriteByte(',')
+		}
+		wr.WriteString(jsonString(agg.name()) + ":")
+		var v float64
+		var ok bool
+		if g != nil {
+			v, ok = g.states[i].result(agg)
+		}
+		if ok {
+			wr.WriteString(field.ValueOf(
+				strconv.FormatFloat(v, 'f', -1, 64)).JSON())
+		} else if agg.op == aggregateCount {
+			wr.WriteByte('0')
+		} else {
+			wr.WriteString("null")
+		}
+	}
+	wr.WriteByte('}')
+}
+
+func (ag *aggregator) respResults(g *aggregateGroup) resp.Value {
+	var vals []resp.Value
+	for i, agg := range ag.aggs {
+		vals = append(vals, resp.StringValue(agg.name()))
+		var v float64
+		var ok bool
+		if g != nil {
+			v, ok = g.states[i].result(agg)
+		}
+		if ok {
+			vals = append(vals,
+				resp.StringValue(strconv.FormatFloat(v, 'f', -1, 64)))
+		} else if agg.op == aggregateCount {
+			vals = append(vals, resp.StringValue("0"))
+		} else {
+			vals = append(vals, resp.NullValue())
+		}
+	}
+	return resp.ArrayValue(vals)
+}
+
+// writeJSON writes the aggregates as the "aggregates" member, or as the
+// "groups" member when there's a GROUPBY.
+func (ag *aggregator) writeJSON(wr *bytes.Buffer) {
+	if ag.grid != "" {
+		ag.writeGridJSON(wr)
+		return
+	}
+	if ag.groupBy == "" {
+		wr.WriteString(`,"aggregates":`)
+		ag.appendJSONResults(wr, ag.groups[""])
+		return
+	}
+	wr.WriteString(`,"groups":[`)
+	for i, g := range ag.sortedGroups() {
+		if i > 0 {
+			wr.WriteByte(',')
+		}
+		wr.WriteString(`{"group":` + jsonString(g.key) + `,"aggregates":`)
+		ag.appendJSONResults(wr, g)
+		wr.WriteByte('}')
+	}
+	wr.WriteByte(']')
+}
+
+// respValues returns the aggregates as a list of name and value pairs, or as
+// a list of group and aggregates pairs when there's a GROUPBY.
+func (ag *aggregator) respValues() []resp.Value {
+	if ag.grid != "" {
+		return ag.gridRespValues()
+	}
+	if ag.groupBy == "" {
+		return ag.respResults(ag.groups[""]).Array()
+	}
+	var vals []resp.Value
+	for _, g := range ag.sortedGroups() {
+		vals = append(vals, resp.ArrayValue([]resp.Value{
+			resp.StringValue(g.key), ag.respResults(g),
+		}))
+	}
+	return vals
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/tidwall/tile38/internal/field"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+func TestAggregatePercentileLimit(t *testing.T) {
+	_, ag, err := parseAggregate([]string{"PERCENTILE", "50", "speed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields field.List
+	fields = fields.Set(field.Make("speed", "10"))
+	o := object.New("1", PO(-115, 33), 0, fields)
+	for i := 0; i < maxPercentileValues; i++ {
+		if err := ag.push(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ag.push(o); err != errTooManyValues {
+		t.Fatalf("expected '%v', got '%v'", errTooManyValues, err)
+	}
+	if v, ok := ag.groups[""].states[0].result(ag.aggs[0]); !ok || v != 10 {
+		t.Fatalf("expected 10, got %v", v)
+	}
+}
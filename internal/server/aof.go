@@ -99,6 +99,15 @@ func (s *Server) loadAOF() (err error) {
 				for _, arg := range args {
 					msg.Args = append(msg.Args, string(arg))
 				}
+				if msg.Command() == "select" && len(msg.Args) == 2 {
+					// following entries belong to the selected database
+					s.selectDB(msg.Args[1])
+					s.aofdb = msg.Args[1]
+					count++
+					continue
+				}
+				msg.DB = s.aofdb
+				msg.replay = true
 				if _, _, err := s.command(&msg, nil); err != nil {
 					if commandErrIsFatal(err) {
 						return err
@@ -119,6 +128,9 @@ func commandErrIsFatal(err error) bool {
 	// FSET (and other writable commands) may return errors that we need
 	// to ignore during the loading process. These errors may occur (though unlikely)
 	// due to the aof rewrite operation.
+	if _, ok := err.(schemaError); ok {
+		return false
+	}
 	return !(err == errKeyNotFound || err == errIDNotFound)
 }
 
@@ -151,24 +163,19 @@ func (s *Server) writeAOF(args []string, d *commandDetails) error {
 		return nil
 	}
 
-	if s.shrinking {
-		nargs := make([]string, len(args))
-		copy(nargs, args)
-		s.shrinklog = append(s.shrinklog, nargs)
-	}
-
-	if s.aof != nil {
-		s.aofdirty.Store(true) // prewrite optimization flag
-		n := len(s.aofbuf)
-		s.aofbuf = redcon.AppendArray(s.aofbuf, len(args))
-		for _, arg := range args {
-			s.aofbuf = redcon.AppendBulkString(s.aofbuf, arg)
-		}
-		s.aofsz += len(s.aofbuf) - n
+	if d != nil && d.db != nil && d.db.name != s.aofdb {
+		// tag the following entries with the database of the command
+		s.appendAOF([]string{"select", d.db.name})
+		s.aofdb = d.db.name
 	}
+	s.appendAOF(args)
 
 	// process geofences
 	if d != nil {
+		for _, child := range d.children {
+			// children always belong to the database of the parent
+			child.db = d.db
+		}
 		// webhook geofences
 		if s.config.followHost() == "" {
 			// for leader only
@@ -204,10 +211,33 @@ func (s *Server) writeAOF(args []string, d *commandDetails) error {
 	return nil
 }
 
+// appendAOF appends a single entry to the aof buffer.
+func (s *Server) appendAOF(args []string) {
+	if s.shrinking {
+		nargs := make([]string, len(args))
+		copy(nargs, args)
+		s.shrinklog = append(s.shrinklog, nargs)
+	}
+
+	if s.aof != nil {
+		s.aofdirty.Store(true) // prewrite optimization flag
+		n := len(s.aofbuf)
+		s.aofbuf = redcon.AppendArray(s.aofbuf, len(args))
+		for _, arg := range args {
+			s.aofbuf = redcon.AppendBulkString(s.aofbuf, arg)
+		}
+		s.aofsz += len(s.aofbuf) - n
+	}
+}
+
 func (s *Server) getQueueCandidates(d *commandDetails) []*Hook {
+	db := d.db
+	if db == nil {
+		return nil
+	}
 	candidates := make(map[*Hook]bool)
 	// add the hooks with "outside" detection
-	s.hooksOut.Ascend(nil, func(v interface{}) bool {
+	db.hooksOut.Ascend(nil, func(v interface{}) bool {
 		hook := v.(*Hook)
 		if hook.Key == d.key {
 			candidates[hook] = true
@@ -215,9 +245,9 @@ func (s *Server) getQueueCandidates(d *commandDetails) []*Hook {
 		return true
 	})
 	// look for candidates that might "cross" geofences
-	if d.old != nil && d.obj != nil && s.hookCross.Len() > 0 {
+	if d.old != nil && d.obj != nil && db.hookCross.Len() > 0 {
 		r1, r2 := d.old.Rect(), d.obj.Rect()
-		s.hookCross.Search(
+		db.hookCross.Search(
 			[2]float64{
 				math.Min(r1.Min.X, r2.Min.X),
 				math.Min(r1.Min.Y, r2.Min.Y),
@@ -226,8 +256,7 @@ func (s *Server) getQueueCandidates(d *commandDetails) []*Hook {
 				math.Max(r1.Max.X, r2.Max.X),
 				math.Max(r1.Max.Y, r2.Max.Y),
 			},
-			func(min, max [2]float64, value interface{}) bool {
-				hook := value.(*Hook)
+			func(min, max [2]float64, hook *Hook) bool {
 				if hook.Key == d.key {
 					candidates[hook] = true
 				}
@@ -237,11 +266,10 @@ func (s *Server) getQueueCandidates(d *commandDetails) []*Hook {
 	// look for candidates that overlap the old object
 	if d.old != nil {
 		r1 := d.old.Rect()
-		s.hookTree.Search(
+		db.hookTree.Search(
 			[2]float64{r1.Min.X, r1.Min.Y},
 			[2]float64{r1.Max.X, r1.Max.Y},
-			func(min, max [2]float64, value interface{}) bool {
-				hook := value.(*Hook)
+			func(min, max [2]float64, hook *Hook) bool {
 				if hook.Key == d.key {
 					candidates[hook] = true
 				}
@@ -251,11 +279,10 @@ func (s *Server) getQueueCandidates(d *commandDetails) []*Hook {
 	// look for candidates that overlap the new object
 	if d.obj != nil {
 		r1 := d.obj.Rect()
-		s.hookTree.Search(
+		db.hookTree.Search(
 			[2]float64{r1.Min.X, r1.Min.Y},
 			[2]float64{r1.Max.X, r1.Max.Y},
-			func(min, max [2]float64, value interface{}) bool {
-				hook := value.(*Hook)
+			func(min, max [2]float64, hook *Hook) bool {
 				if hook.Key == d.key {
 					candidates[hook] = true
 				}
@@ -274,19 +301,33 @@ func (s *Server) getQueueCandidates(d *commandDetails) []*Hook {
 }
 
 func (s *Server) queueHooks(d *commandDetails) error {
+	// Compile a slice of potential hook recipients
+	return s.queueHookMessages(s.getQueueCandidates(d), d)
+}
+
+// queueHookMessages sends the fence messages of the candidate hooks to their
+// channels and queues them for their endpoints.
+func (s *Server) queueHookMessages(candidates []*Hook, d *commandDetails) error {
 	// Create the slices that will store all messages and hooks
 	var cmsgs, wmsgs []string
 	var whooks []*Hook
+	var chooks map[string]*Hook // channels by name
 
-	// Compile a slice of potential hook recipients
-	candidates := s.getQueueCandidates(d)
 	for _, hook := range candidates {
+		if !hook.schedule.active(d.timestamp) {
+			// the fence is outside of its schedule
+			continue
+		}
 		// Calculate all matching fence messages for all candidates and append
 		// them to the appropriate message slice
 		msgs := FenceMatch(hook.Name, hook.ScanWriter, hook.Fence, hook.Metas, d)
 		if len(msgs) > 0 {
 			if hook.channel {
 				cmsgs = append(cmsgs, msgs...)
+				if chooks == nil {
+					chooks = make(map[string]*Hook)
+				}
+				chooks[hook.Name] = hook
 			} else {
 				wmsgs = append(wmsgs, msgs...)
 				whooks = append(whooks, hook)
@@ -310,19 +351,31 @@ func (s *Server) queueHooks(d *commandDetails) error {
 	// Publish all channel messages if any exist
 	if len(cmsgs) > 0 {
 		for _, m := range cmsgs {
-			s.Publish(gjson.Get(m, "hook").String(), m)
+			name := gjson.Get(m, "hook").String()
+			hook := chooks[name]
+			s.Publish(pubsubName(hook.dbname, name), hook.payload(m))
 		}
 	}
 
 	// Queue the webhook messages in the buntdb database
+	byName := make(map[string]*Hook, len(whooks))
+	for _, hook := range whooks {
+		byName[hook.Name] = hook
+	}
 	err := s.qdb.Update(func(tx *buntdb.Tx) error {
 		for _, msg := range wmsgs {
 			s.qidx++ // increment the log id
 			key := hookLogPrefix + uint64ToString(s.qidx)
-			_, _, err := tx.Set(key, msg, hookLogSetDefaults)
+			opts := hookLogSetDefaults
+			if hook := byName[gjson.Get(msg, "hook").String()]; hook != nil {
+				opts = hook.queueOptions()
+				hook.stats.add(&hook.stats.queued, 1)
+			}
+			_, _, err := tx.Set(key, msg, opts)
 			if err != nil {
 				return err
 			}
+			s.qpending.add(msg, 1)
 			log.Debugf("queued hook: %d", s.qidx)
 		}
 		_, _, err := tx.Set("hook:idx", uint64ToString(s.qidx), nil)
@@ -370,6 +423,10 @@ func msgDetectCode(detect string) int {
 		return 3
 	case "inside":
 		return 4
+	case "dwell":
+		return 5
+	case "loiter":
+		return 6
 	default:
 		return 0
 	}
@@ -18,6 +18,21 @@ const maxkeys = 8
 const maxids = 32
 const maxchunk = 4 * 1024 * 1024
 
+// appendAOFValues appends a single command to an aof buffer.
+func appendAOFValues(aofbuf []byte, values []string) []byte {
+	aofbuf = append(aofbuf, '*')
+	aofbuf = append(aofbuf, strconv.FormatInt(int64(len(values)), 10)...)
+	aofbuf = append(aofbuf, '\r', '\n')
+	for _, value := range values {
+		aofbuf = append(aofbuf, '$')
+		aofbuf = append(aofbuf, strconv.FormatInt(int64(len(value)), 10)...)
+		aofbuf = append(aofbuf, '\r', '\n')
+		aofbuf = append(aofbuf, value...)
+		aofbuf = append(aofbuf, '\r', '\n')
+	}
+	return aofbuf
+}
+
 func (s *Server) aofshrink() {
 	start := time.Now()
 	s.mu.Lock()
@@ -27,6 +42,7 @@ func (s *Server) aofshrink() {
 	}
 	s.shrinking = true
 	s.shrinklog = nil
+	shrinkdb := s.aofdb // database of the first entry in the shrink log
 	s.mu.Unlock()
 
 	defer func() {
@@ -45,172 +61,239 @@ func (s *Server) aofshrink() {
 		defer f.Close()
 		var aofbuf []byte
 		var values []string
-		var keys []string
-		var nextkey string
-		var keysdone bool
-		for {
-			if len(keys) == 0 {
-				// load more keys
-				if keysdone {
-					break
+
+		// load the names of the databases, the default database first
+		var dbnames []string
+		func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			dbnames = append(dbnames, defaultDB)
+			s.dbs.Scan(func(name string, _ *database) bool {
+				if name != defaultDB {
+					dbnames = append(dbnames, name)
 				}
-				keysdone = true
-				func() {
-					s.mu.Lock()
-					defer s.mu.Unlock()
-					s.cols.Ascend(nextkey,
-						func(key string, col *collection.Collection) bool {
-							if len(keys) == maxkeys {
-								keysdone = false
-								nextkey = key
-								return false
-							}
-							keys = append(keys, key)
-							return true
-						},
-					)
-				}()
-				continue
+				return true
+			})
+		}()
+		aofdb := defaultDB
+		for _, dbname := range dbnames {
+			var db *database
+			func() {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				db = s.getDB(dbname)
+			}()
+			if dbname != aofdb {
+				aofbuf = appendAOFValues(aofbuf, []string{"select", dbname})
+				aofdb = dbname
 			}
-
-			var idsdone bool
-			var nextid string
+			var keys []string
+			var nextkey string
+			var keysdone bool
 			for {
-				if idsdone {
-					keys = keys[1:]
-					break
+				if len(keys) == 0 {
+					// load more keys
+					if keysdone {
+						break
+					}
+					keysdone = true
+					func() {
+						s.mu.Lock()
+						defer s.mu.Unlock()
+						db.cols.Ascend(nextkey,
+							func(key string, col *collection.Collection) bool {
+								if len(keys) == maxkeys {
+									keysdone = false
+									nextkey = key
+									return false
+								}
+								keys = append(keys, key)
+								return true
+							},
+						)
+					}()
+					continue
 				}
 
-				// load more objects
-				func() {
-					idsdone = true
-					s.mu.Lock()
-					defer s.mu.Unlock()
-					col, ok := s.cols.Get(keys[0])
-					if !ok {
-						return
+				var idsdone bool
+				var nextid string
+				for {
+					if idsdone {
+						keys = keys[1:]
+						break
 					}
-					var now = time.Now().UnixNano() // used for expiration
-					var count = 0                   // the object count
-					col.ScanGreaterOrEqual(nextid, false, nil, nil,
-						func(o *object.Object) bool {
-							if count == maxids {
-								// we reached the max number of ids for one batch
-								nextid = o.ID()
-								idsdone = false
-								return false
-							}
-							// here we fill the values array with a new command
-							values = values[:0]
-							values = append(values, "set")
-							values = append(values, keys[0])
-							values = append(values, o.ID())
-							o.Fields().Scan(func(f field.Field) bool {
-								if !f.Value().IsZero() {
-									values = append(values, "field")
-									values = append(values, f.Name())
-									values = append(values, f.Value().JSON())
+
+					// load more objects
+					func() {
+						idsdone = true
+						s.mu.Lock()
+						defer s.mu.Unlock()
+						col, ok := db.cols.Get(keys[0])
+						if !ok {
+							return
+						}
+						var now = time.Now().UnixNano() // used for expiration
+						var count = 0                   // the object count
+						col.ScanGreaterOrEqual(nextid, false, nil, nil,
+							func(o *object.Object) bool {
+								if count == maxids {
+									// we reached the max number of ids for one batch
+									nextid = o.ID()
+									idsdone = false
+									return false
 								}
-								return true
-							})
-							if o.Expires() != 0 {
-								ttl := math.Floor(float64(o.Expires()-now)/float64(time.Second)*10) / 10
-								if ttl < 0.1 {
-									// always leave a little bit of ttl.
-									ttl = 0.1
+								// here we fill the values array with a new command
+								values = values[:0]
+								values = append(values, "set")
+								values = append(values, keys[0])
+								values = append(values, o.ID())
+								motion := db.motions.Contains(keys[0]) &&
+									!o.Fields().Get(motionTime).Value().IsZero()
+								if motion {
+									// the derived fields of a collection
+									// with motion
+									values = append(values, "motion")
+									for _, name := range motionNames {
+										v := o.Fields().Get(name).Value()
+										if v.IsZero() {
+											values = append(values, "0")
+										} else {
+											values = append(values, v.JSON())
+										}
+									}
+								}
+								o.Fields().Scan(func(f field.Field) bool {
+									if !f.Value().IsZero() && !(motion &&
+										strings.HasPrefix(f.Name(), motionPrefix)) {
+										values = append(values, "field")
+										values = append(values, f.Name())
+										values = append(values, f.Value().JSON())
+									}
+									return true
+								})
+								if o.Expires() != 0 {
+									ttl := math.Floor(float64(o.Expires()-now)/float64(time.Second)*10) / 10
+									if ttl < 0.1 {
+										// always leave a little bit of ttl.
+										ttl = 0.1
+									}
+									values = append(values, "ex")
+									values = append(values, strconv.FormatFloat(ttl, 'f', -1, 64))
+								}
+								if objIsSpatial(o.Geo()) {
+									values = append(values, "object")
+									values = append(values, string(o.Geo().AppendJSON(nil)))
+								} else {
+									values = append(values, "string")
+									values = append(values, o.Geo().String())
 								}
-								values = append(values, "ex")
-								values = append(values, strconv.FormatFloat(ttl, 'f', -1, 64))
-							}
-							if objIsSpatial(o.Geo()) {
-								values = append(values, "object")
-								values = append(values, string(o.Geo().AppendJSON(nil)))
-							} else {
-								values = append(values, "string")
-								values = append(values, o.Geo().String())
-							}
 
-							// append the values to the aof buffer
-							aofbuf = append(aofbuf, '*')
-							aofbuf = append(aofbuf, strconv.FormatInt(int64(len(values)), 10)...)
-							aofbuf = append(aofbuf, '\r', '\n')
-							for _, value := range values {
-								aofbuf = append(aofbuf, '$')
-								aofbuf = append(aofbuf, strconv.FormatInt(int64(len(value)), 10)...)
-								aofbuf = append(aofbuf, '\r', '\n')
-								aofbuf = append(aofbuf, value...)
-								aofbuf = append(aofbuf, '\r', '\n')
-							}
+								// append the values to the aof buffer
+								aofbuf = appendAOFValues(aofbuf, values)
 
-							// increment the object count
-							count++
-							return true
-						},
-					)
+								// increment the object count
+								count++
+								return true
+							},
+						)
 
-				}()
-				if len(aofbuf) > maxchunk {
-					if _, err := f.Write(aofbuf); err != nil {
-						return err
+					}()
+					if len(aofbuf) > maxchunk {
+						if _, err := f.Write(aofbuf); err != nil {
+							return err
+						}
+						aofbuf = aofbuf[:0]
 					}
-					aofbuf = aofbuf[:0]
 				}
 			}
-		}
 
-		// load hooks
-		// first load the names of the hooks
-		var hnames []string
-		func() {
-			s.mu.Lock()
-			defer s.mu.Unlock()
-			hnames = make([]string, 0, s.hooks.Len())
-			s.hooks.Walk(func(v []interface{}) {
-				for _, v := range v {
-					hnames = append(hnames, v.(*Hook).Name)
-				}
-			})
-		}()
-		var hookHint btree.PathHint
-		for _, name := range hnames {
+			// load schemas
 			func() {
 				s.mu.Lock()
 				defer s.mu.Unlock()
-				hook, _ := s.hooks.GetHint(&Hook{Name: name}, &hookHint).(*Hook)
-				if hook == nil {
-					return
-				}
-				hook.cond.L.Lock()
-				defer hook.cond.L.Unlock()
+				db.schemas.Scan(func(key string, sc *schema) bool {
+					values := append([]string{"setschema", key}, sc.args()...)
+					aofbuf = appendAOFValues(aofbuf, values)
+					return true
+				})
+			}()
 
-				var values []string
-				if hook.channel {
-					values = append(values, "setchan", name)
-				} else {
-					values = append(values, "sethook", name,
-						strings.Join(hook.Endpoints, ","))
-				}
-				for _, meta := range hook.Metas {
-					values = append(values, "meta", meta.Name, meta.Value)
-				}
-				if !hook.expires.IsZero() {
-					ex := float64(time.Until(hook.expires)) / float64(time.Second)
-					values = append(values, "ex",
-						strconv.FormatFloat(ex, 'f', 1, 64))
-				}
-				values = append(values, hook.Message.Args...)
-				// append the values to the aof buffer
-				aofbuf = append(aofbuf, '*')
-				aofbuf = append(aofbuf, strconv.FormatInt(int64(len(values)), 10)...)
-				aofbuf = append(aofbuf, '\r', '\n')
-				for _, value := range values {
-					aofbuf = append(aofbuf, '$')
-					aofbuf = append(aofbuf, strconv.FormatInt(int64(len(value)), 10)...)
-					aofbuf = append(aofbuf, '\r', '\n')
-					aofbuf = append(aofbuf, value...)
-					aofbuf = append(aofbuf, '\r', '\n')
-				}
+			// load motion keys
+			func() {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				db.motions.Scan(func(key string) bool {
+					aofbuf = appendAOFValues(aofbuf,
+						[]string{"setmotion", key, "on"})
+					return true
+				})
 			}()
+
+			// load 3d indexes
+			func() {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				db.index3d.Scan(func(key string) bool {
+					aofbuf = appendAOFValues(aofbuf,
+						[]string{"setindex", key, "3d"})
+					return true
+				})
+			}()
+
+			// load hooks
+			// first load the names of the hooks
+			var hnames []string
+			func() {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				hnames = make([]string, 0, db.hooks.Len())
+				db.hooks.Walk(func(v []interface{}) {
+					for _, v := range v {
+						hnames = append(hnames, v.(*Hook).Name)
+					}
+				})
+			}()
+			var hookHint btree.PathHint
+			for _, name := range hnames {
+				func() {
+					s.mu.Lock()
+					defer s.mu.Unlock()
+					hook, _ := db.hooks.GetHint(&Hook{Name: name}, &hookHint).(*Hook)
+					if hook == nil {
+						return
+					}
+					hook.cond.L.Lock()
+					defer hook.cond.L.Unlock()
+
+					var values []string
+					if hook.channel {
+						values = append(values, "setchan", name)
+					} else {
+						values = append(values, "sethook", name,
+							strings.Join(hook.Endpoints, ","))
+					}
+					for _, meta := range hook.Metas {
+						values = append(values, "meta", meta.Name, meta.Value)
+					}
+					if !hook.expires.IsZero() {
+						ex := float64(time.Until(hook.expires)) / float64(time.Second)
+						values = append(values, "ex",
+							strconv.FormatFloat(ex, 'f', 1, 64))
+					}
+					values = append(values, hook.schedule.args()...)
+					values = append(values, hook.policy.args()...)
+					if hook.template != nil {
+						values = append(values, "template", hook.template.src)
+					}
+					if hook.batch != nil {
+						values = append(values, hook.batch.args...)
+					}
+					values = append(values, sendOptionsArgs(hook.sendOpts)...)
+					values = append(values, hook.Message.Args...)
+					// append the values to the aof buffer
+					aofbuf = appendAOFValues(aofbuf, values)
+				}()
+			}
 		}
 		if len(aofbuf) > 0 {
 			if _, err := f.Write(aofbuf); err != nil {
@@ -244,18 +327,12 @@ func (s *Server) aofshrink() {
 			s.flushAOF(false)
 
 			aofbuf = aofbuf[:0]
+			if shrinkdb != aofdb {
+				aofbuf = appendAOFValues(aofbuf, []string{"select", shrinkdb})
+			}
 			for _, values := range s.shrinklog {
 				// append the values to the aof buffer
-				aofbuf = append(aofbuf, '*')
-				aofbuf = append(aofbuf, strconv.FormatInt(int64(len(values)), 10)...)
-				aofbuf = append(aofbuf, '\r', '\n')
-				for _, value := range values {
-					aofbuf = append(aofbuf, '$')
-					aofbuf = append(aofbuf, strconv.FormatInt(int64(len(value)), 10)...)
-					aofbuf = append(aofbuf, '\r', '\n')
-					aofbuf = append(aofbuf, value...)
-					aofbuf = append(aofbuf, '\r', '\n')
-				}
+				aofbuf = appendAOFValues(aofbuf, values)
 			}
 			if _, err := f.Write(aofbuf); err != nil {
 				return err
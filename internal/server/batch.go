@@ -0,0 +1,116 @@
+package server
+
+import (
+	"strconv"
+	"time"
+)
+
+// hookBatch is how a hook sends its events together, as a JSON array, from
+// BATCH max_events max_bytes max_latency.
+type hookBatch struct {
+	events  int           // most events in a batch
+	bytes   int           // most bytes in a batch, zero is no limit
+	latency time.Duration // longest that an event waits for a full batch
+	args    []string
+}
+
+// parseHookBatch parses the BATCH values.
+func parseHookBatch(sevents, sbytes, slatency string) (*hookBatch, error) {
+	events, err := strconv.Atoi(sevents)
+	if err != nil || events < 1 {
+		return nil, errInvalidArgument(sevents)
+	}
+	bytes, err := strconv.Atoi(sbytes)
+	if err != nil || bytes < 0 {
+		return nil, errInvalidArgument(sbytes)
+	}
+	latency, err := strconv.ParseFloat(slatency, 64)
+	if err != nil || latency < 0 {
+		return nil, errInvalidArgument(slatency)
+	}
+	return &hookBatch{
+		events:  events,
+		bytes:   bytes,
+		latency: time.Duration(latency * float64(time.Second)),
+		args:    []string{"batch", sevents, sbytes, slatency},
+	}, nil
+}
+
+func (b *hookBatch) equals(other *hookBatch) bool {
+	if b == nil || other == nil {
+		return b == other
+	}
+	return b.events == other.events && b.bytes == other.bytes &&
+		b.latency == other.latency
+}
+
+// full returns true when the payloads are enough for a batch.
+func (b *hookBatch) full(payloads []string) bool {
+	if len(payloads) >= b.events {
+		return true
+	}
+	if b.bytes > 0 {
+		size := 1
+		for _, p := range payloads {
+			size += len(p) + 1
+		}
+		return size >= b.bytes
+	}
+	return false
+}
+
+// next returns how many of the payloads go in the next batch, which is at
+// least one.
+func (b *hookBatch) next(payloads []string) int {
+	size := 1 // '['
+	for i, p := range payloads {
+		if i == b.events || (i > 0 && b.bytes > 0 && size+len(p)+1 > b.bytes) {
+			return i
+		}
+		size += len(p) + 1 // ',' or ']'
+	}
+	return len(payloads)
+}
+
+func appendBatch(dst []byte, payloads []string) []byte {
+	dst = append(dst, '[')
+	for i, p := range payloads {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, p...)
+	}
+	return append(dst, ']')
+}
+
+// holdFor signals the hook after it holds a batch for the duration. Only one
+// signal is pending at a time, however many events come in before it.
+func (h *Hook) holdFor(d time.Duration) {
+	h.cond.L.Lock()
+	defer h.cond.L.Unlock()
+	if h.holding {
+		return
+	}
+	h.holding = true
+	time.AfterFunc(d, func() {
+		h.cond.L.Lock()
+		h.holding = false
+		h.cond.L.Unlock()
+		h.Signal()
+	})
+}
+
+// holdBatch returns how much longer the hook waits for more events before it
+// sends the queued ones, which is zero when they go now. The ttls are of the
+// queued events, from which the age of the oldest one follows.
+func (h *Hook) holdBatch(payloads []string, ttls []time.Duration,
+) time.Duration {
+	if h.batch == nil || len(payloads) == 0 || h.batch.full(payloads) {
+		return 0
+	}
+	age := h.queueOptions().TTL - ttls[0]
+	if age >= h.batch.latency {
+		return 0
+	}
+	return h.batch.latency - age
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHookBatch(t *testing.T) {
+	b, err := parseHookBatch("3", "20", "0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.latency != time.Second/2 {
+		t.Fatalf("expected 500ms, got %v", b.latency)
+	}
+	payloads := []string{`"aaaa"`, `"bbbb"`, `"cccc"`, `"dddd"`}
+	// ["aaaa","bbbb"] is 15 bytes, with "cccc" it would be 22
+	if n := b.next(payloads); n != 2 {
+		t.Fatalf("expected 2, got %d", n)
+	}
+	if out := string(appendBatch(nil, payloads[:2])); out != `["aaaa","bbbb"]` {
+		t.Fatalf("unexpected batch %s", out)
+	}
+	if b.full(payloads[:1]) || !b.full(payloads[:3]) {
+		t.Fatal("expected a full batch at 20 bytes")
+	}
+	// a payload that's larger than the bytes still goes alone
+	if n := b.next([]string{`"` + string(make([]byte, 30)) + `"`}); n != 1 {
+		t.Fatalf("expected 1, got %d", n)
+	}
+	b.bytes = 0
+	if n := b.next(payloads); n != 3 {
+		t.Fatalf("expected 3, got %d", n)
+	}
+	if !b.equals(&hookBatch{events: 3, latency: time.Second / 2}) ||
+		b.equals(nil) || !(*hookBatch)(nil).equals(nil) {
+		t.Fatal("unexpected equals")
+	}
+	for _, args := range [][3]string{
+		{"0", "0", "1"}, {"1", "-1", "1"}, {"1", "0", "x"},
+	} {
+		if _, err := parseHookBatch(args[0], args[1], args[2]); err == nil {
+			t.Fatalf("%v: expected an error", args)
+		}
+	}
+}
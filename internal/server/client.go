@@ -20,6 +20,7 @@ type Client struct {
 	authd      bool           // client has been authenticated
 	outputType Type           // Null, JSON, or RESP
 	strictRESP bool           // client is in strict RESP mode
+	db         string         // selected database
 	remoteAddr string         // original remote address
 	in         InputStream    // input stream
 	pr         PipelineReader // command reader
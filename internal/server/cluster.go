@@ -0,0 +1,278 @@
+package server
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geometry"
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// defaultClusterRadius is the default cluster radius in pixels, for 256
+// pixel tiles.
+const defaultClusterRadius = 40
+
+// clusterMaxZoom is the zoom level that the clustering starts from, unless
+// a finer zoom level is requested.
+const clusterMaxZoom = 16
+
+// clusterer groups nearby points into clusters at a zoom level, similar to
+// supercluster. The points are clustered at clusterMaxZoom first, and then
+// the clusters of each zoom level are clustered again at the next coarser
+// one, until the requested zoom level. So a cluster is always part of a
+// single cluster at a coarser zoom level. Objects that are not points are
+// clustered by their center.
+type clusterer struct {
+	zoom    int
+	radius  float64
+	members bool
+	points  []clusterPoint
+}
+
+type clusterPoint struct {
+	id string
+	p  geometry.Point
+}
+
+// pointCluster is a group of points. A cluster with one point uses the id of
+// the object, otherwise the id is "cluster:zoom:origin", where the origin is
+// the id of the point that the cluster started from. The points are visited
+// in the order of their ids, so the ids of the clusters only depend on the
+// zoom level and on the points, and are the same for each search that has
+// the same points.
+type pointCluster struct {
+	id      string
+	origin  string
+	count   int
+	sum     geometry.Point // of the points, for the center
+	center  geometry.Point
+	rect    geometry.Rect
+	members []string
+}
+
+// parseCluster parses the arguments that follow the CLUSTER keyword.
+//
+//	CLUSTER zoom [RADIUS pixels] [MEMBERS]
+func parseCluster(vs []string) ([]string, *clusterer, error) {
+	var ok bool
+	var szoom string
+	if vs, szoom, ok = tokenval(vs); !ok || szoom == "" {
+		return nil, nil, errInvalidNumberOfArguments
+	}
+	c := &clusterer{radius: defaultClusterRadius}
+	if c.zoom, ok = parseCellLevel(szoom, 23); !ok {
+		return nil, nil, errInvalidArgument(szoom)
+	}
+	for len(vs) > 0 {
+		switch strings.ToLower(vs[0]) {
+		case "radius":
+			var sradius string
+			if vs, sradius, ok = tokenval(vs[1:]); !ok || sradius == "" {
+				return nil, nil, errInvalidNumberOfArguments
+			}
+			radius, err := strconv.ParseFloat(sradius, 64)
+			if err != nil || !(radius > 0) || math.IsInf(radius, 0) {
+				return nil, nil, errInvalidArgument(sradius)
+			}
+			c.radius = radius
+			continue
+		case "members":
+			c.members = true
+			vs = vs[1:]
+			continue
+		}
+		break
+	}
+	return vs, c, nil
+}
+
+// push adds an object to be clustered.
+func (c *clusterer) push(o *object.Object) {
+	g := o.Geo()
+	if !objIsSpatial(g) || g.Empty() {
+		return
+	}
+	c.points = append(c.points, clusterPoint{id: o.ID(), p: g.Center()})
+}
+
+// clusterPixel returns the web mercator pixel coordinates of a point at a
+// zoom level.
+func clusterPixel(p geometry.Point, zoom int) (x, y float64) {
+	size := 256 * math.Exp2(float64(zoom))
+	sin := math.Sin(p.Y * math.Pi / 180)
+	y = 0.5 - 0.25*math.Log((1+sin)/(1-sin))/math.Pi
+	y = math.Max(0, math.Min(1, y))
+	return (p.X/360 + 0.5) * size, y * size
+}
+
+// clusterZoom clusters the clusters of the next finer zoom level, which are
+// ordered by origin, at a zoom level. Each cluster that is not yet merged
+// starts a new cluster with all of the free clusters whose centers are
+// within the radius of its center.
+func (c *clusterer) clusterZoom(clusters []pointCluster, zoom int,
+) []pointCluster {
+	type cellKey struct{ x, y int }
+	xs := make([]float64, len(clusters))
+	ys := make([]float64, len(clusters))
+	cells := make(map[cellKey][]int)
+	for i := range clusters {
+		xs[i], ys[i] = clusterPixel(clusters[i].center, zoom)
+		k := cellKey{int(xs[i] / c.radius), int(ys[i] / c.radius)}
+		cells[k] = append(cells[k], i)
+	}
+	used := make([]bool, len(clusters))
+	var next []pointCluster
+	for i := range clusters {
+		if used[i] {
+			continue
+		}
+		cl := pointCluster{
+			origin: clusters[i].origin,
+			rect:   clusters[i].rect,
+		}
+		k := cellKey{int(xs[i] / c.radius), int(ys[i] / c.radius)}
+		for x := k.x - 1; x <= k.x+1; x++ {
+			for y := k.y - 1; y <= k.y+1; y++ {
+				for _, j := range cells[cellKey{x, y}] {
+					if used[j] {
+						continue
+					}
+					dx, dy := xs[j]-xs[i], ys[j]-ys[i]
+					if dx*dx+dy*dy > c.radius*c.radius {
+						continue
+					}
+					used[j] = true
+					m := &clusters[j]
+					cl.count += m.count
+					cl.sum.X += m.sum.X
+					cl.sum.Y += m.sum.Y
+					cl.rect.Min.X = math.Min(cl.rect.Min.X, m.rect.Min.X)
+					cl.rect.Min.Y = math.Min(cl.rect.Min.Y, m.rect.Min.Y)
+					cl.rect.Max.X = math.Max(cl.rect.Max.X, m.rect.Max.X)
+					cl.rect.Max.Y = math.Max(cl.rect.Max.Y, m.rect.Max.Y)
+					cl.members = append(cl.members, m.members...)
+				}
+			}
+		}
+		cl.center.X = cl.sum.X / float64(cl.count)
+		cl.center.Y = cl.sum.Y / float64(cl.count)
+		next = append(next, cl)
+	}
+	return next
+}
+
+// clusters returns the clusters, ordered by id.
+func (c *clusterer) clusters() []pointCluster {
+	sort.Slice(c.points, func(i, j int) bool {
+		return c.points[i].id < c.points[j].id
+	})
+	clusters := make([]pointCluster, len(c.points))
+	for i, p := range c.points {
+		clusters[i] = pointCluster{
+			origin: p.id,
+			count:  1,
+			sum:    p.p,
+			center: p.p,
+			rect:   geometry.Rect{Min: p.p, Max: p.p},
+		}
+		if c.members {
+			clusters[i].members = []string{p.id}
+		}
+	}
+	for zoom := max(c.zoom, clusterMaxZoom); zoom >= c.zoom; zoom-- {
+		clusters = c.clusterZoom(clusters, zoom)
+	}
+	for i := range clusters {
+		cl := &clusters[i]
+		cl.id = cl.origin
+		if cl.count > 1 {
+			cl.id = "cluster:" + strconv.Itoa(c.zoom) + ":" + cl.origin
+		}
+		sort.Strings(cl.members)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].id < clusters[j].id
+	})
+	return clusters
+}
+
+// mvtObjs returns the clusters as points for rendering a vector tile.
+func (c *clusterer) mvtObjs() []mvtObj {
+	var objs []mvtObj
+	for _, cl := range c.clusters() {
+		objs = append(objs, mvtObj{
+			id:    cl.id,
+			obj:   geojson.NewPoint(cl.center),
+			count: cl.count,
+		})
+	}
+	return objs
+}
+
+// writeJSON writes the clusters as the "clusters" member.
+func (c *clusterer) writeJSON(wr *bytes.Buffer) {
+	wr.WriteString(`,"clusters":[`)
+	for i, cl := range c.clusters() {
+		if i > 0 {
+			wr.WriteByte(',')
+		}
+		wr.WriteString(`{"id":` + jsonString(cl.id) +
+			`,"count":` + strconv.Itoa(cl.count) +
+			`,"point":` +
+			string(appendJSONSimplePoint(nil, geojson.NewPoint(cl.center))) +
+			`,"bounds":` +
+			string(appendJSONSimpleBounds(nil, geojson.NewRect(cl.rect))))
+		if c.members {
+			wr.WriteString(`,"members":[`)
+			for i, id := range cl.members {
+				if i > 0 {
+					wr.WriteByte(',')
+				}
+				wr.WriteString(jsonString(id))
+			}
+			wr.WriteByte(']')
+		}
+		wr.WriteByte('}')
+	}
+	wr.WriteByte(']')
+}
+
+// respValues returns the clusters as a list of id, count, point, bounds and,
+// for MEMBERS, the member ids.
+func (c *clusterer) respValues() []resp.Value {
+	var vals []resp.Value
+	for _, cl := range c.clusters() {
+		cvals := []resp.Value{
+			resp.StringValue(cl.id),
+			resp.IntegerValue(cl.count),
+			resp.ArrayValue([]resp.Value{
+				resp.FloatValue(cl.center.Y),
+				resp.FloatValue(cl.center.X),
+			}),
+			resp.ArrayValue([]resp.Value{
+				resp.ArrayValue([]resp.Value{
+					resp.FloatValue(cl.rect.Min.Y),
+					resp.FloatValue(cl.rect.Min.X),
+				}),
+				resp.ArrayValue([]resp.Value{
+					resp.FloatValue(cl.rect.Max.Y),
+					resp.FloatValue(cl.rect.Max.X),
+				}),
+			}),
+		}
+		if c.members {
+			var mvals []resp.Value
+			for _, id := range cl.members {
+				mvals = append(mvals, resp.StringValue(id))
+			}
+			cvals = append(cvals, resp.ArrayValue(mvals))
+		}
+		vals = append(vals, resp.ArrayValue(cvals))
+	}
+	return vals
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"errors"
+	"math"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geo"
+	"github.com/tidwall/geojson/geometry"
+)
+
+// corridor is an area made of all of the points that are within a distance
+// of a route.
+type corridor struct {
+	line   *geojson.LineString
+	route  []geometry.Point
+	along  []float64 // distance along the route to each point, in meters
+	meters float64
+}
+
+// newCorridor returns a corridor around a LineString, or a Feature with a
+// LineString geometry.
+func newCorridor(g geojson.Object, meters float64) (*corridor, error) {
+	if f, ok := g.(*geojson.Feature); ok {
+		g = f.Base()
+	}
+	ls, ok := g.(*geojson.LineString)
+	if !ok || ls.Base().NumPoints() < 2 {
+		return nil, errors.New("corridor route must be a linestring")
+	}
+	c := &corridor{line: ls, meters: meters}
+	line := ls.Base()
+	for i := 0; i < line.NumPoints(); i++ {
+		p := line.PointAt(i)
+		along := 0.0
+		if i > 0 {
+			q := c.route[i-1]
+			along = c.along[i-1] + geo.DistanceTo(q.Y, q.X, p.Y, p.X)
+		}
+		c.route = append(c.route, p)
+		c.along = append(c.along, along)
+	}
+	return c, nil
+}
+
+// rect returns the bounding box of the corridor, which is the bounding box
+// of the route expanded by the width of the corridor.
+func (c *corridor) rect() geometry.Rect {
+	rect := c.line.Rect()
+	lat := math.Min(90, math.Max(math.Abs(rect.Min.Y), math.Abs(rect.Max.Y)))
+	minLat, minLon, maxLat, _ := geo.RectFromCenter(lat, 0, c.meters)
+	dlat, dlon := (maxLat-minLat)/2, -minLon
+	rect.Min.X = math.Max(-180, rect.Min.X-dlon)
+	rect.Min.Y = math.Max(-90, rect.Min.Y-dlat)
+	rect.Max.X = math.Min(180, rect.Max.X+dlon)
+	rect.Max.Y = math.Min(90, rect.Max.Y+dlat)
+	return rect
+}
+
+// fraction returns the fraction of the route that comes before a distance
+// along the route.
+func (c *corridor) fraction(along float64) float64 {
+	total := c.along[len(c.along)-1]
+	if total == 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, along/total))
+}
+
+// segmentPoint returns the point on the segment a-b that is closest to p and
+// its position on the segment, from 0 to 1. The closest point is found on a
+// local flat projection around p.
+func segmentPoint(p, a, b geometry.Point) (geometry.Point, float64) {
+	kx := math.Cos(p.Y * math.Pi / 180)
+	ax, ay := (a.X-p.X)*kx, a.Y-p.Y
+	bx, by := (b.X-p.X)*kx, b.Y-p.Y
+	dx, dy := bx-ax, by-ay
+	var t float64
+	if d := dx*dx + dy*dy; d > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/d))
+	}
+	return geometry.Point{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t}, t
+}
+
+// locate returns the distance in meters from a point to the route and the
+// distance along the route to the closest point on the route.
+func (c *corridor) locate(p geometry.Point) (dist, along float64) {
+	dist = math.Inf(1)
+	for i := 0; i < len(c.route)-1; i++ {
+		q, t := segmentPoint(p, c.route[i], c.route[i+1])
+		d := geo.DistanceTo(p.Y, p.X, q.Y, q.X)
+		if d < dist {
+			dist = d
+			along = c.along[i] + (c.along[i+1]-c.along[i])*t
+		}
+	}
+	return dist, along
+}
+
+// locateSegment returns the distance in meters from a segment to the route
+// and the distance along the route to the closest point on the route.
+func (c *corridor) locateSegment(seg geometry.Segment) (dist, along float64) {
+	dist, along = c.locate(seg.A)
+	if d, a := c.locate(seg.B); d < dist {
+		dist, along = d, a
+	}
+	for i := 0; i < len(c.route)-1; i++ {
+		rseg := geometry.Segment{A: c.route[i], B: c.route[i+1]}
+		if seg.IntersectsSegment(rseg) {
+			rx, ry := rseg.B.X-rseg.A.X, rseg.B.Y-rseg.A.Y
+			sx, sy := seg.B.X-seg.A.X, seg.B.Y-seg.A.Y
+			var t float64
+			if d := rx*sy - ry*sx; d != 0 {
+				t = ((seg.A.X-rseg.A.X)*sy - (seg.A.Y-rseg.A.Y)*sx) / d
+			} else {
+				// parallel segments
+				_, t = segmentPoint(seg.A, rseg.A, rseg.B)
+			}
+			t = math.Max(0, math.Min(1, t))
+			return 0, c.along[i] + (c.along[i+1]-c.along[i])*t
+		}
+		for j, p := range []geometry.Point{rseg.A, rseg.B} {
+			q, _ := segmentPoint(p, seg.A, seg.B)
+			if d := geo.DistanceTo(p.Y, p.X, q.Y, q.X); d < dist {
+				dist, along = d, c.along[i+j]
+			}
+		}
+	}
+	return dist, along
+}
+
+// corridorTolerance is the length in meters of the shortest part of a
+// segment that segmentWithin checks.
+const corridorTolerance = 0.01
+
+// corridorMaxDepth is the number of times that segmentWithin halves a
+// segment, so a segment is checked in at most 4096 parts.
+const corridorMaxDepth = 12
+
+// segmentWithin returns true when all of the points of the segment a-b are in
+// the corridor, where da and db are the distances from a and b to the route.
+// The distance to the route changes by no more than the distance between two
+// points, so a segment is within when its ends are close enough to the route
+// for its length, and otherwise it's checked in two halves. A part that is
+// shorter than corridorTolerance, or that is corridorMaxDepth halves deep, is
+// within when its ends are.
+func (c *corridor) segmentWithin(a, b geometry.Point, da, db float64,
+	depth int,
+) bool {
+	if da > c.meters || db > c.meters {
+		return false
+	}
+	h := geo.DistanceTo(a.Y, a.X, b.Y, b.X)
+	if (da+db+h)/2 <= c.meters || h < corridorTolerance ||
+		depth == corridorMaxDepth {
+		return true
+	}
+	m := geometry.Point{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+	dm, _ := c.locate(m)
+	return c.segmentWithin(a, m, da, dm, depth+1) &&
+		c.segmentWithin(m, b, dm, db, depth+1)
+}
+
+// corridorParts appends the points and series that make up an object.
+func corridorParts(g geojson.Object, points []geometry.Point,
+	series []geometry.Series,
+) ([]geometry.Point, []geometry.Series) {
+	switch g := g.(type) {
+	case *geojson.Point:
+		points = append(points, g.Base())
+	case *geojson.LineString:
+		series = append(series, g.Base())
+	case *geojson.Rect:
+		series = append(series, g.Base())
+	case *geojson.Polygon:
+		poly := g.Base()
+		series = append(series, poly.Exterior)
+		for _, hole := range poly.Holes {
+			series = append(series, hole)
+		}
+	case *geojson.Circle:
+		return corridorParts(g.Polygon(), points, series)
+	case *geojson.Feature:
+		return corridorParts(g.Base(), points, series)
+	case geojson.Collection:
+		for _, g := range g.Children() {
+			points, series = corridorParts(g, points, series)
+		}
+	default:
+		points = append(points, g.Center())
+	}
+	return points, series
+}
+
+// match returns true when an object is in the corridor, along with the
+// distance in meters from the object to the route and the fraction of the
+// route that comes before the closest point. For within, all of the points
+// of the object, including the ones along its segments, must be in the
+// corridor. For intersects, any part of the
+// object must be in the corridor.
+func (c *corridor) match(g geojson.Object, within bool,
+) (dist, frac float64, ok bool) {
+	if g.Empty() {
+		return 0, 0, false
+	}
+	points, series := corridorParts(g, nil, nil)
+	dist = math.Inf(1)
+	var along float64
+	var inside = true
+	for _, p := range points {
+		d, a := c.locate(p)
+		if d < dist {
+			dist, along = d, a
+		}
+		if d > c.meters {
+			inside = false
+		}
+	}
+	for _, s := range series {
+		for i := 0; i < s.NumSegments(); i++ {
+			d, a := c.locateSegment(s.SegmentAt(i))
+			if d < dist {
+				dist, along = d, a
+			}
+		}
+		if within {
+			for i := 0; i < s.NumSegments() && inside; i++ {
+				seg := s.SegmentAt(i)
+				da, _ := c.locate(seg.A)
+				db, _ := c.locate(seg.B)
+				inside = c.segmentWithin(seg.A, seg.B, da, db, 0)
+			}
+		}
+	}
+	if dist > 0 && len(series) > 0 && g.Intersects(c.line) {
+		// the route is inside of the object, which is then located on the
+		// route by its center
+		_, along = c.locate(g.Center())
+		dist = 0
+	}
+	if within {
+		ok = inside
+	} else {
+		ok = dist <= c.meters
+	}
+	return dist, c.fraction(along), ok
+}
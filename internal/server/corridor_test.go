@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geo"
+	"github.com/tidwall/geojson/geometry"
+)
+
+func TestCorridorSegmentEdge(t *testing.T) {
+	route := geojson.NewLineString(geometry.NewLine([]geometry.Point{
+		{X: 0, Y: 0}, {X: 1, Y: 0},
+	}, nil))
+	// a segment that runs along the edge of the corridor is never close
+	// enough to the route to be within without being split
+	meters := geo.DistanceTo(0.001, 0, 0, 0) * (1 + 1e-12)
+	c, err := newCorridor(route, meters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edge := geojson.NewLineString(geometry.NewLine([]geometry.Point{
+		{X: 0, Y: 0.001}, {X: 1, Y: 0.001},
+	}, nil))
+	start := time.Now()
+	if _, _, ok := c.match(edge, true); !ok {
+		t.Fatal("expected the edge to be within")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("took %s", time.Since(start))
+	}
+	out := geojson.NewLineString(geometry.NewLine([]geometry.Point{
+		{X: 0, Y: 0.001}, {X: 1, Y: 0.002},
+	}, nil))
+	if _, _, ok := c.match(out, true); ok {
+		t.Fatal("expected the segment to not be within")
+	}
+}
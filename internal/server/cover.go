@@ -0,0 +1,362 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/mmcloughlin/geohash"
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geometry"
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/h3"
+)
+
+var errTooManyCells = errors.New("too many cells")
+
+// maxCoverCells is the default maximum number of cells in a covering.
+const maxCoverCells = 1000
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// parseCellLevel parses the level of a grid cell system.
+func parseCellLevel(s string, max int) (int, bool) {
+	level, err := strconv.Atoi(s)
+	return level, err == nil && level >= 0 && level <= max
+}
+
+// cellPolygon returns the polygon for the vertices of a grid cell. Cells
+// that cross the antimeridian are unwrapped to the east so that the polygon
+// keeps its shape.
+func cellPolygon(ring []geometry.Point, opts *geometry.IndexOptions,
+) geojson.Object {
+	var minX, maxX float64 = 180, -180
+	for _, p := range ring {
+		if p.X < minX {
+			minX = p.X
+		}
+		if p.X > maxX {
+			maxX = p.X
+		}
+	}
+	if maxX-minX > 180 {
+		for i := range ring {
+			if ring[i].X < 0 {
+				ring[i].X += 360
+			}
+		}
+	}
+	ring = append(ring, ring[0])
+	return geojson.NewPolygon(geometry.NewPoly(ring, nil, opts))
+}
+
+// coverer finds the cells of a grid system that intersect an object, by
+// descending the cell hierarchy from the coarsest cells, which for S2 is done
+// by an s2.RegionCoverer. Cells that are not at the requested level are only
+// tested against their bounds, and only the cells at the requested level are
+// tested against their actual shape.
+type coverer struct {
+	obj   geojson.Object
+	opts  *geometry.IndexOptions
+	max   int
+	cells []string
+}
+
+// add adds a cell, and returns false when the covering is full.
+func (c *coverer) add(cell string) bool {
+	if len(c.cells) == c.max {
+		return false
+	}
+	c.cells = append(c.cells, cell)
+	return true
+}
+
+func (c *coverer) intersectsRect(rect geometry.Rect) bool {
+	return c.obj.Intersects(geojson.NewRect(rect))
+}
+
+// cellInteriorScale shrinks the cells at the requested level toward their
+// centers, so that cells which only share an edge with the object are not
+// part of the covering.
+const cellInteriorScale = 1 - 1e-9
+
+// intersectsCell returns true if the object intersects the interior of a
+// cell polygon.
+func (c *coverer) intersectsCell(cell geojson.Object) bool {
+	poly := cell.(*geojson.Polygon).Base()
+	center := poly.Rect().Center()
+	ring := make([]geometry.Point, poly.Exterior.NumPoints())
+	for i := range ring {
+		p := poly.Exterior.PointAt(i)
+		ring[i].X = center.X + (p.X-center.X)*cellInteriorScale
+		ring[i].Y = center.Y + (p.Y-center.Y)*cellInteriorScale
+	}
+	return c.obj.Intersects(geojson.NewPolygon(
+		geometry.NewPoly(ring, nil, c.opts)))
+}
+
+// s2Region is the object of a coverer as an s2.Region, for covering it with
+// an s2.RegionCoverer at a single level. The cells at the level are tested
+// against their actual shape, and the coarser ones against their bounds. The
+// region stops intersecting cells when the covering is full.
+type s2Region struct {
+	c     *coverer
+	level int
+	count int
+	full  bool
+}
+
+func (r *s2Region) CapBound() s2.Cap {
+	return r.RectBound().CapBound()
+}
+
+func (r *s2Region) RectBound() s2.Rect {
+	rect := r.c.obj.Rect()
+	if rect.Min.X < -180 || rect.Max.X > 180 {
+		return s2.FullRect()
+	}
+	return s2.RectFromLatLng(s2.LatLngFromDegrees(rect.Min.Y, rect.Min.X)).
+		AddPoint(s2.LatLngFromDegrees(rect.Max.Y, rect.Max.X))
+}
+
+// ContainsCell is always false, as the cells of a covering at a single level
+// are never replaced by the ones that contain them.
+func (r *s2Region) ContainsCell(cell s2.Cell) bool {
+	return false
+}
+
+func (r *s2Region) IntersectsCell(cell s2.Cell) bool {
+	if r.full {
+		return false
+	}
+	if cell.Level() < r.level {
+		bound := cell.RectBound()
+		rect := geometry.Rect{
+			Min: geometry.Point{X: -180, Y: bound.Lo().Lat.Degrees()},
+			Max: geometry.Point{X: 180, Y: bound.Hi().Lat.Degrees()},
+		}
+		if !bound.Lng.IsInverted() && !bound.Lng.IsFull() {
+			rect.Min.X = bound.Lo().Lng.Degrees()
+			rect.Max.X = bound.Hi().Lng.Degrees()
+		}
+		return r.c.intersectsRect(rect)
+	}
+	if !r.c.intersectsCell(s2CellPolygon(cell.ID(), r.c.opts)) {
+		return false
+	}
+	if r.count == r.c.max {
+		r.full = true
+		return false
+	}
+	r.count++
+	return true
+}
+
+func (r *s2Region) ContainsPoint(p s2.Point) bool {
+	ll := s2.LatLngFromPoint(p)
+	return r.c.obj.Contains(geojson.NewPoint(geometry.Point{
+		X: ll.Lng.Degrees(), Y: ll.Lat.Degrees(),
+	}))
+}
+
+func (r *s2Region) CellUnionBound() []s2.CellID {
+	return r.CapBound().CellUnionBound()
+}
+
+// coverS2 adds the S2 cells at the level, and returns false when the
+// covering is full.
+func (c *coverer) coverS2(level int) bool {
+	region := &s2Region{c: c, level: level}
+	rc := &s2.RegionCoverer{MinLevel: level, MaxLevel: level, MaxCells: c.max}
+	for _, id := range rc.Covering(region) {
+		c.cells = append(c.cells, id.ToToken())
+	}
+	return !region.full
+}
+
+func (c *coverer) coverH3(cell h3.Cell, res int) bool {
+	if cell.Resolution() == res {
+		if c.intersectsCell(h3CellPolygon(cell, c.opts)) {
+			return c.add(cell.String())
+		}
+		return true
+	}
+	// The children of an H3 cell do not exactly fit in their parent, so the
+	// bounds are expanded to include any part of the descendants that are
+	// outside of the cell. The base cells are always searched.
+	if cell.Resolution() > 0 {
+		rect := h3CellPolygon(cell, c.opts).Rect()
+		w, h := (rect.Max.X-rect.Min.X)/2, (rect.Max.Y-rect.Min.Y)/2
+		rect.Min.X, rect.Min.Y = rect.Min.X-w, rect.Min.Y-h
+		rect.Max.X, rect.Max.Y = rect.Max.X+w, rect.Max.Y+h
+		if !c.intersectsRect(rect) {
+			return true
+		}
+	}
+	for _, child := range cell.Children() {
+		if !c.coverH3(child, res) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *coverer) coverHash(hash string, precision int) bool {
+	box := geohash.BoundingBox(hash)
+	rect := geometry.Rect{
+		Min: geometry.Point{X: box.MinLng, Y: box.MinLat},
+		Max: geometry.Point{X: box.MaxLng, Y: box.MaxLat},
+	}
+	if len(hash) == precision {
+		cell := geojson.NewPolygon(geometry.NewPoly([]geometry.Point{
+			rect.Min, {X: rect.Max.X, Y: rect.Min.Y}, rect.Max,
+			{X: rect.Min.X, Y: rect.Max.Y}, rect.Min,
+		}, nil, c.opts))
+		if c.intersectsCell(cell) {
+			return c.add(hash)
+		}
+		return true
+	}
+	if !c.intersectsRect(rect) {
+		return true
+	}
+	for i := 0; i < len(geohashBase32); i++ {
+		if !c.coverHash(hash+geohashBase32[i:i+1], precision) {
+			return false
+		}
+	}
+	return true
+}
+
+// coverObject returns the cells of a grid system at a level that intersect
+// an object. The system is "s2", "h3" or "hash". Returns false when the
+// covering has more than max cells.
+//
+// An object that only touches the edges of cells, such as a line that runs
+// along a cell edge, is covered by the cell that contains its center.
+func coverObject(obj geojson.Object, system string, level, max int,
+	opts *geometry.IndexOptions,
+) ([]string, bool) {
+	if !objIsSpatial(obj) || obj.Empty() {
+		return nil, true
+	}
+	rect := obj.Rect()
+	if rect.Min == rect.Max {
+		// points are always in a single cell
+		p := rect.Min
+		switch system {
+		case "s2":
+			ll := s2.LatLngFromDegrees(p.Y, p.X)
+			return []string{s2.CellIDFromLatLng(ll).Parent(level).ToToken()},
+				true
+		case "h3":
+			return []string{h3.LatLngToCell(p.Y, p.X, level).String()}, true
+		default:
+			return []string{geohash.EncodeWithPrecision(p.Y, p.X,
+				uint(level))}, true
+		}
+	}
+	c := &coverer{obj: obj, opts: opts, max: max}
+	ok := true
+	switch system {
+	case "s2":
+		ok = c.coverS2(level)
+	case "h3":
+		for _, cell := range h3.BaseCells() {
+			if ok = c.coverH3(cell, level); !ok {
+				break
+			}
+		}
+	default:
+		for i := 0; i < len(geohashBase32) && ok; i++ {
+			ok = c.coverHash(geohashBase32[i:i+1], level)
+		}
+	}
+	if len(c.cells) == 0 {
+		center := geojson.NewPoint(obj.Center())
+		return coverObject(center, system, level, max, opts)
+	}
+	return c.cells, ok
+}
+
+// COVER S2|H3|HASH level [MAXCELLS count] area
+func (s *Server) cmdCOVER(msg *Message) (resp.Value, error) {
+	start := time.Now()
+
+	// >> Args
+
+	vs := msg.Args[1:]
+	var ok bool
+	var ssystem, slevel string
+	if vs, ssystem, ok = tokenval(vs); !ok || ssystem == "" {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	if vs, slevel, ok = tokenval(vs); !ok || slevel == "" {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	system := strings.ToLower(ssystem)
+	var level int
+	switch system {
+	case "s2":
+		level, ok = parseS2Level(slevel)
+	case "h3":
+		level, ok = parseH3Resolution(slevel)
+	case "hash":
+		level, ok = parseCellLevel(slevel, 12)
+		ok = ok && level > 0
+	default:
+		return retrerr(errInvalidArgument(ssystem))
+	}
+	if !ok {
+		return retrerr(errInvalidArgument(slevel))
+	}
+	maxCells := maxCoverCells
+	if len(vs) > 0 && strings.ToLower(vs[0]) == "maxcells" {
+		var smax string
+		if vs, smax, ok = tokenval(vs[1:]); !ok || smax == "" {
+			return retrerr(errInvalidNumberOfArguments)
+		}
+		n, err := strconv.ParseUint(smax, 10, 32)
+		if err != nil || n == 0 {
+			return retrerr(errInvalidArgument(smax))
+		}
+		maxCells = int(n)
+	}
+	vs, obj, err := s.parseArea(s.getDB(msg.DB), vs, false)
+	if err != nil {
+		return retrerr(err)
+	}
+	if len(vs) != 0 {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+
+	// >> Operation
+
+	cells, ok := coverObject(obj, system, level, maxCells, &s.geomIndexOpts)
+	if !ok {
+		return retrerr(errTooManyCells)
+	}
+
+	// >> Response
+
+	if msg.OutputType == JSON {
+		var buf bytes.Buffer
+		buf.WriteString(`{"ok":true,"cells":[`)
+		for i, cell := range cells {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(jsonString(cell))
+		}
+		buf.WriteString(`],"elapsed":"` + time.Since(start).String() + "\"}")
+		return resp.StringValue(buf.String()), nil
+	}
+	vals := make([]resp.Value, len(cells))
+	for i, cell := range cells {
+		vals[i] = resp.StringValue(cell)
+	}
+	return resp.ArrayValue(vals), nil
+}
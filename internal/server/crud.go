@@ -31,7 +31,7 @@ func (s *Server) cmdBOUNDS(msg *Message) (resp.Value, error) {
 
 	// >> Operation
 
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		if msg.OutputType == RESP {
 			return resp.NullValue(), nil
@@ -88,7 +88,7 @@ func (s *Server) cmdTYPE(msg *Message) (resp.Value, error) {
 
 	// >> Operation
 
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		if msg.OutputType == RESP {
 			return resp.SimpleStringValue("none"), nil
@@ -107,7 +107,8 @@ func (s *Server) cmdTYPE(msg *Message) (resp.Value, error) {
 	return resp.SimpleStringValue(typ), nil
 }
 
-// GET key id [WITHFIELDS] [OBJECT|POINT|BOUNDS|(HASH geohash)]
+// GET key id [WITHFIELDS] [FIELDS count field ...]
+// [OBJECT|POINT|BOUNDS|(HASH geohash)|(H3 resolution)]
 func (s *Server) cmdGET(msg *Message) (resp.Value, error) {
 	start := time.Now()
 
@@ -121,12 +122,24 @@ func (s *Server) cmdGET(msg *Message) (resp.Value, error) {
 	key, id := args[1], args[2]
 
 	withfields := false
+	var fields *projection
 	kind := "object"
 	var precision int64
 	for i := 3; i < len(args); i++ {
 		switch strings.ToLower(args[i]) {
 		case "withfields":
 			withfields = true
+		case "fields":
+			if fields != nil {
+				return retrerr(errDuplicateArgument(strings.ToUpper(args[i])))
+			}
+			rest, p, err := parseProjection(args[i+1:])
+			if err != nil {
+				return retrerr(err)
+			}
+			fields = p
+			withfields = true
+			i = len(args) - len(rest) - 1
 		case "object":
 			kind = "object"
 		case "point":
@@ -144,6 +157,17 @@ func (s *Server) cmdGET(msg *Message) (resp.Value, error) {
 			if err != nil || precision < 1 || precision > 12 {
 				return retrerr(errInvalidArgument(args[i]))
 			}
+		case "h3":
+			kind = "h3"
+			i++
+			if i == len(args) {
+				return retrerr(errInvalidNumberOfArguments)
+			}
+			res, ok := parseH3Resolution(args[i])
+			if !ok {
+				return retrerr(errInvalidArgument(args[i]))
+			}
+			precision = int64(res)
 		default:
 			return retrerr(errInvalidNumberOfArguments)
 		}
@@ -151,7 +175,7 @@ func (s *Server) cmdGET(msg *Message) (resp.Value, error) {
 
 	// >> Operation
 
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		if msg.OutputType == RESP {
 			return resp.NullValue(), nil
@@ -165,6 +189,9 @@ func (s *Server) cmdGET(msg *Message) (resp.Value, error) {
 		}
 		return retrerr(errIDNotFound)
 	}
+	if fields != nil {
+		o = fields.apply(s, o)
+	}
 
 	// >> Response
 
@@ -193,7 +220,7 @@ func buildObjectResponse(msg *Message, o *object.Object, start time.Time, kind s
 			buf.Write(appendJSONSimplePoint(nil, o.Geo()))
 		} else {
 			point := o.Geo().Center()
-			z := extractZCoordinate(o.Geo())
+			z := collection.Z(o.Geo())
 			if z != 0 {
 				vals = append(vals, resp.ArrayValue([]resp.Value{
 					resp.StringValue(strconv.FormatFloat(point.Y, 'f', -1, 64)),
@@ -218,6 +245,13 @@ func buildObjectResponse(msg *Message, o *object.Object, start time.Time, kind s
 		} else {
 			vals = append(vals, resp.StringValue(p))
 		}
+	case "h3":
+		cell := h3CellOf(o.Geo(), int(precision))
+		if msg.OutputType == JSON {
+			buf.WriteString(`,"h3":"` + cell.String() + `"`)
+		} else {
+			vals = append(vals, resp.StringValue(cell.String()))
+		}
 	case "bounds":
 		if msg.OutputType == JSON {
 			buf.WriteString(`,"bounds":`)
@@ -279,6 +313,72 @@ func buildObjectResponse(msg *Message, o *object.Object, start time.Time, kind s
 	return oval
 }
 
+// DISTANCE key id (key id)|(POINT lat lon)
+func (s *Server) cmdDISTANCE(msg *Message) (resp.Value, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) != 5 && len(args) != 6 {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	key, id := args[1], args[2]
+	var target geojson.Object
+	if len(args) == 6 {
+		if strings.ToLower(args[3]) != "point" {
+			return retrerr(errInvalidArgument(args[3]))
+		}
+		lat, err := strconv.ParseFloat(args[4], 64)
+		if err != nil {
+			return retrerr(errInvalidArgument(args[4]))
+		}
+		lon, err := strconv.ParseFloat(args[5], 64)
+		if err != nil {
+			return retrerr(errInvalidArgument(args[5]))
+		}
+		target = geojson.NewPoint(geometry.Point{X: lon, Y: lat})
+	}
+
+	// >> Operation
+
+	db := s.getDB(msg.DB)
+	get := func(key, id string) (*object.Object, error) {
+		col, _ := db.cols.Get(key)
+		if col == nil {
+			return nil, errKeyNotFound
+		}
+		o := col.Get(id)
+		if o == nil {
+			return nil, errIDNotFound
+		}
+		return o, nil
+	}
+	o, err := get(key, id)
+	if err == nil && target == nil {
+		var o2 *object.Object
+		if o2, err = get(args[3], args[4]); err == nil {
+			target = o2.Geo()
+		}
+	}
+	if err != nil {
+		if msg.OutputType == RESP {
+			return resp.NullValue(), nil
+		}
+		return retrerr(err)
+	}
+	dist := collection.GeodesicDistance(o.Geo(), target)
+
+	// >> Response
+
+	if msg.OutputType == JSON {
+		return resp.StringValue(`{"ok":true,"distance":` +
+			strconv.FormatFloat(dist, 'f', -1, 64) +
+			`,"elapsed":"` + time.Since(start).String() + "\"}"), nil
+	}
+	return resp.FloatValue(dist), nil
+}
+
 // DEL key id [ERRON404]
 func (s *Server) cmdDEL(msg *Message) (resp.Value, commandDetails, error) {
 	start := time.Now()
@@ -305,12 +405,13 @@ func (s *Server) cmdDEL(msg *Message) (resp.Value, commandDetails, error) {
 
 	updated := false
 	var old *object.Object
-	col, _ := s.cols.Get(key)
+	db := s.getDB(msg.DB)
+	col, _ := db.cols.Get(key)
 	if col != nil {
 		old = col.Delete(id)
 		if old != nil {
 			if col.Count() == 0 {
-				s.cols.Delete(key)
+				db.cols.Delete(key)
 			}
 			updated = true
 		} else if erron404 {
@@ -319,7 +420,7 @@ func (s *Server) cmdDEL(msg *Message) (resp.Value, commandDetails, error) {
 	} else if erron404 {
 		return retwerr(errKeyNotFound)
 	}
-	s.groupDisconnectObject(key, id)
+	db.groupDisconnectObject(key, id)
 
 	// >> Response
 
@@ -364,7 +465,8 @@ func (s *Server) cmdPDEL(msg *Message) (resp.Value, commandDetails, error) {
 
 	now := time.Now()
 	var children []*commandDetails
-	col, _ := s.cols.Get(key)
+	db := s.getDB(msg.DB)
+	col, _ := db.cols.Get(key)
 	if col != nil {
 		g := glob.Parse(pattern, false)
 		var ids []string
@@ -389,10 +491,10 @@ func (s *Server) cmdPDEL(msg *Message) (resp.Value, commandDetails, error) {
 				key:       key,
 				obj:       obj,
 			})
-			s.groupDisconnectObject(key, id)
+			db.groupDisconnectObject(key, id)
 		}
 		if col.Count() == 0 {
-			s.cols.Delete(key)
+			db.cols.Delete(key)
 		}
 	}
 
@@ -418,12 +520,12 @@ func (s *Server) cmdPDEL(msg *Message) (resp.Value, commandDetails, error) {
 	return res, d, nil
 }
 
-func (s *Server) cmdDROPop(key string) *collection.Collection {
-	col, _ := s.cols.Get(key)
+func (s *Server) cmdDROPop(db *database, key string) *collection.Collection {
+	col, _ := db.cols.Get(key)
 	if col != nil {
-		s.cols.Delete(key)
+		db.cols.Delete(key)
 	}
-	s.groupDisconnectCollection(key)
+	db.groupDisconnectCollection(key)
 	return col
 }
 
@@ -440,7 +542,7 @@ func (s *Server) cmdDROP(msg *Message) (resp.Value, commandDetails, error) {
 	key := args[1]
 
 	// >> Operation
-	col := s.cmdDROPop(key)
+	col := s.cmdDROPop(s.getDB(msg.DB), key)
 
 	// >> Response
 
@@ -481,12 +583,13 @@ func (s *Server) cmdRENAME(msg *Message) (resp.Value, commandDetails, error) {
 
 	// >> Operation
 
-	col, _ := s.cols.Get(key)
+	db := s.getDB(msg.DB)
+	col, _ := db.cols.Get(key)
 	if col == nil {
 		return retwerr(errKeyNotFound)
 	}
 	var hasHook, hasChannel bool
-	s.hooks.Ascend(nil, func(v interface{}) bool {
+	db.hooks.Ascend(nil, func(v interface{}) bool {
 		h := v.(*Hook)
 		if h.Key == key || h.Key == newKey {
 			if h.channel {
@@ -504,16 +607,10 @@ func (s *Server) cmdRENAME(msg *Message) (resp.Value, commandDetails, error) {
 		return retwerr(errKeyHasChannelsSet)
 	}
 	var updated bool
-	newCol, _ := s.cols.Get(newKey)
-	if newCol == nil {
+	newCol, _ := db.cols.Get(newKey)
+	if newCol == nil || !nx {
+		db.renameKey(key, newKey)
 		updated = true
-	} else if !nx {
-		s.cols.Delete(newKey)
-		updated = true
-	}
-	if updated {
-		s.cols.Delete(key)
-		s.cols.Set(newKey, col)
 	}
 
 	// >> Response
@@ -557,17 +654,18 @@ func (s *Server) cmdFLUSHDB(msg *Message) (resp.Value, commandDetails, error) {
 
 	// >> Operation
 
-	// clear the entire database
+	// clear the entire selected database
+	db := s.getDB(msg.DB)
 
 	// drop each collection
-	keys := s.cols.Keys()
+	keys := db.cols.Keys()
 	for _, key := range keys {
-		s.cmdDROPop(key)
+		s.cmdDROPop(db, key)
 	}
 
 	// delete all channels
 	var names []string
-	s.hooks.Ascend(nil, func(item any) bool {
+	db.hooks.Ascend(nil, func(item any) bool {
 		hook := item.(*Hook)
 		if hook.channel {
 			names = append(names, hook.Name)
@@ -575,12 +673,12 @@ func (s *Server) cmdFLUSHDB(msg *Message) (resp.Value, commandDetails, error) {
 		return true
 	})
 	for _, name := range names {
-		s.cmdDELHOOKop(name, true)
+		s.cmdDELHOOKop(db, name, true)
 	}
 
 	// delete all hooks
 	names = names[:0]
-	s.hooks.Ascend(nil, func(item any) bool {
+	db.hooks.Ascend(nil, func(item any) bool {
 		hook := item.(*Hook)
 		if !hook.channel {
 			names = append(names, hook.Name)
@@ -588,17 +686,19 @@ func (s *Server) cmdFLUSHDB(msg *Message) (resp.Value, commandDetails, error) {
 		return true
 	})
 	for _, name := range names {
-		s.cmdDELHOOKop(name, false)
+		s.cmdDELHOOKop(db, name, false)
 	}
 
-	s.cols.Clear()
-	s.groupHooks.Clear()
-	s.groupObjects.Clear()
-	s.hookExpires.Clear()
-	s.hooks.Clear()
-	s.hooksOut.Clear()
-	s.hookTree.Clear()
-	s.hookCross.Clear()
+	db.cols.Clear()
+	db.schemas.Clear()
+	db.motions.Clear()
+	db.index3d.Clear()
+	db.groupHooks.Clear()
+	db.groupObjects.Clear()
+	db.hooks.Clear()
+	db.hooksOut.Clear()
+	db.hookTree.Clear()
+	db.hookCross.Clear()
 
 	// >> Response
 
@@ -639,6 +739,7 @@ func (s *Server) cmdSET(msg *Message) (resp.Value, commandDetails, error) {
 	kind := "object"
 	var precision int64
 	var oobj geojson.Object
+	var motion []field.Field
 
 	args := msg.Args
 	if len(args) < 3 {
@@ -649,6 +750,21 @@ func (s *Server) cmdSET(msg *Message) (resp.Value, commandDetails, error) {
 
 	for i := 3; i < len(args); i++ {
 		switch strings.ToLower(args[i]) {
+		case "motion":
+			// the derived fields of a SET that is replayed from the aof or
+			// from a leader
+			if !msg.replay || motion != nil {
+				return retwerr(errInvalidArgument(args[i]))
+			}
+			if i+len(motionNames) >= len(args) {
+				return retwerr(errInvalidNumberOfArguments)
+			}
+			var err error
+			motion, err = parseMotion(args[i+1 : i+1+len(motionNames)])
+			if err != nil {
+				return retwerr(err)
+			}
+			i += len(motionNames)
 		case "field":
 			if i+2 >= len(args) {
 				return retwerr(errInvalidNumberOfArguments)
@@ -781,6 +897,26 @@ func (s *Server) cmdSET(msg *Message) (resp.Value, commandDetails, error) {
 			i += 1
 			lat, lon := geohash.Decode(shash)
 			oobj = geojson.NewPoint(geometry.Point{X: lon, Y: lat})
+		case "h3":
+			if i+1 >= len(args) {
+				return retwerr(errInvalidNumberOfArguments)
+			}
+			cell, err := parseH3Cell(args[i+1])
+			if err != nil {
+				return retwerr(err)
+			}
+			i += 1
+			oobj = h3CellPolygon(cell, &s.geomIndexOpts)
+		case "s2":
+			if i+1 >= len(args) {
+				return retwerr(errInvalidNumberOfArguments)
+			}
+			id, err := parseS2Cell(args[i+1])
+			if err != nil {
+				return retwerr(err)
+			}
+			i += 1
+			oobj = s2CellPolygon(id, &s.geomIndexOpts)
 		case "object":
 			if i+1 >= len(args) {
 				return retwerr(errInvalidNumberOfArguments)
@@ -814,17 +950,18 @@ func (s *Server) cmdSET(msg *Message) (resp.Value, commandDetails, error) {
 		return resp.NullValue(), commandDetails{}, nil
 	}
 
-	col, ok := s.cols.Get(key)
-	if !ok {
-		if xx {
-			return nada()
-		}
-		col = collection.New()
-		s.cols.Set(key, col)
+	db := s.selectDB(msg.DB)
+	col, _ := db.cols.Get(key)
+	if col == nil && xx {
+		return nada()
 	}
 
+	var prev *object.Object
+	if col != nil {
+		prev = col.Get(id)
+	}
 	if xx || nx {
-		if col.Get(id) == nil {
+		if prev == nil {
 			if xx {
 				return nada()
 			}
@@ -835,16 +972,43 @@ func (s *Server) cmdSET(msg *Message) (resp.Value, commandDetails, error) {
 		}
 	}
 
+	if !msg.replay && db.motions.Contains(key) {
+		if err := checkMotionFields(fields); err != nil {
+			return retwerr(err)
+		}
+		if objIsSpatial(oobj) {
+			motion = deriveMotion(prev, oobj, time.Now())
+		}
+	}
+	fields = append(fields, motion...)
+
 	var flist field.List
-	if old := col.Get(id); old != nil {
-		flist = old.Fields()
+	if prev != nil {
+		flist = prev.Fields()
 	}
 	for _, f := range fields {
 		flist = flist.Set(f)
 	}
+	if err := s.validateSchema(db, key, flist, fields, prev != nil); err != nil {
+		return retwerr(err)
+	}
+	if col == nil {
+		col = db.newCollection(key)
+		db.cols.Set(key, col)
+	}
 	obj := object.New(id, oobj, ex, flist)
 	old := col.Set(obj)
 
+	if !msg.replay && len(motion) > 0 {
+		// the derived fields go to the aof and to the followers
+		nargs := append([]string{}, args[:3]...)
+		nargs = append(nargs, "motion")
+		for _, f := range motion {
+			nargs = append(nargs, f.Value().Data())
+		}
+		msg.Args = append(nargs, args[3:]...)
+	}
+
 	// >> Response
 
 	var d commandDetails
@@ -964,10 +1128,16 @@ func (s *Server) cmdFSET(msg *Message) (resp.Value, commandDetails, error) {
 	var d commandDetails
 	var updateCount int
 
-	col, ok := s.cols.Get(key)
+	db := s.getDB(msg.DB)
+	col, ok := db.cols.Get(key)
 	if !ok {
 		return retwerr(errKeyNotFound)
 	}
+	if !msg.replay && db.motions.Contains(key) {
+		if err := checkMotionFields(fields); err != nil {
+			return retwerr(err)
+		}
+	}
 	o := col.Get(id)
 	ok = o != nil
 	if !(ok || xx) {
@@ -983,6 +1153,9 @@ func (s *Server) cmdFSET(msg *Message) (resp.Value, commandDetails, error) {
 				updateCount++
 			}
 		}
+		if err := s.validateSchema(db, key, ofields, fields, true); err != nil {
+			return retwerr(err)
+		}
 		obj := object.New(id, o.Geo(), o.Expires(), ofields)
 		col.Set(obj)
 		d.command = "fset"
@@ -1027,7 +1200,7 @@ func (s *Server) cmdFGET(msg *Message) (resp.Value, error) {
 
 	// >> Operation
 
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		return retrerr(errKeyNotFound)
 	}
@@ -1072,7 +1245,7 @@ func (s *Server) cmdEXPIRE(msg *Message) (resp.Value, commandDetails, error) {
 
 	var ok bool
 	var obj *object.Object
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col != nil {
 		// replace the expiration by getting the old object
 		ex := time.Now().Add(
@@ -1130,7 +1303,7 @@ func (s *Server) cmdPERSIST(msg *Message) (resp.Value, commandDetails, error) {
 
 	// >> Operation
 
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		if msg.OutputType == RESP {
 			return resp.IntegerValue(0), commandDetails{}, nil
@@ -1192,7 +1365,7 @@ func (s *Server) cmdTTL(msg *Message) (resp.Value, error) {
 
 	// >> Operation
 
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		if msg.OutputType == JSON {
 			return retrerr(errKeyNotFound)
@@ -1240,7 +1413,7 @@ func (s *Server) cmdEXISTS(msg *Message) (resp.Value, error) {
 
 	// >> Operation
 
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		return retrerr(errKeyNotFound)
 	}
@@ -1272,7 +1445,7 @@ func (s *Server) cmdFEXISTS(msg *Message) (resp.Value, error) {
 
 	// >> Operation
 
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		return retrerr(errKeyNotFound)
 	}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// cursorTokenVersion is the first byte of an encoded cursor token.
+const cursorTokenVersion = 1
+
+// Kinds of cursor tokens, which are the order of the results that they
+// continue.
+const (
+	cursorByID    = 'i' // SCAN
+	cursorByValue = 'v' // SEARCH
+	cursorByDist  = 'd' // NEARBY
+	cursorByWest  = 'w' // WITHIN and INTERSECTS
+)
+
+// cursorToken is an opaque continuation cursor. It holds the position of the
+// last result of a page, rather than the number of results that came before
+// it, so the next page continues directly after that result even when
+// objects are added or removed between pages. The token only contains the
+// position, so it's valid on any server with the same data, such as a
+// follower. The position of a spatial search is also where the next page
+// continues the walk of the spatial index from: the distance for NEARBY, and
+// the west edge of the object for WITHIN and INTERSECTS.
+type cursorToken struct {
+	kind  byte
+	pos   bool // the token has a position, otherwise it's the first page
+	desc  bool // the results are in descending order
+	id    string
+	value string
+	num   float64 // the distance, or the west edge
+}
+
+// from returns the distance or the west edge that a spatial search continues
+// from.
+func (ct *cursorToken) from() float64 {
+	if !ct.pos {
+		return math.Inf(-1)
+	}
+	return ct.num
+}
+
+// cursorKindFor returns the kind of cursor tokens that a command uses.
+func cursorKindFor(cmd string) (byte, bool) {
+	switch cmd {
+	case "scan":
+		return cursorByID, true
+	case "within", "intersects":
+		return cursorByWest, true
+	case "search":
+		return cursorByValue, true
+	case "nearby":
+		return cursorByDist, true
+	}
+	return 0, false
+}
+
+// parseCursorToken parses the value of CURSOR for a command. Returns false
+// if the value is an offset rather than a token. The token BEGIN starts at
+// the first page.
+func parseCursorToken(cmd, s string) (*cursorToken, bool, error) {
+	if len(s) > 0 && s[0] >= '0' && s[0] <= '9' {
+		return nil, false, nil
+	}
+	kind, ok := cursorKindFor(cmd)
+	if !ok {
+		return nil, true, errInvalidArgument(s)
+	}
+	if strings.ToLower(s) == "begin" {
+		return &cursorToken{kind: kind}, true, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) < 2 || b[0] != cursorTokenVersion ||
+		b[1] != kind {
+		return nil, true, errInvalidArgument(s)
+	}
+	ct := &cursorToken{kind: kind, pos: true}
+	b = b[2:]
+	switch kind {
+	case cursorByValue:
+		n, sz := binary.Uvarint(b)
+		if sz <= 0 || uint64(len(b)-sz) < n {
+			return nil, true, errInvalidArgument(s)
+		}
+		ct.value = string(b[sz : sz+int(n)])
+		b = b[sz+int(n):]
+	case cursorByDist, cursorByWest:
+		if len(b) < 8 {
+			return nil, true, errInvalidArgument(s)
+		}
+		ct.num = math.Float64frombits(binary.BigEndian.Uint64(b))
+		b = b[8:]
+	}
+	ct.id = string(b)
+	return ct, true, nil
+}
+
+// String returns the encoded token.
+func (ct *cursorToken) String() string {
+	b := []byte{cursorTokenVersion, ct.kind}
+	switch ct.kind {
+	case cursorByValue:
+		b = binary.AppendUvarint(b, uint64(len(ct.value)))
+		b = append(b, ct.value...)
+	case cursorByDist, cursorByWest:
+		b = binary.BigEndian.AppendUint64(b, math.Float64bits(ct.num))
+	}
+	b = append(b, ct.id...)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// next returns the token that continues after a result.
+func (ct *cursorToken) next(opts ScanWriterParams) *cursorToken {
+	next := &cursorToken{kind: ct.kind, pos: true, desc: ct.desc,
+		id: opts.obj.ID()}
+	switch ct.kind {
+	case cursorByValue:
+		next.value = opts.obj.String()
+	case cursorByDist, cursorByWest:
+		next.num = opts.pos
+	}
+	return next
+}
+
+// after returns true if a result comes after the position of the token.
+// The pos is the distance or the west edge of the result.
+func (ct *cursorToken) after(o *object.Object, pos float64) bool {
+	if !ct.pos {
+		return true
+	}
+	cmp := strings.Compare(o.ID(), ct.id)
+	switch ct.kind {
+	case cursorByValue:
+		if c := strings.Compare(o.String(), ct.value); c != 0 {
+			cmp = c
+		}
+	case cursorByDist, cursorByWest:
+		if pos < ct.num {
+			cmp = -1
+		} else if pos > ct.num {
+			cmp = 1
+		}
+	}
+	if ct.desc {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+// cursorTies holds the results of a spatial search that are at the same
+// position, which are pushed in the order of their ids, so that the next page
+// can continue between them.
+type cursorTies struct {
+	push   func(params ScanWriterParams) bool
+	params []ScanWriterParams
+}
+
+// add adds a result, after pushing the results at an earlier position.
+func (t *cursorTies) add(params ScanWriterParams) bool {
+	if len(t.params) > 0 && t.params[0].pos != params.pos && !t.flush() {
+		return false
+	}
+	t.params = append(t.params, params)
+	return true
+}
+
+// flush pushes the results that are held.
+func (t *cursorTies) flush() bool {
+	sort.Slice(t.params, func(i, j int) bool {
+		return t.params[i].obj.ID() < t.params[j].obj.ID()
+	})
+	for _, params := range t.params {
+		if !t.push(params) {
+			t.params = nil
+			return false
+		}
+	}
+	t.params = t.params[:0]
+	return true
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"time"
+
+	"github.com/tidwall/btree"
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/collection"
+	"github.com/tidwall/tile38/internal/rtree"
+)
+
+// defaultDB is the database that a client uses until it selects another.
+const defaultDB = "0"
+
+// database is a logical database. Each database has its own namespace of
+// collections, schemas, hooks, and channels.
+type database struct {
+	name string
+
+	cols    *btree.Map[string, *collection.Collection] // data collections
+	schemas *btree.Map[string, *schema]                // collection schemas
+	motions *btree.Set[string]                         // keys with motion fields
+	index3d *btree.Set[string]                         // keys with a 3D index
+
+	hooks        *btree.BTree                   // hook name -- [string]*Hook
+	hookCross    *rtree.RTreeGN[float64, *Hook] // hook spatial tree for "cross" geofences
+	hookTree     *rtree.RTreeGN[float64, *Hook] // hook spatial tree for all
+	hooksOut     *btree.BTree                   // hooks with "outside" detection -- [string]*Hook
+	groupHooks   *btree.BTree                   // hooks that are connected to objects
+	groupObjects *btree.BTree                   // objects that are connected to hooks
+}
+
+func newDatabase(name string) *database {
+	return &database{
+		name:         name,
+		cols:         &btree.Map[string, *collection.Collection]{},
+		schemas:      &btree.Map[string, *schema]{},
+		motions:      &btree.Set[string]{},
+		index3d:      &btree.Set[string]{},
+		hooks:        btree.NewNonConcurrent(byHookName),
+		hooksOut:     btree.NewNonConcurrent(byHookName),
+		hookCross:    &rtree.RTreeGN[float64, *Hook]{},
+		hookTree:     &rtree.RTreeGN[float64, *Hook]{},
+		groupHooks:   btree.NewNonConcurrent(byGroupHook),
+		groupObjects: btree.NewNonConcurrent(byGroupObject),
+	}
+}
+
+// renameKey moves the collection at key to newKey, with the schema and the
+// settings of the key, which replace the ones of newKey.
+func (db *database) renameKey(key, newKey string) {
+	col, _ := db.cols.Delete(key)
+	db.cols.Set(newKey, col)
+	db.schemas.Delete(newKey)
+	if sc, ok := db.schemas.Delete(key); ok {
+		db.schemas.Set(newKey, &schema{key: newKey, fields: sc.fields})
+	}
+	for _, keys := range []*btree.Set[string]{db.motions, db.index3d} {
+		keys.Delete(newKey)
+		if keys.Contains(key) {
+			keys.Delete(key)
+			keys.Insert(newKey)
+		}
+	}
+}
+
+// newCollection returns a new collection for key, with the index of the key.
+func (db *database) newCollection(key string) *collection.Collection {
+	col := collection.New()
+	col.SetIndex3D(db.index3d.Contains(key))
+	return col
+}
+
+// getDB returns the database with the provided name. An empty name is the
+// default database. The database must have been created by selectDB, which
+// is always the case for the database of a client.
+func (s *Server) getDB(name string) *database {
+	if name == "" {
+		name = defaultDB
+	}
+	db, _ := s.dbs.Get(name)
+	return db
+}
+
+// selectDB returns the database with the provided name, creating it when it
+// does not exist. The caller must hold the write lock.
+func (s *Server) selectDB(name string) *database {
+	if name == "" {
+		name = defaultDB
+	}
+	db, ok := s.dbs.Get(name)
+	if !ok {
+		db = newDatabase(name)
+		s.dbs.Set(name, db)
+	}
+	return db
+}
+
+// SELECT db
+func (s *Server) cmdSELECT(msg *Message, client *Client) (resp.Value, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) != 2 {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	name := args[1]
+	if name == "" {
+		return retrerr(errInvalidArgument(name))
+	}
+
+	// >> Operation
+
+	s.selectDB(name)
+	if client != nil {
+		client.db = name
+	}
+
+	// >> Response
+
+	return OKMessage(msg, start), nil
+}
+
+// SWAPDB db1 db2
+func (s *Server) cmdSWAPDB(msg *Message) (resp.Value, commandDetails, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) != 3 {
+		return retwerr(errInvalidNumberOfArguments)
+	}
+	if args[1] == "" {
+		return retwerr(errInvalidArgument(args[1]))
+	}
+	if args[2] == "" {
+		return retwerr(errInvalidArgument(args[2]))
+	}
+
+	// >> Operation
+
+	db1 := s.selectDB(args[1])
+	db2 := s.selectDB(args[2])
+	for _, db := range []*database{db1, db2} {
+		var hasHook, hasChannel bool
+		db.hooks.Ascend(nil, func(v interface{}) bool {
+			if v.(*Hook).channel {
+				hasChannel = true
+			} else {
+				hasHook = true
+			}
+			return true
+		})
+		if hasHook {
+			return retwerr(errDatabaseHasHooksSet)
+		}
+		if hasChannel {
+			return retwerr(errDatabaseHasChannelsSet)
+		}
+	}
+	db1.cols, db2.cols = db2.cols, db1.cols
+	db1.schemas, db2.schemas = db2.schemas, db1.schemas
+	db1.motions, db2.motions = db2.motions, db1.motions
+	db1.index3d, db2.index3d = db2.index3d, db1.index3d
+
+	// >> Response
+
+	var d commandDetails
+	d.command = "swapdb"
+	d.updated = db1 != db2
+	d.timestamp = time.Now()
+
+	return OKMessage(msg, start), d, nil
+}
+
+// scanCols iterates over the collections of all databases.
+func (s *Server) scanCols(iter func(db, key string, col *collection.Collection) bool) {
+	s.dbs.Scan(func(name string, db *database) bool {
+		keepGoing := true
+		db.cols.Scan(func(key string, col *collection.Collection) bool {
+			keepGoing = iter(name, key, col)
+			return keepGoing
+		})
+		return keepGoing
+	})
+}
+
+// numCols returns the number of collections in all databases.
+func (s *Server) numCols() int {
+	var n int
+	s.dbs.Scan(func(_ string, db *database) bool {
+		n += db.cols.Len()
+		return true
+	})
+	return n
+}
+
+// numHooks returns the number of hooks and channels in all databases.
+func (s *Server) numHooks() int {
+	var n int
+	s.dbs.Scan(func(_ string, db *database) bool {
+		n += db.hooks.Len()
+		return true
+	})
+	return n
+}
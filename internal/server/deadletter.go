@@ -0,0 +1,337 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/buntdb"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/glob"
+	"github.com/tidwall/tile38/internal/log"
+)
+
+// deadLetterGrace is how much longer than its ttl an event of a hook with a
+// retry policy stays in the queue, which gives the hook the time to move it
+// to the dead letters before it expires.
+const deadLetterGrace = time.Minute
+
+// hookPolicy is how a hook retries the events that it fails to send. The
+// events that it gives up on are kept as dead letters.
+type hookPolicy struct {
+	retries    int           // attempts per event, zero is until the ttl
+	backoff    time.Duration // delay after the first failed attempt
+	maxBackoff time.Duration // the delay doubles until this
+	ttl        time.Duration // how long an event may wait to be sent
+	deadMax    int           // dead letters kept, the oldest go first
+	deadTTL    time.Duration // how long a dead letter is kept
+	hasBackoff bool
+	hasTTL     bool
+	hasDead    bool
+}
+
+func newHookPolicy() *hookPolicy {
+	return &hookPolicy{
+		backoff:    time.Second / 2,
+		maxBackoff: time.Second / 2,
+		ttl:        hookLogSetDefaults.TTL,
+		deadMax:    1000,
+		deadTTL:    24 * time.Hour,
+	}
+}
+
+func parseSeconds(s string) (time.Duration, error) {
+	secs, err := parsePositiveFloat(s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// setBackoff sets the BACKOFF delays.
+func (p *hookPolicy) setBackoff(smin, smax string) (err error) {
+	if p.backoff, err = parseSeconds(smin); err != nil {
+		return err
+	}
+	if p.maxBackoff, err = parseSeconds(smax); err != nil {
+		return err
+	}
+	if p.maxBackoff < p.backoff {
+		return errInvalidArgument(smax)
+	}
+	p.hasBackoff = true
+	return nil
+}
+
+// setDeadLetter sets the DEADLETTER limits.
+func (p *hookPolicy) setDeadLetter(smax, sttl string) (err error) {
+	if p.deadMax, err = strconv.Atoi(smax); err != nil || p.deadMax < 1 {
+		return errInvalidArgument(smax)
+	}
+	if p.deadTTL, err = parseSeconds(sttl); err != nil {
+		return err
+	}
+	if p.deadTTL == 0 {
+		return errInvalidArgument(sttl)
+	}
+	p.hasDead = true
+	return nil
+}
+
+// delay returns how long to wait after the failed attempts of an event.
+func (p *hookPolicy) delay(attempts int) time.Duration {
+	d := p.backoff
+	for i := 1; i < attempts && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	return d
+}
+
+// givesUp returns true when an event is not tried again, because of the
+// attempts or because the rest of its ttl has passed.
+func (p *hookPolicy) givesUp(attempts int, ttl time.Duration) bool {
+	return (p.retries > 0 && attempts >= p.retries) || ttl <= deadLetterGrace
+}
+
+// args returns the policy in the same form as the SETHOOK arguments.
+func (p *hookPolicy) args() []string {
+	if p == nil {
+		return nil
+	}
+	var args []string
+	if p.retries > 0 {
+		args = append(args, "retry", strconv.Itoa(p.retries))
+	}
+	if p.hasBackoff {
+		args = append(args, "backoff", formatSeconds(p.backoff),
+			formatSeconds(p.maxBackoff))
+	}
+	if p.hasTTL {
+		args = append(args, "ttl", formatSeconds(p.ttl))
+	}
+	if p.hasDead {
+		args = append(args, "deadletter", strconv.Itoa(p.deadMax),
+			formatSeconds(p.deadTTL))
+	}
+	return args
+}
+
+func (p *hookPolicy) equals(other *hookPolicy) bool {
+	return strings.Join(p.args(), " ") == strings.Join(other.args(), " ")
+}
+
+func (p *hookPolicy) appendJSON(dst []byte) []byte {
+	dst = append(dst, `,"policy":{"retry":`...)
+	dst = strconv.AppendInt(dst, int64(p.retries), 10)
+	dst = append(dst, `,"backoff":[`...)
+	dst = append(dst, formatSeconds(p.backoff)...)
+	dst = append(dst, ',')
+	dst = append(dst, formatSeconds(p.maxBackoff)...)
+	dst = append(dst, `],"ttl":`...)
+	dst = append(dst, formatSeconds(p.ttl)...)
+	dst = append(dst, `,"deadletter":{"max":`...)
+	dst = strconv.AppendInt(dst, int64(p.deadMax), 10)
+	dst = append(dst, `,"ttl":`...)
+	dst = append(dst, formatSeconds(p.deadTTL)...)
+	return append(dst, "}}"...)
+}
+
+// queueOptions returns how the events of a hook are stored in the queue.
+func (h *Hook) queueOptions() *buntdb.SetOptions {
+	if h.policy == nil {
+		return hookLogSetDefaults
+	}
+	return &buntdb.SetOptions{Expires: true, TTL: h.policy.ttl + deadLetterGrace}
+}
+
+// retryDelay returns how long the hook waits before it tries to send again.
+func (h *Hook) retryDelay() time.Duration {
+	if h.policy == nil {
+		return time.Second / 2
+	}
+	return h.policy.delay(h.attempts)
+}
+
+// deadLetter keeps an event that the hook gave up on, for the dead letter ttl
+// of the policy. The oldest dead letters of the hook are deleted when it has
+// more than the max of the policy.
+func (h *Hook) deadLetter(tx *buntdb.Tx, key, val string, attempts int,
+	lastErr error,
+) error {
+	var b []byte
+	b = append(b, `{"hook":`...)
+	b = appendJSONString(b, h.Name)
+	b = append(b, `,"db":`...)
+	b = appendJSONString(b, h.dbname)
+	b = append(b, `,"attempts":`...)
+	b = strconv.AppendInt(b, int64(attempts), 10)
+	if lastErr != nil {
+		b = append(b, `,"error":`...)
+		b = appendJSONString(b, lastErr.Error())
+	}
+	b = appendJSONTimeFormat(append(b, `,"time":`...), time.Now())
+	b = append(b, `,"event":`...)
+	if gjson.Valid(val) {
+		b = append(b, val...)
+	} else {
+		b = appendJSONString(b, val)
+	}
+	b = append(b, '}')
+	log.Debugf("dead letter: %s: %s", h.Name, key)
+	_, _, err := tx.Set(hookDeadPrefix+key[len(hookLogPrefix):], string(b),
+		&buntdb.SetOptions{Expires: true, TTL: h.policy.deadTTL})
+	if err != nil {
+		return err
+	}
+	// the keys of the dead letters of a hook are in the order of the queue
+	var dkeys []string
+	pivot := appendJSONString([]byte(`{"hook":`), h.Name)
+	tx.AscendEqual("deadletters", string(append(pivot, '}')),
+		func(key, val string) bool {
+			if gjson.Get(val, "db").String() == h.dbname {
+				dkeys = append(dkeys, key)
+			}
+			return true
+		},
+	)
+	for i := 0; i < len(dkeys)-h.policy.deadMax; i++ {
+		if _, err := tx.Delete(dkeys[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deadLetterMatch returns the dead letters of the hooks in the database that
+// match the pattern, as their keys and values.
+func (s *Server) deadLetterMatch(tx *buntdb.Tx, db, pattern string,
+) (keys, vals []string) {
+	tx.Ascend("deadletters", func(key, val string) bool {
+		ldb := gjson.Get(val, "db").String()
+		if ldb == "" {
+			ldb = defaultDB
+		}
+		if ldb != db {
+			return true
+		}
+		if match, _ := glob.Match(pattern, gjson.Get(val, "hook").String()); match {
+			keys = append(keys, key)
+			vals = append(vals, val)
+		}
+		return true
+	})
+	return keys, vals
+}
+
+// DEADLETTER LIST pattern
+// DEADLETTER REPLAY pattern
+// DEADLETTER PURGE pattern
+func (s *Server) cmdDEADLETTER(msg *Message) (resp.Value, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) != 2 {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	op := strings.ToLower(args[0][len("deadletter "):])
+	pattern := args[1]
+
+	// >> Operation
+
+	db := s.getDB(msg.DB)
+	var keys, vals []string
+	var count int
+	var hooks []*Hook
+	err := s.qdb.Update(func(tx *buntdb.Tx) error {
+		keys, vals = s.deadLetterMatch(tx, db.name, pattern)
+		if op == "list" {
+			return nil
+		}
+		for i, key := range keys {
+			if op == "replay" {
+				// put the event back in the queue of its hook
+				hook, _ := db.hooks.Get(&Hook{
+					Name: gjson.Get(vals[i], "hook").String(),
+				}).(*Hook)
+				if hook == nil || hook.channel {
+					continue
+				}
+				s.qidx++
+				qkey := hookLogPrefix + uint64ToString(s.qidx)
+				event := gjson.Get(vals[i], "event")
+				val := event.Raw
+				if event.Type == gjson.String {
+					val = event.String()
+				}
+				if _, _, err := tx.Set(qkey, val,
+					hook.queueOptions()); err != nil {
+					return err
+				}
+				s.qpending.add(val, 1)
+				hook.stats.add(&hook.stats.queued, 1)
+				hooks = append(hooks, hook)
+			}
+			if _, err := tx.Delete(key); err != nil {
+				return err
+			}
+			count++
+		}
+		if op == "replay" {
+			_, _, err := tx.Set("hook:idx", uint64ToString(s.qidx), nil)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return retrerr(err)
+	}
+	for _, hook := range hooks {
+		hook.Signal()
+	}
+
+	// >> Response
+
+	if op != "list" {
+		if msg.OutputType == JSON {
+			return resp.StringValue(`{"ok":true,"count":` +
+				strconv.Itoa(count) + `,"elapsed":"` +
+				time.Since(start).String() + "\"}"), nil
+		}
+		return resp.IntegerValue(count), nil
+	}
+	if msg.OutputType == JSON {
+		buf := []byte(`{"ok":true,"deadletters":[`)
+		for i, key := range keys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, `{"id":`...)
+			buf = appendJSONString(buf, key[len(hookDeadPrefix):])
+			buf = append(buf, ',')
+			buf = append(buf, vals[i][1:]...)
+		}
+		buf = append(buf, `],"elapsed":"`+time.Since(start).String()+`"}`...)
+		return resp.StringValue(string(buf)), nil
+	}
+	rvals := make([]resp.Value, 0, len(keys))
+	for i, key := range keys {
+		rvals = append(rvals, resp.ArrayValue([]resp.Value{
+			resp.StringValue(key[len(hookDeadPrefix):]),
+			resp.StringValue(gjson.Get(vals[i], "hook").String()),
+			resp.IntegerValue(int(gjson.Get(vals[i], "attempts").Int())),
+			resp.StringValue(gjson.Get(vals[i], "error").String()),
+			resp.StringValue(gjson.Get(vals[i], "event").String()),
+		}))
+	}
+	return resp.ArrayValue(rvals), nil
+}
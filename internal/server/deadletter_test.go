@@ -0,0 +1,37 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHookPolicy(t *testing.T) {
+	p := newHookPolicy()
+	if err := p.setBackoff("0.5", "3"); err != nil {
+		t.Fatal(err)
+	}
+	for i, d := range []time.Duration{
+		time.Second / 2, time.Second, 2 * time.Second, 3 * time.Second,
+		3 * time.Second,
+	} {
+		if p.delay(i+1) != d {
+			t.Fatalf("attempt %d: expected %v, got %v", i+1, d, p.delay(i+1))
+		}
+	}
+	if p.givesUp(100, p.ttl+deadLetterGrace) {
+		t.Fatal("expected a policy without RETRY to retry until the ttl")
+	}
+	if !p.givesUp(1, deadLetterGrace) {
+		t.Fatal("expected to give up after the ttl")
+	}
+	p.retries = 3
+	if p.givesUp(2, time.Hour) || !p.givesUp(3, time.Hour) {
+		t.Fatal("expected to give up after three attempts")
+	}
+	if args := p.args(); len(args) != 5 || args[3] != "0.5" || args[4] != "3" {
+		t.Fatalf("unexpected args %v", args)
+	}
+	if err := p.setBackoff("2", "1"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
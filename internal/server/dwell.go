@@ -0,0 +1,196 @@
+package server
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/buntdb"
+	"github.com/tidwall/geojson/geo"
+	"github.com/tidwall/tile38/internal/log"
+)
+
+// dwellState is how long an object has been in the fence of a hook. It's
+// stored in the hook queue, so that it survives a restart.
+type dwellState struct {
+	DB     string  `json:"db"`
+	Hook   string  `json:"hook"`
+	Key    string  `json:"key"`
+	ID     string  `json:"id"`
+	Since  int64   `json:"since"`  // when the object entered the fence
+	Fired  bool    `json:"fired"`  // the dwell was sent
+	Lat    float64 `json:"lat"`    // where the object started to loiter
+	Lon    float64 `json:"lon"`    //
+	LSince int64   `json:"lsince"` // when the object started to loiter
+	LFired bool    `json:"lfired"` // the loiter was sent
+	Due    int64   `json:"due,omitempty"`
+}
+
+// dwellKey returns the hook queue key of the dwell state of an object.
+// Each part is quoted, which makes the key of a hook or collection a prefix
+// of the keys of its objects.
+func dwellKey(parts ...string) string {
+	var b []byte
+	b = append(b, hookDwellPrefix...)
+	for _, part := range parts {
+		b = appendJSONString(b, part)
+		b = append(b, ':')
+	}
+	return string(b)
+}
+
+// updateDue sets when the next dwell or loiter is due, which is zero when
+// both were sent.
+func (ds *dwellState) updateDue(fence *liveFenceSwitches) {
+	ds.Due = 0
+	if fence.dwell != 0 && !ds.Fired {
+		ds.Due = ds.Since + int64(fence.dwell*float64(time.Second))
+	}
+	if fence.loiter != 0 && !ds.LFired {
+		due := ds.LSince + int64(fence.loiter*float64(time.Second))
+		if ds.Due == 0 || due < ds.Due {
+			ds.Due = due
+		}
+	}
+}
+
+// dwellMatch tracks how long an object has been inside the fence of a hook,
+// and returns true for dwell or loiter when the object has been there long
+// enough. The tracking ends when the object is not inside.
+func (s *Server) dwellMatch(
+	hookName, dbname string, fence *liveFenceSwitches,
+	details *commandDetails, inside bool,
+) (dwell, loiter bool) {
+	key := dwellKey(dbname, hookName, details.key, details.obj.ID())
+	now := details.timestamp.UnixNano()
+	err := s.qdb.Update(func(tx *buntdb.Tx) error {
+		val, err := tx.Get(key)
+		if err != nil && err != buntdb.ErrNotFound {
+			return err
+		}
+		exists := err == nil
+		if !inside {
+			if exists {
+				_, err = tx.Delete(key)
+			}
+			return err
+		}
+		var ds dwellState
+		if exists {
+			if err := json.Unmarshal([]byte(val), &ds); err != nil {
+				return err
+			}
+		} else {
+			ds = dwellState{DB: dbname, Hook: hookName, Key: details.key,
+				ID: details.obj.ID(), Since: now}
+		}
+		changed := !exists
+		if fence.dwell != 0 && !ds.Fired &&
+			now-ds.Since >= int64(fence.dwell*float64(time.Second)) {
+			ds.Fired, dwell, changed = true, true, true
+		}
+		if fence.loiter != 0 {
+			center := details.obj.Geo().Center()
+			if !exists || geo.DistanceTo(ds.Lat, ds.Lon,
+				center.Y, center.X) > fence.loiterM {
+				// the object moved away, start loitering again from here
+				ds.Lat, ds.Lon = center.Y, center.X
+				ds.LSince, ds.LFired, changed = now, false, true
+			} else if !ds.LFired &&
+				now-ds.LSince >= int64(fence.loiter*float64(time.Second)) {
+				ds.LFired, loiter, changed = true, true, true
+			}
+		}
+		if !changed {
+			return nil
+		}
+		ds.updateDue(fence)
+		data, _ := json.Marshal(ds)
+		_, _, err = tx.Set(key, string(data), nil)
+		return err
+	})
+	if err != nil {
+		log.Error(err)
+		return false, false
+	}
+	return dwell, loiter
+}
+
+// dwellDisconnect stops tracking the objects of a hook, which are the ones
+// with keys that start with the key of the passed parts.
+func (s *Server) dwellDisconnect(parts ...string) {
+	if !s.loadedAndReady.Load() {
+		// the queue already has the dwells of the hooks in the aof
+		return
+	}
+	prefix := dwellKey(parts...)
+	err := s.qdb.Update(func(tx *buntdb.Tx) error {
+		var keys []string
+		tx.AscendGreaterOrEqual("", prefix, func(key, val string) bool {
+			if !strings.HasPrefix(key, prefix) {
+				return false
+			}
+			keys = append(keys, key)
+			return true
+		})
+		for _, key := range keys {
+			if _, err := tx.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// backgroundDwells sends the dwell and loiter notifications that are due for
+// objects that have not been updated since they entered a fence.
+func (s *Server) backgroundDwells(now time.Time) {
+	if s.config.followHost() != "" {
+		// for leader only
+		return
+	}
+	var states []dwellState
+	s.qdb.View(func(tx *buntdb.Tx) error {
+		return tx.AscendRange("dwells", `{"due":1}`,
+			`{"due":`+strconv.FormatInt(now.UnixNano()+1, 10)+`}`,
+			func(key, val string) bool {
+				var ds dwellState
+				if json.Unmarshal([]byte(val), &ds) == nil {
+					states = append(states, ds)
+				}
+				return true
+			})
+	})
+	for _, ds := range states {
+		db := s.getDB(ds.DB)
+		var hook *Hook
+		if db != nil {
+			hook, _ = db.hooks.Get(&Hook{Name: ds.Hook}).(*Hook)
+		}
+		if hook != nil && !hook.schedule.active(now) {
+			// wait for the schedule
+			continue
+		}
+		var d *commandDetails
+		if hook != nil {
+			if col, _ := db.cols.Get(ds.Key); col != nil {
+				if o := col.Get(ds.ID); o != nil {
+					d = &commandDetails{command: "set", key: ds.Key, db: db,
+						obj: o, old: o, timestamp: now, timer: true}
+				}
+			}
+		}
+		if d == nil || (hook.Fence.dwell == 0 && hook.Fence.loiter == 0) {
+			// the hook or the object is gone
+			s.dwellDisconnect(ds.DB, ds.Hook, ds.Key, ds.ID)
+			continue
+		}
+		if err := s.queueHookMessages([]*Hook{hook}, d); err != nil {
+			log.Error(err)
+		}
+	}
+}
@@ -11,7 +11,8 @@ import (
 
 const bgExpireDelay = time.Second / 10
 
-// backgroundExpiring deletes expired items from the database.
+// backgroundExpiring deletes expired items from the database, and sends the
+// dwell notifications that are due.
 // It's executes every 1/10 of a second.
 func (s *Server) backgroundExpiring(wg *sync.WaitGroup) {
 	defer wg.Done()
@@ -21,19 +22,23 @@ func (s *Server) backgroundExpiring(wg *sync.WaitGroup) {
 		now := time.Now()
 		s.backgroundExpireObjects(now)
 		s.backgroundExpireHooks(now)
+		s.backgroundDwells(now)
 	})
 }
 
 func (s *Server) backgroundExpireObjects(now time.Time) {
 	nano := now.UnixNano()
 	var msgs []*Message
-	s.cols.Scan(func(key string, col *collection.Collection) bool {
+	s.scanCols(func(db, key string, col *collection.Collection) bool {
 		col.ScanExpires(func(o *object.Object) bool {
 			if nano < o.Expires() {
 				return false
 			}
 			s.statsExpired.Add(1)
-			msgs = append(msgs, &Message{Args: []string{"del", key, o.ID()}})
+			msgs = append(msgs, &Message{
+				Args: []string{"del", key, o.ID()},
+				DB:   db,
+			})
 			return true
 		})
 		return true
@@ -43,6 +48,7 @@ func (s *Server) backgroundExpireObjects(now time.Time) {
 		if err != nil {
 			log.Fatal(err)
 		}
+		d.db = s.getDB(msg.DB)
 		if err := s.writeAOF(msg.Args, &d); err != nil {
 			log.Fatal(err)
 		}
@@ -59,7 +65,7 @@ func (s *Server) backgroundExpireHooks(now time.Time) {
 		if h.expires.After(now) {
 			return false
 		}
-		msg := &Message{}
+		msg := &Message{DB: h.dbname}
 		if h.channel {
 			msg.Args = []string{"delchan", h.Name}
 		} else {
@@ -74,6 +80,7 @@ func (s *Server) backgroundExpireHooks(now time.Time) {
 		if err != nil {
 			log.Fatal(err)
 		}
+		d.db = s.getDB(msg.DB)
 		if err := s.writeAOF(msg.Args, &d); err != nil {
 			log.Fatal(err)
 		}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tidwall/resp"
+)
+
+// The stages of a search that can reject an object after it's found by the
+// access path.
+const (
+	rejectCursor = iota
+	rejectMatch
+	rejectWhere
+	rejectWherein
+	rejectWhereeval
+	numRejectStages
+)
+
+var rejectStageNames = [numRejectStages]string{
+	"cursor", "match", "where", "wherein", "whereeval",
+}
+
+// explainStats are the statistics that are collected while a search command
+// runs for EXPLAIN. The methods do nothing on a nil explainStats, so the
+// scanWriter can call them without checking whether the command is being
+// explained.
+type explainStats struct {
+	access     string // the access path that found the objects
+	candidates uint64 // objects visited by the access path
+	nodes      uint64 // nodes of the spatial index visited by the search
+	pushed     uint64 // candidates that were in the search area
+	matched    uint64 // objects that passed all of the tests
+	rejected   [numRejectStages]uint64
+	exprTime   time.Duration // WHERE expressions
+	luaTime    time.Duration // WHEREEVAL scripts
+}
+
+// setAccess sets the access path of the search.
+func (ex *explainStats) setAccess(access string) {
+	if ex != nil {
+		ex.access = access
+	}
+}
+
+// push counts an object that was passed to the scanWriter.
+func (ex *explainStats) push() {
+	if ex != nil {
+		ex.pushed++
+	}
+}
+
+// countNodes adds nodes of the spatial index that were visited.
+func (ex *explainStats) countNodes(nodes uint64) {
+	if ex != nil {
+		ex.nodes += nodes
+	}
+}
+
+// reject counts an object that was rejected at a stage.
+func (ex *explainStats) reject(stage int) {
+	if ex != nil {
+		ex.rejected[stage]++
+	}
+}
+
+// now returns the current time, or the zero time when not explaining.
+func (ex *explainStats) now() time.Time {
+	if ex == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// exprDone adds the time since start to the expression time.
+func (ex *explainStats) exprDone(start time.Time) {
+	if ex != nil {
+		ex.exprTime += time.Since(start)
+	}
+}
+
+// luaDone adds the time since start to the Lua time.
+func (ex *explainStats) luaDone(start time.Time) {
+	if ex != nil {
+		ex.luaTime += time.Since(start)
+	}
+}
+
+// finish records the counters of the scanWriter, before the results are
+// written.
+func (ex *explainStats) finish(sw *scanWriter) {
+	if ex != nil {
+		ex.candidates = sw.numberIters - sw.Offset()
+		ex.matched = sw.count
+	}
+}
+
+// cmdEXPLAIN runs a search command and reports how it found its results
+// rather than the results themselves.
+//
+//	EXPLAIN SCAN|SEARCH|NEARBY|WITHIN|INTERSECTS key ...
+func (s *Server) cmdEXPLAIN(msg *Message) (resp.Value, error) {
+	start := time.Now()
+	if len(msg.Args) < 2 {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	ex := &explainStats{}
+	inner := *msg
+	inner.Args = msg.Args[1:]
+	inner._command = ""
+	inner.explain = ex
+
+	var res resp.Value
+	var err error
+	innerStart := time.Now()
+	switch inner.Command() {
+	default:
+		return retrerr(errInvalidArgument(inner.Args[0]))
+	case "scan":
+		res, err = s.cmdScan(&inner)
+	case "search":
+		res, err = s.cmdSearch(&inner)
+	case "nearby":
+		res, err = s.cmdNearby(&inner)
+	case "within":
+		res, err = s.cmdWITHIN(&inner)
+	case "intersects":
+		res, err = s.cmdINTERSECTS(&inner)
+	}
+	took := time.Since(innerStart)
+	if err != nil {
+		if _, ok := err.(liveFenceSwitches); ok {
+			return retrerr(errors.New("FENCE is not allowed with EXPLAIN"))
+		}
+		return retrerr(err)
+	}
+	var size int
+	if msg.OutputType == JSON {
+		size = len(res.Bytes())
+	} else {
+		b, _ := res.MarshalRESP()
+		size = len(b)
+	}
+
+	m := make(map[string]interface{})
+	m["command"] = inner.Command()
+	m["access"] = ex.access
+	if ex.access == "" {
+		// the key does not exist
+		m["access"] = "none"
+	}
+	m["candidates"] = ex.candidates
+	m["nodes"] = ex.nodes
+	// candidates that were outside of the search area, or past the end of a
+	// range scan
+	var area uint64
+	if ex.candidates > ex.pushed {
+		area = ex.candidates - ex.pushed
+	}
+	m["rejected_area"] = area
+	for i, name := range rejectStageNames {
+		m["rejected_"+name] = ex.rejected[i]
+	}
+	m["matched"] = ex.matched
+	m["expr_time"] = ex.exprTime.String()
+	m["lua_time"] = ex.luaTime.String()
+	m["output_size"] = size
+	m["time"] = took.String()
+
+	if msg.OutputType == JSON {
+		data, _ := json.Marshal(m)
+		return resp.StringValue(`{"ok":true,"explain":` + string(data) +
+			`,"elapsed":"` + time.Since(start).String() + "\"}"), nil
+	}
+	return resp.ArrayValue(respValuesSimpleMap(m)), nil
+}
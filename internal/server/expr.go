@@ -3,12 +3,14 @@ package server
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"sync"
 
 	"github.com/tidwall/expr"
 	"github.com/tidwall/geojson"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/match"
+	"github.com/tidwall/tile38/internal/collection"
 	"github.com/tidwall/tile38/internal/field"
 	"github.com/tidwall/tile38/internal/log"
 	"github.com/tidwall/tile38/internal/object"
@@ -89,6 +91,15 @@ func objExpr(o *object.Object, info expr.RefInfo) (expr.Value, error) {
 			r := gjson.Parse(rf.Value().JSON())
 			return resultToValue(r), nil
 		}
+		switch info.Ident {
+		case "h3":
+			// h3(resolution) is the H3 cell of the object
+			return expr.Function("h3"), nil
+		case "area":
+			return expr.Number(collection.GeodesicArea(o.Geo())), nil
+		case "length":
+			return expr.Number(collection.GeodesicLength(o.Geo())), nil
+		}
 	}
 	return expr.Number(0), nil
 }
@@ -124,6 +135,17 @@ func newExprPool(s *Server) *exprPool {
 		},
 		// call
 		func(info expr.CallInfo, ctx *expr.Context) (expr.Value, error) {
+			if !info.Chain && info.Ident == "h3" {
+				if info.Args.Len() < 1 {
+					return expr.Undefined, nil
+				}
+				res, ok := parseH3Resolution(info.Args.At(0).String())
+				if !ok {
+					return expr.Undefined, nil
+				}
+				o := ctx.UserData.(*object.Object)
+				return expr.String(h3CellOf(o.Geo(), res).String()), nil
+			}
 			if info.Chain {
 				switch info.Ident {
 				case "match":
@@ -191,3 +213,26 @@ func (where whereT) matchExpr(s *Server, o *object.Object) bool {
 	s.epool.Put(ctx)
 	return res.Bool()
 }
+
+// exprValue returns the result of an expression for an object as a field
+// value. Returns the zero value when the result isn't a number, string or
+// boolean.
+func exprValue(s *Server, o *object.Object, expression string) field.Value {
+	ctx := s.epool.Get(o)
+	res, err := expr.Eval(expression, ctx)
+	s.epool.Put(ctx)
+	if err != nil {
+		log.Debugf("%v", err)
+		return field.ZeroValue
+	}
+	switch res.TypeOf() {
+	case "number":
+		return field.ValueOf(strconv.FormatFloat(res.Float64(), 'f', -1, 64))
+	case "string":
+		return field.ValueOf(jsonString(res.String()))
+	case "boolean":
+		return field.ValueOf(strconv.FormatBool(res.Bool()))
+	default:
+		return field.ZeroValue
+	}
+}
@@ -10,8 +10,10 @@ import (
 	"github.com/tidwall/geojson/geo"
 	"github.com/tidwall/geojson/geometry"
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/tile38/internal/collection"
 	"github.com/tidwall/tile38/internal/field"
 	"github.com/tidwall/tile38/internal/glob"
+	"github.com/tidwall/tile38/internal/log"
 	"github.com/tidwall/tile38/internal/object"
 )
 
@@ -29,11 +31,15 @@ func FenceMatch(hookName string, sw *scanWriter, fence *liveFenceSwitches, metas
 	}
 	return nmsgs
 }
-func appendHookDetails(b []byte, hookName string, metas []FenceMeta) []byte {
+func appendHookDetails(b []byte, hookName, db string, metas []FenceMeta) []byte {
 	if len(hookName) > 0 {
 		b = append(b, `,"hook":`...)
 		b = appendJSONString(b, hookName)
 	}
+	if db != defaultDB {
+		b = append(b, `,"db":`...)
+		b = appendJSONString(b, db)
+	}
 	if len(metas) > 0 {
 		b = append(b, `,"meta":{`...)
 		for i, meta := range metas {
@@ -54,8 +60,8 @@ func objIsSpatial(obj geojson.Object) bool {
 	return ok
 }
 
-func hookJSONString(hookName string, metas []FenceMeta) string {
-	return string(appendHookDetails(nil, hookName, metas))
+func hookJSONString(hookName, db string, metas []FenceMeta) string {
+	return string(appendHookDetails(nil, hookName, db, metas))
 }
 
 func multiGlobMatch(globs []string, s string) bool {
@@ -75,9 +81,14 @@ func fenceMatch(
 	hookName string, sw *scanWriter, fence *liveFenceSwitches,
 	metas []FenceMeta, details *commandDetails,
 ) []string {
+	tracksDwell := hookName != "" && fence != nil &&
+		(fence.dwell != 0 || fence.loiter != 0)
 	if details.command == "drop" {
+		if tracksDwell {
+			sw.s.dwellDisconnect(sw.db.name, hookName, details.key)
+		}
 		return []string{
-			`{"command":"drop"` + hookJSONString(hookName, metas) +
+			`{"command":"drop"` + hookJSONString(hookName, sw.db.name, metas) +
 				`,"key":` + jsonString(details.key) +
 				`,"time":` + jsonTimeFormat(details.timestamp) + `}`,
 		}
@@ -98,8 +109,12 @@ func fenceMatch(
 		}
 	}
 	if details.command == "del" {
+		if tracksDwell {
+			sw.s.dwellDisconnect(sw.db.name, hookName, details.key,
+				details.obj.ID())
+		}
 		return []string{
-			`{"command":"del"` + hookJSONString(hookName, metas) +
+			`{"command":"del"` + hookJSONString(hookName, sw.db.name, metas) +
 				`,"key":` + jsonString(details.key) +
 				`,"id":` + jsonString(details.obj.ID()) +
 				`,"time":` + jsonTimeFormat(details.timestamp) + `}`,
@@ -111,7 +126,7 @@ func fenceMatch(
 		if fence.roam.on {
 			if details.command == "set" {
 				roamNearbys, roamFaraways =
-					fenceMatchRoam(sw.s, fence, details.obj, details.old)
+					fenceMatchRoam(sw.db, fence, details.obj, details.old)
 				if len(roamNearbys) == 0 && len(roamFaraways) == 0 {
 					return nil
 				}
@@ -155,20 +170,33 @@ func fenceMatch(
 								details.old.Geo().Center(),
 								details.obj.Geo().Center(),
 							}, nil))
+						lso := object.New("", ls, 0, field.List{})
 						temp := false
+						zrange := fence.zrange
+						if zrange != nil {
+							// the line has no altitude, so the range is
+							// checked using the altitudes of both ends.
+							z1 := collection.Z(details.old.Geo())
+							z2 := collection.Z(details.obj.Geo())
+							if math.Max(z1, z2) < zrange.Min ||
+								math.Min(z1, z2) > zrange.Max {
+								lso = nil
+							}
+							fence.zrange = nil
+						}
 						if fence.cmd == "within" {
 							// because we are testing if the line croses the area we need to use
 							// "intersects" instead of "within".
 							fence.cmd = "intersects"
 							temp = true
 						}
-						lso := object.New("", ls, 0, field.List{})
-						if fenceMatchObject(fence, lso) {
+						if lso != nil && fenceMatchObject(fence, lso) {
 							detect = "cross"
 						}
 						if temp {
 							fence.cmd = "within"
 						}
+						fence.zrange = zrange
 					}
 				}
 			}
@@ -179,7 +207,18 @@ func fenceMatch(
 	// if details.fmap == nil {
 	// 	return nil
 	// }
-	for {
+	var dwell, loiter bool
+	if tracksDwell {
+		inside := detect == "enter" || detect == "inside"
+		dwell, loiter = sw.s.dwellMatch(hookName, sw.db.name, fence, details,
+			inside)
+		dwell = dwell && (fence.detect == nil || fence.detect["dwell"])
+		loiter = loiter && (fence.detect == nil || fence.detect["loiter"])
+	}
+
+	// a dwell timer only sends the dwell and loiter messages
+	send := !details.timer
+	for send {
 		if fence.detect != nil && !fence.detect[detect] {
 			if detect == "enter" {
 				detect = "inside"
@@ -189,23 +228,39 @@ func fenceMatch(
 				detect = "outside"
 				continue
 			}
-			return nil
+			send = false
 		}
 		break
 	}
-	var distance float64
-	if fence.distance && fence.obj != nil {
+	if !send && !dwell && !loiter {
+		return nil
+	}
+	var distance, frac float64
+	if fence.distance && fence.corridor != nil {
+		distance, frac, _ = fence.corridor.match(details.obj.Geo(),
+			fence.cmd == "within")
+	} else if fence.distance && fence.obj != nil {
 		distance = details.obj.Geo().Distance(fence.obj)
+		if fence.hasZ {
+			distance = collection.Distance3D(distance, fence.z,
+				collection.Z(details.obj.Geo()))
+		}
 	}
 
 	sw.fullFields = true
 	sw.msg.OutputType = JSON
-	sw.writeObject(ScanWriterParams{
+	err := sw.writeObject(ScanWriterParams{
 		obj:        details.obj,
 		noTest:     true,
 		dist:       distance,
 		distOutput: fence.distance,
+		frac:       frac,
+		fracOutput: fence.distance && fence.corridor != nil,
 	})
+	if err != nil {
+		log.Warnf("fence: %s: %s: %v", details.key, details.obj.ID(), err)
+		return nil
+	}
 
 	if sw.wr.Len() == 0 {
 		return nil
@@ -222,20 +277,20 @@ func fenceMatch(
 
 	var group string
 	if detect == "enter" {
-		group = sw.s.groupConnect(hookName, details.key, details.obj.ID())
+		group = sw.db.groupConnect(hookName, details.key, details.obj.ID())
 	} else if detect == "cross" {
-		sw.s.groupDisconnect(hookName, details.key, details.obj.ID())
-		group = sw.s.groupConnect(hookName, details.key, details.obj.ID())
+		sw.db.groupDisconnect(hookName, details.key, details.obj.ID())
+		group = sw.db.groupConnect(hookName, details.key, details.obj.ID())
 	} else {
-		group = sw.s.groupGet(hookName, details.key, details.obj.ID())
+		group = sw.db.groupGet(hookName, details.key, details.obj.ID())
 		if group == "" {
-			group = sw.s.groupConnect(hookName, details.key, details.obj.ID())
+			group = sw.db.groupConnect(hookName, details.key, details.obj.ID())
 		}
 	}
 	var msgs []string
-	if fence.detect == nil || fence.detect[detect] {
+	if send && (fence.detect == nil || fence.detect[detect]) {
 		if len(res) > 0 && res[0] == '{' {
-			msgs = append(msgs, makemsg(details.command, group, detect,
+			msgs = append(msgs, makemsg(details.command, group, sw.db.name, detect,
 				hookName, metas, details.key, details.timestamp, res[1:]))
 		} else {
 			msgs = append(msgs, string(res))
@@ -243,12 +298,12 @@ func fenceMatch(
 	}
 	switch detect {
 	case "enter":
-		if fence.detect == nil || fence.detect["inside"] {
-			msgs = append(msgs, makemsg(details.command, group, "inside", hookName, metas, details.key, details.timestamp, res[1:]))
+		if send && (fence.detect == nil || fence.detect["inside"]) {
+			msgs = append(msgs, makemsg(details.command, group, sw.db.name, "inside", hookName, metas, details.key, details.timestamp, res[1:]))
 		}
 	case "exit", "cross":
 		if fence.detect == nil || fence.detect["outside"] {
-			msgs = append(msgs, makemsg(details.command, group, "outside", hookName, metas, details.key, details.timestamp, res[1:]))
+			msgs = append(msgs, makemsg(details.command, group, sw.db.name, "outside", hookName, metas, details.key, details.timestamp, res[1:]))
 		}
 	case "roam":
 		if len(msgs) > 0 {
@@ -272,6 +327,14 @@ func fenceMatch(
 			msgs = nmsgs
 		}
 	}
+	if dwell {
+		msgs = append(msgs, makemsg(details.command, group, sw.db.name, "dwell",
+			hookName, metas, details.key, details.timestamp, res[1:]))
+	}
+	if loiter {
+		msgs = append(msgs, makemsg(details.command, group, sw.db.name, "loiter",
+			hookName, metas, details.key, details.timestamp, res[1:]))
+	}
 	return msgs
 }
 
@@ -292,7 +355,7 @@ func extendRoamMessage(
 		math.Floor(match.meters*1000)/1000, 'f', -1, 64)
 	if fence.roam.scan != "" {
 		nmsg = append(nmsg, `,"scan":[`...)
-		col, _ := sw.s.cols.Get(fence.roam.key)
+		col, _ := sw.db.cols.Get(fence.roam.key)
 		if col != nil {
 			o := col.Get(match.id)
 			if o != nil {
@@ -335,7 +398,7 @@ func extendRoamMessage(
 }
 
 func makemsg(
-	command, group, detect, hookName string,
+	command, group, db, detect, hookName string,
 	metas []FenceMeta, key string, t time.Time, tail string,
 ) string {
 	var buf []byte
@@ -343,7 +406,7 @@ func makemsg(
 	buf = append(append(buf, `","group":"`...), group...)
 	buf = append(append(buf, `","detect":"`...), detect...)
 	buf = append(buf, '"')
-	buf = appendHookDetails(buf, hookName, metas)
+	buf = appendHookDetails(buf, hookName, db, metas)
 	buf = appendJSONString(append(buf, `,"key":`...), key)
 	buf = appendJSONTimeFormat(append(buf, `,"time":`...), t)
 	buf = append(append(buf, ','), tail...)
@@ -358,8 +421,25 @@ func fenceMatchObject(fence *liveFenceSwitches, o *object.Object) bool {
 		// we need to check this object against
 		return false
 	}
+	if fence.zrange != nil && !fence.zrange.Contains(collection.Z(o.Geo())) {
+		return false
+	}
+	if fence.corridor != nil {
+		_, _, ok := fence.corridor.match(o.Geo(), fence.cmd == "within")
+		return ok
+	}
 	switch fence.cmd {
 	case "nearby":
+		if fence.hasZ {
+			// a point with an altitude is a sphere
+			circle := fence.obj.(*geojson.Circle)
+			if circle.Meters() < 0 {
+				return false
+			}
+			meters := o.Geo().Distance(geojson.NewPoint(circle.Center()))
+			return collection.Distance3D(meters, fence.z,
+				collection.Z(o.Geo())) <= circle.Meters()
+		}
 		// nearby is an INTERSECT on a Circle
 		return o.Geo().Intersects(fence.obj)
 	case "within":
@@ -371,13 +451,13 @@ func fenceMatchObject(fence *liveFenceSwitches, o *object.Object) bool {
 }
 
 func fenceMatchNearbys(
-	s *Server, fence *liveFenceSwitches,
+	db *database, fence *liveFenceSwitches,
 	obj *object.Object,
 ) (nearbys []roamMatch) {
 	if obj == nil {
 		return nil
 	}
-	col, _ := s.cols.Get(fence.roam.key)
+	col, _ := db.cols.Get(fence.roam.key)
 	if col == nil {
 		return nil
 	}
@@ -419,11 +499,11 @@ func fenceMatchNearbys(
 }
 
 func fenceMatchRoam(
-	s *Server, fence *liveFenceSwitches,
+	db *database, fence *liveFenceSwitches,
 	obj, old *object.Object,
 ) (nearbys, faraways []roamMatch) {
-	oldNearbys := fenceMatchNearbys(s, fence, old)
-	newNearbys := fenceMatchNearbys(s, fence, obj)
+	oldNearbys := fenceMatchNearbys(db, fence, old)
+	newNearbys := fenceMatchNearbys(db, fence, obj)
 	// Go through all matching objects in new-nearbys and old-nearbys.
 	for i := 0; i < len(oldNearbys); i++ {
 		var match bool
@@ -169,6 +169,15 @@ func (s *Server) followHandleCommand(args []string, followc int, w io.Writer) (i
 		return s.aofsz, errNoLongerFollowing
 	}
 	msg := &Message{Args: args}
+	if msg.Command() == "select" && len(args) == 2 {
+		// following commands belong to the selected database
+		s.selectDB(args[1])
+		s.appendAOF(args)
+		s.aofdb = args[1]
+		return s.aofsz, nil
+	}
+	msg.DB = s.aofdb
+	msg.replay = true
 	_, d, err := s.command(msg, nil)
 	if err != nil {
 		if commandErrIsFatal(err) {
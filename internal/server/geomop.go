@@ -0,0 +1,198 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geometry"
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/geomop"
+)
+
+// geomopArea is an operand of GEOMOP, which is either a stored object or
+// inline GeoJSON.
+type geomopArea struct {
+	key, id string
+	json    string
+}
+
+type geomopArgs struct {
+	op       string
+	areas    []geomopArea
+	meters   float64
+	storeKey string
+	storeID  string
+}
+
+// geomopArity returns the number of areas that an operation takes, and
+// whether it takes meters. It returns zero areas for an unknown operation.
+func geomopArity(op string) (nareas int, meters bool) {
+	switch op {
+	case "union", "intersection", "difference":
+		return 2, false
+	case "hull", "centroid":
+		return 1, false
+	case "simplify", "buffer":
+		return 1, true
+	}
+	return 0, false
+}
+
+// parseGeomopArgs parses the arguments of GEOMOP, without looking up the
+// objects.
+func parseGeomopArgs(vs []string) (ga geomopArgs, err error) {
+	var ok bool
+	var op string
+	if vs, op, ok = tokenval(vs); !ok || op == "" {
+		return ga, errInvalidNumberOfArguments
+	}
+	ga.op = strings.ToLower(op)
+	nareas, meters := geomopArity(ga.op)
+	if nareas == 0 {
+		return ga, errInvalidArgument(op)
+	}
+	for i := 0; i < nareas; i++ {
+		var typ string
+		if vs, typ, ok = tokenval(vs); !ok || typ == "" {
+			return ga, errInvalidNumberOfArguments
+		}
+		var area geomopArea
+		switch strings.ToLower(typ) {
+		case "get":
+			if vs, area.key, ok = tokenval(vs); !ok || area.key == "" {
+				return ga, errInvalidNumberOfArguments
+			}
+			if vs, area.id, ok = tokenval(vs); !ok || area.id == "" {
+				return ga, errInvalidNumberOfArguments
+			}
+		case "object":
+			if vs, area.json, ok = tokenval(vs); !ok || area.json == "" {
+				return ga, errInvalidNumberOfArguments
+			}
+		default:
+			return ga, errInvalidArgument(typ)
+		}
+		ga.areas = append(ga.areas, area)
+	}
+	if meters {
+		var smeters string
+		if vs, smeters, ok = tokenval(vs); !ok || smeters == "" {
+			return ga, errInvalidNumberOfArguments
+		}
+		if ga.meters, err = strconv.ParseFloat(smeters, 64); err != nil {
+			return ga, errInvalidArgument(smeters)
+		}
+	}
+	if len(vs) > 0 {
+		if strings.ToLower(vs[0]) != "store" {
+			return ga, errInvalidArgument(vs[0])
+		}
+		if len(vs) != 3 || vs[1] == "" || vs[2] == "" {
+			return ga, errInvalidNumberOfArguments
+		}
+		ga.storeKey, ga.storeID = vs[1], vs[2]
+	}
+	return ga, nil
+}
+
+// geomopStores returns true when GEOMOP stores its result, which makes it a
+// write operation.
+func geomopStores(args []string) bool {
+	ga, err := parseGeomopArgs(args[1:])
+	return err == nil && ga.storeKey != ""
+}
+
+// runGeomop performs a geometry operation on its operands.
+func runGeomop(op string, areas []geojson.Object, meters float64,
+	opts *geometry.IndexOptions,
+) (geojson.Object, error) {
+	switch op {
+	case "union":
+		return geomop.Union(areas[0], areas[1], opts)
+	case "intersection":
+		return geomop.Intersection(areas[0], areas[1], opts)
+	case "difference":
+		return geomop.Difference(areas[0], areas[1], opts)
+	case "hull":
+		return geomop.ConvexHull(areas[0], opts)
+	case "centroid":
+		return geomop.Centroid(areas[0])
+	case "simplify":
+		return geomop.Simplify(areas[0], meters, opts)
+	default: // buffer
+		return geomop.Buffer(areas[0], meters, opts)
+	}
+}
+
+// cmdGEOMOP performs a geometry operation on stored objects or GeoJSON, and
+// returns the result or stores it like SET.
+//
+//	GEOMOP UNION|INTERSECTION|DIFFERENCE area area [STORE key id]
+//	GEOMOP HULL|CENTROID area [STORE key id]
+//	GEOMOP SIMPLIFY|BUFFER area meters [STORE key id]
+//
+// where area is (GET key id)|(OBJECT geojson).
+func (s *Server) cmdGEOMOP(msg *Message) (resp.Value, error) {
+	start := time.Now()
+
+	// >> Args
+
+	ga, err := parseGeomopArgs(msg.Args[1:])
+	if err != nil {
+		return retrerr(err)
+	}
+
+	// >> Operation
+
+	db := s.getDB(msg.DB)
+	var areas []geojson.Object
+	for _, area := range ga.areas {
+		if area.json != "" {
+			obj, err := geojson.Parse(area.json, &s.geomParseOpts)
+			if err != nil {
+				return retrerr(err)
+			}
+			areas = append(areas, obj)
+			continue
+		}
+		col, _ := db.cols.Get(area.key)
+		if col == nil {
+			return retrerr(errKeyNotFound)
+		}
+		o := col.Get(area.id)
+		if o == nil {
+			return retrerr(errIDNotFound)
+		}
+		areas = append(areas, o.Geo())
+	}
+	res, err := runGeomop(ga.op, areas, ga.meters, &s.geomIndexOpts)
+	if err != nil {
+		return retrerr(err)
+	}
+	if ga.storeKey != "" {
+		// the result is stored by a SET, which is what goes to the aof
+		nmsg := *msg
+		nmsg._command = ""
+		nmsg.Args = []string{"set", ga.storeKey, ga.storeID, "OBJECT",
+			res.JSON()}
+		v, d, err := s.cmdSET(&nmsg)
+		if err != nil {
+			return retrerr(err)
+		}
+		d.db = db
+		if err := s.writeAOF(nmsg.Args, &d); err != nil {
+			return retrerr(err)
+		}
+		return v, nil
+	}
+
+	// >> Response
+
+	if msg.OutputType == JSON {
+		return resp.StringValue(`{"ok":true,"object":` + res.JSON() +
+			`,"elapsed":"` + time.Since(start).String() + "\"}"), nil
+	}
+	return resp.StringValue(res.JSON()), nil
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+	"github.com/mmcloughlin/geohash"
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/bing"
+	"github.com/tidwall/tile38/internal/h3"
+)
+
+// parseGrid parses the arguments that follow the GRID keyword.
+//
+//	GRID HASH precision|QUADKEY level|TILE zoom|H3 resolution|S2 level
+//	  [COUNT|SUM field|AVG field|MIN field|MAX field|PERCENTILE p field ...]
+func parseGrid(vs []string) ([]string, *aggregator, error) {
+	var ok bool
+	var ssystem, slevel string
+	if vs, ssystem, ok = tokenval(vs); !ok || ssystem == "" {
+		return nil, nil, errInvalidNumberOfArguments
+	}
+	if vs, slevel, ok = tokenval(vs); !ok || slevel == "" {
+		return nil, nil, errInvalidNumberOfArguments
+	}
+	ag := &aggregator{
+		grid:   strings.ToLower(ssystem),
+		groups: make(map[string]*aggregateGroup),
+	}
+	switch ag.grid {
+	case "hash":
+		ag.level, ok = parseCellLevel(slevel, 12)
+		ok = ok && ag.level > 0
+	case "quadkey":
+		ag.level, ok = parseCellLevel(slevel, 23)
+		ok = ok && ag.level > 0
+	case "tile":
+		ag.level, ok = parseCellLevel(slevel, 23)
+	case "h3":
+		ag.level, ok = parseH3Resolution(slevel)
+	case "s2":
+		ag.level, ok = parseS2Level(slevel)
+	default:
+		return nil, nil, errInvalidArgument(ssystem)
+	}
+	if !ok {
+		return nil, nil, errInvalidArgument(slevel)
+	}
+	vs, err := ag.parseFuncs(vs, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return vs, ag, nil
+}
+
+// gridCellOf returns the cell of a grid system that contains the center of
+// an object. Tiles are returned as "z/x/y". Returns false for non-spatial
+// objects.
+func gridCellOf(o geojson.Object, system string, level int) (string, bool) {
+	if !objIsSpatial(o) || o.Empty() {
+		return "", false
+	}
+	p := o.Center()
+	switch system {
+	case "hash":
+		return geohash.EncodeWithPrecision(p.Y, p.X, uint(level)), true
+	case "quadkey", "tile":
+		px, py := bing.LatLongToPixelXY(p.Y, p.X, uint64(level))
+		tx, ty := bing.PixelXYToTileXY(px, py)
+		if system == "quadkey" {
+			return bing.TileXYToQuadKey(tx, ty, uint64(level)), true
+		}
+		return strconv.Itoa(level) + "/" + strconv.FormatInt(tx, 10) + "/" +
+			strconv.FormatInt(ty, 10), true
+	case "h3":
+		return h3.LatLngToCell(p.Y, p.X, level).String(), true
+	default:
+		ll := s2.LatLngFromDegrees(p.Y, p.X)
+		return s2.CellIDFromLatLng(ll).Parent(level).ToToken(), true
+	}
+}
+
+// writeGridJSON writes the cells as the "grid" member. Each cell has the
+// number of objects and, when there are aggregate functions, the aggregates.
+func (ag *aggregator) writeGridJSON(wr *bytes.Buffer) {
+	wr.WriteString(`,"grid":[`)
+	for i, g := range ag.sortedGroups() {
+		if i > 0 {
+			wr.WriteByte(',')
+		}
+		wr.WriteString(`{"cell":` + jsonString(g.key) + `,"count":` +
+			strconv.FormatUint(g.count, 10))
+		if len(ag.aggs) > 0 {
+			wr.WriteString(`,"aggregates":`)
+			ag.appendJSONResults(wr, g)
+		}
+		wr.WriteByte('}')
+	}
+	wr.WriteByte(']')
+}
+
+// gridRespValues returns the cells as a list of cell, count and aggregates.
+func (ag *aggregator) gridRespValues() []resp.Value {
+	var vals []resp.Value
+	for _, g := range ag.sortedGroups() {
+		cell := []resp.Value{
+			resp.StringValue(g.key),
+			resp.IntegerValue(int(g.count)),
+		}
+		if len(ag.aggs) > 0 {
+			cell = append(cell, ag.respResults(g))
+		}
+		vals = append(vals, resp.ArrayValue(cell))
+	}
+	return vals
+}
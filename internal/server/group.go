@@ -62,25 +62,25 @@ func newGroupItem(hookName, colKey, objID string) *groupItem {
 	return g
 }
 
-func (s *Server) groupConnect(hookName, colKey, objID string) (groupID string) {
+func (db *database) groupConnect(hookName, colKey, objID string) (groupID string) {
 	g := newGroupItem(hookName, colKey, objID)
-	s.groupHooks.Set(g)
-	s.groupObjects.Set(g)
+	db.groupHooks.Set(g)
+	db.groupObjects.Set(g)
 	return g.groupID
 }
 
-func (s *Server) groupDisconnect(hookName, colKey, objID string) {
+func (db *database) groupDisconnect(hookName, colKey, objID string) {
 	g := &groupItem{
 		hookName: hookName,
 		colKey:   colKey,
 		objID:    objID,
 	}
-	s.groupHooks.Delete(g)
-	s.groupObjects.Delete(g)
+	db.groupHooks.Delete(g)
+	db.groupObjects.Delete(g)
 }
 
-func (s *Server) groupGet(hookName, colKey, objID string) (groupID string) {
-	v := s.groupHooks.Get(&groupItem{
+func (db *database) groupGet(hookName, colKey, objID string) (groupID string) {
+	v := db.groupHooks.Get(&groupItem{
 		hookName: hookName,
 		colKey:   colKey,
 		objID:    objID,
@@ -91,19 +91,19 @@ func (s *Server) groupGet(hookName, colKey, objID string) (groupID string) {
 	return ""
 }
 
-func deleteGroups(s *Server, groups []*groupItem) {
+func deleteGroups(db *database, groups []*groupItem) {
 	var hhint btree.PathHint
 	var ohint btree.PathHint
 	for _, g := range groups {
-		s.groupHooks.DeleteHint(g, &hhint)
-		s.groupObjects.DeleteHint(g, &ohint)
+		db.groupHooks.DeleteHint(g, &hhint)
+		db.groupObjects.DeleteHint(g, &ohint)
 	}
 }
 
 // groupDisconnectObject disconnects all hooks from provide object
-func (s *Server) groupDisconnectObject(colKey, objID string) {
+func (db *database) groupDisconnectObject(colKey, objID string) {
 	var groups []*groupItem
-	s.groupObjects.Ascend(&groupItem{colKey: colKey, objID: objID},
+	db.groupObjects.Ascend(&groupItem{colKey: colKey, objID: objID},
 		func(v interface{}) bool {
 			g := v.(*groupItem)
 			if g.colKey != colKey || g.objID != objID {
@@ -113,14 +113,14 @@ func (s *Server) groupDisconnectObject(colKey, objID string) {
 			return true
 		},
 	)
-	deleteGroups(s, groups)
+	deleteGroups(db, groups)
 }
 
 // groupDisconnectCollection disconnects all hooks from objects in provided
 // collection.
-func (s *Server) groupDisconnectCollection(colKey string) {
+func (db *database) groupDisconnectCollection(colKey string) {
 	var groups []*groupItem
-	s.groupObjects.Ascend(&groupItem{colKey: colKey},
+	db.groupObjects.Ascend(&groupItem{colKey: colKey},
 		func(v interface{}) bool {
 			g := v.(*groupItem)
 			if g.colKey != colKey {
@@ -130,13 +130,13 @@ func (s *Server) groupDisconnectCollection(colKey string) {
 			return true
 		},
 	)
-	deleteGroups(s, groups)
+	deleteGroups(db, groups)
 }
 
 // groupDisconnectHook disconnects all objects from provided hook.
-func (s *Server) groupDisconnectHook(hookName string) {
+func (db *database) groupDisconnectHook(hookName string) {
 	var groups []*groupItem
-	s.groupHooks.Ascend(&groupItem{hookName: hookName},
+	db.groupHooks.Ascend(&groupItem{hookName: hookName},
 		func(v interface{}) bool {
 			g := v.(*groupItem)
 			if g.hookName != hookName {
@@ -146,5 +146,5 @@ func (s *Server) groupDisconnectHook(hookName string) {
 			return true
 		},
 	)
-	deleteGroups(s, groups)
+	deleteGroups(db, groups)
 }
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geometry"
+	"github.com/tidwall/tile38/internal/h3"
+)
+
+// parseH3Cell parses an H3 cell id.
+func parseH3Cell(s string) (h3.Cell, error) {
+	cell, ok := h3.ParseCell(s)
+	if !ok {
+		return 0, errInvalidArgument(s)
+	}
+	return cell, nil
+}
+
+// parseH3Resolution parses an H3 resolution, which is 0 to 15.
+func parseH3Resolution(s string) (int, bool) {
+	return parseCellLevel(s, h3.MaxResolution)
+}
+
+// h3CellPolygon returns the polygon of an H3 cell.
+func h3CellPolygon(cell h3.Cell, opts *geometry.IndexOptions) geojson.Object {
+	verts := cell.Boundary()
+	ring := make([]geometry.Point, 0, len(verts)+1)
+	for _, v := range verts {
+		ring = append(ring, geometry.Point{X: v.Lng, Y: v.Lat})
+	}
+	return cellPolygon(ring, opts)
+}
+
+// h3CellOf returns the H3 cell at a resolution for the center of an object.
+func h3CellOf(o geojson.Object, res int) h3.Cell {
+	center := o.Center()
+	return h3.LatLngToCell(center.Y, center.X, res)
+}
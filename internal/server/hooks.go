@@ -60,6 +60,11 @@ func (s *Server) cmdSetHook(msg *Message) (
 	var types map[string]bool
 	var expires float64
 	var expiresSet bool
+	var schedule *hookSchedule
+	var policy *hookPolicy
+	var template *hookTemplate
+	var batch *hookBatch
+	var sendOpts *endpoint.SendOptions
 	metaMap := make(map[string]string)
 	for {
 		commandvs = vs
@@ -93,6 +98,158 @@ func (s *Server) cmdSetHook(msg *Message) (
 			expires = v
 			expiresSet = true
 			continue
+		case "schedule":
+			var spec string
+			if vs, spec, ok = tokenval(vs); !ok || spec == "" {
+				return NOMessage, d, errInvalidNumberOfArguments
+			}
+			if schedule == nil {
+				schedule = &hookSchedule{}
+			}
+			if err := schedule.addSpec(spec); err != nil {
+				return NOMessage, d, err
+			}
+			continue
+		case "active":
+			var from, to string
+			if vs, from, ok = tokenval(vs); !ok || from == "" {
+				return NOMessage, d, errInvalidNumberOfArguments
+			}
+			if vs, to, ok = tokenval(vs); !ok || to == "" {
+				return NOMessage, d, errInvalidNumberOfArguments
+			}
+			if schedule == nil {
+				schedule = &hookSchedule{}
+			}
+			if err := schedule.setActive(from, to); err != nil {
+				return NOMessage, d, err
+			}
+			continue
+		case "template":
+			if template != nil {
+				return NOMessage, d, errDuplicateArgument(cmd)
+			}
+			var src string
+			if vs, src, ok = tokenval(vs); !ok || src == "" {
+				return NOMessage, d, errInvalidNumberOfArguments
+			}
+			if template, err = parseHookTemplate(src); err != nil {
+				return NOMessage, d, err
+			}
+			continue
+		case "batch":
+			if channel {
+				// channels publish each message as it happens
+				return NOMessage, d, errInvalidArgument(cmd)
+			}
+			if batch != nil {
+				return NOMessage, d, errDuplicateArgument(cmd)
+			}
+			var sevents, sbytes, slatency string
+			if vs, sevents, ok = tokenval(vs); !ok || sevents == "" {
+				return NOMessage, d, errInvalidNumberOfArguments
+			}
+			if vs, sbytes, ok = tokenval(vs); !ok || sbytes == "" {
+				return NOMessage, d, errInvalidNumberOfArguments
+			}
+			if vs, slatency, ok = tokenval(vs); !ok || slatency == "" {
+				return NOMessage, d, errInvalidNumberOfArguments
+			}
+			if batch, err = parseHookBatch(sevents, sbytes, slatency); err != nil {
+				return NOMessage, d, err
+			}
+			for _, url := range endpoints {
+				if !s.epc.Batchable(url) {
+					return NOMessage, d, errors.New(
+						"endpoint does not support batches: " + url)
+				}
+			}
+			continue
+		case "secret", "bearer", "header":
+			if channel {
+				// channels are not sent over http
+				return NOMessage, d, errInvalidArgument(cmd)
+			}
+			if sendOpts == nil {
+				sendOpts = &endpoint.SendOptions{}
+			}
+			var val string
+			if vs, val, ok = tokenval(vs); !ok || val == "" {
+				return NOMessage, d, errInvalidNumberOfArguments
+			}
+			switch cmdlc {
+			case "secret":
+				if sendOpts.Secret != "" {
+					return NOMessage, d, errDuplicateArgument(cmd)
+				}
+				sendOpts.Secret = val
+			case "bearer":
+				if sendOpts.Token != "" {
+					return NOMessage, d, errDuplicateArgument(cmd)
+				}
+				sendOpts.Token = val
+			case "header":
+				if !validHeaderName(val) {
+					return NOMessage, d, errInvalidArgument(val)
+				}
+				var hval string
+				if vs, hval, ok = tokenval(vs); !ok {
+					return NOMessage, d, errInvalidNumberOfArguments
+				}
+				if strings.ContainsAny(hval, "\r\n") {
+					return NOMessage, d, errInvalidArgument(hval)
+				}
+				sendOpts.Headers = append(sendOpts.Headers,
+					[2]string{val, hval})
+			}
+			for _, url := range endpoints {
+				if !s.epc.IsHTTP(url) {
+					return NOMessage, d, errors.New(
+						"endpoint is not http: " + url)
+				}
+			}
+			continue
+		case "retry", "backoff", "ttl", "deadletter":
+			if channel {
+				// channels do not queue their messages
+				return NOMessage, d, errInvalidArgument(cmd)
+			}
+			if policy == nil {
+				policy = newHookPolicy()
+			}
+			var s1, s2 string
+			if vs, s1, ok = tokenval(vs); !ok || s1 == "" {
+				return NOMessage, d, errInvalidNumberOfArguments
+			}
+			switch cmdlc {
+			case "retry":
+				n, err := strconv.Atoi(s1)
+				if err != nil || n < 1 {
+					return NOMessage, d, errInvalidArgument(s1)
+				}
+				policy.retries = n
+			case "backoff":
+				if vs, s2, ok = tokenval(vs); !ok || s2 == "" {
+					return NOMessage, d, errInvalidNumberOfArguments
+				}
+				if err := policy.setBackoff(s1, s2); err != nil {
+					return NOMessage, d, err
+				}
+			case "ttl":
+				ttl, err := parseSeconds(s1)
+				if err != nil {
+					return NOMessage, d, err
+				}
+				policy.ttl, policy.hasTTL = ttl, true
+			case "deadletter":
+				if vs, s2, ok = tokenval(vs); !ok || s2 == "" {
+					return NOMessage, d, errInvalidNumberOfArguments
+				}
+				if err := policy.setDeadLetter(s1, s2); err != nil {
+					return NOMessage, d, err
+				}
+			}
+			continue
 		case "nearby":
 			types = nearbyTypes
 		case "within", "intersects":
@@ -100,7 +257,12 @@ func (s *Server) cmdSetHook(msg *Message) (
 		}
 		break
 	}
-	args, err := s.cmdSearchArgs(true, cmdlc, vs, types)
+	if batch != nil && template != nil && !template.rendersJSON() {
+		// the payloads of a batch are the elements of a JSON array
+		return NOMessage, d, errors.New("template of a batch is not json")
+	}
+	db := s.selectDB(msg.DB)
+	args, err := s.cmdSearchArgs(true, db, cmdlc, vs, types)
 	if args.usingLua() {
 		defer args.Close()
 	}
@@ -124,14 +286,21 @@ func (s *Server) cmdSetHook(msg *Message) (
 	hook := &Hook{
 		Key:       args.key,
 		Name:      name,
+		dbname:    db.name,
 		Endpoints: endpoints,
 		Fence:     &args,
 		Message:   cmsg,
 		epm:       s.epc,
 		Metas:     metas,
+		schedule:  schedule,
+		policy:    policy,
+		template:  template,
+		batch:     batch,
+		sendOpts:  sendOpts,
 		channel:   channel,
 		cond:      sync.NewCond(&sync.Mutex{}),
 		counter:   &s.statsTotalMsgsSent,
+		queue:     &s.qpending,
 	}
 	if expiresSet {
 		hook.expires =
@@ -139,17 +308,15 @@ func (s *Server) cmdSetHook(msg *Message) (
 	}
 	if !channel {
 		hook.db = s.qdb
+		hook.stats = newHookStats()
 	}
 	var wr bytes.Buffer
-	hook.ScanWriter, err = s.newScanWriter(
-		&wr, cmsg, args.key, args.output, args.precision, args.globs, false,
-		args.cursor, args.limit, args.wheres, args.whereins, args.whereevals,
-		args.nofields, args.mvt, args.tileX, args.tileY, args.tileZ)
+	hook.ScanWriter, err = s.newScanWriter(&wr, cmsg, args.searchScanBaseTokens, false)
 	if err != nil {
 
 		return NOMessage, d, err
 	}
-	prevHook, _ := s.hooks.Get(&Hook{Name: name}).(*Hook)
+	prevHook, _ := db.hooks.Get(&Hook{Name: name}).(*Hook)
 	if prevHook != nil {
 		if prevHook.channel != channel {
 			return NOMessage, d,
@@ -170,31 +337,32 @@ func (s *Server) cmdSetHook(msg *Message) (
 			}
 		}
 		prevHook.Close()
-		s.hooks.Delete(prevHook)
-		s.hooksOut.Delete(prevHook)
+		db.hooks.Delete(prevHook)
+		db.hooksOut.Delete(prevHook)
 		if !prevHook.expires.IsZero() {
 			s.hookExpires.Delete(prevHook)
 		}
-		s.groupDisconnectHook(name)
+		db.groupDisconnectHook(name)
+		s.dwellDisconnect(db.name, name)
 	}
 
 	d.updated = true
 	d.timestamp = time.Now()
 
-	s.hooks.Set(hook)
+	db.hooks.Set(hook)
 	if hook.Fence.detect == nil || hook.Fence.detect["outside"] {
-		s.hooksOut.Set(hook)
+		db.hooksOut.Set(hook)
 	}
 
 	// remove previous hook from spatial index
 	if prevHook != nil && prevHook.Fence != nil && prevHook.Fence.obj != nil {
 		rect := prevHook.Fence.obj.Rect()
-		s.hookTree.Delete(
+		db.hookTree.Delete(
 			[2]float64{rect.Min.X, rect.Min.Y},
 			[2]float64{rect.Max.X, rect.Max.Y},
 			prevHook)
 		if prevHook.Fence.detect["cross"] {
-			s.hookCross.Delete(
+			db.hookCross.Delete(
 				[2]float64{rect.Min.X, rect.Min.Y},
 				[2]float64{rect.Max.X, rect.Max.Y},
 				prevHook)
@@ -203,12 +371,12 @@ func (s *Server) cmdSetHook(msg *Message) (
 	// add hook to spatial index
 	if hook != nil && hook.Fence != nil && hook.Fence.obj != nil {
 		rect := hook.Fence.obj.Rect()
-		s.hookTree.Insert(
+		db.hookTree.Insert(
 			[2]float64{rect.Min.X, rect.Min.Y},
 			[2]float64{rect.Max.X, rect.Max.Y},
 			hook)
 		if hook.Fence.detect["cross"] {
-			s.hookCross.Insert(
+			db.hookCross.Insert(
 				[2]float64{rect.Min.X, rect.Min.Y},
 				[2]float64{rect.Max.X, rect.Max.Y},
 				hook)
@@ -237,32 +405,39 @@ func byHookExpires(a, b interface{}) bool {
 	if ha.expires.After(hb.expires) {
 		return false
 	}
-	return ha.Name < hb.Name
+	if ha.Name < hb.Name {
+		return true
+	}
+	if ha.Name > hb.Name {
+		return false
+	}
+	return ha.dbname < hb.dbname
 }
 
-func (s *Server) cmdDELHOOKop(name string, channel bool) (updated bool) {
-	hook, _ := s.hooks.Get(&Hook{Name: name}).(*Hook)
+func (s *Server) cmdDELHOOKop(db *database, name string, channel bool) (updated bool) {
+	hook, _ := db.hooks.Get(&Hook{Name: name}).(*Hook)
 	if hook == nil || hook.channel != channel {
 		return false
 	}
 	hook.Close()
 	// remove hook from maps
-	s.hooks.Delete(hook)
-	s.hooksOut.Delete(hook)
+	db.hooks.Delete(hook)
+	db.hooksOut.Delete(hook)
 	if !hook.expires.IsZero() {
 		s.hookExpires.Delete(hook)
 	}
 	// remove any hook / object connections
-	s.groupDisconnectHook(hook.Name)
+	db.groupDisconnectHook(hook.Name)
+	s.dwellDisconnect(db.name, hook.Name)
 	// remove hook from spatial index
 	if hook.Fence != nil && hook.Fence.obj != nil {
 		rect := hook.Fence.obj.Rect()
-		s.hookTree.Delete(
+		db.hookTree.Delete(
 			[2]float64{rect.Min.X, rect.Min.Y},
 			[2]float64{rect.Max.X, rect.Max.Y},
 			hook)
 		if hook.Fence.detect["cross"] {
-			s.hookCross.Delete(
+			db.hookCross.Delete(
 				[2]float64{rect.Min.X, rect.Min.Y},
 				[2]float64{rect.Max.X, rect.Max.Y},
 				hook)
@@ -287,7 +462,7 @@ func (s *Server) cmdDelHook(msg *Message) (
 		return NOMessage, d, errInvalidNumberOfArguments
 	}
 
-	d.updated = s.cmdDELHOOKop(name, channel)
+	d.updated = s.cmdDELHOOKop(s.getDB(msg.DB), name, channel)
 	d.timestamp = time.Now()
 
 	switch msg.OutputType {
@@ -320,7 +495,8 @@ func (s *Server) cmdPDelHook(msg *Message) (
 
 	count := 0
 	var hooks []*Hook
-	s.forEachHookByPattern(pattern, channel, func(hook *Hook) bool {
+	db := s.getDB(msg.DB)
+	s.forEachHookByPattern(db, pattern, channel, func(hook *Hook) bool {
 		hooks = append(hooks, hook)
 		return true
 	})
@@ -329,7 +505,7 @@ func (s *Server) cmdPDelHook(msg *Message) (
 		if hook.channel != channel {
 			continue
 		}
-		s.cmdDELHOOKop(hook.Name, channel)
+		s.cmdDELHOOKop(db, hook.Name, channel)
 		d.updated = true
 		count++
 	}
@@ -345,11 +521,11 @@ func (s *Server) cmdPDelHook(msg *Message) (
 }
 
 func (s *Server) forEachHookByPattern(
-	pattern string, channel bool, iter func(hook *Hook) bool,
+	db *database, pattern string, channel bool, iter func(hook *Hook) bool,
 ) {
 	g := glob.Parse(pattern, false)
 	hasUpperLimit := g.Limits[1] != ""
-	s.hooks.Ascend(&Hook{Name: g.Limits[0]}, func(v interface{}) bool {
+	db.hooks.Ascend(&Hook{Name: g.Limits[0]}, func(v interface{}) bool {
 		hook := v.(*Hook)
 		if hasUpperLimit && hook.Name > g.Limits[1] {
 			return false
@@ -380,6 +556,7 @@ func (s *Server) cmdHooks(msg *Message) (
 	if len(vs) != 0 {
 		return NOMessage, errInvalidNumberOfArguments
 	}
+	db := s.getDB(msg.DB)
 
 	switch msg.OutputType {
 	case JSON:
@@ -391,7 +568,7 @@ func (s *Server) cmdHooks(msg *Message) (
 			buf.WriteString(`"hooks":[`)
 		}
 		var i int
-		s.forEachHookByPattern(pattern, channel, func(hook *Hook) bool {
+		s.forEachHookByPattern(db, pattern, channel, func(hook *Hook) bool {
 			var ttl = -1
 			if !hook.expires.IsZero() {
 				ttl = int(hook.expires.Sub(start).Seconds())
@@ -432,7 +609,27 @@ func (s *Server) cmdHooks(msg *Message) (
 				buf.WriteString(`:`)
 				buf.WriteString(jsonString(meta.Value))
 			}
-			buf.WriteString(`}}`)
+			buf.WriteString(`}`)
+			buf.Write(hook.schedule.appendJSON(nil, start))
+			if hook.policy != nil {
+				buf.Write(hook.policy.appendJSON(nil))
+			}
+			if hook.template != nil {
+				buf.WriteString(`,"template":` + jsonString(hook.template.src))
+			}
+			if hook.batch != nil {
+				buf.WriteString(`,"batch":{"events":` +
+					strconv.Itoa(hook.batch.events) +
+					`,"bytes":` + strconv.Itoa(hook.batch.bytes) +
+					`,"latency":` + formatSeconds(hook.batch.latency) + `}`)
+			}
+			if hook.sendOpts != nil {
+				buf.Write(appendSendOptionsJSON(nil, hook.sendOpts))
+			}
+			if !channel {
+				s.appendHookStats(buf, hook)
+			}
+			buf.WriteString(`}`)
 			i++
 			return true
 		})
@@ -441,7 +638,7 @@ func (s *Server) cmdHooks(msg *Message) (
 		return resp.StringValue(buf.String()), nil
 	case RESP:
 		var vals []resp.Value
-		s.forEachHookByPattern(pattern, channel, func(hook *Hook) bool {
+		s.forEachHookByPattern(db, pattern, channel, func(hook *Hook) bool {
 			var hvals []resp.Value
 			hvals = append(hvals, resp.StringValue(hook.Name))
 			hvals = append(hvals, resp.StringValue(hook.Key))
@@ -461,6 +658,13 @@ func (s *Server) cmdHooks(msg *Message) (
 				metas = append(metas, resp.StringValue(meta.Value))
 			}
 			hvals = append(hvals, resp.ArrayValue(metas))
+			// a hook without a schedule has an empty one and is active
+			svals := []resp.Value{}
+			for _, arg := range hook.schedule.args() {
+				svals = append(svals, resp.StringValue(arg))
+			}
+			hvals = append(hvals, resp.ArrayValue(svals),
+				resp.StringValue(hook.schedule.state(start)))
 			vals = append(vals, resp.ArrayValue(hvals))
 			return true
 		})
@@ -479,6 +683,13 @@ type Hook struct {
 	Fence      *liveFenceSwitches
 	ScanWriter *scanWriter
 	Metas      []FenceMeta
+	schedule   *hookSchedule // when the hook is active, if not always
+	policy     *hookPolicy   // how failed sends are retried, if set
+	template   *hookTemplate // the payload of the messages, if not as is
+	batch      *hookBatch    // how messages are sent together, if they are
+	attempts   int           // failed sends of the event at failKey
+	failKey    string        // queue key of the event that failed last
+	dbname     string        // name of the database of the hook
 	db         *buntdb.DB
 	channel    bool
 	closed     bool
@@ -487,7 +698,12 @@ type Hook struct {
 	epm        *endpoint.Manager
 	expires    time.Time
 	counter    *atomic.Int64 // counter that grows when a message was sent
+	stats      *hookStats    // delivery statistics, for hooks only
+	queue      *hookQueue    // the pending events of the hooks
 	sig        int
+	holding    bool // a signal is pending for a held batch
+	// the secret and the headers of the http requests, if any
+	sendOpts *endpoint.SendOptions
 }
 
 // Expires returns when the hook expires. Required by the expire.Item interface.
@@ -506,6 +722,17 @@ func (h *Hook) Equals(hook *Hook) bool {
 	if !h.expires.Equal(hook.expires) {
 		return false
 	}
+	if !h.schedule.equals(hook.schedule) || !h.policy.equals(hook.policy) {
+		return false
+	}
+	if (h.template == nil) != (hook.template == nil) ||
+		(h.template != nil && h.template.src != hook.template.src) {
+		return false
+	}
+	if !h.batch.equals(hook.batch) ||
+		!sendOptionsEqual(h.sendOpts, hook.sendOpts) {
+		return false
+	}
 	for i, endpoint := range h.Endpoints {
 		if endpoint != hook.Endpoints[i] {
 			return false
@@ -612,7 +839,7 @@ func (h *Hook) manager() {
 			return h.proc()
 		}() {
 			// a send failed, try again in a moment
-			time.Sleep(time.Second / 2)
+			time.Sleep(h.retryDelay())
 			continue
 		}
 		if sig != h.sig {
@@ -628,16 +855,23 @@ func (h *Hook) manager() {
 // returning true will indicate that all log entries have been
 // successfully handled.
 func (h *Hook) proc() (ok bool) {
-	var keys, vals []string
+	var keys, vals, payloads []string
 	var ttls []time.Duration
+	var hold time.Duration
 	start := time.Now()
 	err := h.db.Update(func(tx *buntdb.Tx) error {
 		// get keys and vals
 		err := tx.AscendGreaterOrEqual("hooks",
 			h.query, func(key, val string) bool {
 				if strings.HasPrefix(key, hookLogPrefix) {
-					// Verify this hooks name matches the one in the notif
-					if h.Name == gjson.Get(val, "hook").String() {
+					// Verify this hooks name and database matches the
+					// one in the notif
+					db := gjson.Get(val, "db").String()
+					if db == "" {
+						db = defaultDB
+					}
+					if h.Name == gjson.Get(val, "hook").String() &&
+						h.dbname == db {
 						keys = append(keys, key)
 						vals = append(vals, val)
 					}
@@ -649,8 +883,7 @@ func (h *Hook) proc() (ok bool) {
 			return err
 		}
 
-		// delete the keys
-		for _, key := range keys {
+		for i, key := range keys {
 			ttl, err := tx.TTL(key)
 			if err != nil {
 				if err != buntdb.ErrNotFound {
@@ -658,12 +891,23 @@ func (h *Hook) proc() (ok bool) {
 				}
 			}
 			ttls = append(ttls, ttl)
-			_, err = tx.Delete(key)
+			payloads = append(payloads, h.payload(vals[i]))
+		}
+		if hold = h.holdBatch(payloads, ttls); hold > 0 {
+			// wait for a full batch
+			return nil
+		}
+
+		// delete the keys
+		for i, key := range keys {
+			_, err := tx.Delete(key)
 			if err != nil {
 				if err != buntdb.ErrNotFound {
 					return err
 				}
 			}
+			// an expired key is deleted too
+			h.queue.add(vals[i], -1)
 		}
 		return nil
 	})
@@ -671,49 +915,119 @@ func (h *Hook) proc() (ok bool) {
 		log.Error(err)
 		return false
 	}
+	if hold > 0 {
+		h.holdFor(hold)
+		return true
+	}
 
-	// send each val. on failure reinsert that one and all of the following
-	for i, key := range keys {
-		val := vals[i]
+	// send each val, or each batch of vals. on failure reinsert that one and
+	// all of the following
+	for i := 0; i < len(keys); {
+		key := keys[i]
+		n, payload := 1, payloads[i]
+		if h.batch != nil {
+			n = h.batch.next(payloads[i:])
+			payload = string(appendBatch(nil, payloads[i:i+n]))
+		}
 		idx := stringToUint64(key[len(hookLogPrefix):])
+		if h.failKey == key {
+			h.stats.add(&h.stats.retried, n)
+		}
 		var sent bool
+		var lastErr error
 		for _, endpoint := range h.Endpoints {
-			err := h.epm.Send(endpoint, val)
+			err := h.epm.SendWith(endpoint, payload, h.sendOpts)
 			if err != nil {
 				log.Debugf("Endpoint connect/send error: %v: %v: %v",
 					idx, endpoint, err)
+				h.stats.sendFailed(endpoint, err)
+				lastErr = err
 				continue
 			}
 			log.Debugf("Endpoint send ok: %v: %v: %v", idx, endpoint, err)
 			sent = true
-			h.counter.Add(1)
+			h.counter.Add(int64(n))
+			h.stats.sent(endpoint, h.latencies(ttls[i:i+n], start))
 			break
 		}
-		if !sent {
-			// failed to send. try to reinsert the remaining.
-			// if this fails we lose log entries.
-			keys = keys[i:]
-			vals = vals[i:]
-			ttls = ttls[i:]
-			h.db.Update(func(tx *buntdb.Tx) error {
-				for i, key := range keys {
-					val := vals[i]
-					ttl := ttls[i] - time.Since(start)
-					if ttl > 0 {
-						opts := &buntdb.SetOptions{
-							Expires: true,
-							TTL:     ttl,
-						}
-						_, _, err := tx.Set(key, val, opts)
-						if err != nil {
-							return err
-						}
+		if sent {
+			if h.failKey == key {
+				h.failKey, h.attempts = "", 0
+			}
+			i += n
+			continue
+		}
+		h.stats.add(&h.stats.failed, n)
+		if h.failKey != key {
+			h.failKey, h.attempts = key, 0
+		}
+		h.attempts++
+		if h.policy != nil &&
+			h.policy.givesUp(h.attempts, ttls[i]-time.Since(start)) {
+			if ttls[i]-time.Since(start) <= deadLetterGrace {
+				h.stats.add(&h.stats.dropped, n)
+			}
+			h.stats.add(&h.stats.dead, n)
+			// give up on these and keep them as dead letters
+			err := h.db.Update(func(tx *buntdb.Tx) error {
+				for j := i; j < i+n; j++ {
+					if err := h.deadLetter(tx, keys[j], vals[j], h.attempts,
+						lastErr); err != nil {
+						return err
 					}
 				}
 				return nil
 			})
-			return false
+			if err != nil {
+				log.Error(err)
+			}
+			h.failKey, h.attempts = "", 0
+			i += n
+			continue
 		}
+		// failed to send. try to reinsert the remaining.
+		// if this fails we lose log entries.
+		keys = keys[i:]
+		vals = vals[i:]
+		ttls = ttls[i:]
+		h.db.Update(func(tx *buntdb.Tx) error {
+			for i, key := range keys {
+				val := vals[i]
+				ttl := ttls[i] - time.Since(start)
+				if h.policy != nil && ttl <= deadLetterGrace {
+					// expired while waiting on the ones before it
+					h.stats.add(&h.stats.dropped, 1)
+					h.stats.add(&h.stats.dead, 1)
+					attempts := 0
+					if key == h.failKey {
+						attempts = h.attempts
+					}
+					if err := h.deadLetter(tx, key, val, attempts,
+						errors.New("expired")); err != nil {
+						return err
+					}
+					continue
+				}
+				if h.policy == nil && ttl <= h.retryDelay() {
+					// expires before the next attempt
+					h.stats.add(&h.stats.dropped, 1)
+					continue
+				}
+				if ttl > 0 {
+					opts := &buntdb.SetOptions{
+						Expires: true,
+						TTL:     ttl,
+					}
+					_, _, err := tx.Set(key, val, opts)
+					if err != nil {
+						return err
+					}
+					h.queue.add(val, 1)
+				}
+			}
+			return nil
+		})
+		return false
 	}
 	return true
 }
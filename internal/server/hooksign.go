@@ -0,0 +1,70 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/tidwall/tile38/internal/endpoint"
+)
+
+// validHeaderName returns true when the name is an HTTP header token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) != -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// sendOptionsArgs returns the options in the same form as the SETHOOK
+// arguments. These are what the aof keeps and what the followers get, so the
+// SECRET, BEARER and HEADER values are stored in plaintext in the aof and are
+// sent to the followers as they are.
+func sendOptionsArgs(opts *endpoint.SendOptions) []string {
+	if opts == nil {
+		return nil
+	}
+	var args []string
+	if opts.Secret != "" {
+		args = append(args, "secret", opts.Secret)
+	}
+	if opts.Token != "" {
+		args = append(args, "bearer", opts.Token)
+	}
+	for _, header := range opts.Headers {
+		args = append(args, "header", header[0], header[1])
+	}
+	return args
+}
+
+func sendOptionsEqual(a, b *endpoint.SendOptions) bool {
+	return strings.Join(sendOptionsArgs(a), "\x00") ==
+		strings.Join(sendOptionsArgs(b), "\x00")
+}
+
+// appendSendOptionsJSON appends the options to the HOOKS output, without the
+// secret, the token and the values of the headers, which may be credentials
+// too.
+func appendSendOptionsJSON(dst []byte, opts *endpoint.SendOptions) []byte {
+	if opts.Secret != "" {
+		dst = append(dst, `,"signed":true`...)
+	}
+	if opts.Token != "" {
+		dst = append(dst, `,"bearer":true`...)
+	}
+	if len(opts.Headers) > 0 {
+		dst = append(dst, `,"headers":[`...)
+		for i, header := range opts.Headers {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONString(dst, header[0])
+		}
+		dst = append(dst, ']')
+	}
+	return dst
+}
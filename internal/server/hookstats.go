@@ -0,0 +1,307 @@
+package server
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/buntdb"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/resp"
+)
+
+// hookLatencyBuckets are the upper bounds, in seconds, of the delivery
+// latency histogram of a hook.
+var hookLatencyBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60,
+}
+
+// hookStats are the delivery statistics of a hook, since it was created.
+type hookStats struct {
+	mu         sync.Mutex
+	queued     int64 // events put in the queue
+	delivered  int64 // events sent to an endpoint
+	failed     int64 // events that failed to send, for every attempt
+	retried    int64 // events that were sent again after a failure
+	dropped    int64 // events that expired before they were sent
+	dead       int64 // events that became dead letters
+	lastErr    string
+	lastErrAt  time.Time
+	latency    []uint64 // events per bucket, the last one is +Inf
+	latencySum float64
+	endpoints  map[string]*endpointStats
+}
+
+// endpointStats are the delivery statistics of one endpoint of a hook.
+type endpointStats struct {
+	delivered int64
+	failed    int64
+	lastErr   string
+	lastErrAt time.Time
+}
+
+func newHookStats() *hookStats {
+	return &hookStats{
+		latency:   make([]uint64, len(hookLatencyBuckets)+1),
+		endpoints: make(map[string]*endpointStats),
+	}
+}
+
+func (hs *hookStats) endpoint(endpoint string) *endpointStats {
+	es := hs.endpoints[endpoint]
+	if es == nil {
+		es = &endpointStats{}
+		hs.endpoints[endpoint] = es
+	}
+	return es
+}
+
+func (hs *hookStats) add(n *int64, events int) {
+	hs.mu.Lock()
+	*n += int64(events)
+	hs.mu.Unlock()
+}
+
+// sent records the events that an endpoint received, with how long each one
+// was in the queue.
+func (hs *hookStats) sent(endpoint string, latencies []time.Duration) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.delivered += int64(len(latencies))
+	hs.endpoint(endpoint).delivered += int64(len(latencies))
+	for _, latency := range latencies {
+		secs := latency.Seconds()
+		i := 0
+		for i < len(hookLatencyBuckets) && secs > hookLatencyBuckets[i] {
+			i++
+		}
+		hs.latency[i]++
+		hs.latencySum += secs
+	}
+}
+
+// sendFailed records an endpoint that failed to receive the events.
+func (hs *hookStats) sendFailed(endpoint string, err error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	es := hs.endpoint(endpoint)
+	es.failed++
+	es.lastErr, es.lastErrAt = err.Error(), time.Now()
+	hs.lastErr, hs.lastErrAt = es.lastErr, es.lastErrAt
+}
+
+// histogram returns the latency histogram as the events per upper bound,
+// counted cumulatively, the total of the events and the sum of the latencies.
+func (hs *hookStats) histogram() (map[float64]uint64, uint64, float64) {
+	buckets := make(map[float64]uint64, len(hookLatencyBuckets))
+	var count uint64
+	for i, n := range hs.latency {
+		count += n
+		if i < len(hookLatencyBuckets) {
+			buckets[hookLatencyBuckets[i]] = count
+		}
+	}
+	return buckets, count, hs.latencySum
+}
+
+func appendJSONErrorTime(dst []byte, lastErr string, at time.Time) []byte {
+	dst = append(dst, `,"last_error":`...)
+	dst = appendJSONString(dst, lastErr)
+	dst = append(dst, `,"last_error_time":`...)
+	if at.IsZero() {
+		return append(dst, "null"...)
+	}
+	return appendJSONTimeFormat(dst, at)
+}
+
+// appendJSON appends the statistics as the members of a JSON object. The
+// pending events are the ones that are in the queue now.
+func (hs *hookStats) appendJSON(dst []byte, endpoints []string,
+	pending int,
+) []byte {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	for _, kv := range []struct {
+		key string
+		val int64
+	}{
+		{"queued", hs.queued}, {"delivered", hs.delivered},
+		{"failed", hs.failed}, {"retried", hs.retried},
+		{"dropped", hs.dropped}, {"dead_letters", hs.dead},
+		{"pending", int64(pending)},
+	} {
+		if kv.key != "queued" {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, '"')
+		dst = append(dst, kv.key...)
+		dst = append(dst, `":`...)
+		dst = strconv.AppendInt(dst, kv.val, 10)
+	}
+	dst = appendJSONErrorTime(dst, hs.lastErr, hs.lastErrAt)
+	buckets, count, sum := hs.histogram()
+	dst = append(dst, `,"latency":{"count":`...)
+	dst = strconv.AppendUint(dst, count, 10)
+	dst = append(dst, `,"sum":`...)
+	dst = strconv.AppendFloat(dst, sum, 'f', -1, 64)
+	dst = append(dst, `,"buckets":{`...)
+	for i, le := range hookLatencyBuckets {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendJSONString(dst, strconv.FormatFloat(le, 'f', -1, 64))
+		dst = append(dst, ':')
+		dst = strconv.AppendUint(dst, buckets[le], 10)
+	}
+	dst = append(dst, `}},"endpoints":[`...)
+	for i, endpoint := range endpoints {
+		es := hs.endpoints[endpoint]
+		if es == nil {
+			es = &endpointStats{}
+		}
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `{"endpoint":`...)
+		dst = appendJSONString(dst, endpoint)
+		dst = append(dst, `,"delivered":`...)
+		dst = strconv.AppendInt(dst, es.delivered, 10)
+		dst = append(dst, `,"failed":`...)
+		dst = strconv.AppendInt(dst, es.failed, 10)
+		dst = appendJSONErrorTime(dst, es.lastErr, es.lastErrAt)
+		dst = append(dst, '}')
+	}
+	return append(dst, ']')
+}
+
+// hookQueue is the number of events in the queue of each hook, by database
+// and name. It's updated as the events are queued and deleted, so the stats
+// don't scan the queue.
+type hookQueue struct {
+	mu      sync.Mutex
+	pending map[[2]string]int
+}
+
+// add adds delta to the pending events of the hook of a queued event.
+func (q *hookQueue) add(val string, delta int) {
+	db := gjson.Get(val, "db").String()
+	if db == "" {
+		db = defaultDB
+	}
+	key := [2]string{db, gjson.Get(val, "hook").String()}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending == nil {
+		q.pending = make(map[[2]string]int)
+	}
+	if q.pending[key] += delta; q.pending[key] <= 0 {
+		delete(q.pending, key)
+	}
+}
+
+// count returns the number of events in the queue of a hook.
+func (q *hookQueue) count(db, name string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending[[2]string{db, name}]
+}
+
+// onQueueExpired deletes an expired item of the queue database, and counts
+// the events that expire in the queue of a hook.
+func (s *Server) onQueueExpired(key, val string, tx *buntdb.Tx) error {
+	if _, err := tx.Delete(key); err != nil && err != buntdb.ErrNotFound {
+		return err
+	}
+	if strings.HasPrefix(key, hookLogPrefix) {
+		s.qpending.add(val, -1)
+	}
+	return nil
+}
+
+// HOOKSTATS pattern
+func (s *Server) cmdHOOKSTATS(msg *Message) (resp.Value, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) != 2 {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	pattern := args[1]
+
+	// >> Operation
+
+	db := s.getDB(msg.DB)
+	var hooks []*Hook
+	s.forEachHookByPattern(db, pattern, false, func(hook *Hook) bool {
+		hooks = append(hooks, hook)
+		return true
+	})
+
+	// >> Response
+
+	var buf []byte
+	buf = append(buf, `{"ok":true,"hooks":[`...)
+	for i, hook := range hooks {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"name":`...)
+		buf = appendJSONString(buf, hook.Name)
+		buf = append(buf, ',')
+		buf = hook.stats.appendJSON(buf, hook.Endpoints,
+			s.qpending.count(db.name, hook.Name))
+		buf = append(buf, '}')
+	}
+	buf = append(buf, `],"elapsed":"`+time.Since(start).String()+`"}`...)
+	if msg.OutputType == JSON {
+		return resp.StringValue(string(buf)), nil
+	}
+	// each hook is its name and the flat pairs of the statistics
+	var vals []resp.Value
+	gjson.GetBytes(buf, "hooks").ForEach(func(_, hook gjson.Result) bool {
+		var pairs []resp.Value
+		hook.ForEach(func(key, val gjson.Result) bool {
+			if key.String() == "name" {
+				return true
+			}
+			pairs = append(pairs, resp.StringValue(key.String()))
+			if val.IsObject() || val.IsArray() {
+				pairs = append(pairs, resp.StringValue(val.Raw))
+			} else {
+				pairs = append(pairs, resp.StringValue(val.String()))
+			}
+			return true
+		})
+		vals = append(vals, resp.ArrayValue([]resp.Value{
+			resp.StringValue(hook.Get("name").String()),
+			resp.ArrayValue(pairs),
+		}))
+		return true
+	})
+	return resp.ArrayValue(vals), nil
+}
+
+// appendHookStats appends the statistics of a hook to its HOOKS output.
+func (s *Server) appendHookStats(buf *bytes.Buffer, hook *Hook) {
+	buf.WriteString(`,"stats":{`)
+	buf.Write(hook.stats.appendJSON(nil, hook.Endpoints,
+		s.qpending.count(hook.dbname, hook.Name)))
+	buf.WriteString(`}`)
+}
+
+// latencies returns how long each of the events with the ttls has been in
+// the queue.
+func (h *Hook) latencies(ttls []time.Duration, start time.Time,
+) []time.Duration {
+	queueTTL := h.queueOptions().TTL
+	since := time.Since(start)
+	latencies := make([]time.Duration, len(ttls))
+	for i, ttl := range ttls {
+		latencies[i] = queueTTL - ttl + since
+	}
+	return latencies
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestHookStats(t *testing.T) {
+	hs := newHookStats()
+	hs.sent("http://a", []time.Duration{time.Millisecond, 2 * time.Second})
+	hs.sent("http://a", []time.Duration{2 * time.Minute})
+	hs.sendFailed("http://b", errors.New("refused"))
+	buckets, count, sum := hs.histogram()
+	if count != 3 || sum != 122.001 {
+		t.Fatalf("expected 3 events in 122.001s, got %d in %v", count, sum)
+	}
+	if buckets[0.005] != 1 || buckets[2.5] != 2 || buckets[60] != 2 {
+		t.Fatalf("unexpected buckets %v", buckets)
+	}
+	out := "{" + string(hs.appendJSON(nil, []string{"http://a", "http://b"}, 4)) + "}"
+	if !gjson.Valid(out) {
+		t.Fatalf("invalid json %s", out)
+	}
+	for path, want := range map[string]string{
+		"delivered":                   "3",
+		"pending":                     "4",
+		"last_error":                  "refused",
+		"latency.buckets.2\\.5":       "2",
+		"endpoints.0.delivered":       "3",
+		"endpoints.1.failed":          "1",
+		"endpoints.0.last_error_time": "",
+		"endpoints.1.last_error":      "refused",
+	} {
+		if got := gjson.Get(out, path).String(); got != want {
+			t.Fatalf("%s: expected '%s', got '%s'", path, want, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tidwall/resp"
+)
+
+// SETINDEX key 2D|3D
+func (s *Server) cmdSETINDEX(msg *Message) (resp.Value, commandDetails, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) != 3 {
+		return retwerr(errInvalidNumberOfArguments)
+	}
+	key := args[1]
+	var on bool
+	switch strings.ToLower(args[2]) {
+	case "2d":
+	case "3d":
+		on = true
+	default:
+		return retwerr(errInvalidArgument(args[2]))
+	}
+
+	// >> Operation
+
+	db := s.selectDB(msg.DB)
+	updated := db.index3d.Contains(key) != on
+	if on {
+		db.index3d.Insert(key)
+	} else {
+		db.index3d.Delete(key)
+	}
+	if col, _ := db.cols.Get(key); col != nil {
+		col.SetIndex3D(on)
+	}
+
+	// >> Response
+
+	var d commandDetails
+	d.command = "setindex"
+	d.key = key
+	d.updated = updated
+	d.timestamp = time.Now()
+
+	return OKMessage(msg, start), d, nil
+}
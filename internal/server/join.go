@@ -0,0 +1,304 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/btree"
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/collection"
+	"github.com/tidwall/tile38/internal/deadline"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// joinMatch is an object from the first key and the ids of the objects from
+// the second key that it matches.
+type joinMatch struct {
+	id      string
+	matches []string
+}
+
+// joinPairs returns the ids of the objects in colB that match each object in
+// colA, by the id of the object. For "intersects" the object intersects the
+// match, for "within" the object is within the match, and for "nearby" the
+// match is within meters of the center of the object. When self is true both
+// are the same collection and an object is not matched with itself. The
+// candidate pairs come from a synchronized traversal of the spatial indexes
+// of both collections.
+//
+// Only the objects of colA with an id from the first one onward, and that
+// pass the filter, are matched. When limit is not zero only the objects with
+// the limit lowest ids are kept, so that a page doesn't hold all of the pairs
+// of the collection.
+func joinPairs(colA, colB *collection.Collection, rel string, meters float64,
+	self bool, first string, limit uint64,
+	filter func(o *object.Object) (bool, error), dl *deadline.Deadline,
+) (*btree.Map[string, []string], error) {
+	var pairs btree.Map[string, []string]
+	tested := make(map[*object.Object]bool)
+	circles := make(map[*object.Object]geojson.Object)
+	if rel != "nearby" {
+		meters = 0
+	}
+	var ferr error
+	colA.Join(colB, meters, dl, func(a, b *object.Object) bool {
+		if self && a.ID() == b.ID() {
+			return true
+		}
+		if a.ID() < first {
+			return true
+		}
+		if limit > 0 && uint64(pairs.Len()) == limit {
+			// the page is full, only lower ids may take a place
+			if last, _, _ := pairs.Max(); a.ID() > last {
+				return true
+			}
+		}
+		ok, seen := tested[a]
+		if !seen {
+			var err error
+			if ok, err = filter(a); err != nil {
+				ferr = err
+				return false
+			}
+			tested[a] = ok
+		}
+		if !ok {
+			return true
+		}
+		g := a.Geo()
+		if !objIsSpatial(g) {
+			return true
+		}
+		var match bool
+		switch rel {
+		case "intersects":
+			match = b.Geo().Intersects(g)
+		case "within":
+			match = g.Within(b.Geo())
+		case "nearby":
+			circle, ok := circles[a]
+			if !ok {
+				circle = geojson.NewCircle(g.Center(), meters,
+					defaultCircleSteps)
+				circles[a] = circle
+			}
+			match = b.Geo().Intersects(circle)
+		}
+		if match {
+			matches, _ := pairs.Get(a.ID())
+			pairs.Set(a.ID(), append(matches, b.ID()))
+			if limit > 0 && uint64(pairs.Len()) > limit {
+				pairs.PopMax()
+			}
+		}
+		return true
+	})
+	if ferr != nil {
+		return nil, ferr
+	}
+	pairs.Scan(func(_ string, matches []string) bool {
+		sort.Strings(matches)
+		return true
+	})
+	return &pairs, nil
+}
+
+// JOIN keyA keyB INTERSECTS|WITHIN|(NEARBY meters) [CURSOR start]
+// [LIMIT count] [MATCH pattern] [WHERE ...] [IDS|COUNT]
+//
+// The objects of keyA are paged in the order of their ids, and the count is
+// always the number of pairs of matching objects: of the page for IDS, and of
+// all of the objects for COUNT.
+func (s *Server) cmdJOIN(msg *Message) (resp.Value, error) {
+	start := time.Now()
+
+	// >> Args
+
+	vs := msg.Args[1:]
+	var ok bool
+	var keyA, keyB, srel string
+	if vs, keyA, ok = tokenval(vs); !ok || keyA == "" {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	if vs, keyB, ok = tokenval(vs); !ok || keyB == "" {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	if vs, srel, ok = tokenval(vs); !ok || srel == "" {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	rel := strings.ToLower(srel)
+	var meters float64
+	switch rel {
+	case "intersects", "within":
+	case "nearby":
+		var smeters string
+		if vs, smeters, ok = tokenval(vs); !ok || smeters == "" {
+			return retrerr(errInvalidNumberOfArguments)
+		}
+		var err error
+		meters, err = strconv.ParseFloat(smeters, 64)
+		if err != nil || meters < 0 || math.IsInf(meters, 0) {
+			return retrerr(errInvalidArgument(smeters))
+		}
+	default:
+		return retrerr(errInvalidArgument(srel))
+	}
+	var lfs liveFenceSwitches
+	var err error
+	vs, lfs.searchScanBaseTokens, err = s.parseSearchScanBaseTokens("join",
+		lfs.searchScanBaseTokens, append([]string{keyA}, vs...))
+	if lfs.usingLua() {
+		defer lfs.Close()
+	}
+	if err != nil {
+		return retrerr(err)
+	}
+	if len(vs) != 0 {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	if lfs.fence {
+		return retrerr(errors.New("FENCE is not allowed for JOIN"))
+	}
+	if lfs.usparse {
+		return retrerr(errors.New("SPARSE is not allowed for JOIN"))
+	}
+	switch lfs.output {
+	case outputObjects, outputIDs:
+		// the default output is the ids
+		lfs.output = outputIDs
+	case outputCount:
+	default:
+		return retrerr(errors.New("invalid output type"))
+	}
+
+	// >> Operation
+
+	var wr bytes.Buffer
+	sw, err := s.newScanWriter(&wr, msg, searchScanBaseTokens{
+		key:        keyA,
+		output:     lfs.output,
+		globs:      lfs.globs,
+		cursor:     lfs.cursor,
+		limit:      lfs.limit,
+		wheres:     lfs.wheres,
+		whereins:   lfs.whereins,
+		whereevals: lfs.whereevals,
+		nofields:   true,
+	}, false)
+	if err != nil {
+		return retrerr(err)
+	}
+	colB, _ := sw.db.cols.Get(keyB)
+	var joins []joinMatch
+	var npairs int
+	var ierr error
+	if sw.col != nil && colB != nil {
+		// the page starts at the object of the cursor
+		var first string
+		var past bool
+		if sw.cursor > 0 {
+			past = true
+			var n uint64
+			sw.col.Scan(false, nil, msg.Deadline, func(o *object.Object) bool {
+				n++
+				if n <= sw.cursor {
+					return true
+				}
+				first, past = o.ID(), false
+				return false
+			})
+		}
+		var limit uint64
+		if sw.output != outputCount {
+			limit = sw.limit
+		}
+		var pairs *btree.Map[string, []string]
+		if !past {
+			pairs, ierr = joinPairs(sw.col, colB, rel, meters, keyA == keyB,
+				first, limit, func(o *object.Object) (bool, error) {
+					ok, _, err := sw.testObject(o)
+					return ok, err
+				}, msg.Deadline)
+		}
+		if pairs != nil && sw.output == outputCount {
+			pairs.Scan(func(_ string, matches []string) bool {
+				npairs += len(matches)
+				return true
+			})
+		} else if pairs != nil && pairs.Len() > 0 {
+			sw.col.Scan(false, sw, msg.Deadline, func(o *object.Object) bool {
+				matches, _ := pairs.Get(o.ID())
+				if len(matches) == 0 {
+					return true
+				}
+				npairs += len(matches)
+				joins = append(joins, joinMatch{id: o.ID(), matches: matches})
+				if uint64(len(joins)) == sw.limit {
+					sw.hitLimit = true
+					return false
+				}
+				// stop after the last object with matches
+				return len(joins) < pairs.Len()
+			})
+		}
+	}
+	if ierr != nil {
+		return retrerr(ierr)
+	}
+	cursor := sw.numberIters
+	if !sw.hitLimit {
+		cursor = 0
+	}
+
+	// >> Response
+
+	if msg.OutputType == JSON {
+		wr.WriteString(`{"ok":true`)
+		if sw.output == outputIDs {
+			wr.WriteString(`,"joins":[`)
+			for i, j := range joins {
+				if i > 0 {
+					wr.WriteByte(',')
+				}
+				wr.WriteString(`{"id":` + jsonString(j.id) + `,"matches":[`)
+				for i, id := range j.matches {
+					if i > 0 {
+						wr.WriteByte(',')
+					}
+					wr.WriteString(jsonString(id))
+				}
+				wr.WriteString(`]}`)
+			}
+			wr.WriteByte(']')
+		}
+		wr.WriteString(`,"count":` + strconv.Itoa(npairs))
+		if sw.output == outputIDs {
+			wr.WriteString(`,"cursor":` + strconv.FormatUint(cursor, 10))
+		}
+		wr.WriteString(`,"elapsed":"` + time.Since(start).String() + "\"}")
+		return resp.BytesValue(wr.Bytes()), nil
+	}
+	if sw.output == outputCount {
+		return resp.IntegerValue(npairs), nil
+	}
+	vals := make([]resp.Value, len(joins))
+	for i, j := range joins {
+		matches := make([]resp.Value, len(j.matches))
+		for i, id := range j.matches {
+			matches[i] = resp.StringValue(id)
+		}
+		vals[i] = resp.ArrayValue([]resp.Value{
+			resp.StringValue(j.id), resp.ArrayValue(matches),
+		})
+	}
+	return resp.ArrayValue([]resp.Value{
+		resp.IntegerValue(int(cursor)), resp.ArrayValue(vals),
+	}), nil
+}
@@ -135,7 +135,7 @@ func appendJSONSimpleBounds(dst []byte, o geojson.Object) []byte {
 
 func appendJSONSimplePoint(dst []byte, o geojson.Object) []byte {
 	point := o.Center()
-	z := extractZCoordinate(o)
+	z := collection.Z(o)
 	dst = append(dst, `{"lat":`...)
 	dst = strconv.AppendFloat(dst, point.Y, 'f', -1, 64)
 	dst = append(dst, `,"lon":`...)
@@ -186,7 +186,7 @@ func (s *Server) cmdJget(msg *Message) (resp.Value, error) {
 			}
 		}
 	}
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		if msg.OutputType == RESP {
 			return resp.NullValue(), nil
@@ -264,10 +264,11 @@ func (s *Server) cmdJset(msg *Message) (res resp.Value, d commandDetails, err er
 			raw = true
 		}
 	}
-	col, _ := s.cols.Get(key)
+	db := s.selectDB(msg.DB)
+	col, _ := db.cols.Get(key)
 	var createcol bool
 	if col == nil {
-		col = collection.New()
+		col = db.newCollection(key)
 		createcol = true
 	}
 	var json string
@@ -296,8 +297,11 @@ func (s *Server) cmdJset(msg *Message) (res resp.Value, d commandDetails, err er
 		// SET key id OBJECT json
 		return s.cmdSET(&nmsg)
 	}
+	if err := s.validateSchema(db, key, fields, nil, o != nil); err != nil {
+		return NOMessage, d, err
+	}
 	if createcol {
-		s.cols.Set(key, col)
+		db.cols.Set(key, col)
 	}
 	var oobj geojson.Object = collection.String(json)
 	obj := object.New(id, oobj, 0, fields)
@@ -330,7 +334,7 @@ func (s *Server) cmdJdel(msg *Message) (res resp.Value, d commandDetails, err er
 	id := msg.Args[2]
 	path := msg.Args[3]
 
-	col, _ := s.cols.Get(key)
+	col, _ := s.getDB(msg.DB).cols.Get(key)
 	if col == nil {
 		if msg.OutputType == RESP {
 			return resp.IntegerValue(0), d, nil
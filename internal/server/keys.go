@@ -24,10 +24,11 @@ func (s *Server) cmdKEYS(msg *Message) (resp.Value, error) {
 	// >> Operation
 
 	keys := []string{}
+	db := s.getDB(msg.DB)
 	g := glob.Parse(pattern, false)
 	everything := g.Limits[0] == "" && g.Limits[1] == ""
 	if everything {
-		s.cols.Scan(
+		db.cols.Scan(
 			func(key string, _ *collection.Collection) bool {
 				match, _ := glob.Match(pattern, key)
 				if match {
@@ -37,7 +38,7 @@ func (s *Server) cmdKEYS(msg *Message) (resp.Value, error) {
 			},
 		)
 	} else {
-		s.cols.Ascend(g.Limits[0],
+		db.cols.Ascend(g.Limits[0],
 			func(key string, _ *collection.Collection) bool {
 				if key > g.Limits[1] {
 					return false
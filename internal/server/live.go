@@ -15,6 +15,7 @@ import (
 )
 
 type liveBuffer struct {
+	db      *database
 	key     string
 	globs   []string
 	fence   *liveFenceSwitches
@@ -51,7 +52,7 @@ func (s *Server) processLives(wg *sync.WaitGroup) {
 			}
 			for lb := range s.lives {
 				lb.cond.L.Lock()
-				if lb.key != "" && lb.key == item.key {
+				if lb.key != "" && lb.key == item.key && lb.db == item.db {
 					lb.details = append(lb.details, item)
 					lb.cond.Broadcast()
 				}
@@ -121,10 +122,8 @@ func (s *Server) goLive(
 	lb.key = lfs.key
 	lb.fence = &lfs
 	s.mu.RLock()
-	sw, err = s.newScanWriter(
-		&wr, msg, lfs.key, lfs.output, lfs.precision, lfs.globs, false,
-		lfs.cursor, lfs.limit, lfs.wheres, lfs.whereins, lfs.whereevals,
-		lfs.nofields, lfs.mvt, lfs.tileX, lfs.tileY, lfs.tileZ)
+	lb.db = s.getDB(msg.DB)
+	sw, err = s.newScanWriter(&wr, msg, lfs.searchScanBaseTokens, false)
 	s.mu.RUnlock()
 
 	// everything below if for live SCAN, NEARBY, WITHIN, INTERSECTS
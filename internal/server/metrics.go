@@ -39,13 +39,25 @@ var (
 			these metrics are NOT taken from basicStats() / extStats()
 			but are calculated independently
 		*/
-		"collection_objects": prometheus.NewDesc("tile38_collection_objects", "Total number of objects per collection", []string{"col"}, nil),
-		"collection_points":  prometheus.NewDesc("tile38_collection_points", "Total number of points per collection", []string{"col"}, nil),
-		"collection_strings": prometheus.NewDesc("tile38_collection_strings", "Total number of strings per collection", []string{"col"}, nil),
-		"collection_weight":  prometheus.NewDesc("tile38_collection_weight_bytes", "Total weight of collection in bytes", []string{"col"}, nil),
+		"collection_objects": prometheus.NewDesc("tile38_collection_objects", "Total number of objects per collection", []string{"db", "col"}, nil),
+		"collection_points":  prometheus.NewDesc("tile38_collection_points", "Total number of points per collection", []string{"db", "col"}, nil),
+		"collection_strings": prometheus.NewDesc("tile38_collection_strings", "Total number of strings per collection", []string{"db", "col"}, nil),
+		"collection_weight":  prometheus.NewDesc("tile38_collection_weight_bytes", "Total weight of collection in bytes", []string{"db", "col"}, nil),
 		"server_info":        prometheus.NewDesc("tile38_server_info", "Server info", []string{"id", "version"}, nil),
 		"replication":        prometheus.NewDesc("tile38_replication_info", "Replication info", []string{"role", "following", "caught_up", "caught_up_once"}, nil),
 		"start_time":         prometheus.NewDesc("tile38_start_time_seconds", "", nil, nil),
+
+		"hook_queued":       prometheus.NewDesc("tile38_hook_events_queued_total", "Total number of events queued per hook", []string{"db", "hook"}, nil),
+		"hook_delivered":    prometheus.NewDesc("tile38_hook_events_delivered_total", "Total number of events delivered per hook", []string{"db", "hook"}, nil),
+		"hook_failed":       prometheus.NewDesc("tile38_hook_events_failed_total", "Total number of failed deliveries of events per hook", []string{"db", "hook"}, nil),
+		"hook_retried":      prometheus.NewDesc("tile38_hook_events_retried_total", "Total number of retried deliveries of events per hook", []string{"db", "hook"}, nil),
+		"hook_dropped":      prometheus.NewDesc("tile38_hook_events_dropped_total", "Total number of events that expired per hook", []string{"db", "hook"}, nil),
+		"hook_dead_letters": prometheus.NewDesc("tile38_hook_dead_letters_total", "Total number of dead letters per hook", []string{"db", "hook"}, nil),
+		"hook_pending":      prometheus.NewDesc("tile38_hook_queue_depth", "Number of events in the queue per hook", []string{"db", "hook"}, nil),
+		"hook_last_error":   prometheus.NewDesc("tile38_hook_last_error_timestamp_seconds", "Time of the last failed delivery per hook", []string{"db", "hook"}, nil),
+		"hook_latency":      prometheus.NewDesc("tile38_hook_delivery_latency_seconds", "Time from queueing to delivery of events per hook", []string{"db", "hook"}, nil),
+		"hook_ep_delivered": prometheus.NewDesc("tile38_hook_endpoint_events_delivered_total", "Total number of events delivered per hook endpoint", []string{"db", "hook", "endpoint"}, nil),
+		"hook_ep_failed":    prometheus.NewDesc("tile38_hook_endpoint_failures_total", "Total number of failed deliveries per hook endpoint", []string{"db", "hook", "endpoint"}, nil),
 	}
 
 	cmdDurations = prometheus.NewSummaryVec(prometheus.SummaryOpts{
@@ -121,33 +133,82 @@ func (s *Server) Collect(ch chan<- prometheus.Metric) {
 	/*
 		add objects/points/strings stats for each collection
 	*/
-	s.cols.Scan(func(key string, col *collection.Collection) bool {
+	s.scanCols(func(db, key string, col *collection.Collection) bool {
 		ch <- prometheus.MustNewConstMetric(
 			metricDescriptions["collection_objects"],
 			prometheus.GaugeValue,
 			float64(col.Count()),
-			key,
+			db, key,
 		)
 		ch <- prometheus.MustNewConstMetric(
 			metricDescriptions["collection_points"],
 			prometheus.GaugeValue,
 			float64(col.PointCount()),
-			key,
+			db, key,
 		)
 		ch <- prometheus.MustNewConstMetric(
 			metricDescriptions["collection_strings"],
 			prometheus.GaugeValue,
 			float64(col.StringCount()),
-			key,
+			db, key,
 		)
 		ch <- prometheus.MustNewConstMetric(
 			metricDescriptions["collection_weight"],
 			prometheus.GaugeValue,
 			float64(col.TotalWeight()),
-			key,
+			db, key,
 		)
 		return true
 	})
+
+	/*
+		add the delivery stats for each hook
+	*/
+	s.dbs.Scan(func(dbname string, db *database) bool {
+		s.forEachHookByPattern(db, "*", false, func(hook *Hook) bool {
+			s.collectHookStats(ch, dbname, hook)
+			return true
+		})
+		return true
+	})
+}
+
+func (s *Server) collectHookStats(ch chan<- prometheus.Metric,
+	dbname string, hook *Hook,
+) {
+	hs := hook.stats
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	for metric, val := range map[string]int64{
+		"hook_queued":       hs.queued,
+		"hook_delivered":    hs.delivered,
+		"hook_failed":       hs.failed,
+		"hook_retried":      hs.retried,
+		"hook_dropped":      hs.dropped,
+		"hook_dead_letters": hs.dead,
+	} {
+		ch <- prometheus.MustNewConstMetric(metricDescriptions[metric],
+			prometheus.CounterValue, float64(val), dbname, hook.Name)
+	}
+	ch <- prometheus.MustNewConstMetric(metricDescriptions["hook_pending"],
+		prometheus.GaugeValue,
+		float64(s.qpending.count(dbname, hook.Name)), dbname, hook.Name)
+	if !hs.lastErrAt.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			metricDescriptions["hook_last_error"], prometheus.GaugeValue,
+			float64(hs.lastErrAt.UnixNano())/1e9, dbname, hook.Name)
+	}
+	buckets, count, sum := hs.histogram()
+	ch <- prometheus.MustNewConstHistogram(metricDescriptions["hook_latency"],
+		count, sum, buckets, dbname, hook.Name)
+	for endpoint, es := range hs.endpoints {
+		ch <- prometheus.MustNewConstMetric(
+			metricDescriptions["hook_ep_delivered"], prometheus.CounterValue,
+			float64(es.delivered), dbname, hook.Name, endpoint)
+		ch <- prometheus.MustNewConstMetric(
+			metricDescriptions["hook_ep_failed"], prometheus.CounterValue,
+			float64(es.failed), dbname, hook.Name, endpoint)
+	}
 }
 
 func toFloat(val interface{}) (float64, bool) {
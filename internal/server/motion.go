@@ -0,0 +1,143 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geo"
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/field"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// The fields that SET derives from the previous position of an object, when
+// motion is on for its collection. Their names have a prefix that clients
+// can't use for their own fields in such a collection.
+const (
+	motionPrefix  = "motion_"
+	motionTime    = motionPrefix + "ts"      // unix time of the update, in seconds
+	motionDelta   = motionPrefix + "dt"      // seconds since the previous update
+	motionSpeed   = motionPrefix + "speed"   // meters per second
+	motionHeading = motionPrefix + "heading" // degrees clockwise from north
+	motionAccel   = motionPrefix + "accel"   // meters per second squared
+)
+
+// motionNames are the derived fields in the order of the MOTION argument of
+// SET, which is how a derived SET is written to the aof and sent to the
+// followers.
+var motionNames = []string{
+	motionTime, motionDelta, motionSpeed, motionHeading, motionAccel,
+}
+
+// parseMotion returns the derived fields of the values of a MOTION argument.
+func parseMotion(vals []string) ([]field.Field, error) {
+	motion := make([]field.Field, len(motionNames))
+	for i, name := range motionNames {
+		if _, err := strconv.ParseFloat(vals[i], 64); err != nil {
+			return nil, errInvalidArgument(vals[i])
+		}
+		motion[i] = field.Make(name, vals[i])
+	}
+	return motion, nil
+}
+
+// checkMotionFields returns an error when a client sets a field with the
+// prefix of the derived fields.
+func checkMotionFields(fields []field.Field) error {
+	for _, f := range fields {
+		if strings.HasPrefix(f.Name(), motionPrefix) {
+			return errInvalidArgument(f.Name())
+		}
+	}
+	return nil
+}
+
+// hasField returns true when the fields have one with the name.
+func hasField(fields []field.Field, name string) bool {
+	for _, f := range fields {
+		if f.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func motionValue(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+// deriveMotion returns the motion fields of an object that moves from prev,
+// which may be nil, to g at the time now.
+func deriveMotion(prev *object.Object, g geojson.Object,
+	now time.Time,
+) []field.Field {
+	ts := float64(now.UnixMilli()) / 1000
+	var dt, speed, heading, accel float64
+	if prev != nil && objIsSpatial(prev.Geo()) {
+		pfields := prev.Fields()
+		pts := pfields.Get(motionTime).Value().Num()
+		if pts > 0 && ts > pts {
+			dt = ts - pts
+			a, b := prev.Geo().Center(), g.Center()
+			meters := geo.DistanceTo(a.Y, a.X, b.Y, b.X)
+			speed = meters / dt
+			// an object that stands still keeps its heading
+			heading = pfields.Get(motionHeading).Value().Num()
+			if meters > 0 {
+				heading = geo.BearingTo(a.Y, a.X, b.Y, b.X)
+			}
+			accel = (speed - pfields.Get(motionSpeed).Value().Num()) / dt
+		}
+	}
+	return []field.Field{
+		field.Make(motionTime, strconv.FormatFloat(ts, 'f', -1, 64)),
+		field.Make(motionDelta, motionValue(dt)),
+		field.Make(motionSpeed, motionValue(speed)),
+		field.Make(motionHeading, motionValue(heading)),
+		field.Make(motionAccel, motionValue(accel)),
+	}
+}
+
+// SETMOTION key ON|OFF
+func (s *Server) cmdSETMOTION(msg *Message) (resp.Value, commandDetails, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) != 3 {
+		return retwerr(errInvalidNumberOfArguments)
+	}
+	key := args[1]
+	var on bool
+	switch strings.ToLower(args[2]) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return retwerr(errInvalidArgument(args[2]))
+	}
+
+	// >> Operation
+
+	db := s.selectDB(msg.DB)
+	updated := db.motions.Contains(key) != on
+	if on {
+		db.motions.Insert(key)
+	} else {
+		db.motions.Delete(key)
+	}
+
+	// >> Response
+
+	var d commandDetails
+	d.command = "setmotion"
+	d.key = key
+	d.updated = updated
+	d.timestamp = time.Now()
+
+	return OKMessage(msg, start), d, nil
+}
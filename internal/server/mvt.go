@@ -10,8 +10,9 @@ import (
 )
 
 type mvtObj struct {
-	id  string
-	obj geojson.Object
+	id    string
+	obj   geojson.Object
+	count int // number of points for a cluster
 }
 
 func mvtDrawRing(f *mvt.Feature, tileX, tileY, tileZ int, ring geometry.Series,
@@ -48,7 +49,12 @@ func mvtAddFeature(l *mvt.Layer, tileX, tileY, tileZ int, o mvtObj) {
 		f = l.AddFeature(mvt.Point)
 		p := g.Base()
 		f.MoveTo(mvt.LatLonXY(p.Y, p.X, tileX, tileY, tileZ))
-		f.AddTag("type", "point")
+		if o.count > 1 {
+			f.AddTag("type", "cluster")
+			f.AddTag("count", o.count)
+		} else {
+			f.AddTag("type", "point")
+		}
 	case *geojson.SimplePoint:
 		f = l.AddFeature(mvt.Point)
 		p := g
@@ -81,12 +87,12 @@ func mvtAddFeature(l *mvt.Layer, tileX, tileY, tileZ int, o mvtObj) {
 		}
 		f.AddTag("type", "polygon")
 	case *geojson.Feature:
-		mvtAddFeature(l, tileX, tileY, tileZ, mvtObj{o.id, g.Base()})
+		mvtAddFeature(l, tileX, tileY, tileZ, mvtObj{o.id, g.Base(), o.count})
 		return
 	default:
 		if g, ok := g.(geojson.Collection); ok {
 			for _, g := range g.Children() {
-				mvtAddFeature(l, tileX, tileY, tileZ, mvtObj{o.id, g})
+				mvtAddFeature(l, tileX, tileY, tileZ, mvtObj{o.id, g, o.count})
 			}
 		}
 		return
@@ -120,10 +126,18 @@ func mvtFilterHTTPArgs(msg *Message, query string) (modified bool) {
 	}
 	var limit string
 	var sparse string
+	var cluster bool
+	var radius string
 	if query != "" {
 		q, _ := url.ParseQuery(query)
 		sparse = q.Get("sparse")
 		limit = q.Get("limit")
+		switch q.Get("cluster") {
+		case "", "0", "false":
+		default:
+			cluster = true
+		}
+		radius = q.Get("radius")
 	}
 	msg._command = ""
 	msg.Args = []string{"INTERSECTS", parts[0]}
@@ -134,6 +148,13 @@ func mvtFilterHTTPArgs(msg *Message, query string) (modified bool) {
 	} else {
 		msg.Args = append(msg.Args, "LIMIT", "100000000")
 	}
+	if cluster {
+		// cluster at the zoom of the tile
+		msg.Args = append(msg.Args, "CLUSTER", parts[1])
+		if radius != "" {
+			msg.Args = append(msg.Args, "RADIUS", radius)
+		}
+	}
 	msg.Args = append(msg.Args, "MVT", parts[2], parts[3], parts[1])
 	return true
 }
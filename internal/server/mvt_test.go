@@ -78,3 +78,28 @@ func TestMVTAddFeatureLineStringTooShort(t *testing.T) {
 	}
 }
 
+
+// Clusters are encoded as points with the number of points in the cluster.
+func TestMVTAddFeatureCluster(t *testing.T) {
+	tileX, tileY, tileZ := 0, 0, 0
+
+	point := geojson.NewPoint(geometry.Point{X: 1, Y: 1})
+
+	actual := mvtRender(tileX, tileY, tileZ,
+		[]mvtObj{{id: "cluster:0:a", obj: point, count: 3}})
+
+	var tile mvt.Tile
+	layer := tile.AddLayer("tile38")
+	layer.SetExtent(4096)
+	f := layer.AddFeature(mvt.Point)
+	f.MoveTo(mvt.LatLonXY(1, 1, tileX, tileY, tileZ))
+	f.AddTag("type", "cluster")
+	f.AddTag("count", 3)
+	f.AddTag("id", "cluster:0:a")
+
+	expected := tile.Render()
+
+	if !bytes.Equal(actual, expected) {
+		t.Fatalf("mvtAddFeature cluster encoding mismatch")
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/tile38/internal/field"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// orderBy orders the results of a search by a field or an expression. Only
+// the best cursor+limit results are kept while searching, in a heap with the
+// worst of the kept results at the top.
+type orderBy struct {
+	name  string
+	expr  bool // name is an expression
+	id    bool // order by the object id instead
+	desc  bool
+	max   uint64 // the number of results to keep
+	seq   uint64
+	items []orderItem
+}
+
+type orderItem struct {
+	opts  ScanWriterParams
+	value field.Value
+	seq   uint64 // the order that the item was found, for ties
+}
+
+// parseOrderBy parses the arguments that follow the ORDERBY keyword. The
+// name is a field, or an expression when it's not a plain field name.
+//
+//	ORDERBY field|expression [ASC|DESC]
+func parseOrderBy(vs []string) ([]string, *orderBy, error) {
+	var ok bool
+	var name string
+	if vs, name, ok = tokenval(vs); !ok || name == "" {
+		return nil, nil, errInvalidNumberOfArguments
+	}
+	ob := &orderBy{name: name, expr: !isFieldName(name)}
+	if len(vs) > 0 {
+		switch strings.ToLower(vs[0]) {
+		case "asc":
+			vs = vs[1:]
+		case "desc":
+			ob.desc = true
+			vs = vs[1:]
+		}
+	}
+	return vs, ob, nil
+}
+
+// isFieldName returns true if the name is a field or a path to a member of
+// the properties, such as "speed" or "properties.speed".
+func isFieldName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			c >= '0' && c <= '9' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the value that an object is ordered by.
+func (ob *orderBy) value(s *Server, o *object.Object) field.Value {
+	if !ob.expr {
+		return getFieldValue(o, ob.name)
+	}
+	return exprValue(s, o, ob.name)
+}
+
+// before returns true if item a comes before item b in the results.
+func (ob *orderBy) before(a, b *orderItem) bool {
+	if ob.id {
+		return a.opts.obj.ID() < b.opts.obj.ID()
+	}
+	if a.value.Less(b.value) {
+		return !ob.desc
+	}
+	if b.value.Less(a.value) {
+		return ob.desc
+	}
+	return a.seq < b.seq
+}
+
+func (ob *orderBy) Len() int { return len(ob.items) }
+func (ob *orderBy) Less(i, j int) bool {
+	return ob.before(&ob.items[j], &ob.items[i])
+}
+func (ob *orderBy) Swap(i, j int) {
+	ob.items[i], ob.items[j] = ob.items[j], ob.items[i]
+}
+func (ob *orderBy) Push(x any) { ob.items = append(ob.items, x.(orderItem)) }
+func (ob *orderBy) Pop() any {
+	item := ob.items[len(ob.items)-1]
+	ob.items = ob.items[:len(ob.items)-1]
+	return item
+}
+
+// push adds a result, keeping only the best cursor+limit results.
+func (ob *orderBy) push(s *Server, opts ScanWriterParams) {
+	item := orderItem{opts: opts, seq: ob.seq}
+	if !ob.id {
+		item.value = ob.value(s, opts.obj)
+	}
+	ob.seq++
+	if uint64(len(ob.items)) < ob.max {
+		heap.Push(ob, item)
+	} else if len(ob.items) > 0 && ob.before(&item, &ob.items[0]) {
+		ob.items[0] = item
+		heap.Fix(ob, 0)
+	}
+}
+
+// results returns the ordered results that come after the cursor, and
+// whether there are more results after them.
+func (ob *orderBy) results(cursor uint64) ([]ScanWriterParams, bool) {
+	sort.Slice(ob.items, func(i, j int) bool {
+		return ob.before(&ob.items[i], &ob.items[j])
+	})
+	var results []ScanWriterParams
+	for i := cursor; i < uint64(len(ob.items)); i++ {
+		results = append(results, ob.items[i].opts)
+	}
+	return results, ob.seq > uint64(len(ob.items))
+}
+
+// setLimit sets the number of results to keep.
+func (ob *orderBy) setLimit(cursor, limit uint64) {
+	ob.max = cursor + limit
+	if ob.max < cursor {
+		ob.max = math.MaxUint64
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/tile38/internal/field"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// projection selects the fields of the objects in the results. A field may
+// be computed from an expression.
+type projection struct {
+	items []projectionItem
+}
+
+type projectionItem struct {
+	name string // the name of the field in the results
+	src  string // the field or expression that the value comes from
+	expr bool   // src is an expression
+}
+
+// parseProjection parses the arguments that follow the FIELDS keyword. Each
+// item is a field, or an expression when it's not a plain field name, and
+// is renamed with AS.
+//
+//	FIELDS count field|expression [AS name] ...
+func parseProjection(vs []string) ([]string, *projection, error) {
+	var ok bool
+	var scount string
+	if vs, scount, ok = tokenval(vs); !ok || scount == "" {
+		return nil, nil, errInvalidNumberOfArguments
+	}
+	n, err := strconv.ParseUint(scount, 10, 64)
+	if err != nil || n == 0 {
+		return nil, nil, errInvalidArgument(scount)
+	}
+	p := &projection{}
+	for i := uint64(0); i < n; i++ {
+		var src string
+		if vs, src, ok = tokenval(vs); !ok || src == "" {
+			return nil, nil, errInvalidNumberOfArguments
+		}
+		item := projectionItem{name: src, src: src, expr: !isFieldName(src)}
+		if len(vs) > 0 && strings.ToLower(vs[0]) == "as" {
+			if vs, item.name, ok = tokenval(vs[1:]); !ok || item.name == "" {
+				return nil, nil, errInvalidNumberOfArguments
+			}
+		}
+		p.items = append(p.items, item)
+	}
+	return vs, p, nil
+}
+
+// apply returns a copy of an object that only has the projected fields.
+func (p *projection) apply(s *Server, o *object.Object) *object.Object {
+	var fields []field.Field
+	for _, item := range p.items {
+		var value field.Value
+		if item.expr {
+			value = exprValue(s, o, item.src)
+		} else {
+			value = getFieldValue(o, item.src)
+		}
+		if !value.IsZero() {
+			fields = append(fields, field.Make(item.name, value.Data()))
+		}
+	}
+	return object.New(o.ID(), o.Geo(), o.Expires(), field.MakeList(fields))
+}
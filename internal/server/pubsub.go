@@ -4,6 +4,7 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,9 +34,32 @@ func newPubsub() *pubsub {
 	}
 }
 
-// Publish a message to subscribers
+// pubsubSep separates the database from the channel in the name under which
+// a channel of a database other than the default one is published.
+const pubsubSep = "\x00"
+
+// pubsubName returns the name under which a channel of a database is
+// published. The channels of the default database keep their own names.
+func pubsubName(db, channel string) string {
+	if db == "" || db == defaultDB {
+		return channel
+	}
+	return db + pubsubSep + channel
+}
+
+// splitPubsubName returns the database and the channel of a published name.
+func splitPubsubName(name string) (db, channel string) {
+	if i := strings.Index(name, pubsubSep); i >= 0 {
+		return name[:i], name[i+len(pubsubSep):]
+	}
+	return defaultDB, name
+}
+
+// Publish a message to subscribers. The channel is the name returned by
+// pubsubName, and only the subscribers of its database receive the message.
 func (s *Server) Publish(channel string, message ...string) int {
 	var msgs []submsg
+	db, name := splitPubsubName(channel)
 	s.pubsub.mu.RLock()
 	if hub := s.pubsub.hubs[pubsubChannel][channel]; hub != nil {
 		for target := range hub.targets {
@@ -43,20 +67,21 @@ func (s *Server) Publish(channel string, message ...string) int {
 				msgs = append(msgs, submsg{
 					kind:    pubsubChannel,
 					target:  target,
-					channel: channel,
+					channel: name,
 					message: message,
 				})
 			}
 		}
 	}
-	for pattern, hub := range s.pubsub.hubs[pubsubPattern] {
-		if match.Match(channel, pattern) {
+	for key, hub := range s.pubsub.hubs[pubsubPattern] {
+		pdb, pattern := splitPubsubName(key)
+		if pdb == db && match.Match(name, pattern) {
 			for target := range hub.targets {
 				for _, message := range message {
 					msgs = append(msgs, submsg{
 						kind:    pubsubPattern,
 						target:  target,
-						channel: channel,
+						channel: name,
 						pattern: pattern,
 						message: message,
 					})
@@ -162,6 +187,10 @@ func (s *Server) cmdPublish(msg *Message) (resp.Value, error) {
 	}
 
 	channel := msg.Args[1]
+	if !msg.replay {
+		// the leader publishes the names of its database channels
+		channel = pubsubName(msg.DB, channel)
+	}
 	message := msg.Args[2]
 	//geofence := gjson.Valid(message) && gjson.Get(message, "fence").Bool()
 	n := s.Publish(channel, message) //, geofence)
@@ -187,6 +216,7 @@ func (s *Server) liveSubscription(
 
 	outputType := msg.OutputType
 	connType := msg.ConnType
+	db := msg.DB // the selected database of the client
 	if websocket {
 		outputType = JSON
 	} else if msg.StrictRESP {
@@ -377,13 +407,15 @@ func (s *Server) liveSubscription(
 				writeWrongNumberOfArgsErr(msg.Command())
 			}
 			for i := 1; i < len(msg.Args); i++ {
+				// channels resolve in the selected database of the client
 				channel := msg.Args[i]
+				name := pubsubName(db, channel)
 				if un {
-					delete(m[kind], channel)
-					s.pubsub.unregister(kind, channel, target)
+					delete(m[kind], name)
+					s.pubsub.unregister(kind, name, target)
 				} else {
-					m[kind][channel] = true
-					s.pubsub.register(kind, channel, target)
+					m[kind][name] = true
+					s.pubsub.register(kind, name, target)
 				}
 				writeSubscribe(msg.Command(), channel, len(m[0])+len(m[1]))
 			}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"github.com/golang/geo/s2"
+	"github.com/tidwall/geojson"
+	"github.com/tidwall/geojson/geometry"
+)
+
+// parseS2Cell parses an S2 cell token.
+func parseS2Cell(s string) (s2.CellID, error) {
+	id := s2.CellIDFromToken(s)
+	if !id.IsValid() {
+		return 0, errInvalidArgument(s)
+	}
+	return id, nil
+}
+
+// parseS2Level parses an S2 level, which is 0 to 30.
+func parseS2Level(s string) (int, bool) {
+	return parseCellLevel(s, s2.MaxLevel)
+}
+
+// s2CellPolygon returns the polygon of an S2 cell. The edges of large cells
+// are split so that the polygon follows the curve of the cell.
+func s2CellPolygon(id s2.CellID, opts *geometry.IndexOptions) geojson.Object {
+	cell := s2.CellFromCellID(id)
+	steps := 1
+	if level := id.Level(); level < 8 {
+		steps = 1 << (8 - level)
+	}
+	ring := make([]geometry.Point, 0, 4*steps+1)
+	for i := 0; i < 4; i++ {
+		a, b := cell.Vertex(i), cell.Vertex((i+1)%4)
+		for j := 0; j < steps; j++ {
+			ll := s2.LatLngFromPoint(s2.Interpolate(float64(j)/float64(steps), a, b))
+			ring = append(ring, geometry.Point{
+				X: ll.Lng.Degrees(), Y: ll.Lat.Degrees(),
+			})
+		}
+	}
+	return cellPolygon(ring, opts)
+}
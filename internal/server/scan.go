@@ -43,10 +43,7 @@ func (s *Server) cmdScan(msg *Message) (res resp.Value, err error) {
 		return NOMessage, err
 	}
 	wr := &bytes.Buffer{}
-	sw, err := s.newScanWriter(
-		wr, msg, args.key, args.output, args.precision, args.globs, false,
-		args.cursor, args.limit, args.wheres, args.whereins, args.whereevals,
-		args.nofields, args.mvt, args.tileX, args.tileY, args.tileZ)
+	sw, err := s.newScanWriter(wr, msg, args.searchScanBaseTokens, false)
 	if err != nil {
 		return NOMessage, err
 	}
@@ -57,42 +54,47 @@ func (s *Server) cmdScan(msg *Message) (res resp.Value, err error) {
 	if sw.col != nil {
 		if sw.output == outputCount && len(sw.wheres) == 0 &&
 			len(sw.whereins) == 0 && len(sw.whereevals) == 0 &&
-			sw.globEverything {
+			sw.globEverything && args.token == nil {
+			sw.explain.setAccess("count")
 			count := sw.col.Count() - int(args.cursor)
 			if count < 0 {
 				count = 0
 			}
 			sw.count = uint64(count)
 		} else {
+			iter := func(o *object.Object) bool {
+				keepGoing, err := sw.pushObject(ScanWriterParams{
+					obj: o,
+				})
+				if err != nil {
+					ierr = err
+					return false
+				}
+				return keepGoing
+			}
 			limits := multiGlobParse(sw.globs, args.desc)
-			if limits[0] == "" && limits[1] == "" {
-				sw.col.Scan(args.desc, sw,
-					msg.Deadline,
-					func(o *object.Object) bool {
-						keepGoing, err := sw.pushObject(ScanWriterParams{
-							obj: o,
-						})
-						if err != nil {
-							ierr = err
-							return false
-						}
-						return keepGoing
-					},
-				)
+			if args.token != nil && args.token.pos {
+				// continue from the last id of the previous page
+				start := args.token.id
+				sw.explain.setAccess("id scan from cursor")
+				if limits[0] == "" && limits[1] == "" {
+					sw.col.ScanGreaterOrEqual(start, args.desc, sw,
+						msg.Deadline, iter)
+				} else {
+					if args.desc && limits[0] < start ||
+						!args.desc && limits[0] > start {
+						start = limits[0]
+					}
+					sw.col.ScanRange(start, limits[1], args.desc, sw,
+						msg.Deadline, iter)
+				}
+			} else if limits[0] == "" && limits[1] == "" {
+				sw.explain.setAccess("id scan")
+				sw.col.Scan(args.desc, sw, msg.Deadline, iter)
 			} else {
+				sw.explain.setAccess("id range scan")
 				sw.col.ScanRange(limits[0], limits[1], args.desc, sw,
-					msg.Deadline,
-					func(o *object.Object) bool {
-						keepGoing, err := sw.pushObject(ScanWriterParams{
-							obj: o,
-						})
-						if err != nil {
-							ierr = err
-							return false
-						}
-						return keepGoing
-					},
-				)
+					msg.Deadline, iter)
 			}
 		}
 	}
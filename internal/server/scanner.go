@@ -32,10 +32,16 @@ const (
 	outputPoints
 	outputHashes
 	outputBounds
+	outputH3
+	outputS2Cells
+	outputAggregate
+	outputGrid
+	outputCluster
 )
 
 type scanWriter struct {
 	s              *Server
+	db             *database
 	wr             *bytes.Buffer
 	name           string
 	msg            *Message
@@ -45,9 +51,17 @@ type scanWriter struct {
 	wheres         []whereT
 	whereins       []whereinT
 	whereevals     []whereevalT
+	agg            *aggregator
+	cluster        *clusterer
+	order          *orderBy
+	token          *cursorToken
+	fields         *projection
+	explain        *explainStats
 	numberIters    uint64
 	numberItems    uint64
 	nofields       bool
+	area           bool // write the area of the objects
+	length         bool // write the length of the objects
 	cursor         uint64
 	limit          uint64
 	hitLimit       bool
@@ -72,59 +86,84 @@ type ScanWriterParams struct {
 	obj             *object.Object
 	dist            float64
 	distOutput      bool // query or fence requested distance output
+	frac            float64
+	fracOutput      bool    // fraction along a corridor route
+	pos             float64 // distance or west edge, for tokens
 	noTest          bool
 	ignoreGlobMatch bool
 	clip            geojson.Object
 	skipTesting     bool
+	cells           []string // S2 cells of the object, for S2CELLS
 }
 
+// newScanWriter returns a writer for the results of a scan or search with
+// the tokens that were parsed from the command.
 func (s *Server) newScanWriter(
-	wr *bytes.Buffer, msg *Message, name string, output outputT,
-	precision uint64, globs []string, matchValues bool,
-	cursor, limit uint64, wheres []whereT, whereins []whereinT,
-	whereevals []whereevalT, nofields, mvt bool, tileX, tileY, tileZ int,
+	wr *bytes.Buffer, msg *Message, t searchScanBaseTokens, matchValues bool,
 ) (
 	*scanWriter, error,
 ) {
-	switch output {
+	switch t.output {
 	default:
 		return nil, errors.New("invalid output type")
 	case outputIDs, outputObjects, outputCount, outputBounds, outputPoints,
-		outputHashes:
+		outputHashes, outputH3, outputS2Cells, outputAggregate, outputGrid,
+		outputCluster:
 	}
+	limit := t.limit
 	if limit == 0 {
-		if output == outputCount {
+		switch t.output {
+		case outputCount, outputAggregate, outputGrid, outputCluster:
 			limit = math.MaxUint64
-		} else {
+		default:
 			limit = limitItems
 		}
 	}
 	sw := &scanWriter{
 		s:           s,
 		wr:          wr,
-		name:        name,
+		name:        t.key,
 		msg:         msg,
-		globs:       globs,
+		globs:       t.globs,
 		limit:       limit,
-		cursor:      cursor,
-		output:      output,
-		nofields:    nofields,
-		precision:   precision,
-		whereevals:  whereevals,
+		cursor:      t.cursor,
+		output:      t.output,
+		nofields:    t.nofields,
+		area:        t.area,
+		length:      t.length,
+		precision:   t.precision,
+		whereevals:  t.whereevals,
+		agg:         t.agg,
+		cluster:     t.cluster,
 		matchValues: matchValues,
 	}
+	order := t.order
+	if order != nil {
+		switch t.output {
+		case outputCount, outputAggregate, outputGrid, outputCluster:
+			// the order does not change these results
+			order = nil
+		default:
+			order.setLimit(t.cursor, limit)
+		}
+	}
 
-	sw.mvt = mvt
-	sw.tileX = tileX
-	sw.tileY = tileY
-	sw.tileZ = tileZ
+	sw.order = order
+	sw.token = t.token
+	sw.fields = t.fields
+	sw.explain = msg.explain
+	sw.mvt = t.mvt
+	sw.tileX = t.tileX
+	sw.tileY = t.tileY
+	sw.tileZ = t.tileZ
 
-	if len(globs) == 0 || (len(globs) == 1 && globs[0] == "*") {
+	if len(t.globs) == 0 || (len(t.globs) == 1 && t.globs[0] == "*") {
 		sw.globEverything = true
 	}
-	sw.wheres = wheres
-	sw.whereins = whereins
-	sw.col, _ = sw.s.cols.Get(sw.name)
+	sw.wheres = t.wheres
+	sw.whereins = t.whereins
+	sw.db = s.getDB(msg.DB)
+	sw.col, _ = sw.db.cols.Get(sw.name)
 	return sw, nil
 }
 
@@ -132,12 +171,33 @@ func (sw *scanWriter) hasFieldsOutput() bool {
 	switch sw.output {
 	default:
 		return false
-	case outputObjects, outputPoints, outputHashes, outputBounds:
+	case outputObjects, outputPoints, outputHashes, outputBounds, outputH3,
+		outputS2Cells:
 		return !sw.nofields
 	}
 }
 
 func (sw *scanWriter) writeFoot() {
+	sw.explain.finish(sw)
+	if sw.order != nil {
+		// the ordered results that come after the cursor
+		sw.filled, sw.hitLimit = sw.order.results(sw.cursor)
+		sw.count = uint64(len(sw.filled))
+		sw.numberIters = sw.cursor + sw.count
+		if sw.fields != nil {
+			for i := range sw.filled {
+				sw.filled[i].obj = sw.fields.apply(sw.s, sw.filled[i].obj)
+			}
+		}
+		if !sw.fullFields {
+			for _, opts := range sw.filled {
+				opts.obj.Fields().Scan(func(f field.Field) bool {
+					sw.fkeys.Insert(f.Name())
+					return true
+				})
+			}
+		}
+	}
 	if sw.mvt {
 		sw.wr.WriteString(`,"mvt":"`)
 	} else {
@@ -167,7 +227,11 @@ func (sw *scanWriter) writeFoot() {
 				sw.wr.WriteString(`,"bounds":[`)
 			case outputHashes:
 				sw.wr.WriteString(`,"hashes":[`)
-			case outputCount:
+			case outputH3:
+				sw.wr.WriteString(`,"h3cells":[`)
+			case outputS2Cells:
+				sw.wr.WriteString(`,"s2cells":[`)
+			case outputCount, outputAggregate, outputGrid, outputCluster:
 
 			}
 		case RESP:
@@ -175,6 +239,9 @@ func (sw *scanWriter) writeFoot() {
 	}
 	var mvtTile []byte
 	if sw.mvt {
+		if sw.output == outputCluster {
+			sw.mvtObjs = sw.cluster.mvtObjs()
+		}
 		mvtTile = mvtRender(sw.tileX, sw.tileY, sw.tileZ, sw.mvtObjs)
 	} else {
 		for _, opts := range sw.filled {
@@ -185,6 +252,10 @@ func (sw *scanWriter) writeFoot() {
 	if !sw.hitLimit {
 		cursor = 0
 	}
+	var token string
+	if sw.token != nil && sw.hitLimit && len(sw.filled) > 0 {
+		token = sw.token.next(sw.filled[len(sw.filled)-1]).String()
+	}
 	switch sw.msg.OutputType {
 	case JSON:
 		if sw.mvt {
@@ -195,15 +266,32 @@ func (sw *scanWriter) writeFoot() {
 			default:
 				sw.wr.WriteByte(']')
 			case outputCount:
+			case outputAggregate, outputGrid:
+				sw.agg.writeJSON(sw.wr)
+			case outputCluster:
+				sw.cluster.writeJSON(sw.wr)
 			}
 		}
 		sw.wr.WriteString(`,"count":` + strconv.FormatUint(sw.count, 10))
-		sw.wr.WriteString(`,"cursor":` + strconv.FormatUint(cursor, 10))
+		if sw.token != nil {
+			sw.wr.WriteString(`,"cursor":` + jsonString(token))
+		} else {
+			sw.wr.WriteString(`,"cursor":` + strconv.FormatUint(cursor, 10))
+		}
 	case RESP:
 		if sw.output == outputCount {
 			sw.respOut = resp.IntegerValue(int(sw.count))
 		} else {
+			switch sw.output {
+			case outputAggregate, outputGrid:
+				sw.values = sw.agg.respValues()
+			case outputCluster:
+				sw.values = sw.cluster.respValues()
+			}
 			values := []resp.Value{resp.IntegerValue(int(cursor))}
+			if sw.token != nil {
+				values[0] = resp.StringValue(token)
+			}
 			if sw.mvt {
 				values = append(values, resp.BytesValue(mvtTile))
 			} else {
@@ -214,19 +302,6 @@ func (sw *scanWriter) writeFoot() {
 	}
 }
 
-func extractZCoordinate(o geojson.Object) float64 {
-	for {
-		switch g := o.(type) {
-		case *geojson.Point:
-			return g.Z()
-		case *geojson.Feature:
-			o = g.Base()
-		default:
-			return 0
-		}
-	}
-}
-
 func isPathKey(s string, key string) bool {
 	return strings.HasPrefix(s, key) && (s == key || s[len(key)] == '.')
 }
@@ -246,7 +321,7 @@ func getFieldValue(o *object.Object, name string) field.Value {
 		}
 	}
 	if name == "z" {
-		z := extractZCoordinate(o.Geo())
+		z := collection.Z(o.Geo())
 		return field.ValueOf(strconv.FormatFloat(z, 'f', -1, 64))
 	}
 	return o.Fields().Get(name).Value()
@@ -255,25 +330,33 @@ func getFieldValue(o *object.Object, name string) field.Value {
 func (sw *scanWriter) fieldMatch(o *object.Object) (bool, error) {
 	for _, where := range sw.wheres {
 		if where.expr {
-			if !where.matchExpr(sw.s, o) {
+			start := sw.explain.now()
+			match := where.matchExpr(sw.s, o)
+			sw.explain.exprDone(start)
+			if !match {
+				sw.explain.reject(rejectWhere)
 				return false, nil
 			}
 		} else {
 			if !where.matchField(getFieldValue(o, where.name)) {
+				sw.explain.reject(rejectWhere)
 				return false, nil
 			}
 		}
 	}
 	for _, wherein := range sw.whereins {
 		if !wherein.match(getFieldValue(o, wherein.name)) {
+			sw.explain.reject(rejectWherein)
 			return false, nil
 		}
 	}
 	if len(sw.whereevals) > 0 {
+		start := sw.explain.now()
+		defer sw.explain.luaDone(start)
 		fieldNames := make(map[string]field.Value)
 		var props string
 		if objIsSpatial(o.Geo()) {
-			z := extractZCoordinate(o.Geo())
+			z := collection.Z(o.Geo())
 			fieldNames["z"] = field.ValueOf(strconv.FormatFloat(z, 'f', -1, 64))
 			props = gjson.Get(o.Geo().Members(), "properties").Raw
 		}
@@ -287,6 +370,7 @@ func (sw *scanWriter) fieldMatch(o *object.Object) (bool, error) {
 				return false, err
 			}
 			if !match {
+				sw.explain.reject(rejectWhereeval)
 				return false, nil
 			}
 		}
@@ -315,6 +399,11 @@ func (sw *scanWriter) globMatch(o *object.Object) (ok, keepGoing bool) {
 
 // Increment cursor
 func (sw *scanWriter) Offset() uint64 {
+	if sw.order != nil || sw.token != nil {
+		// the cursor is applied to the ordered results, or the token is
+		// used instead
+		return 0
+	}
 	return sw.cursor
 }
 
@@ -322,12 +411,19 @@ func (sw *scanWriter) Step(n uint64) {
 	sw.numberIters += n
 }
 
+// CountNodes is called with the number of nodes of the spatial index that a
+// search visited, for EXPLAIN.
+func (sw *scanWriter) CountNodes(nodes uint64) {
+	sw.explain.countNodes(nodes)
+}
+
 // ok is whether the object passes the test and should be written
 // keepGoing is whether there could be more objects to test
 func (sw *scanWriter) testObject(o *object.Object,
 ) (ok, keepGoing bool, err error) {
 	match, kg := sw.globMatch(o)
 	if !match {
+		sw.explain.reject(rejectMatch)
 		return false, kg, nil
 	}
 	ok, err = sw.fieldMatch(o)
@@ -341,6 +437,11 @@ func (sw *scanWriter) pushObject(opts ScanWriterParams) (keepGoing bool,
 	err error,
 ) {
 	keepGoing = true
+	sw.explain.push()
+	if sw.token != nil && !sw.token.after(opts.obj, opts.pos) {
+		sw.explain.reject(rejectCursor)
+		return true, nil
+	}
 	if !opts.noTest {
 		var ok bool
 		var err error
@@ -352,8 +453,20 @@ func (sw *scanWriter) pushObject(opts ScanWriterParams) (keepGoing bool,
 			return keepGoing, nil
 		}
 	}
+	if sw.output == outputGrid && !sw.agg.binnable(opts.obj) {
+		return keepGoing, nil
+	}
 	sw.count++
-	if sw.output == outputCount {
+	switch sw.output {
+	case outputCount:
+		return sw.count < sw.limit, nil
+	case outputAggregate, outputGrid:
+		if err := sw.agg.push(opts.obj); err != nil {
+			return false, err
+		}
+		return sw.count < sw.limit, nil
+	case outputCluster:
+		sw.cluster.push(opts.obj)
 		return sw.count < sw.limit, nil
 	}
 	if opts.clip != nil {
@@ -365,8 +478,21 @@ func (sw *scanWriter) pushObject(opts ScanWriterParams) (keepGoing bool,
 			opts.obj.Fields(),
 		)
 	}
+	if sw.output == outputS2Cells {
+		if opts.cells, err = sw.s2Cells(opts.obj); err != nil {
+			return false, err
+		}
+	}
 	if sw.mvt {
-		sw.mvtObjs = append(sw.mvtObjs, mvtObj{opts.obj.ID(), opts.obj.Geo()})
+		sw.mvtObjs = append(sw.mvtObjs,
+			mvtObj{id: opts.obj.ID(), obj: opts.obj.Geo()})
+	}
+	if sw.order != nil {
+		sw.order.push(sw.s, opts)
+		return keepGoing, nil
+	}
+	if sw.fields != nil {
+		opts.obj = sw.fields.apply(sw.s, opts.obj)
 	}
 	if !sw.fullFields {
 		opts.obj.Fields().Scan(func(f field.Field) bool {
@@ -383,13 +509,16 @@ func (sw *scanWriter) pushObject(opts ScanWriterParams) (keepGoing bool,
 	return keepGoing, nil
 }
 
-func (sw *scanWriter) writeObject(opts ScanWriterParams) {
+func (sw *scanWriter) writeObject(opts ScanWriterParams) error {
 	n := len(sw.filled)
-	sw.pushObject(opts)
+	if _, err := sw.pushObject(opts); err != nil {
+		return err
+	}
 	if len(sw.filled) > n {
 		sw.writeFilled(sw.filled[len(sw.filled)-1])
 		sw.filled = sw.filled[:n]
 	}
+	return nil
 }
 
 func (sw *scanWriter) writeFilled(opts ScanWriterParams) {
@@ -434,9 +563,16 @@ func (sw *scanWriter) writeFilled(opts ScanWriterParams) {
 			jsfields += `]`
 		}
 		if sw.output == outputIDs {
-			if opts.distOutput || opts.dist > 0 {
-				wr.WriteString(`{"id":` + jsonString(opts.obj.ID()) +
-					`,"distance":` + strconv.FormatFloat(opts.dist, 'f', -1, 64) + "}")
+			if opts.distOutput || opts.dist > 0 || sw.area || sw.length {
+				wr.WriteString(`{"id":` + jsonString(opts.obj.ID()))
+				if opts.distOutput || opts.dist > 0 {
+					wr.WriteString(`,"distance":` + strconv.FormatFloat(opts.dist, 'f', -1, 64))
+				}
+				if opts.fracOutput {
+					wr.WriteString(`,"fraction":` + strconv.FormatFloat(opts.frac, 'f', -1, 64))
+				}
+				sw.writeMeasures(&wr, opts.obj)
+				wr.WriteString("}")
 			} else {
 				wr.WriteString(jsonString(opts.obj.ID()))
 			}
@@ -451,6 +587,18 @@ func (sw *scanWriter) writeFilled(opts ScanWriterParams) {
 				center := opts.obj.Geo().Center()
 				p := geohash.EncodeWithPrecision(center.Y, center.X, uint(sw.precision))
 				wr.WriteString(`,"hash":"` + p + `"`)
+			case outputH3:
+				cell := h3CellOf(opts.obj.Geo(), int(sw.precision))
+				wr.WriteString(`,"h3":"` + cell.String() + `"`)
+			case outputS2Cells:
+				wr.WriteString(`,"cells":[`)
+				for i, cell := range opts.cells {
+					if i > 0 {
+						wr.WriteByte(',')
+					}
+					wr.WriteString(jsonString(cell))
+				}
+				wr.WriteByte(']')
 			case outputBounds:
 				wr.WriteString(`,"bounds":` + string(appendJSONSimpleBounds(nil, opts.obj.Geo())))
 			}
@@ -458,6 +606,10 @@ func (sw *scanWriter) writeFilled(opts ScanWriterParams) {
 			if opts.distOutput || opts.dist > 0 {
 				wr.WriteString(`,"distance":` + strconv.FormatFloat(opts.dist, 'f', -1, 64))
 			}
+			if opts.fracOutput {
+				wr.WriteString(`,"fraction":` + strconv.FormatFloat(opts.frac, 'f', -1, 64))
+			}
+			sw.writeMeasures(&wr, opts.obj)
 
 			wr.WriteString(`}`)
 		}
@@ -466,8 +618,14 @@ func (sw *scanWriter) writeFilled(opts ScanWriterParams) {
 		vals := make([]resp.Value, 1, 3)
 		vals[0] = resp.StringValue(opts.obj.ID())
 		if sw.output == outputIDs {
-			if opts.distOutput || opts.dist > 0 {
-				vals = append(vals, resp.FloatValue(opts.dist))
+			if opts.distOutput || opts.dist > 0 || sw.area || sw.length {
+				if opts.distOutput || opts.dist > 0 {
+					vals = append(vals, resp.FloatValue(opts.dist))
+				}
+				if opts.fracOutput {
+					vals = append(vals, resp.FloatValue(opts.frac))
+				}
+				vals = sw.appendMeasures(vals, opts.obj)
 				sw.values = append(sw.values, resp.ArrayValue(vals))
 			} else {
 				sw.values = append(sw.values, vals[0])
@@ -478,7 +636,7 @@ func (sw *scanWriter) writeFilled(opts ScanWriterParams) {
 				vals = append(vals, resp.StringValue(opts.obj.String()))
 			case outputPoints:
 				point := opts.obj.Geo().Center()
-				z := extractZCoordinate(opts.obj.Geo())
+				z := collection.Z(opts.obj.Geo())
 				if z != 0 {
 					vals = append(vals, resp.ArrayValue([]resp.Value{
 						resp.FloatValue(point.Y),
@@ -495,6 +653,15 @@ func (sw *scanWriter) writeFilled(opts ScanWriterParams) {
 				center := opts.obj.Geo().Center()
 				p := geohash.EncodeWithPrecision(center.Y, center.X, uint(sw.precision))
 				vals = append(vals, resp.StringValue(p))
+			case outputH3:
+				cell := h3CellOf(opts.obj.Geo(), int(sw.precision))
+				vals = append(vals, resp.StringValue(cell.String()))
+			case outputS2Cells:
+				var cells []resp.Value
+				for _, cell := range opts.cells {
+					cells = append(cells, resp.StringValue(cell))
+				}
+				vals = append(vals, resp.ArrayValue(cells))
 			case outputBounds:
 				bbox := opts.obj.Rect()
 				vals = append(vals, resp.ArrayValue([]resp.Value{
@@ -525,7 +692,55 @@ func (sw *scanWriter) writeFilled(opts ScanWriterParams) {
 			if opts.distOutput || opts.dist > 0 {
 				vals = append(vals, resp.FloatValue(opts.dist))
 			}
+			if opts.fracOutput {
+				vals = append(vals, resp.FloatValue(opts.frac))
+			}
+			vals = sw.appendMeasures(vals, opts.obj)
 			sw.values = append(sw.values, resp.ArrayValue(vals))
 		}
 	}
 }
+
+// writeMeasures writes the geodesic area and length of an object to a JSON
+// result, when they are requested.
+func (sw *scanWriter) writeMeasures(wr *bytes.Buffer, o *object.Object) {
+	if sw.area {
+		area := collection.GeodesicArea(o.Geo())
+		wr.WriteString(`,"area":` + strconv.FormatFloat(area, 'f', -1, 64))
+	}
+	if sw.length {
+		length := collection.GeodesicLength(o.Geo())
+		wr.WriteString(`,"length":` + strconv.FormatFloat(length, 'f', -1, 64))
+	}
+}
+
+// appendMeasures appends the geodesic area and length of an object to a RESP
+// result, when they are requested. They are appended last, as one array of
+// name and value pairs, so that they aren't taken for the distance.
+func (sw *scanWriter) appendMeasures(vals []resp.Value, o *object.Object,
+) []resp.Value {
+	if !sw.area && !sw.length {
+		return vals
+	}
+	var mvals []resp.Value
+	if sw.area {
+		mvals = append(mvals, resp.StringValue("area"),
+			resp.FloatValue(collection.GeodesicArea(o.Geo())))
+	}
+	if sw.length {
+		mvals = append(mvals, resp.StringValue("length"),
+			resp.FloatValue(collection.GeodesicLength(o.Geo())))
+	}
+	return append(vals, resp.ArrayValue(mvals))
+}
+
+// s2Cells returns the S2 cells at the output level that cover an object. It
+// fails when the covering has more than maxCoverCells cells.
+func (sw *scanWriter) s2Cells(o *object.Object) ([]string, error) {
+	cells, ok := coverObject(o.Geo(), "s2", int(sw.precision), maxCoverCells,
+		&sw.s.geomIndexOpts)
+	if !ok {
+		return nil, errTooManyCells
+	}
+	return cells, nil
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // schedules may name any time zone
+)
+
+// scheduleWindow is a daily window of time on some days of the week, from
+// SCHEDULE "Mon-Fri 08:00-18:00 America/Chicago".
+type scheduleWindow struct {
+	days  [7]bool // by time.Weekday
+	start int     // minutes since midnight
+	end   int     // minutes since midnight, at or before start is overnight
+	loc   *time.Location
+}
+
+// hookSchedule is when a hook sends its messages. A hook with no schedule is
+// always active.
+type hookSchedule struct {
+	specs     []string // SCHEDULE values
+	windows   []scheduleWindow
+	hasActive bool // ACTIVE from to
+	from, to  time.Time
+	sfrom     string
+	sto       string
+}
+
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseScheduleDays parses days like "Mon-Fri", "Sat,Sun" or "*".
+func parseScheduleDays(s string, days *[7]bool) bool {
+	if s == "*" {
+		*days = [7]bool{true, true, true, true, true, true, true}
+		return true
+	}
+	for _, part := range strings.Split(strings.ToLower(s), ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		d1, ok := scheduleDays[first]
+		if !ok {
+			return false
+		}
+		d2 := d1
+		if isRange {
+			if d2, ok = scheduleDays[last]; !ok {
+				return false
+			}
+		}
+		// a range like Fri-Mon goes over the weekend
+		for d := d1; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == d2 {
+				break
+			}
+		}
+	}
+	return true
+}
+
+// parseScheduleClock parses a time of day like "08:00" into minutes since
+// midnight. "24:00" is the end of the day.
+func parseScheduleClock(s string) (int, bool) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok || len(hh) == 0 || len(hh) > 2 || len(mm) != 2 {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 ||
+		h*60+m > 24*60 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// parseScheduleWindow parses a SCHEDULE value, which is the days, the hours
+// and an optional time zone that defaults to UTC.
+func parseScheduleWindow(spec string) (w scheduleWindow, err error) {
+	parts := strings.Fields(spec)
+	if len(parts) < 2 || len(parts) > 3 {
+		return w, errInvalidArgument(spec)
+	}
+	if !parseScheduleDays(parts[0], &w.days) {
+		return w, errInvalidArgument(spec)
+	}
+	first, last, ok := strings.Cut(parts[1], "-")
+	if !ok {
+		return w, errInvalidArgument(spec)
+	}
+	var ok1, ok2 bool
+	w.start, ok1 = parseScheduleClock(first)
+	w.end, ok2 = parseScheduleClock(last)
+	if !ok1 || !ok2 || w.start == w.end || w.start == 24*60 {
+		return w, errInvalidArgument(spec)
+	}
+	w.loc = time.UTC
+	if len(parts) == 3 {
+		if w.loc, err = time.LoadLocation(parts[2]); err != nil {
+			return w, errInvalidArgument(spec)
+		}
+	}
+	return w, nil
+}
+
+// contains returns true when the time is in the window. An overnight window
+// belongs to the day that it starts on.
+func (w scheduleWindow) contains(t time.Time) bool {
+	t = t.In(w.loc)
+	m := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return w.days[t.Weekday()] && m >= w.start && m < w.end
+	}
+	if m >= w.start {
+		return w.days[t.Weekday()]
+	}
+	return m < w.end && w.days[(t.Weekday()+6)%7]
+}
+
+// parseScheduleTime parses an ACTIVE time, which is either RFC 3339 or unix
+// seconds.
+func parseScheduleTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, errInvalidArgument(s)
+	}
+	return time.Unix(0, int64(secs*float64(time.Second))), nil
+}
+
+// addSpec adds a SCHEDULE value.
+func (hs *hookSchedule) addSpec(spec string) error {
+	w, err := parseScheduleWindow(spec)
+	if err != nil {
+		return err
+	}
+	hs.specs = append(hs.specs, spec)
+	hs.windows = append(hs.windows, w)
+	return nil
+}
+
+// setActive sets the ACTIVE window.
+func (hs *hookSchedule) setActive(sfrom, sto string) (err error) {
+	if hs.hasActive {
+		return errDuplicateArgument("ACTIVE")
+	}
+	if hs.from, err = parseScheduleTime(sfrom); err != nil {
+		return err
+	}
+	if hs.to, err = parseScheduleTime(sto); err != nil {
+		return err
+	}
+	if !hs.to.After(hs.from) {
+		return errInvalidArgument(sto)
+	}
+	hs.hasActive, hs.sfrom, hs.sto = true, sfrom, sto
+	return nil
+}
+
+// active returns true when the hook sends messages at the time.
+func (hs *hookSchedule) active(t time.Time) bool {
+	if hs == nil {
+		return true
+	}
+	if hs.hasActive && (t.Before(hs.from) || !t.Before(hs.to)) {
+		return false
+	}
+	if len(hs.windows) == 0 {
+		return true
+	}
+	for _, w := range hs.windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// args returns the schedule in the same form as the SETHOOK arguments.
+func (hs *hookSchedule) args() []string {
+	if hs == nil {
+		return nil
+	}
+	var args []string
+	for _, spec := range hs.specs {
+		args = append(args, "schedule", spec)
+	}
+	if hs.hasActive {
+		args = append(args, "active", hs.sfrom, hs.sto)
+	}
+	return args
+}
+
+// equals returns true when both schedules have the same arguments.
+func (hs *hookSchedule) equals(other *hookSchedule) bool {
+	a, b := hs.args(), other.args()
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// appendJSON appends the schedule and whether it's active at the time. A
+// hook without a schedule has an empty one and is always active.
+func (hs *hookSchedule) appendJSON(dst []byte, t time.Time) []byte {
+	dst = append(dst, `,"schedule":[`...)
+	if hs == nil {
+		dst = append(dst, `],"state":`...)
+		return appendJSONString(dst, hs.state(t))
+	}
+	for i, spec := range hs.specs {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendJSONString(dst, spec)
+	}
+	dst = append(dst, ']')
+	if hs.hasActive {
+		dst = append(dst, `,"active":{"from":`...)
+		dst = appendJSONString(dst, hs.sfrom)
+		dst = append(dst, `,"to":`...)
+		dst = appendJSONString(dst, hs.sto)
+		dst = append(dst, '}')
+	}
+	dst = append(dst, `,"state":`...)
+	dst = appendJSONString(dst, hs.state(t))
+	return dst
+}
+
+// state returns "active" or "inactive" for the time.
+func (hs *hookSchedule) state(t time.Time) string {
+	if hs.active(t) {
+		return "active"
+	}
+	return "inactive"
+}
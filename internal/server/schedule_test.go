@@ -0,0 +1,84 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleWindow(t *testing.T) {
+	utc := func(day, hour, min int) time.Time {
+		return time.Date(2026, 10, day, hour, min, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		spec string
+		t    time.Time
+		in   bool
+	}{
+		// in October, Chicago is five hours behind UTC
+		{"Mon-Fri 08:00-18:00 America/Chicago", utc(16, 13, 0), true},
+		{"Mon-Fri 08:00-18:00 America/Chicago", utc(16, 12, 59), false},
+		{"Mon-Fri 08:00-18:00 America/Chicago", utc(16, 23, 0), false},
+		{"Mon-Fri 08:00-18:00 America/Chicago", utc(17, 15, 0), false},
+		{"Mon-Fri 08:00-18:00 America/Chicago", utc(19, 22, 59), true},
+		// overnight windows belong to the day that they start on
+		{"Fri 22:00-06:00", utc(16, 23, 0), true},
+		{"Fri 22:00-06:00", utc(17, 5, 59), true},
+		{"Fri 22:00-06:00", utc(17, 6, 0), false},
+		{"Fri 22:00-06:00", utc(17, 23, 0), false},
+		{"Fri 22:00-06:00", utc(16, 5, 0), false},
+		{"Fri-Mon 00:00-24:00", utc(18, 12, 0), true},
+		{"Fri-Mon 00:00-24:00", utc(20, 12, 0), false},
+		{"sat,SUN 10:00-11:00", utc(18, 10, 30), true},
+		{"* 10:00-11:00", utc(20, 10, 30), true},
+	}
+	for _, c := range cases {
+		w, err := parseScheduleWindow(c.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", c.spec, err)
+		}
+		if w.contains(c.t) != c.in {
+			t.Fatalf("%s: expected %v at %v", c.spec, c.in, c.t)
+		}
+	}
+	for _, spec := range []string{
+		"Mon-Fri", "Mon-Fri 8-18", "Funday 08:00-18:00", "Mon 08:00-08:00",
+		"Mon 08:00-25:00", "Mon 08:00-18:00 Mars/Base", "Mon 08:00-18:00 UTC x",
+	} {
+		if _, err := parseScheduleWindow(spec); err == nil {
+			t.Fatalf("%s: expected an error", spec)
+		}
+	}
+}
+
+func TestHookScheduleActive(t *testing.T) {
+	var hs *hookSchedule
+	if !hs.active(time.Now()) {
+		t.Fatal("expected a hook without a schedule to be active")
+	}
+	hs = &hookSchedule{}
+	if err := hs.setActive("2026-10-01T00:00:00Z", "1792800000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := hs.addSpec("Mon-Fri 08:00-18:00"); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		t      time.Time
+		active bool
+	}{
+		{time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), false},
+		{time.Unix(1792800000, 0), false},
+	} {
+		if hs.active(c.t) != c.active {
+			t.Fatalf("expected %v at %v", c.active, c.t)
+		}
+	}
+	if err := hs.setActive("0", "1"); err == nil {
+		t.Fatal("expected a duplicate error")
+	}
+	if err := (&hookSchedule{}).setActive("10", "5"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,418 @@
+package server
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/resp"
+	"github.com/tidwall/tile38/internal/field"
+	"github.com/tidwall/tile38/internal/glob"
+	"github.com/tidwall/tile38/internal/object"
+)
+
+// schemaError is returned when a write does not conform to the schema of
+// its collection.
+type schemaError struct {
+	msg string
+}
+
+func (err schemaError) Error() string {
+	return "schema: " + err.msg
+}
+
+// schemaField is a single field declaration in a collection schema.
+type schemaField struct {
+	name     string
+	kind     string // "number", "string", "bool" or "json"
+	required bool
+	hasMin   bool
+	min      float64
+	hasMax   bool
+	max      float64
+}
+
+// schema is an optional set of field rules that are attached to a key.
+type schema struct {
+	key    string
+	fields []schemaField
+}
+
+func schemaKindMatch(kind string, value field.Value) bool {
+	switch kind {
+	case "number":
+		return value.Kind() == field.Number
+	case "string":
+		return value.Kind() == field.String
+	case "bool":
+		return value.Kind() == field.True || value.Kind() == field.False
+	case "json":
+		return value.Kind() == field.JSON
+	}
+	return false
+}
+
+// validate checks that the fields conform to the schema. A field that is
+// missing is only checked for REQUIRED. As a zero number is never stored, a
+// number field that is missing is zero when the write has it. It also counts
+// as REQUIRED when the object exists already, which it was written with.
+func (sc *schema) validate(fields field.List, written []field.Field,
+	exists bool,
+) error {
+	for _, sf := range sc.fields {
+		f := fields.Get(sf.name)
+		value := f.Value()
+		if f.Name() == "" {
+			if sf.kind != "number" || !hasField(written, sf.name) {
+				if sf.required && !(exists && sf.kind == "number") {
+					return schemaError{"missing required field '" +
+						sf.name + "'"}
+				}
+				continue
+			}
+			value = field.ZeroValue
+		}
+		if !schemaKindMatch(sf.kind, value) {
+			return schemaError{"field '" + sf.name + "' must be a " + sf.kind}
+		}
+		if sf.hasMin && value.Num() < sf.min {
+			return schemaError{"field '" + sf.name + "' is less than " +
+				strconv.FormatFloat(sf.min, 'f', -1, 64)}
+		}
+		if sf.hasMax && value.Num() > sf.max {
+			return schemaError{"field '" + sf.name + "' is greater than " +
+				strconv.FormatFloat(sf.max, 'f', -1, 64)}
+		}
+	}
+	return nil
+}
+
+// args returns the schema in the same form as the SETSCHEMA arguments.
+func (sc *schema) args() []string {
+	var args []string
+	for _, sf := range sc.fields {
+		args = append(args, "field", sf.name, sf.kind)
+		if sf.required {
+			args = append(args, "required")
+		}
+		if sf.hasMin {
+			args = append(args, "min",
+				strconv.FormatFloat(sf.min, 'f', -1, 64))
+		}
+		if sf.hasMax {
+			args = append(args, "max",
+				strconv.FormatFloat(sf.max, 'f', -1, 64))
+		}
+	}
+	return args
+}
+
+func (sc *schema) appendJSON(dst []byte) []byte {
+	dst = append(dst, `{"key":`...)
+	dst = appendJSONString(dst, sc.key)
+	dst = append(dst, `,"fields":[`...)
+	for i, sf := range sc.fields {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `{"name":`...)
+		dst = appendJSONString(dst, sf.name)
+		dst = append(dst, `,"kind":`...)
+		dst = appendJSONString(dst, sf.kind)
+		dst = append(dst, `,"required":`...)
+		dst = strconv.AppendBool(dst, sf.required)
+		if sf.hasMin {
+			dst = append(dst, `,"min":`...)
+			dst = strconv.AppendFloat(dst, sf.min, 'f', -1, 64)
+		}
+		if sf.hasMax {
+			dst = append(dst, `,"max":`...)
+			dst = strconv.AppendFloat(dst, sf.max, 'f', -1, 64)
+		}
+		dst = append(dst, '}')
+	}
+	dst = append(dst, `]}`...)
+	return dst
+}
+
+// parseSchemaFields parses one or more field declarations.
+// FIELD name kind [REQUIRED] [MIN value] [MAX value] ...
+func parseSchemaFields(vs []string) ([]schemaField, error) {
+	var fields []schemaField
+	seen := make(map[string]bool)
+	for len(vs) > 0 {
+		var tok string
+		vs, tok, _ = tokenval(vs)
+		if strings.ToLower(tok) != "field" {
+			return nil, errInvalidArgument(tok)
+		}
+		var sf schemaField
+		var kind string
+		var ok bool
+		if vs, sf.name, ok = tokenval(vs); !ok || sf.name == "" {
+			return nil, errInvalidNumberOfArguments
+		}
+		if isReservedFieldName(sf.name) {
+			return nil, errInvalidArgument(sf.name)
+		}
+		if seen[sf.name] {
+			return nil, errDuplicateArgument(sf.name)
+		}
+		seen[sf.name] = true
+		if vs, kind, ok = tokenval(vs); !ok || kind == "" {
+			return nil, errInvalidNumberOfArguments
+		}
+		sf.kind = strings.ToLower(kind)
+		switch sf.kind {
+		case "number", "string", "bool", "json":
+		default:
+			return nil, errInvalidArgument(kind)
+		}
+	options:
+		for len(vs) > 0 {
+			switch strings.ToLower(vs[0]) {
+			case "required":
+				if sf.required {
+					return nil, errDuplicateArgument(vs[0])
+				}
+				sf.required = true
+				vs = vs[1:]
+			case "min", "max":
+				if sf.kind != "number" {
+					return nil, errInvalidArgument(vs[0])
+				}
+				opt := strings.ToLower(vs[0])
+				var sval string
+				if vs, sval, ok = tokenval(vs[1:]); !ok || sval == "" {
+					return nil, errInvalidNumberOfArguments
+				}
+				val, err := strconv.ParseFloat(sval, 64)
+				if err != nil {
+					return nil, errInvalidArgument(sval)
+				}
+				if opt == "min" {
+					sf.hasMin, sf.min = true, val
+				} else {
+					sf.hasMax, sf.max = true, val
+				}
+			default:
+				break options
+			}
+		}
+		if sf.hasMin && sf.hasMax && sf.min > sf.max {
+			return nil, errInvalidArgument(
+				strconv.FormatFloat(sf.max, 'f', -1, 64))
+		}
+		fields = append(fields, sf)
+	}
+	if len(fields) == 0 {
+		return nil, errInvalidNumberOfArguments
+	}
+	return fields, nil
+}
+
+// validateSchema checks that the fields conform to the schema of the
+// collection at key, if any. The written fields are the ones of the write.
+func (s *Server) validateSchema(db *database, key string, fields field.List,
+	written []field.Field, exists bool,
+) error {
+	sc, ok := db.schemas.Get(key)
+	if !ok {
+		return nil
+	}
+	return sc.validate(fields, written, exists)
+}
+
+// SETSCHEMA key FIELD name kind [REQUIRED] [MIN value] [MAX value] ...
+func (s *Server) cmdSETSCHEMA(msg *Message) (resp.Value, commandDetails, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) < 5 {
+		return retwerr(errInvalidNumberOfArguments)
+	}
+	key := args[1]
+	fields, err := parseSchemaFields(args[2:])
+	if err != nil {
+		return retwerr(err)
+	}
+
+	// >> Operation
+
+	s.selectDB(msg.DB).schemas.Set(key, &schema{key: key, fields: fields})
+
+	// >> Response
+
+	var d commandDetails
+	d.command = "setschema"
+	d.key = key
+	d.updated = true
+	d.timestamp = time.Now()
+
+	return OKMessage(msg, start), d, nil
+}
+
+// DELSCHEMA key
+func (s *Server) cmdDELSCHEMA(msg *Message) (resp.Value, commandDetails, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) != 2 {
+		return retwerr(errInvalidNumberOfArguments)
+	}
+	key := args[1]
+
+	// >> Operation
+
+	_, deleted := s.getDB(msg.DB).schemas.Delete(key)
+
+	// >> Response
+
+	var d commandDetails
+	d.command = "delschema"
+	d.key = key
+	d.updated = deleted
+	d.timestamp = time.Now()
+
+	var res resp.Value
+	switch msg.OutputType {
+	case JSON:
+		res = OKMessage(msg, start)
+	case RESP:
+		if deleted {
+			res = resp.IntegerValue(1)
+		} else {
+			res = resp.IntegerValue(0)
+		}
+	}
+	return res, d, nil
+}
+
+// SCHEMAS pattern
+func (s *Server) cmdSCHEMAS(msg *Message) (resp.Value, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) != 2 {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	pattern := args[1]
+
+	// >> Operation
+
+	var schemas []*schema
+	g := glob.Parse(pattern, false)
+	s.getDB(msg.DB).schemas.Ascend(g.Limits[0], func(key string, sc *schema) bool {
+		if g.Limits[1] != "" && key > g.Limits[1] {
+			return false
+		}
+		if match, _ := glob.Match(pattern, key); match {
+			schemas = append(schemas, sc)
+		}
+		return true
+	})
+
+	// >> Response
+
+	if msg.OutputType == JSON {
+		buf := []byte(`{"ok":true,"schemas":[`)
+		for i, sc := range schemas {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = sc.appendJSON(buf)
+		}
+		buf = append(buf, `],"elapsed":"`+time.Since(start).String()+`"}`...)
+		return resp.StringValue(string(buf)), nil
+	}
+	vals := make([]resp.Value, 0, len(schemas))
+	for _, sc := range schemas {
+		var fvals []resp.Value
+		for _, arg := range sc.args() {
+			fvals = append(fvals, resp.StringValue(arg))
+		}
+		vals = append(vals, resp.ArrayValue([]resp.Value{
+			resp.StringValue(sc.key),
+			resp.ArrayValue(fvals),
+		}))
+	}
+	return resp.ArrayValue(vals), nil
+}
+
+// VALIDATE key [FIELD name kind [REQUIRED] [MIN value] [MAX value] ...]
+func (s *Server) cmdVALIDATE(msg *Message) (resp.Value, error) {
+	start := time.Now()
+
+	// >> Args
+
+	args := msg.Args
+	if len(args) < 2 {
+		return retrerr(errInvalidNumberOfArguments)
+	}
+	key := args[1]
+	db := s.getDB(msg.DB)
+	var sc *schema
+	if len(args) > 2 {
+		fields, err := parseSchemaFields(args[2:])
+		if err != nil {
+			return retrerr(err)
+		}
+		sc = &schema{key: key, fields: fields}
+	} else {
+		sc, _ = db.schemas.Get(key)
+		if sc == nil {
+			return retrerr(clientErrorf("schema not found"))
+		}
+	}
+
+	// >> Operation
+
+	var count int
+	var ids, errs []string
+	col, _ := db.cols.Get(key)
+	if col != nil {
+		col.Scan(false, nil, msg.Deadline, func(o *object.Object) bool {
+			count++
+			if err := sc.validate(o.Fields(), nil, true); err != nil {
+				ids = append(ids, o.ID())
+				errs = append(errs, err.Error())
+			}
+			return true
+		})
+	}
+
+	// >> Response
+
+	if msg.OutputType == JSON {
+		var buf bytes.Buffer
+		buf.WriteString(`{"ok":true,"count":` + strconv.Itoa(count))
+		buf.WriteString(`,"invalid":[`)
+		for i := range ids {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`{"id":` + jsonString(ids[i]) +
+				`,"err":` + jsonString(errs[i]) + `}`)
+		}
+		buf.WriteString(`],"elapsed":"` + time.Since(start).String() + `"}`)
+		return resp.StringValue(buf.String()), nil
+	}
+	vals := make([]resp.Value, 0, len(ids))
+	for i := range ids {
+		vals = append(vals, resp.ArrayValue([]resp.Value{
+			resp.StringValue(ids[i]),
+			resp.StringValue(errs[i]),
+		}))
+	}
+	return resp.ArrayValue([]resp.Value{
+		resp.IntegerValue(count),
+		resp.ArrayValue(vals),
+	}), nil
+}
@@ -14,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/tidwall/geojson"
 	"github.com/tidwall/geojson/geo"
 	"github.com/tidwall/resp"
 	"github.com/tidwall/tile38/internal/log"
@@ -120,8 +121,9 @@ func (pl *lStatePool) New() *lua.LState {
 		}
 	}
 
-	getArgs := func(ls *lua.LState) (evalCmd string, args []string) {
+	getArgs := func(ls *lua.LState) (evalCmd, evalDB string, args []string) {
 		evalCmd = ls.GetGlobal("EVAL_CMD").String()
+		evalDB = ls.GetGlobal("EVAL_DB").String()
 
 		// Trying to work with unknown number of args.
 		// When we see empty arg we call it enough.
@@ -135,9 +137,9 @@ func (pl *lStatePool) New() *lua.LState {
 		return
 	}
 	call := func(ls *lua.LState) int {
-		evalCmd, args := getArgs(ls)
+		evalCmd, evalDB, args := getArgs(ls)
 		var numRet int
-		if res, err := pl.s.luaTile38Call(evalCmd, args[0], args[1:]...); err != nil {
+		if res, err := pl.s.luaTile38Call(evalCmd, evalDB, args[0], args[1:]...); err != nil {
 			ls.RaiseError("ERR %s", err.Error())
 			numRet = 0
 		} else {
@@ -147,8 +149,8 @@ func (pl *lStatePool) New() *lua.LState {
 		return numRet
 	}
 	pcall := func(ls *lua.LState) int {
-		evalCmd, args := getArgs(ls)
-		if res, err := pl.s.luaTile38Call(evalCmd, args[0], args[1:]...); err != nil {
+		evalCmd, evalDB, args := getArgs(ls)
+		if res, err := pl.s.luaTile38Call(evalCmd, evalDB, args[0], args[1:]...); err != nil {
 			ls.Push(ConvertToLua(ls, resp.ErrorValue(err)))
 		} else {
 			ls.Push(ConvertToLua(ls, res))
@@ -182,6 +184,34 @@ func (pl *lStatePool) New() *lua.LState {
 		ls.Push(lua.LNumber(dt))
 		return 1
 	}
+	geomopFn := func(ls *lua.LState) int {
+		op := strings.ToLower(ls.ToString(1))
+		nareas, meters := geomopArity(op)
+		if nareas == 0 {
+			ls.RaiseError("ERR %s", errInvalidArgument(ls.ToString(1)))
+			return 0
+		}
+		var areas []geojson.Object
+		for i := 0; i < nareas; i++ {
+			obj, err := geojson.Parse(ls.ToString(2+i), &pl.s.geomParseOpts)
+			if err != nil {
+				ls.RaiseError("ERR %s", err.Error())
+				return 0
+			}
+			areas = append(areas, obj)
+		}
+		var m float64
+		if meters {
+			m = float64(ls.ToNumber(2 + nareas))
+		}
+		res, err := runGeomop(op, areas, m, &pl.s.geomIndexOpts)
+		if err != nil {
+			ls.RaiseError("ERR %s", err.Error())
+			return 0
+		}
+		ls.Push(lua.LString(res.JSON()))
+		return 1
+	}
 	var exports = map[string]lua.LGFunction{
 		"call":         call,
 		"pcall":        pcall,
@@ -189,6 +219,7 @@ func (pl *lStatePool) New() *lua.LState {
 		"status_reply": statusReply,
 		"sha1hex":      sha1hex,
 		"distance_to":  distanceTo,
+		"geomop":       geomopFn,
 	}
 	L.SetGlobal("tile38", L.SetFuncs(L.NewTable(), exports))
 
@@ -474,6 +505,7 @@ func (s *Server) cmdEvalUnified(scriptIsSha bool, msg *Message) (res resp.Value,
 			"ARGV":     argsTbl,
 			"DEADLINE": luaDeadline,
 			"EVAL_CMD": lua.LString(msg.Command()),
+			"EVAL_DB":  lua.LString(msg.DB),
 		})
 
 	compiled, ok := s.luascripts.Get(shaSum)
@@ -504,6 +536,7 @@ func (s *Server) cmdEvalUnified(scriptIsSha bool, msg *Message) (res resp.Value,
 			"ARGV":     lua.LNil,
 			"DEADLINE": lua.LNil,
 			"EVAL_CMD": lua.LNil,
+			"EVAL_DB":  lua.LNil,
 		})
 	if err := luaState.PCall(0, 1, nil); err != nil {
 		if strings.Contains(err.Error(), "context deadline exceeded") {
@@ -686,13 +719,17 @@ func (s *Server) commandInScript(msg *Message) (
 	case "server":
 		res, err = s.cmdSERVER(msg)
 	}
+	if d.updated && d.db == nil {
+		d.db = s.getDB(msg.DB)
+	}
 	s.sendMonitor(err, msg, nil, true)
 	return
 }
 
-func (s *Server) luaTile38Call(evalcmd string, cmd string, args ...string) (resp.Value, error) {
+func (s *Server) luaTile38Call(evalcmd, db string, cmd string, args ...string) (resp.Value, error) {
 	msg := &Message{}
 	msg.OutputType = RESP
+	msg.DB = db
 	msg.Args = append([]string{cmd}, args...)
 
 	if msg.Command() == "timeout" {
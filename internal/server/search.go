@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/golang/geo/s2"
 	"github.com/iwpnd/sectr"
 	"github.com/mmcloughlin/geohash"
 	"github.com/tidwall/geojson"
@@ -16,7 +18,9 @@ import (
 	"github.com/tidwall/tile38/internal/bing"
 	"github.com/tidwall/tile38/internal/buffer"
 	"github.com/tidwall/tile38/internal/clip"
+	"github.com/tidwall/tile38/internal/collection"
 	"github.com/tidwall/tile38/internal/glob"
+	"github.com/tidwall/tile38/internal/h3"
 	"github.com/tidwall/tile38/internal/object"
 )
 
@@ -24,9 +28,14 @@ const defaultCircleSteps = 64
 
 type liveFenceSwitches struct {
 	searchScanBaseTokens
-	obj  geojson.Object
-	cmd  string
-	roam roamSwitches
+	obj    geojson.Object
+	cmd    string
+	roam   roamSwitches
+	zrange *collection.ZRange // altitude range of the area, if any
+	hasZ   bool               // nearby point has an altitude
+	z      float64            // altitude of the nearby point
+
+	corridor *corridor // area around a route, for CORRIDOR
 }
 
 type roamSwitches struct {
@@ -196,7 +205,8 @@ func parseRectArea(ltyp string, vs []string) (nvs []string,
 }
 
 func (s *Server) cmdSearchArgs(
-	fromFenceCmd bool, cmd string, vs []string, types map[string]bool,
+	fromFenceCmd bool, db *database, cmd string, vs []string,
+	types map[string]bool,
 ) (lfs liveFenceSwitches, err error) {
 	var t searchScanBaseTokens
 	if fromFenceCmd {
@@ -206,6 +216,11 @@ func (s *Server) cmdSearchArgs(
 	if err != nil {
 		return
 	}
+	if !fromFenceCmd && (t.dwell != 0 || t.loiter != 0) {
+		// the time spent in a fence is tracked per hook
+		err = errors.New("DWELL and LOITER are only allowed for hooks")
+		return
+	}
 	lfs.searchScanBaseTokens = t
 	var typ string
 	var ok bool
@@ -234,7 +249,7 @@ func (s *Server) cmdSearchArgs(
 		return
 	}
 	switch ltyp {
-	case "point":
+	case "point", "pointz":
 		var slat, slon, smeters string
 		if vs, slat, ok = tokenval(vs); !ok || slat == "" {
 			err = errInvalidNumberOfArguments
@@ -253,6 +268,19 @@ func (s *Server) cmdSearchArgs(
 			err = errInvalidArgument(slon)
 			return
 		}
+		if ltyp == "pointz" {
+			// the point has an altitude
+			var sz string
+			if vs, sz, ok = tokenval(vs); !ok || sz == "" {
+				err = errInvalidNumberOfArguments
+				return
+			}
+			if lfs.z, err = strconv.ParseFloat(sz, 64); err != nil {
+				err = errInvalidArgument(sz)
+				return
+			}
+			lfs.hasZ = true
+		}
 		// radius is optional for nearby, but mandatory for others
 		if cmd == "nearby" {
 			if vs, smeters, ok = tokenval(vs); ok && smeters != "" {
@@ -304,10 +332,6 @@ func (s *Server) cmdSearchArgs(
 		}
 		lfs.obj = geojson.NewCircle(geometry.Point{X: lon, Y: lat}, meters, defaultCircleSteps)
 	case "object":
-		if lfs.clip {
-			err = errInvalidArgument("cannot clip with object")
-			return
-		}
 		var obj string
 		if vs, obj, ok = tokenval(vs); !ok || obj == "" {
 			err = errInvalidNumberOfArguments
@@ -317,6 +341,10 @@ func (s *Server) cmdSearchArgs(
 		if err != nil {
 			return
 		}
+		if lfs.clip && !clip.Polygonal(lfs.obj) {
+			err = errInvalidArgument("cannot clip with object")
+			return
+		}
 	case "sector":
 		if lfs.clip {
 			err = errInvalidArgument("cannot clip with " + ltyp)
@@ -387,10 +415,29 @@ func (s *Server) cmdSearchArgs(
 			lfs.mvt = true
 			lfs.clip = true
 		}
-	case "get":
-		if lfs.clip {
-			err = errInvalidArgument("cannot clip with get")
+	case "h3":
+		var scell string
+		if vs, scell, ok = tokenval(vs); !ok || scell == "" {
+			err = errInvalidNumberOfArguments
+			return
+		}
+		var cell h3.Cell
+		if cell, err = parseH3Cell(scell); err != nil {
+			return
+		}
+		lfs.obj = h3CellPolygon(cell, &s.geomIndexOpts)
+	case "s2":
+		var stoken string
+		if vs, stoken, ok = tokenval(vs); !ok || stoken == "" {
+			err = errInvalidNumberOfArguments
+			return
 		}
+		var id s2.CellID
+		if id, err = parseS2Cell(stoken); err != nil {
+			return
+		}
+		lfs.obj = s2CellPolygon(id, &s.geomIndexOpts)
+	case "get":
 		var key, id string
 		if vs, key, ok = tokenval(vs); !ok || key == "" {
 			err = errInvalidNumberOfArguments
@@ -400,7 +447,7 @@ func (s *Server) cmdSearchArgs(
 			err = errInvalidNumberOfArguments
 			return
 		}
-		col, _ := s.cols.Get(key)
+		col, _ := db.cols.Get(key)
 		if col == nil {
 			err = errKeyNotFound
 			return
@@ -410,7 +457,76 @@ func (s *Server) cmdSearchArgs(
 			err = errIDNotFound
 			return
 		}
+		if lfs.clip && !clip.Polygonal(o.Geo()) {
+			err = errInvalidArgument("cannot clip with get")
+			return
+		}
 		lfs.obj = o.Geo()
+	case "corridor":
+		if lfs.clip {
+			err = errInvalidArgument("cannot clip with " + ltyp)
+			return
+		}
+		if lfs.usparse {
+			err = errors.New("SPARSE is not allowed with CORRIDOR")
+			return
+		}
+		var route geojson.Object
+		var styp string
+		if vs, styp, ok = tokenval(vs); !ok || styp == "" {
+			err = errInvalidNumberOfArguments
+			return
+		}
+		switch strings.ToLower(styp) {
+		case "object":
+			var obj string
+			if vs, obj, ok = tokenval(vs); !ok || obj == "" {
+				err = errInvalidNumberOfArguments
+				return
+			}
+			if route, err = geojson.Parse(obj, &s.geomParseOpts); err != nil {
+				return
+			}
+		case "get":
+			var key, id string
+			if vs, key, ok = tokenval(vs); !ok || key == "" {
+				err = errInvalidNumberOfArguments
+				return
+			}
+			if vs, id, ok = tokenval(vs); !ok || id == "" {
+				err = errInvalidNumberOfArguments
+				return
+			}
+			col, _ := db.cols.Get(key)
+			if col == nil {
+				err = errKeyNotFound
+				return
+			}
+			o := col.Get(id)
+			if o == nil {
+				err = errIDNotFound
+				return
+			}
+			route = o.Geo()
+		default:
+			err = errInvalidArgument(styp)
+			return
+		}
+		var smeters string
+		if vs, smeters, ok = tokenval(vs); !ok || smeters == "" {
+			err = errInvalidNumberOfArguments
+			return
+		}
+		var meters float64
+		if meters, err = strconv.ParseFloat(smeters, 64); err != nil ||
+			meters < 0 || math.IsInf(meters, 0) {
+			err = errInvalidArgument(smeters)
+			return
+		}
+		if lfs.corridor, err = newCorridor(route, meters); err != nil {
+			return
+		}
+		lfs.obj = geojson.NewRect(lfs.corridor.rect())
 	case "roam":
 		lfs.roam.on = true
 		if vs, lfs.roam.key, ok = tokenval(vs); !ok || lfs.roam.key == "" {
@@ -452,7 +568,35 @@ func (s *Server) cmdSearchArgs(
 			err = errInvalidNumberOfArguments
 			return
 		}
-		if strings.ToLower(tok) != "clipby" {
+		switch strings.ToLower(tok) {
+		case "zrange":
+			if lfs.zrange != nil {
+				err = errDuplicateArgument(strings.ToUpper(tok))
+				return
+			}
+			var smin, smax string
+			if vs, smin, ok = tokenval(vs); !ok || smin == "" {
+				err = errInvalidNumberOfArguments
+				return
+			}
+			if vs, smax, ok = tokenval(vs); !ok || smax == "" {
+				err = errInvalidNumberOfArguments
+				return
+			}
+			var zr collection.ZRange
+			if zr.Min, err = strconv.ParseFloat(smin, 64); err != nil {
+				err = errInvalidArgument(smin)
+				return
+			}
+			if zr.Max, err = strconv.ParseFloat(smax, 64); err != nil ||
+				zr.Max < zr.Min {
+				err = errInvalidArgument(smax)
+				return
+			}
+			lfs.zrange = &zr
+			continue
+		case "clipby":
+		default:
 			err = errInvalidNumberOfArguments
 			return
 		}
@@ -479,7 +623,16 @@ func (s *Server) cmdSearchArgs(
 		}
 	}
 
-	if lfs.hasbuffer {
+	if lfs.roam.on && (lfs.dwell != 0 || lfs.loiter != 0) {
+		err = errors.New("DWELL and LOITER are not allowed for ROAM")
+		return
+	}
+
+	if lfs.hasbuffer && lfs.corridor != nil {
+		// the buffer widens the corridor
+		lfs.corridor.meters += lfs.buffer
+		lfs.obj = geojson.NewRect(lfs.corridor.rect())
+	} else if lfs.hasbuffer {
 		lfs.obj, err = buffer.Simple(lfs.obj, lfs.buffer)
 		if err != nil {
 			return
@@ -489,20 +642,25 @@ func (s *Server) cmdSearchArgs(
 	return
 }
 
+func isFloat(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
 var nearbyTypes = map[string]bool{
-	"point": true,
+	"point": true, "pointz": true,
 }
 var withinOrIntersectsTypes = map[string]bool{
 	"geo": true, "bounds": true, "hash": true, "tile": true, "quadkey": true,
 	"get": true, "object": true, "circle": true, "point": true, "sector": true,
-	"mvt": true,
+	"mvt": true, "h3": true, "s2": true, "corridor": true,
 }
 
 func (s *Server) cmdNearby(msg *Message) (res resp.Value, err error) {
 	start := time.Now()
 	vs := msg.Args[1:]
 	wr := &bytes.Buffer{}
-	sargs, err := s.cmdSearchArgs(false, "nearby", vs, nearbyTypes)
+	sargs, err := s.cmdSearchArgs(false, s.getDB(msg.DB), "nearby", vs, nearbyTypes)
 	if sargs.usingLua() {
 		defer sargs.Close()
 		defer func() {
@@ -520,11 +678,7 @@ func (s *Server) cmdNearby(msg *Message) (res resp.Value, err error) {
 	if sargs.fence {
 		return NOMessage, sargs
 	}
-	sw, err := s.newScanWriter(
-		wr, msg, sargs.key, sargs.output, sargs.precision, sargs.globs, false,
-		sargs.cursor, sargs.limit, sargs.wheres, sargs.whereins,
-		sargs.whereevals, sargs.nofields,
-		sargs.mvt, sargs.tileX, sargs.tileY, sargs.tileZ)
+	sw, err := s.newScanWriter(wr, msg, sargs.searchScanBaseTokens, false)
 	if err != nil {
 		return NOMessage, err
 	}
@@ -533,19 +687,28 @@ func (s *Server) cmdNearby(msg *Message) (res resp.Value, err error) {
 	}
 	var ierr error
 	if sw.col != nil {
-		iterStep := func(o *object.Object, dist float64) bool {
-			keepGoing, err := sw.pushObject(ScanWriterParams{
+		push := func(params ScanWriterParams) bool {
+			keepGoing, err := sw.pushObject(params)
+			if err != nil {
+				ierr = err
+				return false
+			}
+			return keepGoing
+		}
+		ties := cursorTies{push: push}
+		iterStep := func(o *object.Object, dist, pos float64) bool {
+			params := ScanWriterParams{
 				obj:             o,
 				dist:            dist,
 				distOutput:      sargs.distance,
+				pos:             pos,
 				ignoreGlobMatch: true,
 				skipTesting:     true,
-			})
-			if err != nil {
-				ierr = err
-				return false
 			}
-			return keepGoing
+			if sargs.token == nil {
+				return push(params)
+			}
+			return ties.add(params)
 		}
 		maxDist := sargs.obj.(*geojson.Circle).Meters()
 		if sargs.sparse > 0 {
@@ -557,24 +720,59 @@ func (s *Server) cmdNearby(msg *Message) (res resp.Value, err error) {
 			// An intersects operation is required for SPARSE
 			iter := func(o *object.Object) bool {
 				var dist float64
-				if sargs.distance {
+				if sargs.distance || sargs.hasZ {
 					dist = o.Geo().Distance(sargs.obj)
 				}
-				return iterStep(o, dist)
+				if sargs.hasZ {
+					dist = collection.Distance3D(dist, sargs.z,
+						collection.Z(o.Geo()))
+					if dist > maxDist {
+						return true
+					}
+				}
+				if !sargs.distance {
+					dist = 0
+				}
+				return iterStep(o, dist, dist)
+			}
+			sw.explain.setAccess("rtree sparse")
+			if sargs.zrange != nil {
+				sw.col.IntersectsZ(sargs.obj, *sargs.zrange, sargs.sparse, sw,
+					msg.Deadline, iter)
+			} else {
+				sw.col.Intersects(sargs.obj, sargs.sparse, sw, msg.Deadline,
+					iter)
 			}
-			sw.col.Intersects(sargs.obj, sargs.sparse, sw, msg.Deadline, iter)
 		} else {
 			iter := func(o *object.Object, dist float64) bool {
 				if maxDist > 0 && dist > maxDist {
 					return false
 				}
+				if sargs.zrange != nil &&
+					!sargs.zrange.Contains(collection.Z(o.Geo())) {
+					return true
+				}
 				var meters float64
 				if sargs.distance {
 					meters = dist
 				}
-				return iterStep(o, meters)
+				return iterStep(o, meters, dist)
 			}
-			sw.col.Nearby(sargs.obj, sw, msg.Deadline, iter)
+			switch {
+			case sargs.hasZ:
+				// the 3D distances don't follow the spatial index, so the
+				// results before a CURSOR token are skipped
+				sw.explain.setAccess("rtree nearest 3d")
+				sw.col.NearbyZ(sargs.obj, sargs.z, sw, msg.Deadline, iter)
+			case sargs.token != nil:
+				sw.explain.setAccess("rtree nearest from cursor")
+				sw.col.NearbyFrom(sargs.obj, sargs.token.from(), sw,
+					msg.Deadline, iter)
+			default:
+				sw.explain.setAccess("rtree nearest")
+				sw.col.Nearby(sargs.obj, sw, msg.Deadline, iter)
+			}
+			ties.flush()
 		}
 	}
 	if ierr != nil {
@@ -601,7 +799,7 @@ func (s *Server) cmdWITHINorINTERSECTS(cmd string, msg *Message) (res resp.Value
 	vs := msg.Args[1:]
 
 	wr := &bytes.Buffer{}
-	sargs, err := s.cmdSearchArgs(false, cmd, vs, withinOrIntersectsTypes)
+	sargs, err := s.cmdSearchArgs(false, s.getDB(msg.DB), cmd, vs, withinOrIntersectsTypes)
 	if sargs.usingLua() {
 		defer sargs.Close()
 		defer func() {
@@ -619,11 +817,7 @@ func (s *Server) cmdWITHINorINTERSECTS(cmd string, msg *Message) (res resp.Value
 	if sargs.fence {
 		return NOMessage, sargs
 	}
-	sw, err := s.newScanWriter(
-		wr, msg, sargs.key, sargs.output, sargs.precision, sargs.globs, false,
-		sargs.cursor, sargs.limit, sargs.wheres, sargs.whereins,
-		sargs.whereevals, sargs.nofields,
-		sargs.mvt, sargs.tileX, sargs.tileY, sargs.tileZ)
+	sw, err := s.newScanWriter(wr, msg, sargs.searchScanBaseTokens, false)
 	if err != nil {
 		return NOMessage, err
 	}
@@ -631,34 +825,122 @@ func (s *Server) cmdWITHINorINTERSECTS(cmd string, msg *Message) (res resp.Value
 		wr.WriteString(`{"ok":true`)
 	}
 	var ierr error
-	if sw.col != nil {
+	if sw.col != nil && sargs.token != nil {
+		// With a CURSOR token, the results are in the order of their west
+		// edges and then of their ids, and the search continues from the
+		// west edge of the token.
+		ties := cursorTies{push: func(params ScanWriterParams) bool {
+			keepGoing, err := sw.pushObject(params)
+			if err != nil {
+				ierr = err
+				return false
+			}
+			return keepGoing
+		}}
+		iter := func(o *object.Object, west float64) bool {
+			if sargs.zrange != nil &&
+				!sargs.zrange.Contains(collection.Z(o.Geo())) {
+				return true
+			}
+			params := ScanWriterParams{obj: o, pos: west}
+			switch {
+			case sargs.corridor != nil:
+				dist, frac, ok := sargs.corridor.match(o.Geo(),
+					cmd == "within")
+				if !ok {
+					return true
+				}
+				params.dist, params.distOutput = dist, true
+				params.frac, params.fracOutput = frac, true
+			case cmd == "within":
+				if !o.Geo().Within(sargs.obj) {
+					return true
+				}
+			default:
+				if !o.Geo().Intersects(sargs.obj) {
+					return true
+				}
+				if sargs.clip {
+					params.clip = sargs.obj
+				}
+			}
+			return ties.add(params)
+		}
+		sw.explain.setAccess("rtree from cursor")
+		sw.col.SearchFrom(sargs.obj.Rect(), sargs.token.from(), sw,
+			msg.Deadline, iter)
+		ties.flush()
+	} else if sw.col != nil && sargs.corridor != nil {
+		iter := func(o *object.Object) bool {
+			dist, frac, ok := sargs.corridor.match(o.Geo(), cmd == "within")
+			if !ok {
+				return true
+			}
+			keepGoing, err := sw.pushObject(ScanWriterParams{
+				obj:        o,
+				dist:       dist,
+				distOutput: true,
+				frac:       frac,
+				fracOutput: true,
+			})
+			if err != nil {
+				ierr = err
+				return false
+			}
+			return keepGoing
+		}
+		sw.explain.setAccess("rtree corridor")
+		if sargs.zrange != nil {
+			sw.col.IntersectsZ(sargs.obj, *sargs.zrange, 0, sw,
+				msg.Deadline, iter)
+		} else {
+			sw.col.Intersects(sargs.obj, 0, sw, msg.Deadline, iter)
+		}
+	} else if sw.col != nil {
+		switch {
+		case sargs.sparse > 0:
+			sw.explain.setAccess("rtree sparse")
+		case sargs.zrange != nil:
+			sw.explain.setAccess("rtree with z range")
+		default:
+			sw.explain.setAccess("rtree")
+		}
 		switch cmd {
 		case "within":
-			sw.col.Within(sargs.obj, sargs.sparse, sw, msg.Deadline,
-				func(o *object.Object) bool {
-					keepGoing, err := sw.pushObject(ScanWriterParams{obj: o})
-					if err != nil {
-						ierr = err
-						return false
-					}
-					return keepGoing
-				},
-			)
+			iter := func(o *object.Object) bool {
+				keepGoing, err := sw.pushObject(ScanWriterParams{obj: o})
+				if err != nil {
+					ierr = err
+					return false
+				}
+				return keepGoing
+			}
+			if sargs.zrange != nil {
+				sw.col.WithinZ(sargs.obj, *sargs.zrange, sargs.sparse, sw,
+					msg.Deadline, iter)
+			} else {
+				sw.col.Within(sargs.obj, sargs.sparse, sw, msg.Deadline, iter)
+			}
 		case "intersects":
-			sw.col.Intersects(sargs.obj, sargs.sparse, sw, msg.Deadline,
-				func(o *object.Object) bool {
-					params := ScanWriterParams{obj: o}
-					if sargs.clip {
-						params.clip = sargs.obj
-					}
-					keepGoing, err := sw.pushObject(params)
-					if err != nil {
-						ierr = err
-						return false
-					}
-					return keepGoing
-				},
-			)
+			iter := func(o *object.Object) bool {
+				params := ScanWriterParams{obj: o}
+				if sargs.clip {
+					params.clip = sargs.obj
+				}
+				keepGoing, err := sw.pushObject(params)
+				if err != nil {
+					ierr = err
+					return false
+				}
+				return keepGoing
+			}
+			if sargs.zrange != nil {
+				sw.col.IntersectsZ(sargs.obj, *sargs.zrange, sargs.sparse, sw,
+					msg.Deadline, iter)
+			} else {
+				sw.col.Intersects(sargs.obj, sargs.sparse, sw, msg.Deadline,
+					iter)
+			}
 		}
 	}
 	if ierr != nil {
@@ -735,11 +1017,7 @@ func (s *Server) cmdSearch(msg *Message) (res resp.Value, err error) {
 	if err != nil {
 		return NOMessage, err
 	}
-	sw, err := s.newScanWriter(
-		wr, msg, sargs.key, sargs.output, sargs.precision, sargs.globs, true,
-		sargs.cursor, sargs.limit, sargs.wheres, sargs.whereins,
-		sargs.whereevals, sargs.nofields,
-		sargs.mvt, sargs.tileX, sargs.tileY, sargs.tileZ)
+	sw, err := s.newScanWriter(wr, msg, sargs.searchScanBaseTokens, true)
 	if err != nil {
 		return NOMessage, err
 	}
@@ -748,43 +1026,47 @@ func (s *Server) cmdSearch(msg *Message) (res resp.Value, err error) {
 	}
 	var ierr error
 	if sw.col != nil {
-		if sw.output == outputCount && len(sw.wheres) == 0 && sw.globEverything {
+		if sw.output == outputCount && len(sw.wheres) == 0 &&
+			sw.globEverything && sargs.token == nil {
+			sw.explain.setAccess("count")
 			count := sw.col.Count() - int(sargs.cursor)
 			if count < 0 {
 				count = 0
 			}
 			sw.count = uint64(count)
 		} else {
+			iter := func(o *object.Object) bool {
+				keepGoing, err := sw.pushObject(ScanWriterParams{
+					obj: o,
+				})
+				if err != nil {
+					ierr = err
+					return false
+				}
+				return keepGoing
+			}
 			limits := multiGlobParse(sw.globs, sargs.desc)
-			if limits[0] == "" && limits[1] == "" {
-				sw.col.SearchValues(sargs.desc, sw, msg.Deadline,
-					func(o *object.Object) bool {
-						keepGoing, err := sw.pushObject(ScanWriterParams{
-							obj: o,
-						})
-						if err != nil {
-							ierr = err
-							return false
-						}
-						return keepGoing
-					},
-				)
+			if sargs.token != nil && sargs.token.pos {
+				// continue from the last value of the previous page
+				value, id := sargs.token.value, sargs.token.id
+				sw.explain.setAccess("value scan from cursor")
+				if limits[0] != "" || limits[1] != "" {
+					if sargs.desc && value >= limits[0] ||
+						!sargs.desc && value < limits[0] {
+						value, id = limits[0], ""
+					}
+				}
+				sw.col.SearchValuesFrom(value, id, limits[1], sargs.desc, sw,
+					msg.Deadline, iter)
+			} else if limits[0] == "" && limits[1] == "" {
+				sw.explain.setAccess("value scan")
+				sw.col.SearchValues(sargs.desc, sw, msg.Deadline, iter)
 			} else {
+				sw.explain.setAccess("value range scan")
 				// must disable globSingle for string value type matching because
 				// globSingle is only for ID matches, not values.
 				sw.col.SearchValuesRange(limits[0], limits[1], sargs.desc, sw,
-					msg.Deadline,
-					func(o *object.Object) bool {
-						keepGoing, err := sw.pushObject(ScanWriterParams{
-							obj: o,
-						})
-						if err != nil {
-							ierr = err
-							return false
-						}
-						return keepGoing
-					},
-				)
+					msg.Deadline, iter)
 			}
 		}
 	}
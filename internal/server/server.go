@@ -31,9 +31,7 @@ import (
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/redcon"
 	"github.com/tidwall/resp"
-	"github.com/tidwall/rtree"
 	"github.com/tidwall/tile38/core"
-	"github.com/tidwall/tile38/internal/collection"
 	"github.com/tidwall/tile38/internal/deadline"
 	"github.com/tidwall/tile38/internal/endpoint"
 	"github.com/tidwall/tile38/internal/log"
@@ -48,16 +46,19 @@ func errTimeoutOnCmd(cmd string) error {
 }
 
 const (
-	goingLive     = "going live"
-	hookLogPrefix = "hook:log:"
+	goingLive       = "going live"
+	hookLogPrefix   = "hook:log:"
+	hookDwellPrefix = "hook:dwell:"
+	hookDeadPrefix  = "hook:dead:"
 )
 
 // commandDetails is detailed information about a mutable command. It's used
 // for geofence formulas.
 type commandDetails struct {
-	command string // client command, like "SET" or "DEL"
-	key     string // collection key
-	newKey  string // new key, for RENAME command
+	command string    // client command, like "SET" or "DEL"
+	key     string    // collection key
+	newKey  string    // new key, for RENAME command
+	db      *database // database of the collection
 
 	obj *object.Object // target object
 	old *object.Object // previous object, if any
@@ -67,6 +68,7 @@ type commandDetails struct {
 	parent    bool              // when true, only children are forwarded
 	pattern   string            // PDEL key pattern
 	children  []*commandDetails // for multi actions such as "PDEL"
+	timer     bool              // from a dwell timer, not from a command
 }
 
 type rwlocker interface {
@@ -200,18 +202,13 @@ type Server struct {
 	shrinklog [][]string  // aof shrinking log
 
 	// database
-	qdb  *buntdb.DB // hook queue log
-	qidx uint64     // hook queue log last idx
+	qdb      *buntdb.DB // hook queue log
+	qidx     uint64     // hook queue log last idx
+	qpending hookQueue  // events in the queue log of each hook
 
-	cols *btree.Map[string, *collection.Collection] // data collections
-
-	hooks        *btree.BTree // hook name -- [string]*Hook
-	hookCross    *rtree.RTree // hook spatial tree for "cross" geofences
-	hookTree     *rtree.RTree // hook spatial tree for all
-	hooksOut     *btree.BTree // hooks with "outside" detection -- [string]*Hook
-	groupHooks   *btree.BTree // hooks that are connected to objects
-	groupObjects *btree.BTree // objects that are connected to hooks
-	hookExpires  *btree.BTree // queue of all hooks marked for expiration
+	dbs         *btree.Map[string, *database] // logical databases
+	aofdb       string                        // database of the last aof entry
+	hookExpires *btree.BTree                  // queue of all hooks marked for expiration
 
 	// followers (external aof readers)
 	follows   map[*bytes.Buffer]bool
@@ -304,33 +301,29 @@ func Serve(opts Options) error {
 
 	// Initialize the s
 	s := &Server{
-		mu:        lock,
-		unix:      opts.UnixSocketPath,
-		host:      opts.Host,
-		port:      opts.Port,
-		dir:       opts.Dir,
-		follows:   make(map[*bytes.Buffer]bool),
-		fcond:     sync.NewCond(&sync.Mutex{}),
-		lives:     make(map[*liveBuffer]bool),
-		lcond:     sync.NewCond(&sync.Mutex{}),
-		hooks:     btree.NewNonConcurrent(byHookName),
-		hooksOut:  btree.NewNonConcurrent(byHookName),
-		hookCross: &rtree.RTree{},
-		hookTree:  &rtree.RTree{},
-		aofconnM:  make(map[net.Conn]io.Closer),
-		started:   time.Now(),
-		conns:     make(map[int]*Client),
-		http:      opts.UseHTTP,
-		pubsub:    newPubsub(),
-		pubq:      pubQueue{cond: sync.NewCond(&sync.Mutex{})},
-		monconns:  make(map[net.Conn]bool),
-		cols:      &btree.Map[string, *collection.Collection]{},
-
-		groupHooks:   btree.NewNonConcurrent(byGroupHook),
-		groupObjects: btree.NewNonConcurrent(byGroupObject),
-		hookExpires:  btree.NewNonConcurrent(byHookExpires),
-		opts:         opts,
-	}
+		mu:       lock,
+		unix:     opts.UnixSocketPath,
+		host:     opts.Host,
+		port:     opts.Port,
+		dir:      opts.Dir,
+		follows:  make(map[*bytes.Buffer]bool),
+		fcond:    sync.NewCond(&sync.Mutex{}),
+		lives:    make(map[*liveBuffer]bool),
+		lcond:    sync.NewCond(&sync.Mutex{}),
+		aofconnM: make(map[net.Conn]io.Closer),
+		started:  time.Now(),
+		conns:    make(map[int]*Client),
+		http:     opts.UseHTTP,
+		pubsub:   newPubsub(),
+		pubq:     pubQueue{cond: sync.NewCond(&sync.Mutex{})},
+		monconns: make(map[net.Conn]bool),
+		dbs:      &btree.Map[string, *database]{},
+		aofdb:    defaultDB,
+
+		hookExpires: btree.NewNonConcurrent(byHookExpires),
+		opts:        opts,
+	}
+	s.selectDB(defaultDB)
 	s.epool = newExprPool(s)
 	s.epc = endpoint.NewManager(s)
 	defer s.epc.Shutdown()
@@ -452,6 +445,31 @@ func Serve(opts Options) error {
 	if err != nil {
 		return err
 	}
+	err = qdb.CreateIndex("dwells", hookDwellPrefix+"*", buntdb.IndexJSON("due"))
+	if err != nil {
+		return err
+	}
+	err = qdb.CreateIndex("deadletters", hookDeadPrefix+"*", buntdb.IndexJSONCaseSensitive("hook"))
+	if err != nil {
+		return err
+	}
+	// count the events that are in the queue log, and the ones that expire
+	if err := qdb.View(func(tx *buntdb.Tx) error {
+		return tx.Ascend("hooks", func(key, val string) bool {
+			s.qpending.add(val, 1)
+			return true
+		})
+	}); err != nil {
+		return err
+	}
+	var qconfig buntdb.Config
+	if err := qdb.ReadConfig(&qconfig); err != nil {
+		return err
+	}
+	qconfig.OnExpiredSync = s.onQueueExpired
+	if err := qdb.SetConfig(qconfig); err != nil {
+		return err
+	}
 
 	s.qdb = qdb
 	s.qidx = qidx
@@ -693,6 +711,7 @@ func (s *Server) netServe() error {
 							msg.OutputType = defaultOutputType
 						}
 						msg.StrictRESP = client.strictRESP
+						msg.DB = client.db
 						if msg.Command() == "quit" {
 							if msg.OutputType == RESP {
 								io.WriteString(client, "+OK\r\n")
@@ -1182,7 +1201,8 @@ func (s *Server) handleInputCommand(client *Client, msg *Message) error {
 	case "set", "del", "drop", "fset", "flushdb",
 		"setchan", "pdelchan", "delchan",
 		"sethook", "pdelhook", "delhook",
-		"expire", "persist", "jset", "pdel", "rename", "renamenx":
+		"expire", "persist", "jset", "pdel", "rename", "renamenx",
+		"setschema", "delschema", "swapdb", "setindex", "setmotion":
 		// write operations
 		write = true
 		s.mu.Lock()
@@ -1203,9 +1223,27 @@ func (s *Server) handleInputCommand(client *Client, msg *Message) error {
 		if s.config.readOnly() {
 			return writeErr("read only")
 		}
+	case "geomop":
+		if geomopStores(msg.Args) {
+			// a write operation, but the aof gets a SET of the result
+			// instead of the command itself
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.config.followHost() != "" {
+				return writeErr("not the leader")
+			}
+			if s.config.readOnly() {
+				return writeErr("read only")
+			}
+		} else {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+		}
 	case "get", "keys", "scan", "nearby", "within", "intersects", "hooks",
 		"chans", "search", "ttl", "bounds", "server", "info", "type", "jget",
-		"evalro", "evalrosha", "role", "fget", "exists", "fexists":
+		"evalro", "evalrosha", "role", "fget", "exists", "fexists",
+		"schemas", "validate", "cover", "join", "explain", "distance",
+		"hookstats":
 		// read operations
 		s.mu.RLock()
 		defer s.mu.RUnlock()
@@ -1217,7 +1255,8 @@ func (s *Server) handleInputCommand(client *Client, msg *Message) error {
 		if s.config.followHost() != "" && !s.caughtUpOnce() {
 			return writeErr("catching up to leader")
 		}
-	case "follow", "slaveof", "replconf", "readonly", "config":
+	case "follow", "slaveof", "replconf", "readonly", "config", "select",
+		"deadletter":
 		// system operations
 		// does not write to aof, but requires a write lock.
 		s.mu.Lock()
@@ -1317,7 +1356,14 @@ func randomKey(n int) string {
 
 func (s *Server) reset() {
 	s.aofsz = 0
-	s.cols.Clear()
+	s.aofdb = defaultDB
+	s.dbs.Scan(func(name string, db *database) bool {
+		db.cols.Clear()
+		db.schemas.Clear()
+		db.motions.Clear()
+		db.index3d.Clear()
+		return true
+	})
 }
 
 func (s *Server) command(msg *Message, client *Client) (
@@ -1358,6 +1404,26 @@ func (s *Server) command(msg *Message, client *Client) (
 		res, d, err = s.cmdPDelHook(msg)
 	case "chans":
 		res, err = s.cmdHooks(msg)
+	case "hookstats":
+		res, err = s.cmdHOOKSTATS(msg)
+	case "deadletter list", "deadletter replay", "deadletter purge":
+		res, err = s.cmdDEADLETTER(msg)
+	case "setschema":
+		res, d, err = s.cmdSETSCHEMA(msg)
+	case "delschema":
+		res, d, err = s.cmdDELSCHEMA(msg)
+	case "schemas":
+		res, err = s.cmdSCHEMAS(msg)
+	case "validate":
+		res, err = s.cmdVALIDATE(msg)
+	case "setindex":
+		res, d, err = s.cmdSETINDEX(msg)
+	case "setmotion":
+		res, d, err = s.cmdSETMOTION(msg)
+	case "select":
+		res, err = s.cmdSELECT(msg, client)
+	case "swapdb":
+		res, d, err = s.cmdSWAPDB(msg)
 	case "expire":
 		res, d, err = s.cmdEXPIRE(msg)
 	case "persist":
@@ -1447,7 +1513,7 @@ func (s *Server) command(msg *Message, client *Client) (
 		res, err = s.cmdConfigSet(msg)
 	case "config rewrite":
 		res, err = s.cmdConfigRewrite(msg)
-	case "config", "script":
+	case "config", "script", "deadletter":
 		// These get rewritten into "config foo" and "script bar"
 		err = fmt.Errorf("unknown command '%s'", msg.Args[0])
 		if len(msg.Args) > 1 {
@@ -1476,10 +1542,23 @@ func (s *Server) command(msg *Message, client *Client) (
 		res, err = s.cmdPublish(msg)
 	case "test":
 		res, err = s.cmdTEST(msg)
+	case "cover":
+		res, err = s.cmdCOVER(msg)
+	case "join":
+		res, err = s.cmdJOIN(msg)
+	case "explain":
+		res, err = s.cmdEXPLAIN(msg)
+	case "distance":
+		res, err = s.cmdDISTANCE(msg)
+	case "geomop":
+		res, err = s.cmdGEOMOP(msg)
 	case "monitor":
 		res, err = s.cmdMonitor(msg)
 	}
 
+	if d.updated && d.db == nil {
+		d.db = s.getDB(msg.DB)
+	}
 	s.sendMonitor(err, msg, client, false)
 	return
 }
@@ -1567,6 +1646,9 @@ type Message struct {
 	Auth           string
 	AcceptEncoding string
 	Deadline       *deadline.Deadline
+	DB             string        // selected database, empty for the default
+	explain        *explainStats // set when the command is run by EXPLAIN
+	replay         bool          // a command of the aof or of the leader
 }
 
 // Command returns the first argument as a lowercase string
@@ -62,13 +62,19 @@ func (s *Server) cmdSTATS(msg *Message) (resp.Value, error) {
 	var ms = []map[string]interface{}{}
 	for i := 1; i < len(args); i++ {
 		key := args[i]
-		col, _ := s.cols.Get(key)
+		col, _ := s.getDB(msg.DB).cols.Get(key)
 		if col != nil {
 			m := make(map[string]interface{})
 			m["num_points"] = col.PointCount()
 			m["in_memory_size"] = col.TotalWeight()
 			m["num_objects"] = col.Count()
 			m["num_strings"] = col.StringCount()
+			if col.Index3D() {
+				m["index_dims"] = 3
+			}
+			if s.getDB(msg.DB).motions.Contains(key) {
+				m["motion"] = true
+			}
 			switch msg.OutputType {
 			case JSON:
 				ms = append(ms, m)
@@ -173,10 +179,10 @@ func (s *Server) basicStats(m map[string]interface{}) {
 	m["http_transport"] = s.http
 	m["pid"] = os.Getpid()
 	m["aof_size"] = s.aofsz
-	m["num_collections"] = s.cols.Len()
-	m["num_hooks"] = s.hooks.Len()
+	m["num_collections"] = s.numCols()
+	m["num_hooks"] = s.numHooks()
 	sz := 0
-	s.cols.Scan(func(key string, col *collection.Collection) bool {
+	s.scanCols(func(_, key string, col *collection.Collection) bool {
 		sz += col.TotalWeight()
 		return true
 	})
@@ -184,7 +190,7 @@ func (s *Server) basicStats(m map[string]interface{}) {
 	points := 0
 	objects := 0
 	nstrings := 0
-	s.cols.Scan(func(key string, col *collection.Collection) bool {
+	s.scanCols(func(_, key string, col *collection.Collection) bool {
 		points += col.PointCount()
 		objects += col.Count()
 		nstrings += col.StringCount()
@@ -209,18 +215,29 @@ func (s *Server) basicStats(m map[string]interface{}) {
 	m["cpus"] = runtime.NumCPU()
 	n, _ := runtime.ThreadCreateProfile(nil)
 	m["threads"] = float64(n)
-	var nevents int
+	var nevents, ndead int
 	s.qdb.View(func(tx *buntdb.Tx) error {
 		// All entries in the buntdb log are events, except for one, which
-		// is "hook:idx".
+		// is "hook:idx", the dwell times of objects in fences, and the
+		// dead letters.
 		nevents, _ = tx.Len()
 		nevents -= 1 // Ignore the "hook:idx"
+		tx.Ascend("dwells", func(key, val string) bool {
+			nevents--
+			return true
+		})
+		tx.Ascend("deadletters", func(key, val string) bool {
+			ndead++
+			return true
+		})
+		nevents -= ndead
 		if nevents < 0 {
 			nevents = 0
 		}
 		return nil
 	})
 	m["pending_events"] = nevents
+	m["dead_letters"] = ndead
 }
 
 // extStats populates the passed map with extended system/go/tile38 statistics
@@ -347,7 +364,7 @@ func (s *Server) extStats(m map[string]interface{}) {
 	points := 0
 	objects := 0
 	strings := 0
-	s.cols.Scan(func(key string, col *collection.Collection) bool {
+	s.scanCols(func(_, key string, col *collection.Collection) bool {
 		points += col.PointCount()
 		objects += col.Count()
 		strings += col.StringCount()
@@ -361,13 +378,18 @@ func (s *Server) extStats(m map[string]interface{}) {
 	// Number of string in the database
 	m["tile38_num_strings"] = strings
 	// Number of collections in the database
-	m["tile38_num_collections"] = s.cols.Len()
+	m["tile38_num_collections"] = s.numCols()
 	// Number of hooks in the database
-	m["tile38_num_hooks"] = s.hooks.Len()
-	// Number of hook groups in the database
-	m["tile38_num_hook_groups"] = s.groupHooks.Len()
-	// Number of object groups in the database
-	m["tile38_num_object_groups"] = s.groupObjects.Len()
+	m["tile38_num_hooks"] = s.numHooks()
+	// Number of hook and object groups in the database
+	var hookGroups, objectGroups int
+	s.dbs.Scan(func(_ string, db *database) bool {
+		hookGroups += db.groupHooks.Len()
+		objectGroups += db.groupObjects.Len()
+		return true
+	})
+	m["tile38_num_hook_groups"] = hookGroups
+	m["tile38_num_object_groups"] = objectGroups
 
 	avgsz := 0
 	if points != 0 {
@@ -378,7 +400,7 @@ func (s *Server) extStats(m map[string]interface{}) {
 	m["tile38_avg_point_size"] = avgsz
 
 	sz := 0
-	s.cols.Scan(func(key string, col *collection.Collection) bool {
+	s.scanCols(func(_, key string, col *collection.Collection) bool {
 		sz += col.TotalWeight()
 		return true
 	})
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// hookTemplate renders the messages of a hook into a custom payload, from
+// TEMPLATE '{"vehicle":{{id}},"lat":{{object.coordinates.1}}}'.
+//
+// A {{path}} is replaced by the JSON value at the gjson path of the message,
+// or null when the message has no such value. A {{{path}}} is replaced by the
+// value as plain text, which for a string is without the quotes. The text is
+// escaped as the inside of a JSON string, so it can't break out of the quotes
+// that a template puts around it.
+type hookTemplate struct {
+	src   string
+	parts []templatePart
+}
+
+type templatePart struct {
+	text string // the literal text, or the path
+	path bool
+	raw  bool // {{{path}}}
+}
+
+// parseHookTemplate parses a TEMPLATE value.
+func parseHookTemplate(src string) (*hookTemplate, error) {
+	t := &hookTemplate{src: src}
+	s := src
+	for len(s) > 0 {
+		i := strings.Index(s, "{{")
+		if i == -1 {
+			t.parts = append(t.parts, templatePart{text: s})
+			break
+		}
+		if i > 0 {
+			t.parts = append(t.parts, templatePart{text: s[:i]})
+		}
+		s = s[i+2:]
+		open, close := "{{", "}}"
+		raw := strings.HasPrefix(s, "{")
+		if raw {
+			s = s[1:]
+			open, close = "{{{", "}}}"
+		}
+		j := strings.Index(s, close)
+		if j == -1 {
+			return nil, errors.New("template has an unclosed '" + open + "'")
+		}
+		path := strings.TrimSpace(s[:j])
+		if path == "" {
+			return nil, errors.New("template has an empty '" + open + close + "'")
+		}
+		t.parts = append(t.parts, templatePart{text: path, path: true, raw: raw})
+		s = s[j+len(close):]
+	}
+	return t, nil
+}
+
+// render returns the payload of a message.
+func (t *hookTemplate) render(msg string) string {
+	var b []byte
+	for _, part := range t.parts {
+		if !part.path {
+			b = append(b, part.text...)
+			continue
+		}
+		res := gjson.Get(msg, part.text)
+		switch {
+		case !res.Exists():
+			if !part.raw {
+				b = append(b, "null"...)
+			}
+		case part.raw:
+			q := appendJSONString(nil, res.String())
+			b = append(b, q[1:len(q)-1]...)
+		default:
+			b = append(b, res.Raw...)
+		}
+	}
+	return string(b)
+}
+
+// rendersJSON returns true when the template renders JSON, as it does for a
+// message without any of its paths.
+func (t *hookTemplate) rendersJSON() bool {
+	return gjson.Valid(t.render("{}"))
+}
+
+// payload returns what the hook sends for a message, which is the message
+// itself when the hook has no template.
+func (h *Hook) payload(msg string) string {
+	if h.template == nil {
+		return msg
+	}
+	return h.template.render(msg)
+}
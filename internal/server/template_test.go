@@ -0,0 +1,70 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestHookTemplate(t *testing.T) {
+	msg := `{"command":"set","detect":"enter","hook":"h1","key":"fleet",` +
+		`"id":"truck1","meta":{"team":"north"},` +
+		`"object":{"type":"Point","coordinates":[-115,33]},` +
+		`"fields":{"speed":42}}`
+	cases := []struct {
+		src, out string
+	}{
+		{`{"vehicle":{{id}},"lat":{{object.coordinates.1}}}`,
+			`{"vehicle":"truck1","lat":33}`},
+		{`{{{id}}} is {{{detect}}} at {{{fields.speed}}} for {{{meta.team}}}`,
+			`truck1 is enter at 42 for north`},
+		{`{"missing":{{nearby.meters}},"text":"{{{nope}}}"}`,
+			`{"missing":null,"text":""}`},
+		{`{"pos":{{ object.coordinates }}}`, `{"pos":[-115,33]}`},
+		{`plain`, `plain`},
+	}
+	for _, c := range cases {
+		tmpl, err := parseHookTemplate(c.src)
+		if err != nil {
+			t.Fatalf("%s: %v", c.src, err)
+		}
+		if out := tmpl.render(msg); out != c.out {
+			t.Fatalf("%s: expected '%s', got '%s'", c.src, c.out, out)
+		}
+	}
+	for _, src := range []string{`{{id`, `{{{id}}`, `{{}}`, `{{{ }}}`} {
+		if _, err := parseHookTemplate(src); err == nil {
+			t.Fatalf("%s: expected an error", src)
+		}
+	}
+
+	// a batch needs a template that renders json
+	for src, ok := range map[string]bool{
+		`{"vehicle":{{id}},"event":"{{{detect}}}"}`: true,
+		`{{object}}`:                   true,
+		`{{{id}}} entered`:             false,
+		`{"speed":{{{fields.speed}}}}`: false,
+	} {
+		tmpl, err := parseHookTemplate(src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if tmpl.rendersJSON() != ok {
+			t.Fatalf("%s: expected %v", src, ok)
+		}
+	}
+
+	// the raw values are escaped for a JSON string
+	tmpl, err := parseHookTemplate(`{"text":"{{{id}}} at {{{object}}}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = `{"id":"tr\"uck\\1\n","object":{"type":"Point"}}`
+	out := tmpl.render(msg)
+	if out != `{"text":"tr\"uck\\1\n at {\"type\":\"Point\"}"}` {
+		t.Fatalf("unexpected '%s'", out)
+	}
+	if gjson.Get(out, "text").String() != "tr\"uck\\1\n at {\"type\":\"Point\"}" {
+		t.Fatalf("invalid json '%s'", out)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/golang/geo/s2"
 	"github.com/iwpnd/sectr"
 	"github.com/mmcloughlin/geohash"
 	"github.com/tidwall/geojson"
@@ -16,9 +17,10 @@ import (
 	"github.com/tidwall/resp"
 	"github.com/tidwall/tile38/internal/bing"
 	"github.com/tidwall/tile38/internal/clip"
+	"github.com/tidwall/tile38/internal/h3"
 )
 
-func (s *Server) parseArea(ovs []string, doClip bool) (vs []string, o geojson.Object, err error) {
+func (s *Server) parseArea(db *database, ovs []string, doClip bool) (vs []string, o geojson.Object, err error) {
 	var ok bool
 	var typ string
 	vs = ovs[:]
@@ -146,10 +148,6 @@ func (s *Server) parseArea(ovs []string, doClip bool) (vs []string, o geojson.Ob
 		}
 		o = geojson.NewCircle(geometry.Point{X: lon, Y: lat}, meters, defaultCircleSteps)
 	case "object":
-		if doClip {
-			err = fmt.Errorf("invalid clip type '%s'", typ)
-			return
-		}
 		var obj string
 		if vs, obj, ok = tokenval(vs); !ok || obj == "" {
 			err = errInvalidNumberOfArguments
@@ -159,6 +157,10 @@ func (s *Server) parseArea(ovs []string, doClip bool) (vs []string, o geojson.Ob
 		if err != nil {
 			return
 		}
+		if doClip && !clip.Polygonal(o) {
+			err = fmt.Errorf("invalid clip type '%s'", typ)
+			return
+		}
 	case "bounds":
 		var sminLat, sminLon, smaxlat, smaxlon string
 		if vs, sminLat, ok = tokenval(vs); !ok || sminLat == "" {
@@ -209,6 +211,28 @@ func (s *Server) parseArea(ovs []string, doClip bool) (vs []string, o geojson.Ob
 			Min: geometry.Point{X: box.MinLng, Y: box.MinLat},
 			Max: geometry.Point{X: box.MaxLng, Y: box.MaxLat},
 		})
+	case "h3":
+		var scell string
+		if vs, scell, ok = tokenval(vs); !ok || scell == "" {
+			err = errInvalidNumberOfArguments
+			return
+		}
+		var cell h3.Cell
+		if cell, err = parseH3Cell(scell); err != nil {
+			return
+		}
+		o = h3CellPolygon(cell, &s.geomIndexOpts)
+	case "s2":
+		var stoken string
+		if vs, stoken, ok = tokenval(vs); !ok || stoken == "" {
+			err = errInvalidNumberOfArguments
+			return
+		}
+		var id s2.CellID
+		if id, err = parseS2Cell(stoken); err != nil {
+			return
+		}
+		o = s2CellPolygon(id, &s.geomIndexOpts)
 	case "quadkey":
 		var key string
 		if vs, key, ok = tokenval(vs); !ok || key == "" {
@@ -260,10 +284,6 @@ func (s *Server) parseArea(ovs []string, doClip bool) (vs []string, o geojson.Ob
 			Max: geometry.Point{X: maxLon, Y: maxLat},
 		})
 	case "get":
-		if doClip {
-			err = fmt.Errorf("invalid clip type '%s'", typ)
-			return
-		}
 		var key, id string
 		if vs, key, ok = tokenval(vs); !ok || key == "" {
 			err = errInvalidNumberOfArguments
@@ -273,7 +293,7 @@ func (s *Server) parseArea(ovs []string, doClip bool) (vs []string, o geojson.Ob
 			err = errInvalidNumberOfArguments
 			return
 		}
-		col, _ := s.cols.Get(key)
+		col, _ := db.cols.Get(key)
 		if col == nil {
 			err = errKeyNotFound
 			return
@@ -284,26 +304,31 @@ func (s *Server) parseArea(ovs []string, doClip bool) (vs []string, o geojson.Ob
 			return
 		}
 		o = obj.Geo()
+		if doClip && !clip.Polygonal(o) {
+			err = fmt.Errorf("invalid clip type '%s'", typ)
+			return
+		}
 	}
 	return
 }
 
 // TEST (POINT lat lon)|(GET key id)|(BOUNDS minlat minlon maxlat maxlon)|
 // (OBJECT geojson)|(CIRCLE lat lon meters)|(TILE x y z)|(QUADKEY quadkey)|
-// (HASH geohash) INTERSECTS|WITHIN [CLIP] (POINT lat lon)|(GET key id)|
-// (BOUNDS minlat minlon maxlat maxlon)|(OBJECT geojson)|
-// (CIRCLE lat lon meters)|(TILE x y z)|(QUADKEY quadkey)|(HASH geohash)|
-// (SECTOR lat lon meters bearing1 bearing2)
+// (HASH geohash)|(H3 cell)|(S2 token) INTERSECTS|WITHIN [CLIP]
+// (POINT lat lon)|(GET key id)|(BOUNDS minlat minlon maxlat maxlon)|
+// (OBJECT geojson)|(CIRCLE lat lon meters)|(TILE x y z)|(QUADKEY quadkey)|
+// (HASH geohash)|(H3 cell)|(S2 token)|(SECTOR lat lon meters bearing1 bearing2)
 func (s *Server) cmdTEST(msg *Message) (res resp.Value, err error) {
 	start := time.Now()
 
 	vs := msg.Args[1:]
+	db := s.getDB(msg.DB)
 
 	var ok bool
 	var test string
 	var clipped geojson.Object
 	var area1, area2 *areaExpression
-	if vs, area1, err = s.parseAreaExpression(vs, false); err != nil {
+	if vs, area1, err = s.parseAreaExpression(db, vs, false); err != nil {
 		return
 	}
 	if vs, test, ok = tokenval(vs); !ok || test == "" {
@@ -330,7 +355,7 @@ func (s *Server) cmdTEST(msg *Message) (res resp.Value, err error) {
 			doClip = true
 		}
 	}
-	if vs, area2, err = s.parseAreaExpression(vs, doClip); err != nil {
+	if vs, area2, err = s.parseAreaExpression(db, vs, doClip); err != nil {
 		return
 	}
 	if doClip && (area1.obj == nil || area2.obj == nil) {
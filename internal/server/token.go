@@ -22,6 +22,8 @@ var errIDAlreadyExists = errors.New("id already exists")
 var errPathNotFound = errors.New("path not found")
 var errKeyHasHooksSet = errors.New("key has hooks set")
 var errKeyHasChannelsSet = errors.New("key has channels set")
+var errDatabaseHasHooksSet = errors.New("database has hooks set")
+var errDatabaseHasChannelsSet = errors.New("database has channels set")
 var errNotRectangle = errors.New("not a rectangle")
 
 func errInvalidArgument(arg string) error {
@@ -236,13 +238,23 @@ type searchScanBaseTokens struct {
 	fence      bool
 	distance   bool
 	nodwell    bool
+	dwell      float64 // seconds inside a fence before a dwell
+	loiter     float64 // seconds within loiterM meters before a loiter
+	loiterM    float64
 	detect     map[string]bool
 	accept     map[string]bool
 	globs      []string
 	wheres     []whereT
 	whereins   []whereinT
 	whereevals []whereevalT
+	agg        *aggregator
+	cluster    *clusterer
+	order      *orderBy
+	token      *cursorToken
+	fields     *projection
 	nofields   bool
+	area       bool
+	length     bool
 	ulimit     bool
 	limit      uint64
 	usparse    bool
@@ -482,6 +494,24 @@ func (s *Server) parseSearchScanBaseTokens(
 					return
 				}
 				continue
+			case "orderby":
+				if t.order != nil {
+					err = errDuplicateArgument(strings.ToUpper(wtok))
+					return
+				}
+				if vs, t.order, err = parseOrderBy(nvs); err != nil {
+					return
+				}
+				continue
+			case "fields":
+				if t.fields != nil {
+					err = errDuplicateArgument(strings.ToUpper(wtok))
+					return
+				}
+				if vs, t.fields, err = parseProjection(nvs); err != nil {
+					return
+				}
+				continue
 			case "sparse":
 				vs = nvs
 				if ssparse != "" {
@@ -533,6 +563,22 @@ func (s *Server) parseSearchScanBaseTokens(
 				}
 				t.distance = true
 				continue
+			case "area":
+				vs = nvs
+				if t.area {
+					err = errDuplicateArgument(strings.ToUpper(wtok))
+					return
+				}
+				t.area = true
+				continue
+			case "length":
+				vs = nvs
+				if t.length {
+					err = errDuplicateArgument(strings.ToUpper(wtok))
+					return
+				}
+				t.length = true
+				continue
 			case "detect":
 				vs = nvs
 				if t.detect != nil {
@@ -551,7 +597,8 @@ func (s *Server) parseSearchScanBaseTokens(
 					default:
 						err = errInvalidArgument(peek)
 						return
-					case "inside", "outside", "enter", "exit", "cross":
+					case "inside", "outside", "enter", "exit", "cross",
+						"dwell", "loiter":
 					}
 					if t.detect[part] {
 						err = errDuplicateArgument(s)
@@ -569,6 +616,43 @@ func (s *Server) parseSearchScanBaseTokens(
 					}
 				}
 				continue
+			case "dwell":
+				vs = nvs
+				if t.dwell != 0 {
+					err = errDuplicateArgument(strings.ToUpper(wtok))
+					return
+				}
+				var sdwell string
+				if vs, sdwell, ok = tokenval(vs); !ok || sdwell == "" {
+					err = errInvalidNumberOfArguments
+					return
+				}
+				if t.dwell, err = parsePositiveFloat(sdwell); err != nil {
+					return
+				}
+				continue
+			case "loiter":
+				vs = nvs
+				if t.loiter != 0 {
+					err = errDuplicateArgument(strings.ToUpper(wtok))
+					return
+				}
+				var sloiter, smeters string
+				if vs, sloiter, ok = tokenval(vs); !ok || sloiter == "" {
+					err = errInvalidNumberOfArguments
+					return
+				}
+				if vs, smeters, ok = tokenval(vs); !ok || smeters == "" {
+					err = errInvalidNumberOfArguments
+					return
+				}
+				if t.loiter, err = parsePositiveFloat(sloiter); err != nil {
+					return
+				}
+				if t.loiterM, err = parsePositiveFloat(smeters); err != nil {
+					return
+				}
+				continue
 			case "nodwell":
 				vs = nvs
 				if t.desc || asc {
@@ -635,11 +719,47 @@ func (s *Server) parseSearchScanBaseTokens(
 			return
 		}
 	}
+	if t.order != nil {
+		if cmd == "search" || cmd == "join" {
+			err = errors.New("ORDERBY is not allowed for " + strings.ToUpper(cmd))
+			return
+		}
+		if ssparse != "" {
+			err = errors.New("ORDERBY is not allowed when SPARSE is specified")
+			return
+		}
+		if t.fence {
+			err = errors.New("ORDERBY is not allowed when FENCE is specified")
+			return
+		}
+	}
+	if t.fields != nil {
+		if cmd == "join" {
+			err = errors.New("FIELDS is not allowed for JOIN")
+			return
+		}
+		if t.nofields {
+			err = errors.New("FIELDS is not allowed when NOFIELDS is specified")
+			return
+		}
+	}
+	if cmd == "join" {
+		if t.area {
+			err = errors.New("AREA is not allowed for JOIN")
+			return
+		}
+		if t.length {
+			err = errors.New("LENGTH is not allowed for JOIN")
+			return
+		}
+	}
 	if ssparse != "" && slimit != "" {
 		err = errors.New("LIMIT is not allowed when SPARSE is specified")
 		return
 	}
 	if scursor != "" && ssparse != "" {
+		// this includes the cursor tokens, which continue from a position
+		// in the spatial index that the sparse results are not ordered by
 		err = errors.New("CURSOR is not allowed when SPARSE is specified")
 		return
 	}
@@ -651,6 +771,22 @@ func (s *Server) parseSearchScanBaseTokens(
 		err = errors.New("DETECT is not allowed when FENCE is not specified")
 		return
 	}
+	if t.dwell != 0 && !t.fence {
+		err = errors.New("DWELL is not allowed when FENCE is not specified")
+		return
+	}
+	if t.loiter != 0 && !t.fence {
+		err = errors.New("LOITER is not allowed when FENCE is not specified")
+		return
+	}
+	if t.detect["dwell"] && t.dwell == 0 {
+		err = errors.New("DETECT dwell requires DWELL")
+		return
+	}
+	if t.detect["loiter"] && t.loiter == 0 {
+		err = errors.New("DETECT loiter requires LOITER")
+		return
+	}
 
 	t.output = defaultSearchOutput
 	var nvs []string
@@ -677,6 +813,68 @@ func (s *Server) parseSearchScanBaseTokens(
 				err = errInvalidNumberOfArguments
 				return
 			}
+		case "h3":
+			// H3 is also an area type. It's only the output when followed
+			// by a resolution.
+			var sres string
+			if nvs, sres, ok = tokenval(nvs); ok {
+				var res int
+				if res, ok = parseH3Resolution(sres); ok {
+					t.output = outputH3
+					t.precision = uint64(res)
+				}
+			}
+			if !ok {
+				if cmd == "scan" {
+					if sres == "" {
+						err = errInvalidNumberOfArguments
+					} else {
+						err = errInvalidArgument(sres)
+					}
+					return
+				}
+				updline = false
+			}
+		case "s2cells":
+			var slevel string
+			if nvs, slevel, ok = tokenval(nvs); !ok || slevel == "" {
+				err = errInvalidNumberOfArguments
+				return
+			}
+			var level int
+			if level, ok = parseS2Level(slevel); !ok {
+				err = errInvalidArgument(slevel)
+				return
+			}
+			t.output = outputS2Cells
+			t.precision = uint64(level)
+		case "aggregate":
+			if t.fence {
+				err = errors.New("AGGREGATE is not allowed when FENCE is specified")
+				return
+			}
+			if nvs, t.agg, err = parseAggregate(nvs); err != nil {
+				return
+			}
+			t.output = outputAggregate
+		case "grid":
+			if t.fence {
+				err = errors.New("GRID is not allowed when FENCE is specified")
+				return
+			}
+			if nvs, t.agg, err = parseGrid(nvs); err != nil {
+				return
+			}
+			t.output = outputGrid
+		case "cluster":
+			if t.fence {
+				err = errors.New("CLUSTER is not allowed when FENCE is specified")
+				return
+			}
+			if nvs, t.cluster, err = parseCluster(nvs); err != nil {
+				return
+			}
+			t.output = outputCluster
 		case "bounds":
 			t.output = outputBounds
 		case "ids":
@@ -687,10 +885,25 @@ func (s *Server) parseSearchScanBaseTokens(
 		}
 	}
 	if scursor != "" {
-		if t.cursor, err = strconv.ParseUint(scursor, 10, 64); err != nil {
-			err = errInvalidArgument(scursor)
+		var isToken bool
+		if t.token, isToken, err = parseCursorToken(cmd, scursor); err != nil {
 			return
 		}
+		if !isToken {
+			if t.cursor, err = strconv.ParseUint(scursor, 10, 64); err != nil {
+				err = errInvalidArgument(scursor)
+				return
+			}
+		}
+	}
+	if t.token != nil {
+		if t.order != nil {
+			err = errors.New("ORDERBY is not allowed with a CURSOR token")
+			return
+		}
+		if cmd == "scan" || cmd == "search" {
+			t.token.desc = t.desc
+		}
 	}
 	if sprecision != "" {
 		t.precision, err = strconv.ParseUint(sprecision, 10, 64)
@@ -722,6 +935,16 @@ func (s *Server) parseSearchScanBaseTokens(
 	return
 }
 
+// parsePositiveFloat parses a number that must be greater than zero, such as
+// the seconds of DWELL.
+func parsePositiveFloat(s string) (float64, error) {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, errInvalidArgument(s)
+	}
+	return n, nil
+}
+
 func detectExprToken(vs []string) bool {
 	// Detect the kind of where, either:
 	// - expr
@@ -761,7 +984,7 @@ func (ps *parentStack) pop() (e *areaExpression, empty bool) {
 	return x, false
 }
 
-func (s *Server) parseAreaExpression(vsin []string, doClip bool) (vsout []string, ae *areaExpression, err error) {
+func (s *Server) parseAreaExpression(db *database, vsin []string, doClip bool) (vsout []string, ae *areaExpression, err error) {
 	ps := &parentStack{}
 	vsout = vsin[:]
 	var negate, needObj bool
@@ -851,8 +1074,8 @@ loop:
 				ae = &areaExpression{op: OR, children: []*areaExpression{ae}}
 			}
 			vsout = nvs
-		case "point", "circle", "object", "bounds", "hash", "quadkey", "tile", "get", "sector":
-			parsedVs, parsedObj, areaErr := s.parseArea(vsout, doClip)
+		case "point", "circle", "object", "bounds", "hash", "quadkey", "tile", "get", "sector", "h3", "s2":
+			parsedVs, parsedObj, areaErr := s.parseArea(db, vsout, doClip)
 			if areaErr != nil {
 				err = areaErr
 				return
@@ -10,10 +10,13 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 
 	_ "embed"
 )
@@ -25,6 +28,10 @@ func subTestAOF(g *testGroup) {
 	g.regSubTest("AOFMD5", aof_AOFMD5_test)
 	g.regSubTest("AOFSHRINK", aof_AOFSHRINK_test)
 	g.regSubTest("READONLY", aof_READONLY_test)
+	g.regSubTest("SELECT", aof_SELECT_test)
+	g.regSubTest("SETINDEX", aof_SETINDEX_test)
+	g.regSubTest("SETMOTION", aof_SETMOTION_test)
+	g.regSubTest("SETHOOK options", aof_SETHOOK_options_test)
 }
 
 func loadAOFAndClose(aof any) error {
@@ -279,11 +286,119 @@ func aof_AOFSHRINK_test(mc *mockServer) error {
 	return err
 }
 
+func aof_SETINDEX_test(mc *mockServer) error {
+	var aof string
+	aof += "SET drones 1 POINT 33 -115 50\r\n"
+	aof += "SETINDEX drones 3d\r\n"
+	aof += "SET drones 2 POINT 33 -115 150\r\n"
+	mc2, err := loadAOF(aof)
+	if err != nil {
+		return err
+	}
+	defer mc2.Close()
+	return mc2.DoBatch(
+		Do("STATS", "drones").JSON().Func(func(s string) error {
+			if gjson.Get(s, "stats.0.index_dims").Int() != 3 {
+				return fmt.Errorf("expected 3d index, got '%s'", s)
+			}
+			return nil
+		}),
+		Do("WITHIN", "drones", "IDS", "BOUNDS", 32, -116, 34, -114,
+			"ZRANGE", 100, 200).Str("[0 [2]]"),
+		Do("AOFSHRINK").OK(),
+	)
+}
+
+func aof_SETMOTION_test(mc *mockServer) error {
+	var aof string
+	aof += "SETMOTION fleet on\r\n"
+	aof += "SET fleet truck1 MOTION 100 2 5 0 0 POINT 33 -115\r\n"
+	// a replayed field with the prefix is loaded as it is
+	aof += "SET fleet truck2 FIELD motion_state 1 POINT 33 -115\r\n"
+	mc2, err := loadAOF(aof)
+	if err != nil {
+		return err
+	}
+	defer mc2.Close()
+	// the replayed fields are not derived again
+	return mc2.DoBatch(
+		Do("GET", "fleet", "truck1", "WITHFIELDS").JSON().Func(func(s string) error {
+			if gjson.Get(s, "fields").String() != `{"motion_dt":2,"motion_speed":5,"motion_ts":100}` {
+				return fmt.Errorf("unexpected fields '%s'", s)
+			}
+			return nil
+		}),
+		Do("GET", "fleet", "truck2", "WITHFIELDS").JSON().Func(func(s string) error {
+			if gjson.Get(s, "fields").String() != `{"motion_state":1}` {
+				return fmt.Errorf("unexpected fields '%s'", s)
+			}
+			return nil
+		}),
+		Do("STATS", "fleet").JSON().Func(func(s string) error {
+			if !gjson.Get(s, "stats.0.motion").Bool() {
+				return fmt.Errorf("expected motion, got '%s'", s)
+			}
+			return nil
+		}),
+		// the shrink runs in the background, and the server must not be
+		// closed before it's done
+		Do("AOFSHRINK").OK(),
+		Sleep(time.Second/2),
+	)
+}
+
+func aof_SETHOOK_options_test(mc *mockServer) error {
+	hooks := func(s string) string {
+		// the stats are not in the aof
+		s, _ = sjson.Delete(s, "hooks.0.stats")
+		return gjson.Get(s, "hooks").Raw
+	}
+	var expected string
+	err := mc.DoBatch(
+		Do("SETHOOK", "h1", "http://127.0.0.1:1/x", "RETRY", 2, "BACKOFF", 1, 4,
+			"TTL", 60, "DEADLETTER", 5, 600, "TEMPLATE", `{"id":{{id}}}`, "BATCH", 10, 0, 1,
+			"SECRET", "s", "BEARER", "t", "HEADER", "X-A", "b",
+			"NEARBY", "fleet", "FENCE", "POINT", 33, -115, 100).Str("1"),
+		Do("HOOKS", "*").JSON().Func(func(s string) error {
+			expected = hooks(s)
+			return nil
+		}),
+		Do("AOFSHRINK").OK(),
+		Sleep(time.Second/2),
+	)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(expected, `"signed":true`) {
+		return fmt.Errorf("unexpected hooks '%s'", expected)
+	}
+	aof, err := mc.readAOF()
+	if err != nil {
+		return err
+	}
+	mc2, err := loadAOF(string(aof))
+	if err != nil {
+		return err
+	}
+	defer mc2.Close()
+	// the shrunk aof has the same hook
+	return mc2.DoBatch(
+		Do("HOOKS", "*").JSON().Func(func(s string) error {
+			if hooks(s) != expected {
+				return fmt.Errorf("expected '%s', got '%s'", expected, hooks(s))
+			}
+			return nil
+		}),
+	)
+}
+
 func aof_READONLY_test(mc *mockServer) error {
 	return mc.DoBatch(
 		Do("SET", "mykey", "myid", "POINT", "10", "10").OK(),
 		Do("READONLY", "yes").OK(),
 		Do("SET", "mykey", "myid", "POINT", "10", "10").Err("read only"),
+		Do("GEOMOP", "CENTROID", "GET", "mykey", "myid").Str(`{"type":"Point","coordinates":[10,10]}`),
+		Do("GEOMOP", "CENTROID", "GET", "mykey", "myid", "STORE", "mykey", "c").Err("read only"),
 		Do("READONLY", "no").OK(),
 		Do("SET", "mykey", "myid", "POINT", "10", "10").OK(),
 		Do("READONLY").Err("wrong number of arguments for 'readonly' command"),
@@ -345,3 +460,26 @@ func aof_migrate_test(mc *mockServer) error {
 
 	return nil
 }
+
+func aof_SELECT_test(mc *mockServer) error {
+	var aof string
+	aof += "SET fleet truck1 POINT 33 -115\r\n"
+	aof += "SELECT staging\r\n"
+	aof += "SET fleet truck2 POINT 34 -116\r\n"
+	aof += "SELECT 0\r\n"
+	aof += "SET fleet truck3 POINT 35 -117\r\n"
+	mc2, err := loadAOF(aof)
+	if err != nil {
+		return err
+	}
+	defer mc2.Close()
+	return mc2.DoBatch(
+		Do("SCAN", "fleet", "IDS").Str("[0 [truck1 truck3]]"),
+		Do("SELECT", "staging").OK(),
+		Do("SCAN", "fleet", "IDS").Str("[0 [truck2]]"),
+		Do("AOFSHRINK").OK(),
+		Do("SET", "fleet", "truck4", "POINT", 36, -118).OK(),
+		Do("SELECT", "0").OK(),
+		Do("SCAN", "fleet", "IDS").Str("[0 [truck1 truck3]]"),
+	)
+}
@@ -2,12 +2,17 @@ package tests
 
 import (
 	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,7 +28,18 @@ func subTestFence(g *testGroup) {
 	// Standard
 	g.regSubTest("basic", fence_basic_test)
 	g.regSubTest("channel message order", fence_channel_message_order_test)
+	g.regSubTest("channel database", fence_channel_database_test)
 	g.regSubTest("detect inside,outside", fence_detect_inside_test)
+	g.regSubTest("detect zrange", fence_detect_zrange_test)
+	g.regSubTest("detect corridor", fence_detect_corridor_test)
+	g.regSubTest("detect fields", fence_detect_fields_test)
+	g.regSubTest("detect dwell", fence_detect_dwell_test)
+	g.regSubTest("schedule", fence_schedule_test)
+	g.regSubTest("dead letters", fence_deadletter_test)
+	g.regSubTest("template", fence_template_test)
+	g.regSubTest("batch", fence_batch_test)
+	g.regSubTest("hook stats", fence_hookstats_test)
+	g.regSubTest("signed", fence_signed_test)
 
 	// Roaming
 	g.regSubTest("roaming live", fence_roaming_live_test)
@@ -249,6 +265,64 @@ func fence_channel_message_order_test(mc *mockServer) error {
 	return <-finalErr
 }
 
+func fence_channel_database_test(mc *mockServer) error {
+	// Subscribe to the same channel in the default database and in db 1
+	sc0, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer sc0.Close()
+	psc0 := redis.PubSubConn{Conn: sc0}
+	if err := psc0.PSubscribe("*"); err != nil {
+		return err
+	}
+	sc1, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer sc1.Close()
+	if _, err := sc1.Do("SELECT", "1"); err != nil {
+		return err
+	}
+	psc1 := redis.PubSubConn{Conn: sc1}
+	if err := psc1.Subscribe("x"); err != nil {
+		return err
+	}
+	for _, psc := range []redis.PubSubConn{psc0, psc1} {
+		if _, ok := psc.ReceiveWithTimeout(time.Second).(redis.Subscription); !ok {
+			return errors.New("expected a subscription")
+		}
+	}
+
+	bc, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer bc.Close()
+	for _, cmd := range []string{
+		"SELECT 1",
+		"SETCHAN x NEARBY fleet FENCE DETECT enter POINT 33 -115 5000",
+		"SET fleet truck POINT 33 -115",
+		"SELECT 0",
+		"PUBLISH x hello",
+	} {
+		if _, err := do(bc, cmd); err != nil {
+			return err
+		}
+	}
+
+	// The fence message only reaches the subscriber of db 1
+	v, ok := psc1.ReceiveWithTimeout(time.Second).(redis.Message)
+	if !ok || v.Channel != "x" || gjson.GetBytes(v.Data, "id").String() != "truck" {
+		return fmt.Errorf("expected the fence message, got '%v'", v)
+	}
+	v, ok = psc0.ReceiveWithTimeout(time.Second).(redis.Message)
+	if !ok || v.Channel != "x" || string(v.Data) != "hello" {
+		return fmt.Errorf("expected 'hello', got '%v'", v)
+	}
+	return nil
+}
+
 func fence_detect_inside_test(mc *mockServer) error {
 	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", mc.port))
 	if err != nil {
@@ -313,6 +387,709 @@ func fence_detect_inside_test(mc *mockServer) error {
 	return nil
 }
 
+func fence_detect_zrange_test(mc *mockServer) error {
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = fmt.Fprintf(conn, "WITHIN drones FENCE DETECT enter,exit BOUNDS 33.618824 -84.457973 33.654359 -84.399859 ZRANGE 0 100\r\n")
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+	res := string(buf[:n])
+	if res != "+OK\r\n" {
+		return fmt.Errorf("expected OK, got '%v'", res)
+	}
+	rd := &fenceReader{conn, bufio.NewReader(conn)}
+
+	c, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// below the ceiling
+	if _, err := c.Do("SET", "drones", "1", "POINT", "33.642301", "-84.43118", "50"); err != nil {
+		return err
+	}
+	if err := rd.receiveExpect("command", "set", "detect", "enter",
+		"key", "drones", "id", "1"); err != nil {
+		return err
+	}
+
+	// climb above the ceiling without moving
+	if _, err := c.Do("SET", "drones", "1", "POINT", "33.642301", "-84.43118", "120"); err != nil {
+		return err
+	}
+	if err := rd.receiveExpect("command", "set", "detect", "exit",
+		"key", "drones", "id", "1"); err != nil {
+		return err
+	}
+
+	// descend back into the fence
+	if _, err := c.Do("SET", "drones", "1", "POINT", "33.642301", "-84.43118", "20"); err != nil {
+		return err
+	}
+	return rd.receiveExpect("command", "set", "detect", "enter",
+		"key", "drones", "id", "1")
+}
+
+func fence_detect_corridor_test(mc *mockServer) error {
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	args := []string{"INTERSECTS", "trucks", "FENCE", "DETECT", "enter,exit",
+		"DISTANCE", "CORRIDOR", "OBJECT",
+		`{"type":"LineString","coordinates":[[0,0],[1,0]]}`, "500"}
+	cmd := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		cmd += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err = conn.Write([]byte(cmd)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+	res := string(buf[:n])
+	if res != "+OK\r\n" {
+		return fmt.Errorf("expected OK, got '%v'", res)
+	}
+	rd := &fenceReader{conn, bufio.NewReader(conn)}
+
+	c, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// halfway along the route
+	if _, err := c.Do("SET", "trucks", "1", "POINT", "0.001", "0.5"); err != nil {
+		return err
+	}
+	if err := rd.receiveExpect("command", "set", "detect", "enter",
+		"key", "trucks", "id", "1", "fraction", "0.5"); err != nil {
+		return err
+	}
+
+	// too far from the route
+	if _, err := c.Do("SET", "trucks", "1", "POINT", "0.01", "0.5"); err != nil {
+		return err
+	}
+	return rd.receiveExpect("command", "set", "detect", "exit",
+		"key", "trucks", "id", "1")
+}
+
+func fence_detect_fields_test(mc *mockServer) error {
+	conn, err := net.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	args := []string{"NEARBY", "trucks", "FENCE", "DETECT", "enter",
+		"FIELDS", "2", "speed", "speed * 3.6", "AS", "kmh",
+		"POINT", "33", "-115", "5000"}
+	cmd := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		cmd += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err = conn.Write([]byte(cmd)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+	res := string(buf[:n])
+	if res != "+OK\r\n" {
+		return fmt.Errorf("expected OK, got '%v'", res)
+	}
+	rd := &fenceReader{conn, bufio.NewReader(conn)}
+
+	c, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// only the projected fields are in the message
+	if _, err := c.Do("SET", "trucks", "1", "FIELD", "speed", "10",
+		"FIELD", "secret", "1", "POINT", "33", "-115"); err != nil {
+		return err
+	}
+	return rd.receiveExpect("command", "set", "detect", "enter",
+		"key", "trucks", "id", "1", "fields", `{"kmh":36,"speed":10}`)
+}
+
+func fence_detect_dwell_test(mc *mockServer) error {
+	sc, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	psc := redis.PubSubConn{Conn: sc}
+	if err := psc.Subscribe("dw"); err != nil {
+		return err
+	}
+	receive := func(valex ...string) error {
+		for {
+			switch v := psc.ReceiveWithTimeout(time.Second * 3).(type) {
+			case redis.Message:
+				for i := 0; i < len(valex); i += 2 {
+					if gjson.GetBytes(v.Data, valex[i]).String() != valex[i+1] {
+						return fmt.Errorf("expected '%s'='%s', got '%s'",
+							valex[i], valex[i+1], v.Data)
+					}
+				}
+				return nil
+			case error:
+				return v
+			}
+		}
+	}
+
+	c, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for _, args := range [][]interface{}{
+		{"NEARBY", "fleet", "DWELL", "1", "POINT", "33", "-115", "5000"},
+		{"NEARBY", "fleet", "FENCE", "DWELL", "1", "POINT", "33", "-115", "5000"},
+		{"SETCHAN", "dw", "NEARBY", "fleet", "FENCE", "DETECT", "dwell", "POINT", "33", "-115", "5000"},
+		{"SETCHAN", "dw", "NEARBY", "fleet", "FENCE", "DWELL", "0", "POINT", "33", "-115", "5000"},
+		{"SETCHAN", "dw", "NEARBY", "fleet", "FENCE", "NODWELL", "LOITER", "1", "100", "ROAM", "fleet", "*", "100"},
+	} {
+		if _, err := c.Do(args[0].(string), args[1:]...); err == nil {
+			return fmt.Errorf("expected an error for %v", args)
+		}
+	}
+	if _, err := c.Do("SETCHAN", "dw", "NEARBY", "fleet", "FENCE",
+		"DETECT", "enter,exit,dwell,loiter", "DWELL", "1", "LOITER", "1", "100",
+		"POINT", "33", "-115", "5000"); err != nil {
+		return err
+	}
+
+	// the timer sends the dwell and loiter of an object that stays put
+	if _, err := c.Do("SET", "fleet", "truck1", "POINT", "33", "-115"); err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := receive("detect", "enter", "id", "truck1"); err != nil {
+		return err
+	}
+	if err := receive("detect", "dwell", "command", "set", "id", "truck1"); err != nil {
+		return err
+	}
+	if err := receive("detect", "loiter", "id", "truck1"); err != nil {
+		return err
+	}
+	if time.Since(start) < time.Second {
+		return errors.New("dwell was sent too early")
+	}
+
+	// moving away starts a new loiter, but the dwell was already sent
+	if _, err := c.Do("SET", "fleet", "truck1", "POINT", "33.01", "-115"); err != nil {
+		return err
+	}
+	if err := receive("detect", "loiter", "object.coordinates", "[-115,33.01]"); err != nil {
+		return err
+	}
+
+	// leaving the fence ends the dwell
+	if _, err := c.Do("SET", "fleet", "truck1", "POINT", "34", "-115"); err != nil {
+		return err
+	}
+	if err := receive("detect", "exit", "id", "truck1"); err != nil {
+		return err
+	}
+	if _, err := c.Do("SET", "fleet", "truck1", "POINT", "33", "-115"); err != nil {
+		return err
+	}
+	if err := receive("detect", "enter", "id", "truck1"); err != nil {
+		return err
+	}
+
+	// deleting the object ends the dwell, so nothing else is sent
+	if _, err := c.Do("DEL", "fleet", "truck1"); err != nil {
+		return err
+	}
+	if err := receive("command", "del", "id", "truck1"); err != nil {
+		return err
+	}
+	if _, err := c.Do("SET", "fleet", "truck2", "POINT", "33", "-115"); err != nil {
+		return err
+	}
+	if err := receive("detect", "enter", "id", "truck2"); err != nil {
+		return err
+	}
+	if err := receive("detect", "dwell", "id", "truck2"); err != nil {
+		return err
+	}
+	return receive("detect", "loiter", "id", "truck2")
+}
+
+func fence_schedule_test(mc *mockServer) error {
+	sc, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	psc := redis.PubSubConn{Conn: sc}
+	if err := psc.PSubscribe("sch*"); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	past := fmt.Sprint(now.Add(-time.Hour).Unix())
+	future := fmt.Sprint(now.Add(time.Hour).Unix())
+	// a daily window that starts two hours from now
+	m := now.UTC().Hour()*60 + now.UTC().Minute()
+	clock := func(m int) string {
+		m %= 24 * 60
+		return fmt.Sprintf("%02d:%02d", m/60, m%60)
+	}
+	later := "* " + clock(m+120) + "-" + clock(m+180)
+
+	fence := []interface{}{"NEARBY", "fleet", "FENCE", "DETECT", "enter",
+		"POINT", 33, -115, 5000}
+	err = mc.DoBatch(
+		Do(append([]interface{}{"SETCHAN", "sch1", "ACTIVE", past, now.Unix()}, fence...)...).Str("1"),
+		Do(append([]interface{}{"SETCHAN", "sch2", "SCHEDULE", "* 00:00-24:00 America/Chicago", "ACTIVE", past, future}, fence...)...).Str("1"),
+		Do(append([]interface{}{"SETCHAN", "sch3", "SCHEDULE", later}, fence...)...).Str("1"),
+		Do(append([]interface{}{"SETCHAN", "sch3", "SCHEDULE", later}, fence...)...).Str("0"),
+		Do(append([]interface{}{"SETCHAN", "bad", "SCHEDULE", "Mon-Fri 8-18"}, fence...)...).Err("invalid argument 'Mon-Fri 8-18'"),
+		Do(append([]interface{}{"SETCHAN", "bad", "ACTIVE", future, past}, fence...)...).Err("invalid argument '"+past+"'"),
+		Do(append([]interface{}{"SETCHAN", "bad", "ACTIVE", past}, fence...)...).Err("invalid argument 'NEARBY'"),
+		Do("CHANS", "sch*").JSON().Func(func(s string) error {
+			exs := []string{
+				"chans.0.state", "inactive",
+				"chans.0.active.to", fmt.Sprint(now.Unix()),
+				"chans.1.state", "active",
+				"chans.1.schedule.0", "* 00:00-24:00 America/Chicago",
+				"chans.2.state", "inactive",
+				"chans.2.schedule.0", later,
+			}
+			for i := 0; i < len(exs); i += 2 {
+				if gjson.Get(s, exs[i]).String() != exs[i+1] {
+					return fmt.Errorf("expected '%s'='%s', got '%s'",
+						exs[i], exs[i+1], s)
+				}
+			}
+			return nil
+		}),
+		Do("CHANS", "sch1").Func(func(s string) error {
+			if !strings.HasSuffix(s, " [active "+past+" "+fmt.Sprint(now.Unix())+"] inactive]]") {
+				return fmt.Errorf("unexpected chans '%s'", s)
+			}
+			return nil
+		}),
+		// a channel without a schedule is always active
+		Do(append([]interface{}{"SETCHAN", "nosch"}, fence...)...).Str("1"),
+		Do("CHANS", "nosch").JSON().Func(func(s string) error {
+			if gjson.Get(s, "chans.0.schedule").Raw != "[]" ||
+				gjson.Get(s, "chans.0.state").String() != "active" {
+				return fmt.Errorf("unexpected chans '%s'", s)
+			}
+			return nil
+		}),
+		Do("CHANS", "nosch").Func(func(s string) error {
+			if !strings.HasSuffix(s, " [] [] active]]") {
+				return fmt.Errorf("unexpected chans '%s'", s)
+			}
+			return nil
+		}),
+		Do("DELCHAN", "nosch").Str("1"),
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).OK(),
+		Do("SET", "fleet", "truck1", "POINT", 34, -115).OK(),
+		Do("SET", "fleet", "truck2", "POINT", 33, -115).OK(),
+	)
+	if err != nil {
+		return err
+	}
+	// only the active fence sends its messages
+	for _, id := range []string{"truck1", "truck2"} {
+		for {
+			v := psc.ReceiveWithTimeout(time.Second * 3)
+			if err, ok := v.(error); ok {
+				return err
+			}
+			if msg, ok := v.(redis.Message); ok {
+				hook := gjson.GetBytes(msg.Data, "hook").String()
+				if hook != "sch2" || gjson.GetBytes(msg.Data, "id").String() != id {
+					return fmt.Errorf("unexpected message '%s'", msg.Data)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func fence_deadletter_test(mc *mockServer) error {
+	var healthy atomic.Bool
+	var received atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		received.Add(1)
+		fmt.Fprintln(w, "OK!")
+	}))
+	defer ts.Close()
+
+	count := func(n int) func(s string) error {
+		return func(s string) error {
+			if got := len(gjson.Get(s, "deadletters").Array()); got != n {
+				return fmt.Errorf("expected %d dead letters, got '%s'", n, s)
+			}
+			return nil
+		}
+	}
+	fence := []interface{}{"NEARBY", "fleet", "FENCE", "DETECT", "enter",
+		"POINT", 33, -115, 5000}
+	err := mc.DoBatch(
+		Do(append([]interface{}{"SETHOOK", "dl1", ts.URL, "RETRY", 2, "BACKOFF", 0.1, 0.2}, fence...)...).Str("1"),
+		Do(append([]interface{}{"SETHOOK", "dl1", ts.URL, "RETRY", 2, "BACKOFF", 0.1, 0.2}, fence...)...).Str("0"),
+		Do(append([]interface{}{"SETHOOK", "bad", ts.URL, "RETRY", 0}, fence...)...).Err("invalid argument '0'"),
+		Do(append([]interface{}{"SETHOOK", "bad", ts.URL, "BACKOFF", 1, 0.5}, fence...)...).Err("invalid argument '0.5'"),
+		Do(append([]interface{}{"SETHOOK", "bad", ts.URL, "TTL", -1}, fence...)...).Err("invalid argument '-1'"),
+		Do(append([]interface{}{"SETHOOK", "bad", ts.URL, "DEADLETTER", 0, 60}, fence...)...).Err("invalid argument '0'"),
+		Do(append([]interface{}{"SETHOOK", "bad", ts.URL, "DEADLETTER", 1, 0}, fence...)...).Err("invalid argument '0'"),
+		Do(append([]interface{}{"SETCHAN", "bad", "RETRY", 2}, fence...)...).Err("invalid argument 'RETRY'"),
+		Do("HOOKS", "dl1").JSON().Func(func(s string) error {
+			if gjson.Get(s, "hooks.0.policy.retry").Int() != 2 ||
+				gjson.Get(s, "hooks.0.policy.backoff.1").Float() != 0.2 ||
+				gjson.Get(s, "hooks.0.policy.ttl").Int() != 30 ||
+				gjson.Get(s, "hooks.0.policy.deadletter.max").Int() != 1000 ||
+				gjson.Get(s, "hooks.0.policy.deadletter.ttl").Int() != 86400 {
+				return fmt.Errorf("unexpected policy '%s'", s)
+			}
+			return nil
+		}),
+		Do("DEADLETTER").Err("unknown command 'DEADLETTER'"),
+		Do("DEADLETTER", "LIST").Err("wrong number of arguments for 'deadletter' command"),
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).OK(),
+		Sleep(time.Second),
+		Do("DEADLETTER", "LIST", "*").JSON().Func(func(s string) error {
+			if err := count(1)(s); err != nil {
+				return err
+			}
+			if gjson.Get(s, "deadletters.0.hook").String() != "dl1" ||
+				gjson.Get(s, "deadletters.0.attempts").Int() != 2 ||
+				gjson.Get(s, "deadletters.0.event.id").String() != "truck1" {
+				return fmt.Errorf("unexpected dead letter '%s'", s)
+			}
+			return nil
+		}),
+		Do("DEADLETTER", "LIST", "other*").JSON().Func(count(0)),
+		Do("SERVER").JSON().Func(func(s string) error {
+			if gjson.Get(s, "stats.dead_letters").Int() != 1 {
+				return fmt.Errorf("expected a dead letter in '%s'", s)
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	healthy.Store(true)
+	err = mc.DoBatch(
+		Do("DEADLETTER", "REPLAY", "dl*").Str("1"),
+		Do("DEADLETTER", "REPLAY", "dl*").JSON().Str(`{"ok":true,"count":0}`),
+		Sleep(time.Second/2),
+		Do("DEADLETTER", "LIST", "*").JSON().Func(count(0)),
+	)
+	if err != nil {
+		return err
+	}
+	if received.Load() != 1 {
+		return fmt.Errorf("expected the replayed event to be sent, got %d",
+			received.Load())
+	}
+	healthy.Store(false)
+	return mc.DoBatch(
+		Do("SET", "fleet", "truck2", "POINT", 33, -115).OK(),
+		Sleep(time.Second),
+		Do("DEADLETTER", "LIST", "*").JSON().Func(count(1)),
+		Do("DEADLETTER", "PURGE", "*").Str("1"),
+		Do("DEADLETTER", "LIST", "*").JSON().Func(count(0)),
+		// only the newest dead letters are kept
+		Do(append([]interface{}{"SETHOOK", "dl1", ts.URL, "RETRY", 2, "BACKOFF", 0.1, 0.2, "DEADLETTER", 1, 60}, fence...)...).Str("1"),
+		Do("HOOKS", "dl1").JSON().Func(func(s string) error {
+			if gjson.Get(s, "hooks.0.policy.deadletter.max").Int() != 1 ||
+				gjson.Get(s, "hooks.0.policy.deadletter.ttl").Int() != 60 {
+				return fmt.Errorf("unexpected policy '%s'", s)
+			}
+			return nil
+		}),
+		Do("SET", "fleet", "truck3", "POINT", 33, -115).OK(),
+		Sleep(time.Second),
+		Do("SET", "fleet", "truck4", "POINT", 33, -115).OK(),
+		Sleep(time.Second),
+		Do("DEADLETTER", "LIST", "*").JSON().Func(func(s string) error {
+			if err := count(1)(s); err != nil {
+				return err
+			}
+			if gjson.Get(s, "deadletters.0.event.id").String() != "truck4" {
+				return fmt.Errorf("unexpected dead letter '%s'", s)
+			}
+			return nil
+		}),
+	)
+}
+
+func fence_template_test(mc *mockServer) error {
+	sc, err := redis.Dial("tcp", fmt.Sprintf(":%d", mc.port))
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	psc := redis.PubSubConn{Conn: sc}
+	if err := psc.Subscribe("tmpl"); err != nil {
+		return err
+	}
+	tmpl := `{"vehicle":{{id}},"event":"{{{detect}}}","team":{{meta.team}},` +
+		`"speed":{{fields.speed}},"lat":{{object.coordinates.1}}}`
+	fence := []interface{}{"NEARBY", "fleet", "FENCE", "DETECT", "enter",
+		"POINT", 33, -115, 5000}
+	err = mc.DoBatch(
+		Do(append([]interface{}{"SETCHAN", "tmpl", "META", "team", "north", "TEMPLATE", tmpl}, fence...)...).Str("1"),
+		Do(append([]interface{}{"SETCHAN", "tmpl", "META", "team", "north", "TEMPLATE", tmpl}, fence...)...).Str("0"),
+		Do(append([]interface{}{"SETCHAN", "bad", "TEMPLATE", "{{id"}, fence...)...).Err("template has an unclosed '{{'"),
+		Do(append([]interface{}{"SETCHAN", "bad", "TEMPLATE", "a", "TEMPLATE", "b"}, fence...)...).Err("duplicate argument 'TEMPLATE'"),
+		Do("CHANS", "tmpl").JSON().Func(func(s string) error {
+			if gjson.Get(s, "chans.0.template").String() != tmpl {
+				return fmt.Errorf("expected the template in '%s'", s)
+			}
+			return nil
+		}),
+		Do("SET", "fleet", "truck1", "FIELD", "speed", 42, "POINT", 33, -115).OK(),
+	)
+	if err != nil {
+		return err
+	}
+	expected := `{"vehicle":"truck1","event":"enter","team":"north",` +
+		`"speed":42,"lat":33}`
+	for {
+		v := psc.ReceiveWithTimeout(time.Second * 3)
+		if err, ok := v.(error); ok {
+			return err
+		}
+		if msg, ok := v.(redis.Message); ok {
+			if string(msg.Data) != expected {
+				return fmt.Errorf("expected '%s', got '%s'", expected, msg.Data)
+			}
+			return nil
+		}
+	}
+}
+
+func fence_batch_test(mc *mockServer) error {
+	var mu sync.Mutex
+	var batches [][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var ids []string
+		for _, id := range gjson.GetBytes(body, "#.id").Array() {
+			ids = append(ids, id.String())
+		}
+		mu.Lock()
+		batches = append(batches, ids)
+		mu.Unlock()
+		fmt.Fprintln(w, "OK!")
+	}))
+	defer ts.Close()
+
+	fence := []interface{}{"NEARBY", "fleet", "FENCE", "DETECT", "enter",
+		"POINT", 33, -115, 5000}
+	err := mc.DoBatch(
+		Do(append([]interface{}{"SETHOOK", "bh", ts.URL, "BATCH", 3, 0, 0.5}, fence...)...).Str("1"),
+		Do(append([]interface{}{"SETHOOK", "bh", ts.URL, "BATCH", 3, 0, 0.5}, fence...)...).Str("0"),
+		Do(append([]interface{}{"SETHOOK", "bad", ts.URL, "BATCH", 0, 0, 1}, fence...)...).Err("invalid argument '0'"),
+		Do(append([]interface{}{"SETHOOK", "bad", "redis://localhost:6379/fleet", "BATCH", 3, 0, 1}, fence...)...).Err("endpoint does not support batches: redis://localhost:6379/fleet"),
+		Do(append([]interface{}{"SETCHAN", "bad", "BATCH", 3, 0, 1}, fence...)...).Err("invalid argument 'BATCH'"),
+		Do(append([]interface{}{"SETHOOK", "bad", ts.URL, "TEMPLATE", "{{{id}}} entered", "BATCH", 3, 0, 1}, fence...)...).Err("template of a batch is not json"),
+		Do("HOOKS", "bh").JSON().Func(func(s string) error {
+			if gjson.Get(s, "hooks.0.batch.events").Int() != 3 ||
+				gjson.Get(s, "hooks.0.batch.latency").Float() != 0.5 {
+				return fmt.Errorf("unexpected batch '%s'", s)
+			}
+			return nil
+		}),
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).OK(),
+		Do("SET", "fleet", "truck2", "POINT", 33, -115).OK(),
+		Do("SET", "fleet", "truck3", "POINT", 33, -115).OK(),
+		Do("SET", "fleet", "truck4", "POINT", 33, -115).OK(),
+		Do("SET", "fleet", "truck5", "POINT", 33, -115).OK(),
+		Sleep(time.Second),
+	)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// the events arrive in order, in batches of up to three
+	var ids []string
+	for _, batch := range batches {
+		if len(batch) == 0 || len(batch) > 3 {
+			return fmt.Errorf("unexpected batches %v", batches)
+		}
+		ids = append(ids, batch...)
+	}
+	if len(batches) >= 5 ||
+		strings.Join(ids, ",") != "truck1,truck2,truck3,truck4,truck5" {
+		return fmt.Errorf("unexpected batches %v", batches)
+	}
+	return nil
+}
+
+func fence_hookstats_test(mc *mockServer) error {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "OK!")
+	}))
+	defer ts.Close()
+
+	stats := func(exs ...interface{}) func(s string) error {
+		return func(s string) error {
+			for i := 0; i < len(exs); i += 2 {
+				if got := gjson.Get(s, exs[i].(string)).Int(); got != int64(exs[i+1].(int)) {
+					return fmt.Errorf("expected '%s'=%d, got '%s'",
+						exs[i], exs[i+1], s)
+				}
+			}
+			return nil
+		}
+	}
+	fence := []interface{}{"NEARBY", "fleet", "FENCE", "DETECT", "enter",
+		"POINT", 33, -115, 5000}
+	err := mc.DoBatch(
+		Do(append([]interface{}{"SETHOOK", "hs", ts.URL, "RETRY", 2, "BACKOFF", 0.1, 0.1}, fence...)...).Str("1"),
+		Do("HOOKSTATS").Err("wrong number of arguments for 'hookstats' command"),
+		Do("HOOKSTATS", "hs").JSON().Func(stats("hooks.#", 1,
+			"hooks.0.queued", 0, "hooks.0.pending", 0)),
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).OK(),
+		Sleep(time.Second/2),
+		Do("HOOKSTATS", "hs").JSON().Func(stats(
+			"hooks.0.queued", 1, "hooks.0.delivered", 1, "hooks.0.failed", 0,
+			"hooks.0.latency.count", 1, "hooks.0.latency.buckets.60", 1,
+			"hooks.0.endpoints.0.delivered", 1)),
+	)
+	if err != nil {
+		return err
+	}
+	healthy.Store(false)
+	return mc.DoBatch(
+		Do("SET", "fleet", "truck2", "POINT", 33, -115).OK(),
+		Sleep(time.Second),
+		Do("HOOKSTATS", "hs").JSON().Func(func(s string) error {
+			if err := stats(
+				"hooks.0.queued", 2, "hooks.0.delivered", 1,
+				"hooks.0.failed", 2, "hooks.0.retried", 1,
+				"hooks.0.dead_letters", 1, "hooks.0.pending", 0,
+				"hooks.0.endpoints.0.failed", 2)(s); err != nil {
+				return err
+			}
+			if !strings.Contains(gjson.Get(s, "hooks.0.last_error").String(), "503") {
+				return fmt.Errorf("expected the last error in '%s'", s)
+			}
+			return nil
+		}),
+		Do("HOOKS", "hs").JSON().Func(stats("hooks.0.stats.delivered", 1)),
+		Do("HOOKSTATS", "hs").Func(func(s string) error {
+			if !strings.HasPrefix(s, "[[hs [queued 2 delivered 1 failed 2") {
+				return fmt.Errorf("unexpected '%s'", s)
+			}
+			return nil
+		}),
+		Do("DEADLETTER", "PURGE", "hs").Str("1"),
+		// an event that waits to be sent again is pending
+		Do("SETHOOK", "hs2", ts.URL, "BACKOFF", 10, 10, "NEARBY", "fleet2", "FENCE", "DETECT", "enter", "POINT", 33, -115, 5000).Str("1"),
+		Do("SET", "fleet2", "truck1", "POINT", 33, -115).OK(),
+		Sleep(time.Second/2),
+		Do("HOOKSTATS", "hs2").JSON().Func(stats("hooks.0.queued", 1,
+			"hooks.0.failed", 1, "hooks.0.pending", 1)),
+		Do("DELHOOK", "hs2").Str("1"),
+	)
+}
+
+func fence_signed_test(mc *mockServer) error {
+	received := make(chan error, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- func() error {
+			stamp := r.Header.Get("X-Tile38-Timestamp")
+			mac := hmac.New(sha256.New, []byte("s3cr3t"))
+			mac.Write([]byte(stamp + "." + string(body)))
+			sig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+			if stamp == "" || r.Header.Get("X-Tile38-Signature") != sig {
+				return fmt.Errorf("invalid signature for '%s'", body)
+			}
+			if r.Header.Get("Authorization") != "Bearer tok" ||
+				r.Header.Get("X-Fleet") != "north" {
+				return fmt.Errorf("missing headers in %v", r.Header)
+			}
+			return nil
+		}()
+		fmt.Fprintln(w, "OK!")
+	}))
+	defer ts.Close()
+
+	fence := []interface{}{"NEARBY", "fleet", "FENCE", "DETECT", "enter",
+		"POINT", 33, -115, 5000}
+	opts := []interface{}{"SECRET", "s3cr3t", "BEARER", "tok",
+		"HEADER", "X-Fleet", "north"}
+	err := mc.DoBatch(
+		Do(append(append([]interface{}{"SETHOOK", "signed", ts.URL}, opts...), fence...)...).Str("1"),
+		Do(append(append([]interface{}{"SETHOOK", "signed", ts.URL}, opts...), fence...)...).Str("0"),
+		Do(append([]interface{}{"SETHOOK", "bad", ts.URL, "SECRET", "a", "SECRET", "b"}, fence...)...).Err("duplicate argument 'SECRET'"),
+		Do(append([]interface{}{"SETHOOK", "bad", ts.URL, "HEADER", "Bad Name", "x"}, fence...)...).Err("invalid argument 'Bad Name'"),
+		Do(append([]interface{}{"SETHOOK", "bad", "redis://localhost:6379/fleet", "SECRET", "a"}, fence...)...).Err("endpoint is not http: redis://localhost:6379/fleet"),
+		Do(append([]interface{}{"SETCHAN", "bad", "SECRET", "a"}, fence...)...).Err("invalid argument 'SECRET'"),
+		Do("HOOKS", "signed").JSON().Func(func(s string) error {
+			if strings.Contains(s, "s3cr3t") || strings.Contains(s, "tok") ||
+				strings.Contains(s, "north") ||
+				!gjson.Get(s, "hooks.0.signed").Bool() ||
+				!gjson.Get(s, "hooks.0.bearer").Bool() ||
+				gjson.Get(s, "hooks.0.headers").Raw != `["X-Fleet"]` {
+				return fmt.Errorf("unexpected hooks '%s'", s)
+			}
+			return nil
+		}),
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).OK(),
+	)
+	if err != nil {
+		return err
+	}
+	select {
+	case err := <-received:
+		return err
+	case <-time.After(time.Second * 3):
+		return errors.New("timeout waiting for the signed request")
+	}
+}
+
 // do performs the passed command on the passed redis client
 func do(c redis.Conn, cmd string) (interface{}, error) {
 	// Split out all parameters
@@ -64,5 +64,26 @@ func follower_follow_test(mc *mockServer) error {
 		return err
 	}
 
+	err = mc.DoBatch(
+		Do("SELECT", "staging").OK(),
+		Do("SET", "mykey", "truck10", "POINT", 10, 10).OK(),
+		Do("SELECT", "0").OK(),
+		Do("SET", "mykey", "truck11", "POINT", 10, 10).OK(),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = mc2.DoBatch(
+		Sleep(time.Second/2),
+		Do("GET", "mykey", "truck10").Str("<nil>"),
+		Do("GET", "mykey", "truck11").Str(`{"type":"Point","coordinates":[10,10]}`),
+		Do("SELECT", "staging").OK(),
+		Do("SCAN", "mykey", "IDS").Str("[0 [truck10]]"),
+	)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -6,11 +6,13 @@ import (
 	"math"
 	"math/rand"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 func subTestSearch(g *testGroup) {
@@ -33,6 +35,20 @@ func subTestSearch(g *testGroup) {
 	g.regSubTest("MATCH", keys_MATCH_test)
 	g.regSubTest("FIELDS", keys_FIELDS_search_test)
 	g.regSubTest("BUFFER", keys_BUFFER_search_test)
+	g.regSubTest("ZRANGE", keys_ZRANGE_search_test)
+	g.regSubTest("H3", keys_H3_search_test)
+	g.regSubTest("S2", keys_S2_search_test)
+	g.regSubTest("COVER", keys_COVER_search_test)
+	g.regSubTest("AGGREGATE", keys_AGGREGATE_search_test)
+	g.regSubTest("GRID", keys_GRID_search_test)
+	g.regSubTest("CLUSTER", keys_CLUSTER_search_test)
+	g.regSubTest("JOIN", keys_JOIN_search_test)
+	g.regSubTest("CORRIDOR", keys_CORRIDOR_search_test)
+	g.regSubTest("ORDERBY", keys_ORDERBY_search_test)
+	g.regSubTest("CURSOR TOKEN", keys_CURSOR_TOKEN_search_test)
+	g.regSubTest("EXPLAIN", keys_EXPLAIN_search_test)
+	g.regSubTest("FIELDS PROJECTION", keys_FIELDS_PROJECTION_search_test)
+	g.regSubTest("CLIP POLYGON", keys_CLIP_POLYGON_search_test)
 }
 
 func keys_KNN_basic_test(mc *mockServer) error {
@@ -789,6 +805,510 @@ func keys_BUFFER_search_test(mc *mockServer) error {
 	})
 }
 
+func keys_ZRANGE_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SETINDEX", "drones", "2D"}, {"OK"},
+		{"SET", "drones", "1", "POINT", 33, -115, 20}, {"OK"},
+		{"SET", "drones", "2", "POINT", 33.001, -115, 120}, {"OK"},
+		{"SET", "drones", "3", "POINT", 33.002, -115, 400}, {"OK"},
+		{"SET", "drones", "4", "POINT", 33.003, -115}, {"OK"},
+		{"WITHIN", "drones", "IDS", "BOUNDS", 32, -116, 34, -114}, {match("[0 [1 2 3 4]]")},
+		{"WITHIN", "drones", "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 10, 200}, {match("[0 [1 2]]")},
+		{"INTERSECTS", "drones", "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 100, 1000}, {match("[0 [2 3]]")},
+		{"NEARBY", "drones", "IDS", "POINT", 33, -115, 200000, "ZRANGE", 0, 100}, {"[0 [1 4]]"},
+		{"WITHIN", "drones", "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 200}, {"ERR wrong number of arguments for 'within' command"},
+		{"WITHIN", "drones", "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 200, 100}, {"ERR invalid argument '100'"},
+		{"WITHIN", "drones", "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 0, 1, "ZRANGE", 0, 1}, {"ERR duplicate argument 'ZRANGE'"},
+
+		{"SETINDEX", "drones", "4D"}, {"ERR invalid argument '4D'"},
+		{"SETINDEX", "drones", "3D"}, {"OK"},
+		{"STATS", "drones"}, {"[[in_memory_size 68 index_dims 3 num_objects 4 num_points 4 num_strings 0]]"},
+		{"WITHIN", "drones", "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 10, 200}, {match("[0 [1 2]]")},
+		{"SET", "drones", "1", "POINT", 33, -115, 500}, {"OK"},
+		{"WITHIN", "drones", "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 10, 200}, {match("[0 [2]]")},
+		{"INTERSECTS", "drones", "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 100, 1000}, {match("[0 [1 2 3]]")},
+		{"SETINDEX", "drones", "2D"}, {"OK"},
+		{"INTERSECTS", "drones", "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 100, 1000}, {match("[0 [1 2 3]]")},
+
+		// 3d distance
+		{"NEARBY", "drones", "IDS", "POINTZ", 33, -115, 500, 1000}, {"[0 [1 3 2 4]]"},
+		{"NEARBY", "drones", "IDS", "POINTZ", 33, -115, 0, 1000}, {"[0 [2 4 3 1]]"},
+		{"NEARBY", "drones", "IDS", "POINTZ", 33, -115, 0, 400}, {"[0 [2 4]]"},
+		{"NEARBY", "drones", "DISTANCE", "IDS", "POINTZ", 33, -115, 0, 1000, "ZRANGE", 500, 500}, {"[0 [[1 500]]]"},
+		{"NEARBY", "drones", "LIMIT", 1, "IDS", "POINTZ", 33, -115, 400, 1000}, {"[1 [1]]"},
+		{"NEARBY", "drones", "CURSOR", 1, "LIMIT", 2, "IDS", "POINTZ", 33, -115, 400, 1000}, {"[3 [3 2]]"},
+		{"WITHIN", "drones", "SPARSE", 1, "IDS", "BOUNDS", 32, -116, 34, -114, "ZRANGE", 100, 200}, {"[0 [2]]"},
+		{"NEARBY", "drones", "IDS", "POINT", 33, -115, 0, 1000}, {"ERR wrong number of arguments for 'nearby' command"},
+
+		// the index is of the key, not of the collection
+		{"SETINDEX", "drones", "3D"}, {"OK"},
+		{"DROP", "drones"}, {"1"},
+		{"SET", "drones", "1", "POINT", 33, -115, 20}, {"OK"},
+		{"STATS", "drones"}, {"[[in_memory_size 17 index_dims 3 num_objects 1 num_points 1 num_strings 0]]"},
+		{"RENAME", "drones", "drones2"}, {"OK"},
+		{"SET", "drones", "1", "POINT", 33, -115, 20}, {"OK"},
+		{"STATS", "drones", "drones2"}, {"[[in_memory_size 17 num_objects 1 num_points 1 num_strings 0] [in_memory_size 17 index_dims 3 num_objects 1 num_points 1 num_strings 0]]"},
+	})
+}
+
+func keys_H3_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "mykey", "1", "POINT", 37.775938728915946, -122.41795063018799}, {"OK"},
+		{"SET", "mykey", "2", "POINT", 37.7765, -122.4185}, {"OK"},
+		{"SET", "mykey", "3", "POINT", 37.79, -122.40}, {"OK"},
+		{"SET", "mykey", "4", "POINT", 33.5, -115.5}, {"OK"},
+		{"WITHIN", "mykey", "IDS", "H3", "8928308280fffff"}, {match("[0 [1 2]]")},
+		{"INTERSECTS", "mykey", "IDS", "H3", "872830828ffffff"}, {match("[0 [1 2]]")},
+		{"WITHIN", "mykey", "IDS", "H3", "85283083fffffff"}, {match("[0 [1 2 3]]")},
+		{"WITHIN", "mykey", "H3", "89283082a33ffff"}, {"[0 [[3 {\"type\":\"Point\",\"coordinates\":[-122.4,37.79]}]]]"},
+		{"WITHIN", "mykey", "IDS", "H3", "8928308280ffff"}, {"ERR invalid argument '8928308280ffff'"},
+		{"WITHIN", "mykey", "IDS", "H3"}, {"ERR wrong number of arguments for 'within' command"},
+		{"NEARBY", "mykey", "IDS", "H3", "8928308280fffff"}, {"ERR invalid argument 'H3'"},
+		{"TEST", "POINT", 37.7765, -122.4185, "WITHIN", "H3", "8928308280fffff"}, {"1"},
+		{"TEST", "POINT", 37.79, -122.40, "WITHIN", "H3", "8928308280fffff"}, {"0"},
+
+		// output
+		{"SCAN", "mykey", "H3", 9}, {"[0 [[1 8928308280fffff] [2 8928308280fffff] [3 89283082a33ffff] [4 8929a64065bffff]]]"},
+		{"SCAN", "mykey", "H3", 5}, {"[0 [[1 85283083fffffff] [2 85283083fffffff] [3 85283083fffffff] [4 8529a643fffffff]]]"},
+		{"WITHIN", "mykey", "H3", 7, "H3", "89283082a33ffff"}, {"[0 [[3 87283082affffff]]]"},
+		{"NEARBY", "mykey", "H3", 0, "POINT", 33.5, -115.5, 1000}, {"[0 [[4 8029fffffffffff]]]"},
+		{"SCAN", "mykey", "H3", 16}, {"ERR invalid argument '16'"},
+		{"SCAN", "mykey", "H3"}, {"ERR wrong number of arguments for 'scan' command"},
+		{"GET", "mykey", "3", "H3", 7}, {"87283082affffff"},
+		{"GET", "mykey", "3", "H3", -1}, {"ERR invalid argument '-1'"},
+
+		// set input
+		{"SET", "cells", "a", "H3", "8928308280fffff"}, {"OK"},
+		{"SET", "cells", "b", "H3", "89283082a33ffff"}, {"OK"},
+		{"SET", "cells", "c", "H3", "hello"}, {"ERR invalid argument 'hello'"},
+		{"GET", "cells", "a", "H3", 9}, {"8928308280fffff"},
+		{"INTERSECTS", "cells", "IDS", "POINT", 37.7765, -122.4185}, {"[0 [a]]"},
+		{"WITHIN", "cells", "IDS", "H3", "85283083fffffff"}, {match("[0 [a b]]")},
+
+		// expressions
+		{"SCAN", "mykey", "WHERE", "h3(9) == '8928308280fffff'", "IDS"}, {"[0 [1 2]]"},
+		{"SCAN", "mykey", "WHERE", "h3(5) != '85283083fffffff'", "IDS"}, {"[0 [4]]"},
+		{"SET", "mykey", "5", "FIELD", "h3", 1, "POINT", 37.7765, -122.4185}, {"OK"},
+		{"SCAN", "mykey", "WHERE", "h3 == 1", "IDS"}, {"[0 [5]]"},
+	})
+}
+
+func keys_S2_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "mykey", "1", "POINT", 37.775938728915946, -122.41795063018799}, {"OK"},
+		{"SET", "mykey", "2", "POINT", 37.7765, -122.4185}, {"OK"},
+		{"SET", "mykey", "3", "POINT", 37.79, -122.40}, {"OK"},
+		{"SET", "mykey", "4", "POINT", 33.5, -115.5}, {"OK"},
+		{"WITHIN", "mykey", "IDS", "S2", "8085809c"}, {match("[0 [1 2]]")},
+		{"INTERSECTS", "mykey", "IDS", "S2", "808581"}, {match("[0 [1 2 3]]")},
+		{"WITHIN", "mykey", "IDS", "S2", "80d0a2983"}, {"[0 [4]]"},
+		{"WITHIN", "mykey", "IDS", "S2", "hello"}, {"ERR invalid argument 'hello'"},
+		{"WITHIN", "mykey", "IDS", "S2"}, {"ERR wrong number of arguments for 'within' command"},
+		{"TEST", "POINT", 37.7765, -122.4185, "WITHIN", "S2", "8085809c"}, {"1"},
+		{"TEST", "POINT", 37.79, -122.40, "WITHIN", "S2", "8085809c"}, {"0"},
+
+		// output
+		{"SCAN", "mykey", "S2CELLS", 13}, {"[0 [[1 [8085809c]] [2 [8085809c]] [3 [80858064]] [4 [80d0a29c]]]]"},
+		{"WITHIN", "mykey", "S2CELLS", 5, "S2", "80d0a3"}, {"[0 [[4 [80d4]]]]"},
+		{"SCAN", "mykey", "S2CELLS", 31}, {"ERR invalid argument '31'"},
+		{"SCAN", "mykey", "S2CELLS"}, {"ERR wrong number of arguments for 'scan' command"},
+
+		// set input
+		{"SET", "cells", "a", "S2", "8085809c"}, {"OK"},
+		{"SET", "cells", "c", "S2", "hello"}, {"ERR invalid argument 'hello'"},
+		{"INTERSECTS", "cells", "IDS", "POINT", 37.7765, -122.4185}, {"[0 [a]]"},
+		{"SCAN", "cells", "S2CELLS", 13}, {"[0 [[a [8085809c]]]]"},
+		{"SCAN", "cells", "S2CELLS", 12}, {"[0 [[a [8085809]]]]"},
+		{"SCAN", "cells", "S2CELLS", 19}, {"ERR too many cells"},
+	})
+}
+
+func keys_COVER_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"COVER", "S2", 13, "POINT", 37.7765, -122.4185}, {"[8085809c]"},
+		{"COVER", "H3", 9, "POINT", 37.7765, -122.4185}, {"[8928308280fffff]"},
+		{"COVER", "HASH", 5, "POINT", 33, -115}, {"[9my5x]"},
+		{"COVER", "HASH", 1, "BOUNDS", 10, 10, 20, 20}, {"[s]"},
+		{"COVER", "HASH", 2, "BOUNDS", 10, 10, 20, 20}, {"[s1 s3 s4 s5 s6 s7]"},
+		{"COVER", "HASH", 2, "HASH", "s1"}, {"[s1]"},
+		{"COVER", "S2", 12, "S2", "8085809c"}, {"[8085809]"},
+		{"COVER", "S2", 10, "BOUNDS", 37.7, -122.5, 37.8, -122.3}, {"[80857f 808581 808587 808f79 808f7d 808f7f 808f81 808f83]"},
+		{"COVER", "S2", 20, "BOUNDS", 37.7, -122.5, 37.8, -122.3}, {"ERR too many cells"},
+		{"COVER", "H3", 5, "H3", "85283083fffffff"}, {"[85283083fffffff]"},
+		{"COVER", "HASH", 2, "MAXCELLS", 3, "BOUNDS", 10, 10, 20, 20}, {"ERR too many cells"},
+		{"COVER", "HASH", 2, "MAXCELLS", 0, "BOUNDS", 10, 10, 20, 20}, {"ERR invalid argument '0'"},
+		{"COVER", "HASH", 13, "POINT", 33, -115}, {"ERR invalid argument '13'"},
+		{"COVER", "QUADKEY", 2, "POINT", 33, -115}, {"ERR invalid argument 'QUADKEY'"},
+		{"COVER", "S2", 13}, {"ERR wrong number of arguments for 'cover' command"},
+	})
+}
+
+func keys_AGGREGATE_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "fleet", "1", "FIELD", "speed", 10, "POINT", 33.01, -115.01}, {"OK"},
+		{"SET", "fleet", "2", "FIELD", "speed", 20, "POINT", 33.02, -115.02}, {"OK"},
+		{"SET", "fleet", "3", "FIELD", "speed", 30, "POINT", 33.03, -115.03}, {"OK"},
+		{"SET", "fleet", "4", "FIELD", "speed", 40, "POINT", 34.5, -116.5}, {"OK"},
+		{"SET", "fleet", "1", "FIELD", "kind", "car", "POINT", 33.01, -115.01}, {"OK"},
+		{"SET", "fleet", "2", "FIELD", "kind", "car", "POINT", 33.02, -115.02}, {"OK"},
+		{"SET", "fleet", "3", "FIELD", "kind", "truck", "POINT", 33.03, -115.03}, {"OK"},
+		{"SET", "fleet", "4", "FIELD", "kind", "truck", "POINT", 34.5, -116.5}, {"OK"},
+
+		{"SCAN", "fleet", "AGGREGATE", "COUNT", "SUM", "speed", "AVG", "speed", "MIN", "speed", "MAX", "speed"}, {
+			"[0 [count 4 sum(speed) 100 avg(speed) 25 min(speed) 10 max(speed) 40]]"},
+		{"SCAN", "fleet", "AGGREGATE", "PERCENTILE", 50, "speed", "PERCENTILE", 90, "speed"}, {
+			"[0 [p50(speed) 25 p90(speed) 37]]"},
+		{"SCAN", "fleet", "WHERE", "speed", 20, 40, "AGGREGATE", "AVG", "speed"}, {"[0 [avg(speed) 30]]"},
+		{"SCAN", "fleet", "MATCH", "1", "AGGREGATE", "AVG", "speed"}, {"[0 [avg(speed) 10]]"},
+		{"SCAN", "fleet", "WHERE", "speed > 100", "AGGREGATE", "COUNT", "AVG", "speed"}, {"[0 [count 0 avg(speed) <nil>]]"},
+		{"SCAN", "fleet", "AGGREGATE", "GROUPBY", "kind", "COUNT", "MAX", "speed"}, {
+			"[0 [[car [count 2 max(speed) 20]] [truck [count 2 max(speed) 40]]]]"},
+		{"WITHIN", "fleet", "AGGREGATE", "COUNT", "AVG", "speed", "BOUNDS", 33, -116, 34, -115}, {
+			"[0 [count 3 avg(speed) 20]]"},
+		{"INTERSECTS", "fleet", "AGGREGATE", "GROUPBY", "kind", "SUM", "speed", "BOUNDS", 33, -116, 34, -115}, {
+			"[0 [[car [sum(speed) 30]] [truck [sum(speed) 30]]]]"},
+		{"NEARBY", "fleet", "AGGREGATE", "MIN", "speed", "POINT", 33, -115, 5000}, {"[0 [min(speed) 10]]"},
+		{"SCAN", "fleet", "AGGREGATE"}, {"ERR wrong number of arguments for 'scan' command"},
+		{"SCAN", "fleet", "AGGREGATE", "SUM"}, {"ERR wrong number of arguments for 'scan' command"},
+		{"SCAN", "fleet", "AGGREGATE", "MEDIAN", "speed"}, {"ERR invalid argument 'MEDIAN'"},
+		{"SCAN", "fleet", "AGGREGATE", "PERCENTILE", 101, "speed"}, {"ERR invalid argument '101'"},
+		{"SCAN", "fleet", "AGGREGATE", "GROUPBY", "kind", "GROUPBY", "kind", "COUNT"}, {"ERR duplicate argument 'GROUPBY'"},
+		{"SETHOOK", "hook", "http://localhost:8080", "NEARBY", "fleet", "FENCE", "AGGREGATE", "COUNT", "POINT", 33, -115, 5000}, {"AGGREGATE is not allowed when FENCE is specified"},
+
+		{"OUTPUT", "json"}, {`{"ok":true}`},
+		{"SCAN", "fleet", "AGGREGATE", "COUNT", "AVG", "speed"}, {
+			`{"ok":true,"aggregates":{"count":4,"avg(speed)":25},"count":4,"cursor":0}`},
+		{"SCAN", "fleet", "WHERE", "speed > 100", "AGGREGATE", "COUNT", "AVG", "speed"}, {
+			`{"ok":true,"aggregates":{"count":0,"avg(speed)":null},"count":0,"cursor":0}`},
+		{"SCAN", "fleet", "AGGREGATE", "GROUPBY", "kind", "SUM", "speed"}, {
+			`{"ok":true,"groups":[{"group":"car","aggregates":{"sum(speed)":30}},{"group":"truck","aggregates":{"sum(speed)":70}}],"count":4,"cursor":0}`},
+		{"OUTPUT", "resp"}, {"OK"},
+	})
+}
+
+func keys_GRID_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "fleet", "1", "FIELD", "speed", 10, "POINT", 33.01, -115.01}, {"OK"},
+		{"SET", "fleet", "2", "FIELD", "speed", 20, "POINT", 33.02, -115.02}, {"OK"},
+		{"SET", "fleet", "3", "FIELD", "speed", 30, "POINT", 33.03, -115.03}, {"OK"},
+		{"SET", "fleet", "4", "FIELD", "speed", 40, "POINT", 34.5, -116.5}, {"OK"},
+		{"SET", "fleet", "5", "STRING", "hello"}, {"OK"},
+
+		{"SCAN", "fleet", "GRID", "HASH", 3}, {"[0 [[9my 3] [9qj 1]]]"},
+		{"SCAN", "fleet", "GRID", "HASH", 3, "AVG", "speed", "MAX", "speed"}, {"[0 [[9my 3 [avg(speed) 20 max(speed) 30]] [9qj 1 [avg(speed) 40 max(speed) 40]]]]"},
+		{"SCAN", "fleet", "GRID", "QUADKEY", 8}, {"[0 [[02301303 1] [02301332 3]]]"},
+		{"SCAN", "fleet", "GRID", "TILE", 8}, {"[0 [[8/45/101 1] [8/46/103 3]]]"},
+		{"SCAN", "fleet", "GRID", "H3", 5}, {"[0 [[8529a2a3fffffff 1] [85485b07fffffff 2] [85485b3bfffffff 1]]]"},
+		{"SCAN", "fleet", "GRID", "S2", 8}, {"[0 [[80c4d 1] [80d73 3]]]"},
+		{"WITHIN", "fleet", "GRID", "HASH", 5, "SUM", "speed", "BOUNDS", 33, -116, 34, -115}, {"[0 [[9my5y 3 [sum(speed) 60]]]]"},
+		{"SCAN", "fleet", "WHERE", "speed", 20, 30, "GRID", "HASH", 2}, {"[0 [[9m 2]]]"},
+		{"SCAN", "fleet", "GRID", "HASH", 13}, {"ERR invalid argument '13'"},
+		{"SCAN", "fleet", "GRID", "TILE", 24}, {"ERR invalid argument '24'"},
+		{"SCAN", "fleet", "GRID", "BOX", 2}, {"ERR invalid argument 'BOX'"},
+		{"SCAN", "fleet", "GRID", "HASH"}, {"ERR wrong number of arguments for 'scan' command"},
+		{"SCAN", "fleet", "GRID", "HASH", 3, "GROUPBY", "speed"}, {"ERR wrong number of arguments for 'scan' command"},
+
+		{"OUTPUT", "json"}, {`{"ok":true}`},
+		{"SCAN", "fleet", "GRID", "HASH", 3, "AVG", "speed"}, {`{"ok":true,"grid":[{"cell":"9my","count":3,"aggregates":{"avg(speed)":20}},{"cell":"9qj","count":1,"aggregates":{"avg(speed)":40}}],"count":4,"cursor":0}`},
+		{"SCAN", "fleet", "GRID", "TILE", 8}, {`{"ok":true,"grid":[{"cell":"8/45/101","count":1},{"cell":"8/46/103","count":3}],"count":4,"cursor":0}`},
+		{"OUTPUT", "resp"}, {"OK"},
+	})
+}
+
+func keys_CLUSTER_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "fleet", "1", "POINT", 33.01, -115.01}, {"OK"},
+		{"SET", "fleet", "2", "POINT", 33.02, -115.02}, {"OK"},
+		{"SET", "fleet", "3", "POINT", 33.03, -115.03}, {"OK"},
+		{"SET", "fleet", "4", "POINT", 34.5, -116.5}, {"OK"},
+
+		{"SCAN", "fleet", "CLUSTER", 10}, {"[0 [[4 1 [34.5 -116.5] [[34.5 -116.5] [34.5 -116.5]]] [cluster:10:1 3 [33.02 -115.02] [[33.01 -115.03] [33.03 -115.01]]]]]"},
+		{"SCAN", "fleet", "CLUSTER", 10, "MEMBERS"}, {"[0 [[4 1 [34.5 -116.5] [[34.5 -116.5] [34.5 -116.5]] [4]] [cluster:10:1 3 [33.02 -115.02] [[33.01 -115.03] [33.03 -115.01]] [1 2 3]]]]"},
+		{"SCAN", "fleet", "CLUSTER", 14}, {"[0 [[1 1 [33.01 -115.01] [[33.01 -115.01] [33.01 -115.01]]] [2 1 [33.02 -115.02] [[33.02 -115.02] [33.02 -115.02]]] [3 1 [33.03 -115.03] [[33.03 -115.03] [33.03 -115.03]]] [4 1 [34.5 -116.5] [[34.5 -116.5] [34.5 -116.5]]]]]"},
+		{"SCAN", "fleet", "CLUSTER", 14, "RADIUS", 200}, {"[0 [[3 1 [33.03 -115.03] [[33.03 -115.03] [33.03 -115.03]]] [4 1 [34.5 -116.5] [[34.5 -116.5] [34.5 -116.5]]] [cluster:14:1 2 [33.015 -115.015] [[33.01 -115.02] [33.02 -115.01]]]]]"},
+		{"WITHIN", "fleet", "CLUSTER", 4, "MEMBERS", "BOUNDS", 30, -120, 35, -110}, {"[0 [[cluster:4:1 4 [33.39 -115.39] [[33.01 -116.5] [34.5 -115.01]] [1 2 3 4]]]]"},
+		{"WITHIN", "fleet", "CLUSTER", 10, "BOUNDS", 33, -116, 34, -115}, {"[0 [[cluster:10:1 3 [33.02 -115.02] [[33.01 -115.03] [33.03 -115.01]]]]]"},
+		{"SCAN", "fleet", "CLUSTER", 24}, {"ERR invalid argument '24'"},
+		{"SCAN", "fleet", "CLUSTER", 10, "RADIUS", 0}, {"ERR invalid argument '0'"},
+		{"SCAN", "fleet", "CLUSTER"}, {"ERR wrong number of arguments for 'scan' command"},
+		{"INTERSECTS", "fleet", "CLUSTER", 10, "MVT", 0, 0, 0}, {func(v interface{}) (resp, expect interface{}) {
+			vv := v.([]string)
+			return len(vv) == 2 && len(vv[1]) > 0, true
+		}},
+
+		{"OUTPUT", "json"}, {`{"ok":true}`},
+		{"SCAN", "fleet", "CLUSTER", 10, "MEMBERS"}, {`{"ok":true,"clusters":[{"id":"4","count":1,"point":{"lat":34.5,"lon":-116.5},"bounds":{"sw":{"lat":34.5,"lon":-116.5},"ne":{"lat":34.5,"lon":-116.5}},"members":["4"]},{"id":"cluster:10:1","count":3,"point":{"lat":33.02,"lon":-115.02},"bounds":{"sw":{"lat":33.01,"lon":-115.03},"ne":{"lat":33.03,"lon":-115.01}},"members":["1","2","3"]}],"count":4,"cursor":0}`},
+		{"OUTPUT", "resp"}, {"OK"},
+	})
+}
+
+func keys_JOIN_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "parcels", "p1", "BOUNDS", 33, -115, 34, -114}, {"OK"},
+		{"SET", "parcels", "p2", "BOUNDS", 33.5, -114.5, 34.5, -113.5}, {"OK"},
+		{"SET", "parcels", "p3", "BOUNDS", 40, -100, 41, -99}, {"OK"},
+		{"SET", "sensors", "s1", "FIELD", "temp", 20, "POINT", 33.2, -114.8}, {"OK"},
+		{"SET", "sensors", "s2", "FIELD", "temp", 30, "POINT", 33.8, -114.2}, {"OK"},
+		{"SET", "sensors", "s3", "FIELD", "temp", 40, "POINT", 45, -90}, {"OK"},
+		{"SET", "sensors", "s4", "FIELD", "temp", 50, "POINT", 34.2, -113.8}, {"OK"},
+
+		{"JOIN", "sensors", "parcels", "WITHIN"}, {"[0 [[s1 [p1]] [s2 [p1 p2]] [s4 [p2]]]]"},
+		{"JOIN", "parcels", "sensors", "INTERSECTS"}, {"[0 [[p1 [s1 s2]] [p2 [s2 s4]]]]"},
+		{"JOIN", "parcels", "parcels", "INTERSECTS"}, {"[0 [[p1 [p2]] [p2 [p1]]]]"},
+		{"JOIN", "sensors", "sensors", "NEARBY", 70000}, {"[0 [[s2 [s4]] [s4 [s2]]]]"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "WHERE", "temp", 25, 100}, {"[0 [[s2 [p1 p2]] [s4 [p2]]]]"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "MATCH", "s1"}, {"[0 [[s1 [p1]]]]"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "LIMIT", 1}, {"[1 [[s1 [p1]]]]"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "CURSOR", 1, "LIMIT", 1}, {"[2 [[s2 [p1 p2]]]]"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "CURSOR", 2, "LIMIT", 1}, {"[4 [[s4 [p2]]]]"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "CURSOR", 2, "LIMIT", 5}, {"[0 [[s4 [p2]]]]"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "CURSOR", 10}, {"[0 []]"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "WHERE", "temp", 25, 100, "LIMIT", 1}, {"[2 [[s2 [p1 p2]]]]"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "COUNT"}, {"4"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "CURSOR", 1, "COUNT"}, {"3"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "MATCH", "s4", "COUNT"}, {"1"},
+		{"JOIN", "sensors", "nokey", "WITHIN"}, {"[0 []]"},
+		{"JOIN", "sensors", "parcels", "CONTAINS"}, {"ERR invalid argument 'CONTAINS'"},
+		{"JOIN", "sensors", "parcels", "NEARBY", -1}, {"ERR invalid argument '-1'"},
+		{"JOIN", "sensors", "parcels", "WITHIN", "POINTS"}, {"ERR invalid output type"},
+		{"JOIN", "sensors", "parcels"}, {"ERR wrong number of arguments for 'join' command"},
+
+		{"OUTPUT", "json"}, {`{"ok":true}`},
+		{"JOIN", "sensors", "parcels", "WITHIN", "LIMIT", 2}, {`{"ok":true,"joins":[{"id":"s1","matches":["p1"]},{"id":"s2","matches":["p1","p2"]}],"count":3,"cursor":2}`},
+		{"JOIN", "sensors", "parcels", "WITHIN", "COUNT"}, {`{"ok":true,"count":4}`},
+		{"OUTPUT", "resp"}, {"OK"},
+	})
+}
+
+func keys_CORRIDOR_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "routes", "r1", "OBJECT", `{"type":"LineString","coordinates":[[0,0],[1,0]]}`}, {"OK"},
+		{"SET", "routes", "p1", "POINT", 0, 0}, {"OK"},
+		{"SET", "fleet", "t1", "POINT", 0.001, 0.5}, {"OK"},
+		{"SET", "fleet", "t2", "POINT", 0.01, 0.25}, {"OK"},
+		{"SET", "fleet", "t3", "POINT", 0, 1.2}, {"OK"},
+		{"SET", "fleet", "t4", "BOUNDS", -0.1, 0.7, 0.1, 0.8}, {"OK"},
+		{"SET", "fleet", "t5", "BOUNDS", 0.001, 0.9, 0.02, 0.95}, {"OK"},
+
+		{"WITHIN", "fleet", "IDS", "CORRIDOR", "GET", "routes", "r1", 500}, {"[0 [[t1 111.19492664455875 0.5]]]"},
+		{"INTERSECTS", "fleet", "IDS", "CORRIDOR", "GET", "routes", "r1", 500}, {"[0 [[t1 111.19492664455875 0.5] [t4 0 0.8000000000000002] [t5 111.19492664455875 0.9000000000000001]]]"},
+		{"INTERSECTS", "fleet", "IDS", "CORRIDOR", "OBJECT", `{"type":"LineString","coordinates":[[0,0],[1,0]]}`, 2000}, {"[0 [[t2 1111.9492664455875 0.25] [t1 111.19492664455875 0.5] [t4 0 0.8000000000000002] [t5 111.19492664455875 0.9000000000000001]]]"},
+		{"INTERSECTS", "fleet", "BUFFER", 1500, "IDS", "CORRIDOR", "GET", "routes", "r1", 500}, {"[0 [[t2 1111.9492664455875 0.25] [t1 111.19492664455875 0.5] [t4 0 0.8000000000000002] [t5 111.19492664455875 0.9000000000000001]]]"},
+		{"INTERSECTS", "fleet", "COUNT", "CORRIDOR", "GET", "routes", "r1", 30000}, {"5"},
+		{"INTERSECTS", "fleet", "MATCH", "t1", "POINTS", "CORRIDOR", "GET", "routes", "r1", 500}, {"[0 [[t1 [0.001 0.5] 111.19492664455875 0.5]]]"},
+		{"SET", "routes", "r3", "OBJECT", `{"type":"LineString","coordinates":[[0,0],[1,1],[2,0]]}`}, {"OK"},
+		{"SET", "bends", "b1", "OBJECT", `{"type":"LineString","coordinates":[[0.5,0.5],[1.5,0.5]]}`}, {"OK"},
+		{"SET", "bends", "b2", "OBJECT", `{"type":"LineString","coordinates":[[0.5,0.5],[1,0.999],[1.5,0.5]]}`}, {"OK"},
+		{"WITHIN", "bends", "IDS", "CORRIDOR", "GET", "routes", "r3", 1000}, {"[0 [[b2 0 0.25]]]"},
+		{"SET", "zones", "z1", "BOUNDS", -0.5, -0.5, 0.5, 2}, {"OK"},
+		{"INTERSECTS", "zones", "IDS", "CORRIDOR", "GET", "routes", "r1", 500}, {"[0 [[z1 0 0.75]]]"},
+		{"WITHIN", "fleet", "IDS", "CORRIDOR", "GET", "routes", "p1", 500}, {"ERR corridor route must be a linestring"},
+		{"WITHIN", "fleet", "IDS", "CORRIDOR", "GET", "routes", "r2", 500}, {"ERR id not found"},
+		{"WITHIN", "fleet", "IDS", "CORRIDOR", "GET", "routes", "r1", -1}, {"ERR invalid argument '-1'"},
+		{"WITHIN", "fleet", "IDS", "CORRIDOR", "POINT", 0, 0, 500}, {"ERR invalid argument 'POINT'"},
+		{"WITHIN", "fleet", "IDS", "CORRIDOR", "GET", "routes", "r1"}, {"ERR wrong number of arguments for 'within' command"},
+
+		{"OUTPUT", "json"}, {`{"ok":true}`},
+		{"INTERSECTS", "fleet", "IDS", "CORRIDOR", "GET", "routes", "r1", 500}, {`{"ok":true,"ids":[{"id":"t1","distance":111.19492664455875,"fraction":0.5},{"id":"t4","distance":0,"fraction":0.8000000000000002},{"id":"t5","distance":111.19492664455875,"fraction":0.9000000000000001}],"count":3,"cursor":0}`},
+		{"INTERSECTS", "fleet", "MATCH", "t1", "POINTS", "CORRIDOR", "GET", "routes", "r1", 500}, {`{"ok":true,"points":[{"id":"t1","point":{"lat":0.001,"lon":0.5},"distance":111.19492664455875,"fraction":0.5}],"count":1,"cursor":0}`},
+		{"OUTPUT", "resp"}, {"OK"},
+	})
+}
+
+func keys_ORDERBY_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "fleet", "v1", "FIELD", "speed", 30, "POINT", 33.01, -115.01}, {"OK"},
+		{"SET", "fleet", "v2", "FIELD", "speed", 90, "POINT", 33.02, -115.02}, {"OK"},
+		{"SET", "fleet", "v3", "FIELD", "speed", 60, "POINT", 33.03, -115.03}, {"OK"},
+		{"SET", "fleet", "v4", "FIELD", "speed", 90, "POINT", 33.04, -115.04}, {"OK"},
+		{"SET", "fleet", "v5", "POINT", 33.05, -115.05}, {"OK"},
+		{"SET", "fleet", "v6", "FIELD", "speed", 10, "POINT", 45, -90}, {"OK"},
+		{"SET", "fleet", "v7", "FIELD", "name", "bob", "POINT", 33.06, -115.06}, {"OK"},
+
+		{"SCAN", "fleet", "ORDERBY", "speed", "IDS"}, {"[0 [v5 v7 v6 v1 v3 v2 v4]]"},
+		{"SCAN", "fleet", "ORDERBY", "speed", "DESC", "IDS"}, {"[0 [v2 v4 v3 v1 v6 v5 v7]]"},
+		{"SCAN", "fleet", "ORDERBY", "speed", "DESC", "LIMIT", 2, "IDS"}, {"[2 [v2 v4]]"},
+		{"SCAN", "fleet", "ORDERBY", "speed", "DESC", "CURSOR", 2, "LIMIT", 2, "IDS"}, {"[4 [v3 v1]]"},
+		{"SCAN", "fleet", "ORDERBY", "speed", "DESC", "CURSOR", 6, "LIMIT", 2, "IDS"}, {"[0 [v7]]"},
+		{"SCAN", "fleet", "ORDERBY", "speed", "ASC", "WHERE", "speed", 20, 100, "IDS"}, {"[0 [v1 v3 v2 v4]]"},
+		{"SCAN", "fleet", "ORDERBY", "name", "DESC", "LIMIT", 1, "IDS"}, {"[1 [v7]]"},
+		{"SCAN", "fleet", "ORDERBY", "speed % 60", "DESC", "LIMIT", 3, "IDS"}, {"[3 [v1 v2 v4]]"},
+		{"WITHIN", "fleet", "ORDERBY", "speed", "DESC", "LIMIT", 3, "IDS", "BOUNDS", 33, -116, 34, -115}, {"[3 [v4 v2 v3]]"},
+		{"INTERSECTS", "fleet", "ORDERBY", "speed", "LIMIT", 2, "POINTS", "BOUNDS", 33, -116, 34, -115}, {"[2 [[v7 [33.06 -115.06] [name bob]] [v5 [33.05 -115.05]]]]"},
+		{"NEARBY", "fleet", "ORDERBY", "speed", "DESC", "LIMIT", 2, "IDS", "POINT", 33, -115, 5000}, {"[2 [v2 v3]]"},
+		{"NEARBY", "fleet", "ORDERBY", "speed", "DESC", "LIMIT", 2, "IDS", "POINT", 33, -115}, {"[2 [v2 v4]]"},
+		{"SCAN", "fleet", "ORDERBY", "speed", "COUNT"}, {"7"},
+		{"SCAN", "fleet", "ORDERBY"}, {"ERR wrong number of arguments for 'scan' command"},
+		{"SCAN", "fleet", "ORDERBY", "speed", "ORDERBY", "speed", "IDS"}, {"ERR duplicate argument 'ORDERBY'"},
+		{"SEARCH", "fleet", "ORDERBY", "speed", "IDS"}, {"ORDERBY is not allowed for SEARCH"},
+		{"WITHIN", "fleet", "ORDERBY", "speed", "SPARSE", 1, "IDS", "BOUNDS", 33, -116, 34, -115}, {"ORDERBY is not allowed when SPARSE is specified"},
+		{"SETHOOK", "hook", "http://localhost:8080", "NEARBY", "fleet", "FENCE", "ORDERBY", "speed", "POINT", 33, -115, 5000}, {"ORDERBY is not allowed when FENCE is specified"},
+
+		{"OUTPUT", "json"}, {`{"ok":true}`},
+		{"SCAN", "fleet", "ORDERBY", "speed", "DESC", "LIMIT", 2, "IDS"}, {`{"ok":true,"ids":["v2","v4"],"count":2,"cursor":2}`},
+		{"SCAN", "fleet", "ORDERBY", "speed", "DESC", "MATCH", "v[13]", "OBJECTS"}, {`{"ok":true,"fields":["speed"],"objects":[{"id":"v3","object":{"type":"Point","coordinates":[-115.03,33.03]},"fields":[60]},{"id":"v1","object":{"type":"Point","coordinates":[-115.01,33.01]},"fields":[30]}],"count":2,"cursor":0}`},
+		{"OUTPUT", "resp"}, {"OK"},
+	})
+}
+
+func keys_CURSOR_TOKEN_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "fleet", "b", "POINT", 33.01, -115}, {"OK"},
+		{"SET", "fleet", "c", "POINT", 33.02, -115}, {"OK"},
+		{"SET", "fleet", "d", "POINT", 33.02, -115}, {"OK"},
+		{"SET", "fleet", "e", "POINT", 33.02, -115}, {"OK"},
+		{"SET", "fleet", "f", "POINT", 33.04, -115}, {"OK"},
+		{"SET", "names", "n1", "STRING", "carol"}, {"OK"},
+		{"SET", "names", "n2", "STRING", "alice"}, {"OK"},
+		{"SET", "names", "n3", "STRING", "bob"}, {"OK"},
+		{"SET", "names", "n4", "STRING", "bob"}, {"OK"},
+
+		{"SCAN", "fleet", "CURSOR", "BEGIN", "LIMIT", 2, "IDS"}, {"[AWlj [b c]]"},
+		// objects added before the cursor don't shift the next page
+		{"SET", "fleet", "a", "POINT", 33, -115}, {"OK"},
+		{"SCAN", "fleet", "CURSOR", "AWlj", "LIMIT", 2, "IDS"}, {"[AWll [d e]]"},
+		{"SCAN", "fleet", "CURSOR", "AWll", "LIMIT", 2, "IDS"}, {"[ [f]]"},
+		{"SCAN", "fleet", "CURSOR", "AWll", "LIMIT", 2, "DESC", "IDS"}, {"[AWlj [d c]]"},
+		{"SCAN", "fleet", "CURSOR", "AWlj", "MATCH", "d*", "LIMIT", 2, "IDS"}, {"[ [d]]"},
+		{"SCAN", "fleet", "CURSOR", "AWlj", "COUNT"}, {"3"},
+
+		{"SEARCH", "names", "CURSOR", "BEGIN", "LIMIT", 2, "IDS"}, {"[AXYDYm9ibjM [n2 n3]]"},
+		{"SEARCH", "names", "CURSOR", "AXYDYm9ibjM", "LIMIT", 2, "IDS"}, {"[AXYFY2Fyb2xuMQ [n4 n1]]"},
+		{"SEARCH", "names", "CURSOR", "AXYFY2Fyb2xuMQ", "DESC", "LIMIT", 2, "IDS"}, {"[AXYDYm9ibjM [n4 n3]]"},
+
+		{"WITHIN", "fleet", "CURSOR", "BEGIN", "LIMIT", 3, "IDS", "BOUNDS", 32, -116, 34, -114}, {"[AXfAXMAAAAAAAGM [a b c]]"},
+		{"WITHIN", "fleet", "CURSOR", "AXYDYm9ibjM", "LIMIT", 3, "IDS", "BOUNDS", 32, -116, 34, -114}, {"ERR invalid argument 'AXYDYm9ibjM'"},
+		{"INTERSECTS", "fleet", "CURSOR", "AXfAXMAAAAAAAGM", "LIMIT", 3, "IDS", "BOUNDS", 32, -116, 34, -114}, {"[AXfAXMAAAAAAAGY [d e f]]"},
+		{"INTERSECTS", "fleet", "CURSOR", "AXfAXMAAAAAAAGY", "LIMIT", 3, "IDS", "BOUNDS", 32, -116, 34, -114}, {"[ []]"},
+		{"INTERSECTS", "fleet", "CURSOR", "AWlj", "IDS", "BOUNDS", 32, -116, 34, -114}, {"ERR invalid argument 'AWlj'"},
+
+		{"NEARBY", "fleet", "CURSOR", "BEGIN", "LIMIT", 3, "IDS", "POINT", 33, -115}, {"[AWRAoV_MDIDKw2M [a b c]]"},
+		{"NEARBY", "fleet", "CURSOR", "AWRAoV_MDIDKw2M", "LIMIT", 3, "IDS", "POINT", 33, -115}, {"[AWRAsV_MDIDKw2Y [d e f]]"},
+
+		{"SCAN", "fleet", "CURSOR", "AWRAoV_MDIDKw2M", "IDS"}, {"ERR invalid argument 'AWRAoV_MDIDKw2M'"},
+		{"SCAN", "fleet", "CURSOR", "bad-token", "IDS"}, {"ERR invalid argument 'bad-token'"},
+		{"SCAN", "fleet", "CURSOR", "BEGIN", "ORDERBY", "id", "IDS"}, {"ORDERBY is not allowed with a CURSOR token"},
+		{"WITHIN", "fleet", "SPARSE", 1, "CURSOR", "BEGIN", "IDS", "BOUNDS", 32, -116, 34, -114}, {"CURSOR is not allowed when SPARSE is specified"},
+		{"INTERSECTS", "fleet", "CURSOR", "AXfAXMAAAAAAAGM", "SPARSE", 1, "IDS", "BOUNDS", 32, -116, 34, -114}, {"CURSOR is not allowed when SPARSE is specified"},
+		{"NEARBY", "fleet", "SPARSE", 1, "CURSOR", "AWRAoV_MDIDKw2M", "IDS", "POINT", 33, -115, 1000}, {"CURSOR is not allowed when SPARSE is specified"},
+		{"JOIN", "fleet", "fleet", "INTERSECTS", "CURSOR", "BEGIN"}, {"ERR invalid argument 'BEGIN'"},
+
+		{"OUTPUT", "json"}, {`{"ok":true}`},
+		{"SCAN", "fleet", "CURSOR", "BEGIN", "LIMIT", 2, "IDS"}, {`{"ok":true,"ids":["a","b"],"count":2,"cursor":"AWli"}`},
+		{"SCAN", "fleet", "CURSOR", "AWli", "IDS"}, {`{"ok":true,"ids":["c","d","e","f"],"count":4,"cursor":""}`},
+		{"OUTPUT", "resp"}, {"OK"},
+	})
+}
+
+func keys_EXPLAIN_search_test(mc *mockServer) error {
+	// explainMatch compares the EXPLAIN report without the timings, and the
+	// output size which includes the elapsed time in JSON
+	explainMatch := func(expect string) func(v interface{}) (interface{}, interface{}) {
+		return func(v interface{}) (interface{}, interface{}) {
+			s := v.(string)
+			for _, key := range []string{"expr_time", "lua_time", "time", "output_size"} {
+				s, _ = sjson.Delete(s, "explain."+key)
+			}
+			return s, expect
+		}
+	}
+	return mc.DoBatch([][]interface{}{
+		{"SET", "fleet", "a", "FIELD", "speed", 10, "POINT", 33.01, -115.01}, {"OK"},
+		{"SET", "fleet", "b", "FIELD", "speed", 20, "POINT", 33.02, -115.02}, {"OK"},
+		{"SET", "fleet", "c", "FIELD", "speed", 30, "POINT", 33.03, -115.03}, {"OK"},
+		{"SET", "fleet", "d", "FIELD", "speed", 40, "POINT", 34.5, -116.5}, {"OK"},
+		{"SET", "fleet", "e", "FIELD", "speed", 50, "POINT", 33.04, -115.04}, {"OK"},
+		{"SET", "names", "n1", "STRING", "carol"}, {"OK"},
+		{"SET", "names", "n2", "STRING", "alice"}, {"OK"},
+
+		{"EXPLAIN"}, {"ERR wrong number of arguments for 'explain' command"},
+		{"EXPLAIN", "GET", "fleet", "a"}, {"ERR invalid argument 'GET'"},
+		{"EXPLAIN", "WITHIN", "fleet", "FENCE", "BOUNDS", 33, -116, 34, -115}, {"FENCE is not allowed with EXPLAIN"},
+		{"EXPLAIN", "WITHIN", "fleet", "IDS", "BOUNDS", 33, -116, 34, -115}, {func(s string) bool {
+			return strings.HasPrefix(s, "[access rtree candidates 4 command within "+
+				"expr_time 0s lua_time 0s matched 4 nodes 1 output_size 40 "+
+				"rejected_area 0 rejected_cursor 0 rejected_match 0 "+
+				"rejected_where 0 rejected_whereeval 0 rejected_wherein 0 time ")
+		}},
+
+		{"OUTPUT", "json"}, {`{"ok":true}`},
+		{"EXPLAIN", "WITHIN", "fleet", "WHERE", "speed", 15, 45, "IDS", "BOUNDS", 33, -115.5, 34, -115},
+		{explainMatch(`{"ok":true,"explain":{"access":"rtree","candidates":4,"command":"within","matched":2,"nodes":1,"rejected_area":0,"rejected_cursor":0,"rejected_match":0,"rejected_where":2,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"EXPLAIN", "INTERSECTS", "fleet", "WHEREIN", "speed", 2, 10, 30, "WHEREEVAL", "return FIELDS.speed > 10", 0, "IDS", "BOUNDS", 33, -115.5, 34, -115},
+		{explainMatch(`{"ok":true,"explain":{"access":"rtree","candidates":4,"command":"intersects","matched":1,"nodes":1,"rejected_area":0,"rejected_cursor":0,"rejected_match":0,"rejected_where":0,"rejected_whereeval":1,"rejected_wherein":2}}`)},
+		{"EXPLAIN", "WITHIN", "fleet", "SPARSE", 1, "BOUNDS", 33, -115.5, 34, -115},
+		{explainMatch(`{"ok":true,"explain":{"access":"rtree sparse","candidates":1,"command":"within","matched":1,"nodes":4,"rejected_area":0,"rejected_cursor":0,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"EXPLAIN", "SCAN", "fleet", "MATCH", "*[bc]", "WHERE", "speed > 10", "IDS"},
+		{explainMatch(`{"ok":true,"explain":{"access":"id scan","candidates":5,"command":"scan","matched":2,"nodes":0,"rejected_area":0,"rejected_cursor":0,"rejected_match":3,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"EXPLAIN", "SCAN", "fleet", "MATCH", "c*", "IDS"},
+		{explainMatch(`{"ok":true,"explain":{"access":"id range scan","candidates":2,"command":"scan","matched":1,"nodes":0,"rejected_area":1,"rejected_cursor":0,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"EXPLAIN", "SCAN", "fleet", "COUNT"},
+		{explainMatch(`{"ok":true,"explain":{"access":"count","candidates":0,"command":"scan","matched":5,"nodes":0,"rejected_area":0,"rejected_cursor":0,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"EXPLAIN", "SCAN", "fleet", "CURSOR", "AWlj", "IDS"},
+		{explainMatch(`{"ok":true,"explain":{"access":"id scan from cursor","candidates":3,"command":"scan","matched":2,"nodes":0,"rejected_area":0,"rejected_cursor":1,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"EXPLAIN", "SEARCH", "names", "IDS"},
+		{explainMatch(`{"ok":true,"explain":{"access":"value scan","candidates":2,"command":"search","matched":2,"nodes":0,"rejected_area":0,"rejected_cursor":0,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"EXPLAIN", "NEARBY", "fleet", "LIMIT", 2, "IDS", "POINT", 33, -115},
+		{explainMatch(`{"ok":true,"explain":{"access":"rtree nearest","candidates":2,"command":"nearby","matched":2,"nodes":1,"rejected_area":0,"rejected_cursor":0,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"EXPLAIN", "NEARBY", "fleet", "IDS", "POINT", 33, -115, 5000},
+		{explainMatch(`{"ok":true,"explain":{"access":"rtree nearest","candidates":4,"command":"nearby","matched":3,"nodes":1,"rejected_area":1,"rejected_cursor":0,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		// a CURSOR token continues the search without visiting the objects
+		// of the earlier pages again
+		{"WITHIN", "fleet", "CURSOR", "BEGIN", "LIMIT", 2, "IDS", "BOUNDS", 33, -117, 35, -115}, {`{"ok":true,"ids":["d","e"],"count":2,"cursor":"AXfAXMKPYAAAAGU"}`},
+		{"EXPLAIN", "WITHIN", "fleet", "CURSOR", "AXfAXMKPYAAAAGU", "LIMIT", 2, "IDS", "BOUNDS", 33, -117, 35, -115},
+		{explainMatch(`{"ok":true,"explain":{"access":"rtree from cursor","candidates":4,"command":"within","matched":2,"nodes":1,"rejected_area":1,"rejected_cursor":1,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"NEARBY", "fleet", "CURSOR", "BEGIN", "LIMIT", 2, "IDS", "POINT", 33, -115}, {`{"ok":true,"ids":["a","b"],"count":2,"cursor":"AWRApqywPxwuGWI"}`},
+		{"EXPLAIN", "NEARBY", "fleet", "CURSOR", "AWRApqywPxwuGWI", "LIMIT", 2, "IDS", "POINT", 33, -115},
+		{explainMatch(`{"ok":true,"explain":{"access":"rtree nearest from cursor","candidates":4,"command":"nearby","matched":2,"nodes":1,"rejected_area":1,"rejected_cursor":1,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"EXPLAIN", "WITHIN", "nofleet", "IDS", "BOUNDS", 33, -116, 34, -115},
+		{explainMatch(`{"ok":true,"explain":{"access":"none","candidates":0,"command":"within","matched":0,"nodes":0,"rejected_area":0,"rejected_cursor":0,"rejected_match":0,"rejected_where":0,"rejected_whereeval":0,"rejected_wherein":0}}`)},
+		{"OUTPUT", "resp"}, {"OK"},
+	})
+}
+
+func keys_FIELDS_PROJECTION_search_test(mc *mockServer) error {
+	return mc.DoBatch([][]interface{}{
+		{"SET", "fleet", "a", "FIELD", "speed", 10, "FIELD", "fuel", 80, "POINT", 33.01, -115}, {"OK"},
+		{"SET", "fleet", "b", "FIELD", "speed", 30, "FIELD", "fuel", 20, "POINT", 33.02, -115}, {"OK"},
+		{"SET", "fleet", "c", "FIELD", "fuel", 50, "OBJECT", `{"type":"Feature","geometry":{"type":"Point","coordinates":[-115,33.03]},"properties":{"name":"Carl"}}`}, {"OK"},
+		{"SET", "names", "n1", "FIELD", "age", 30, "FIELD", "score", 5, "STRING", "alice"}, {"OK"},
+
+		{"SCAN", "fleet", "FIELDS", 1, "speed", "POINTS"}, {"[0 [[a [33.01 -115] [speed 10]] [b [33.02 -115] [speed 30]] [c [33.03 -115]]]]"},
+		{"SCAN", "fleet", "FIELDS", 2, "speed * 2", "AS", "double", "properties.name", "AS", "name", "POINTS"}, {"[0 [[a [33.01 -115] [double 20]] [b [33.02 -115] [double 60]] [c [33.03 -115] [name Carl]]]]"},
+		{"SCAN", "fleet", "FIELDS", 1, "speed", "ORDERBY", "fuel", "POINTS"}, {"[0 [[b [33.02 -115] [speed 30]] [c [33.03 -115]] [a [33.01 -115] [speed 10]]]]"},
+		{"WITHIN", "fleet", "FIELDS", 1, "fuel", "WHERE", "speed", 20, 40, "POINTS", "BOUNDS", 33, -116, 34, -114}, {"[0 [[b [33.02 -115] [fuel 20]]]]"},
+		{"INTERSECTS", "fleet", "FIELDS", 1, "fuel", "AS", "f", "POINTS", "BOUNDS", 33, -116, 34, -114}, {"[0 [[c [33.03 -115] [f 50]] [b [33.02 -115] [f 20]] [a [33.01 -115] [f 80]]]]"},
+		{"NEARBY", "fleet", "LIMIT", 1, "FIELDS", 1, "speed", "POINTS", "POINT", 33, -115}, {"[1 [[a [33.01 -115] [speed 10]]]]"},
+		{"SEARCH", "names", "FIELDS", 1, "age", "OBJECTS"}, {"[0 [[n1 alice [age 30]]]]"},
+		{"SCAN", "fleet", "FIELDS", 1, "speed", "NOFIELDS", "IDS"}, {"FIELDS is not allowed when NOFIELDS is specified"},
+		{"JOIN", "fleet", "fleet", "INTERSECTS", "FIELDS", 1, "speed"}, {"FIELDS is not allowed for JOIN"},
+
+		{"OUTPUT", "json"}, {`{"ok":true}`},
+		{"SCAN", "fleet", "FIELDS", 1, "speed", "POINTS"}, {`{"ok":true,"fields":["speed"],"points":[{"id":"a","point":{"lat":33.01,"lon":-115},"fields":[10]},{"id":"b","point":{"lat":33.02,"lon":-115},"fields":[30]},{"id":"c","point":{"lat":33.03,"lon":-115},"fields":[0]}],"count":3,"cursor":0}`},
+		{"SCAN", "fleet", "FIELDS", 1, "speed > 20", "AS", "fast", "POINTS"}, {`{"ok":true,"fields":["fast"],"points":[{"id":"a","point":{"lat":33.01,"lon":-115},"fields":[false]},{"id":"b","point":{"lat":33.02,"lon":-115},"fields":[true]},{"id":"c","point":{"lat":33.03,"lon":-115},"fields":[false]}],"count":3,"cursor":0}`},
+		{"OUTPUT", "resp"}, {"OK"},
+	})
+}
+
+func keys_CLIP_POLYGON_search_test(mc *mockServer) error {
+	// a U shaped town with a park in the bottom
+	town := `{"type":"Polygon","coordinates":[[[-115,33],[-114.9,33],[-114.9,33.1],[-114.93,33.1],[-114.93,33.03],[-114.97,33.03],[-114.97,33.1],[-115,33.1],[-115,33]],[[-114.99,33.005],[-114.98,33.005],[-114.98,33.015],[-114.99,33.015],[-114.99,33.005]]]}`
+	return mc.DoBatch([][]interface{}{
+		{"SET", "cities", "town", "OBJECT", town}, {"OK"},
+		{"SET", "cities", "center", "POINT", 33.05, -114.95}, {"OK"},
+		{"SET", "roads", "r1", "OBJECT", `{"type":"LineString","coordinates":[[-115.05,33.05],[-114.85,33.05]]}`}, {"OK"},
+		{"SET", "roads", "r2", "OBJECT", `{"type":"LineString","coordinates":[[-115.05,33.01],[-114.85,33.01]]}`}, {"OK"},
+		{"SET", "roads", "r3", "OBJECT", `{"type":"LineString","coordinates":[[-115.05,33.2],[-114.85,33.2]]}`}, {"OK"},
+		{"SET", "lots", "l1", "BOUNDS", 33, -114.95, 33.02, -114.88}, {"OK"},
+
+		{"INTERSECTS", "roads", "CLIP", "GET", "cities", "town"}, {`[0 [[r2 {"type":"MultiLineString","coordinates":[[[-115,33.01],[-114.99,33.01]],[[-114.98,33.01],[-114.9,33.01]]]}] [r1 {"type":"MultiLineString","coordinates":[[[-115,33.05],[-114.97,33.05]],[[-114.93,33.05],[-114.9,33.05]]]}]]]`},
+		{"INTERSECTS", "lots", "CLIP", "GET", "cities", "town"}, {`[0 [[l1 {"type":"Polygon","coordinates":[[[-114.95,33],[-114.9,33],[-114.9,33.02],[-114.95,33.02],[-114.95,33]]]}]]]`},
+		{"INTERSECTS", "roads", "CLIP", "OBJECT", `{"type":"MultiPolygon","coordinates":[[[[-115,33],[-114.99,33],[-114.99,33.1],[-115,33.1],[-115,33]]],[[[-114.91,33],[-114.9,33],[-114.9,33.1],[-114.91,33.1],[-114.91,33]]]]}`}, {`[0 [[r2 {"type":"MultiLineString","coordinates":[[[-115,33.01],[-114.99,33.01]],[[-114.91,33.01],[-114.9,33.01]]]}] [r1 {"type":"MultiLineString","coordinates":[[[-115,33.05],[-114.99,33.05]],[[-114.91,33.05],[-114.9,33.05]]]}]]]`},
+		{"INTERSECTS", "roads", "CLIP", "GET", "cities", "center"}, {"ERR invalid argument 'cannot clip with get'"},
+		{"INTERSECTS", "roads", "CLIP", "OBJECT", `{"type":"LineString","coordinates":[[-115,33],[-114,34]]}`}, {"ERR invalid argument 'cannot clip with object'"},
+	})
+}
+
 // match sorts the response and compares to the expected input
 func match(expectIn string) func(org, v interface{}) (resp, expect interface{}) {
 	return func(v, org interface{}) (resp, expect interface{}) {
@@ -3,6 +3,7 @@ package tests
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"strings"
 	"time"
@@ -38,6 +39,11 @@ func subTestKeys(g *testGroup) {
 	g.regSubTest("HEALTHZ", keys_HEALTHZ_test)
 	g.regSubTest("SERVER", keys_SERVER_test)
 	g.regSubTest("INFO", keys_INFO_test)
+	g.regSubTest("SCHEMA", keys_SCHEMA_test)
+	g.regSubTest("SELECT", keys_SELECT_test)
+	g.regSubTest("DISTANCE", keys_DISTANCE_test)
+	g.regSubTest("GEOMOP", keys_GEOMOP_test)
+	g.regSubTest("SETMOTION", keys_SETMOTION_test)
 }
 
 func keys_BOUNDS_test(mc *mockServer) error {
@@ -574,6 +580,17 @@ func keys_FIELDS_test(mc *mockServer) error {
 		Do("SET", "fleet", "truck1", "FIELD", "speed", "2", "POINT", "-112", "33").JSON().OK(),
 		Do("GET", "fleet", "truck1", "WITHFIELDS").JSON().Str(`{"ok":true,"object":{"type":"Point","coordinates":[33,-112]},"fields":{"speed":2}}`),
 
+		// Field projections
+		Do("SET", "fleet", "truck3", "FIELD", "speed", 10, "FIELD", "heading", 90, "FIELD", "fuel", 50, "POINT", 33, -112).OK(),
+		Do("GET", "fleet", "truck3", "FIELDS", 1, "speed").Str(`[{"type":"Point","coordinates":[-112,33]} [speed 10]]`),
+		Do("GET", "fleet", "truck3", "FIELDS", 2, "speed", "speed * 3.6", "AS", "kmh", "POINT").Str("[[33 -112] [kmh 36 speed 10]]"),
+		Do("GET", "fleet", "truck3", "FIELDS", 2, "fuel", "AS", "f", "missing").JSON().Str(`{"ok":true,"object":{"type":"Point","coordinates":[-112,33]},"fields":{"f":50}}`),
+		Do("GET", "fleet", "truck3", "FIELDS", 0).Err("invalid argument '0'"),
+		Do("GET", "fleet", "truck3", "FIELDS", 2, "speed").Err("wrong number of arguments for 'get' command"),
+		Do("GET", "fleet", "truck3", "FIELDS", 1, "speed", "AS").Err("wrong number of arguments for 'get' command"),
+		Do("GET", "fleet", "truck3", "FIELDS", 1, "speed", "FIELDS", 1, "fuel").Err("duplicate argument 'FIELDS'"),
+		Do("DEL", "fleet", "truck3").Str("1"),
+
 		// Do some whereins queries
 		Do("SET", "whereins", "id1", "FIELD", "test", "2", "POINT", "-112", "33").JSON().OK(),
 		Do("SET", "whereins", "id2", "FIELD", "TEST", "3", "POINT", "-111", "32").JSON().OK(),
@@ -854,3 +871,189 @@ func keys_INFO_test(mc *mockServer) error {
 		}),
 	)
 }
+
+func keys_SCHEMA_test(mc *mockServer) error {
+	return mc.DoBatch(
+		Do("SETSCHEMA", "fleet").Err("wrong number of arguments for 'setschema' command"),
+		Do("SETSCHEMA", "fleet", "FIELD", "speed", "float").Err("invalid argument 'float'"),
+		Do("SETSCHEMA", "fleet", "FIELD", "name", "string", "MIN", 0).Err("invalid argument 'MIN'"),
+		Do("SETSCHEMA", "fleet", "FIELD", "z", "number").Err("invalid argument 'z'"),
+		Do("SET", "fleet", "truck0", "FIELD", "speed", "fast", "POINT", 33, -115).OK(),
+		Do("SETSCHEMA", "fleet", "FIELD", "speed", "number", "MIN", 0, "MAX", 300, "FIELD", "name", "string", "REQUIRED").OK(),
+		Do("SCHEMAS", "*").Str("[[fleet [field speed number min 0 max 300 field name string required]]]"),
+		Do("SCHEMAS", "*").JSON().Str(`{"ok":true,"schemas":[{"key":"fleet","fields":[{"name":"speed","kind":"number","required":false,"min":0,"max":300},{"name":"name","kind":"string","required":true}]}]}`),
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).Err("schema: missing required field 'name'"),
+		Do("SET", "fleet", "truck1", "FIELD", "name", "Truck", "FIELD", "speed", "fast", "POINT", 33, -115).Err("schema: field 'speed' must be a number"),
+		Do("SET", "fleet", "truck1", "FIELD", "name", "Truck", "FIELD", "speed", 301, "POINT", 33, -115).Err("schema: field 'speed' is greater than 300"),
+		Do("SET", "fleet", "truck1", "FIELD", "name", "Truck", "FIELD", "speed", -1, "POINT", 33, -115).JSON().Err("schema: field 'speed' is less than 0"),
+		Do("SET", "fleet", "truck1", "FIELD", "name", "Truck", "FIELD", "speed", 90, "POINT", 33, -115).OK(),
+		Do("SET", "fleet", "truck2", "FIELD", "name", "Truck", "POINT", 33, -115).OK(),
+		Do("FSET", "fleet", "truck1", "speed", "fast").Err("schema: field 'speed' must be a number"),
+		Do("FSET", "fleet", "truck1", "name", 12).Err("schema: field 'name' must be a string"),
+		Do("FSET", "fleet", "truck1", "speed", 100).Str("1"),
+		Do("JSET", "fleet", "truck3", "hello", "world").Err("schema: missing required field 'name'"),
+		Do("GET", "fleet", "truck1", "WITHFIELDS").Str(`[{"type":"Point","coordinates":[-115,33]} [name Truck speed 100]]`),
+		Do("SET", "other", "truck1", "POINT", 33, -115).OK(),
+		Do("VALIDATE", "fleet").Str("[3 [[truck0 schema: field 'speed' must be a number]]]"),
+		Do("VALIDATE", "fleet", "FIELD", "speed", "number", "MAX", 95).JSON().Str(`{"ok":true,"count":3,"invalid":[{"id":"truck0","err":"schema: field 'speed' must be a number"},{"id":"truck1","err":"schema: field 'speed' is greater than 95"}]}`),
+		Do("VALIDATE", "other").Err("schema not found"),
+		Do("DELSCHEMA", "fleet").Str("1"),
+		Do("DELSCHEMA", "fleet").Str("0"),
+		Do("SCHEMAS", "*").Str("[]"),
+		Do("SET", "fleet", "truck4", "FIELD", "speed", "fast", "POINT", 33, -115).OK(),
+		Do("SETSCHEMA", "fleet", "FIELD", "active", "bool", "FIELD", "meta", "json").OK(),
+		Do("FSET", "fleet", "truck4", "active", "yes").Err("schema: field 'active' must be a bool"),
+		Do("FSET", "fleet", "truck4", "active", "true", "meta", `{"a":1}`).Str("2"),
+		Do("SETSCHEMA", "counts", "FIELD", "n", "number", "REQUIRED", "FIELD", "m", "number", "MIN", 1).OK(),
+		Do("SET", "counts", "c1", "POINT", 33, -115).Err("schema: missing required field 'n'"),
+		Do("SET", "counts", "c1", "FIELD", "n", 0, "POINT", 33, -115).OK(),
+		Do("SET", "counts", "c1", "POINT", 33, -114).OK(),
+		Do("SET", "counts", "c2", "FIELD", "n", 1, "FIELD", "m", 0, "POINT", 33, -115).Err("schema: field 'm' is less than 1"),
+		Do("RENAME", "counts", "counts2").OK(),
+		Do("SCHEMAS", "counts*").Str("[[counts2 [field n number required field m number min 1]]]"),
+		Do("SET", "counts2", "c2", "POINT", 33, -115).Err("schema: missing required field 'n'"),
+		Do("SET", "counts", "c2", "POINT", 33, -115).OK(),
+		Do("FLUSHDB").OK(),
+		Do("SCHEMAS", "*").Str("[]"),
+	)
+}
+
+func keys_SELECT_test(mc *mockServer) error {
+	return mc.DoBatch(
+		Do("SELECT").Err("wrong number of arguments for 'select' command"),
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).OK(),
+		Do("SETCHAN", "ch1", "NEARBY", "fleet", "FENCE", "POINT", 33, -115, 100).Str("1"),
+		Do("SELECT", "staging").OK(),
+		Do("KEYS", "*").Str("[]"),
+		Do("CHANS", "*").Str("[]"),
+		Do("GET", "fleet", "truck1").Str("<nil>"),
+		Do("SET", "fleet", "truck2", "POINT", 34, -116).OK(),
+		Do("SETCHAN", "ch1", "NEARBY", "fleet", "FENCE", "POINT", 34, -116, 100).Str("1"),
+		Do("SWAPDB", "0", "staging").Err("database has channels set"),
+		Do("DELCHAN", "ch1").Str("1"),
+		Do("SELECT", "0").OK(),
+		Do("GET", "fleet", "truck2").Str("<nil>"),
+		Do("GET", "fleet", "truck1").Str(`{"type":"Point","coordinates":[-115,33]}`),
+		Do("DELCHAN", "ch1").Str("1"),
+		Do("SWAPDB", "0", "staging").OK(),
+		Do("GET", "fleet", "truck2").Str(`{"type":"Point","coordinates":[-116,34]}`),
+		Do("GET", "fleet", "truck1").Str("<nil>"),
+		Do("SELECT", "staging").JSON().OK(),
+		Do("GET", "fleet", "truck1").Str(`{"type":"Point","coordinates":[-115,33]}`),
+		Do("FLUSHDB").OK(),
+		Do("KEYS", "*").Str("[]"),
+		Do("SELECT", "0").OK(),
+		Do("KEYS", "*").Str("[fleet]"),
+	)
+}
+
+func keys_DISTANCE_test(mc *mockServer) error {
+	return mc.DoBatch(
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).OK(),
+		Do("SET", "fleet", "truck2", "POINT", 33.01, -115).OK(),
+		Do("SET", "zones", "zone1", "BOUNDS", 33.02, -115.01, 33.03, -114.99).OK(),
+		Do("DISTANCE", "fleet", "truck1", "fleet", "truck2").Str("1111.9492664448107"),
+		Do("DISTANCE", "fleet", "truck1", "fleet", "truck2").JSON().Str(`{"ok":true,"distance":1111.9492664448107}`),
+		Do("DISTANCE", "fleet", "truck2", "zones", "zone1").Str("1111.993603555706"),
+		Do("DISTANCE", "zones", "zone1", "POINT", 33.025, -115).Str("0"),
+		Do("DISTANCE", "fleet", "truck1", "POINT", 33, -115).Str("0"),
+		Do("DISTANCE", "fleet", "truck3", "fleet", "truck1").Str("<nil>"),
+		Do("DISTANCE", "fleet", "truck3", "fleet", "truck1").JSON().Err("id not found"),
+		Do("DISTANCE", "fleet", "truck1", "nada", "truck1").JSON().Err("key not found"),
+		Do("DISTANCE", "fleet", "truck1", "CIRCLE", 33, -115).Err("invalid argument 'CIRCLE'"),
+		Do("DISTANCE", "fleet", "truck1", "POINT", "hi", -115).Err("invalid argument 'hi'"),
+		Do("DISTANCE", "fleet", "truck1", "fleet").Err("wrong number of arguments for 'distance' command"),
+		Do("SET", "roads", "road1", "OBJECT", `{"type":"LineString","coordinates":[[-115,33],[-115,33.01]]}`).OK(),
+		Do("SCAN", "roads", "LENGTH", "IDS").Str("[0 [[road1 [length 1111.9492664448107]]]]"),
+		Do("SCAN", "zones", "AREA", "LENGTH", "IDS").Str("[0 [[zone1 [area 2073329.0050934544 length 5953.077658723724]]]]"),
+		Do("NEARBY", "fleet", "DISTANCE", "AREA", "IDS", "POINT", 33, -115).Str("[0 [[truck1 0 [area 0]] [truck2 1111.9492664448107 [area 0]]]]"),
+		Do("SCAN", "zones", "AREA", "IDS").JSON().Str(`{"ok":true,"ids":[{"id":"zone1","area":2073329.0050934544}],"count":1,"cursor":0}`),
+		Do("SCAN", "roads", "WHERE", "length > 1000", "IDS").Str("[0 [road1]]"),
+		Do("SCAN", "roads", "WHERE", "length > 2000", "IDS").Str("[0 []]"),
+		Do("SCAN", "roads", "LENGTH", "LENGTH", "IDS").Err("duplicate argument 'LENGTH'"),
+	)
+}
+
+func keys_GEOMOP_test(mc *mockServer) error {
+	square1 := `{"type":"Polygon","coordinates":[[[-115,33],[-114.98,33],[-114.98,33.02],[-115,33.02],[-115,33]]]}`
+	square2 := `{"type":"Polygon","coordinates":[[[-114.99,33.01],[-114.97,33.01],[-114.97,33.03],[-114.99,33.03],[-114.99,33.01]]]}`
+	return mc.DoBatch(
+		Do("SET", "zones", "z1", "OBJECT", square1).OK(),
+		Do("SET", "zones", "z2", "OBJECT", square2).OK(),
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).OK(),
+		Do("GEOMOP", "UNION", "GET", "zones", "z1", "GET", "zones", "z2").Str(`{"type":"Polygon","coordinates":[[[-115,33],[-114.98,33],[-114.98,33.01],[-114.97,33.01],[-114.97,33.03],[-114.99,33.03],[-114.99,33.02],[-115,33.02],[-115,33]]]}`),
+		Do("GEOMOP", "INTERSECTION", "GET", "zones", "z1", "OBJECT", square2).Str(`{"type":"Polygon","coordinates":[[[-114.99,33.01],[-114.98,33.01],[-114.98,33.02],[-114.99,33.02],[-114.99,33.01]]]}`),
+		Do("GEOMOP", "INTERSECTION", "GET", "zones", "z1", "OBJECT", square2).JSON().Str(`{"ok":true,"object":{"type":"Polygon","coordinates":[[[-114.99,33.01],[-114.98,33.01],[-114.98,33.02],[-114.99,33.02],[-114.99,33.01]]]}}`),
+		Do("GEOMOP", "DIFFERENCE", "GET", "zones", "z1", "GET", "zones", "z2").Str(`{"type":"Polygon","coordinates":[[[-115,33],[-114.98,33],[-114.98,33.01],[-114.99,33.01],[-114.99,33.02],[-115,33.02],[-115,33]]]}`),
+		Do("GEOMOP", "HULL", "OBJECT", `{"type":"MultiPoint","coordinates":[[0,0],[2,0],[1,1],[2,2],[0,2]]}`).Str(`{"type":"Polygon","coordinates":[[[0,0],[2,0],[2,2],[0,2],[0,0]]]}`),
+		Do("GEOMOP", "CENTROID", "GET", "zones", "z1").Str(`{"type":"Point","coordinates":[-114.99000000000002,33.01]}`),
+		Do("GEOMOP", "SIMPLIFY", "OBJECT", `{"type":"LineString","coordinates":[[0,0],[0.001,0.0001],[0.002,0]]}`, 20).Str(`{"type":"LineString","coordinates":[[0,0],[0.002,0]]}`),
+		Do("GEOMOP", "BUFFER", "GET", "fleet", "truck1", 100, "STORE", "zones", "z3").OK(),
+		Do("INTERSECTS", "zones", "IDS", "POINT", 33.0005, -115).Str("[0 [z3 z1]]"),
+		Do("INTERSECTS", "zones", "IDS", "POINT", 33.002, -115.002).Str("[0 []]"),
+		Do("GEOMOP", "UNION", "GET", "zones", "z1", "GET", "zones", "z2", "STORE", "zones", "z4").JSON().OK(),
+		Do("GEOMOP", "CENTROID", "GET", "zones", "z4").Str(`{"type":"Point","coordinates":[-114.985,33.015]}`),
+		Do("GEOMOP", "UNION", "GET", "zones", "z1", "GET", "fleet", "truck1").Err("not a polygon"),
+		Do("GEOMOP", "UNION", "GET", "zones", "z1", "GET", "zones", "z9").Err("id not found"),
+		Do("GEOMOP", "BUFFER", "GET", "fleet", "truck1", -1).Err("invalid meters"),
+		Do("GEOMOP", "BUFFER", "GET", "fleet", "truck1", "hi").Err("invalid argument 'hi'"),
+		Do("GEOMOP", "SPLIT", "GET", "fleet", "truck1").Err("invalid argument 'SPLIT'"),
+		Do("GEOMOP", "CENTROID", "POINT", 33, -115).Err("invalid argument 'POINT'"),
+		Do("GEOMOP", "CENTROID", "GET", "fleet", "truck1", "KEEP").Err("invalid argument 'KEEP'"),
+		Do("GEOMOP", "CENTROID", "GET", "fleet", "truck1", "STORE", "zones").Err("wrong number of arguments for 'geomop' command"),
+		Do("GEOMOP", "CENTROID").Err("wrong number of arguments for 'geomop' command"),
+	)
+}
+
+func keys_SETMOTION_test(mc *mockServer) error {
+	return mc.DoBatch(
+		Do("SETMOTION", "fleet", "on").OK(),
+		Do("SET", "fleet", "truck1", "POINT", 33, -115).OK(),
+		Sleep(time.Second/2),
+		// about 93 meters east
+		Do("SET", "fleet", "truck1", "POINT", 33, -114.999).OK(),
+		Do("GET", "fleet", "truck1", "WITHFIELDS").JSON().Func(func(s string) error {
+			dt := gjson.Get(s, "fields.motion_dt").Float()
+			speed := gjson.Get(s, "fields.motion_speed").Float()
+			heading := gjson.Get(s, "fields.motion_heading").Float()
+			accel := gjson.Get(s, "fields.motion_accel").Float()
+			if dt < 0.4 || dt > 2 || speed < 40 || speed > 240 ||
+				heading < 89.9 || heading > 90.1 ||
+				math.Abs(accel-speed/dt) > 0.01 ||
+				gjson.Get(s, "fields.motion_ts").Float() == 0 {
+				return fmt.Errorf("unexpected fields '%s'", s)
+			}
+			return nil
+		}),
+		Do("SCAN", "fleet", "WHERE", "motion_speed > 20 && motion_heading > 89", "IDS").Str("[0 [truck1]]"),
+		Do("STATS", "fleet").JSON().Func(func(s string) error {
+			if !gjson.Get(s, "stats.0.motion").Bool() {
+				return fmt.Errorf("expected motion, got '%s'", s)
+			}
+			return nil
+		}),
+		// the derived fields are reserved, and a field of the client with
+		// the same name as one without the prefix is its own
+		Do("SET", "fleet", "truck2", "FIELD", "motion_ts", 100, "POINT", 33, -115).Err("invalid argument 'motion_ts'"),
+		Do("FSET", "fleet", "truck1", "motion_speed", 1).Err("invalid argument 'motion_speed'"),
+		Do("SET", "fleet", "truck2", "FIELD", "ts", 100, "FIELD", "speed", 7, "POINT", 33, -115).OK(),
+		Do("GET", "fleet", "truck2", "WITHFIELDS").JSON().Func(func(s string) error {
+			if gjson.Get(s, "fields.ts").Float() != 100 ||
+				gjson.Get(s, "fields.speed").Float() != 7 ||
+				gjson.Get(s, "fields.motion_ts").Float() == 0 {
+				return fmt.Errorf("unexpected fields '%s'", s)
+			}
+			return nil
+		}),
+		// only a replayed SET has the derived fields as an argument
+		Do("SET", "fleet", "truck4", "MOTION", 100, 2, 5, 90, 0, "POINT", 33, -115).Err("invalid argument 'MOTION'"),
+		Do("SETMOTION", "fleet", "off").OK(),
+		Do("SET", "fleet", "truck3", "POINT", 33, -115).OK(),
+		Do("GET", "fleet", "truck3", "WITHFIELDS").Str(`[{"type":"Point","coordinates":[-115,33]}]`),
+		// the prefix is only reserved in a collection with motion
+		Do("SET", "fleet", "truck3", "FIELD", "motion_state", 1, "POINT", 33, -115).OK(),
+		Do("FSET", "fleet", "truck3", "motion_state", 2).Str("1"),
+		Do("SETMOTION", "fleet", "maybe").Err("invalid argument 'maybe'"),
+		Do("SETMOTION", "fleet").Err("wrong number of arguments for 'setmotion' command"),
+	)
+}
@@ -9,6 +9,7 @@ import (
 
 func subTestMetrics(g *testGroup) {
 	g.regSubTest("basic", metrics_basic_test)
+	g.regSubTest("hooks", metrics_hooks_test)
 }
 
 func downloadURLWithStatusCode(u string) (int, string, error) {
@@ -32,6 +33,10 @@ func metrics_basic_test(mc *mockServer) error {
 	mc.Do("SET", "metrics_test_2", "2", "FIELD", "foo", 19.19, "POINT", 19, 19)
 	mc.Do("SET", "metrics_test_2", "3", "FIELD", "foo", 19.19, "POINT", 19, 19)
 	mc.Do("SET", "metrics_test_2", "truck1:driver", "STRING", "John Denton")
+	mc.Do("SELECT", "1")
+	mc.Do("SET", "metrics_test_1", "1", "POINT", 5, 5)
+	mc.Do("SET", "metrics_test_1", "2", "POINT", 5, 5)
+	mc.Do("SELECT", "0")
 
 	status, index, err := downloadURLWithStatusCode(maddr)
 	if err != nil {
@@ -56,9 +61,10 @@ func metrics_basic_test(mc *mockServer) error {
 		`tile38_cmd_duration_seconds_count{cmd="set"}`,
 		`go_build_info`,
 		`go_threads`,
-		`tile38_collection_objects{col="metrics_test_1"} 1`,
-		`tile38_collection_objects{col="metrics_test_2"} 3`,
-		`tile38_collection_points{col="metrics_test_2"} 2`,
+		`tile38_collection_objects{col="metrics_test_1",db="0"} 1`,
+		`tile38_collection_objects{col="metrics_test_2",db="0"} 3`,
+		`tile38_collection_points{col="metrics_test_2",db="0"} 2`,
+		`tile38_collection_objects{col="metrics_test_1",db="1"} 2`,
 		`tile38_replication_info`,
 		`role="leader"`,
 	} {
@@ -68,3 +74,31 @@ func metrics_basic_test(mc *mockServer) error {
 	}
 	return nil
 }
+
+func metrics_hooks_test(mc *mockServer) error {
+	maddr := fmt.Sprintf("http://127.0.0.1:%d/metrics", mc.metricsPort())
+	_, err := mc.Do("SETHOOK", "mh", "http://127.0.0.1:1/endpoint", "NEARBY",
+		"fleet", "FENCE", "POINT", 33, -115, 5000)
+	if err != nil {
+		return err
+	}
+	defer mc.Do("DELHOOK", "mh")
+	status, metrics, err := downloadURLWithStatusCode(maddr)
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return fmt.Errorf("Expected status code 200, got: %d", status)
+	}
+	for _, want := range []string{
+		`tile38_hook_events_queued_total{db="0",hook="mh"} 0`,
+		`tile38_hook_events_delivered_total{db="0",hook="mh"} 0`,
+		`tile38_hook_queue_depth{db="0",hook="mh"} 0`,
+		`tile38_hook_delivery_latency_seconds_count{db="0",hook="mh"} 0`,
+	} {
+		if !strings.Contains(metrics, want) {
+			return fmt.Errorf("wanted metric: %s, got: %s", want, metrics)
+		}
+	}
+	return nil
+}
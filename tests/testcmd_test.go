@@ -174,7 +174,8 @@ func testcmd_INTERSECTS_CLIP_test(mc *mockServer) error {
 	return mc.DoBatch(
 		Do("SET", "mykey", "point1", "POINT", 37.7335, -122.4412).OK(),
 
-		Do("TEST", "OBJECT", poly9, "INTERSECTS", "CLIP", "OBJECT", "{}").Err("invalid clip type 'OBJECT'"),
+		Do("TEST", "OBJECT", poly9, "INTERSECTS", "CLIP", "OBJECT", "{}").Err("missing type"),
+		Do("TEST", "OBJECT", poly9, "INTERSECTS", "CLIP", "OBJECT", `{"type":"Point","coordinates":[-122.4412,37.7335]}`).Err("invalid clip type 'OBJECT'"),
 		Do("TEST", "OBJECT", poly9, "INTERSECTS", "CLIP", "CIRCLE", "1", "2", "3").Err("invalid clip type 'CIRCLE'"),
 		Do("TEST", "OBJECT", poly9, "INTERSECTS", "CLIP", "GET", "mykey", "point1").Err("invalid clip type 'GET'"),
 		Do("TEST", "OBJECT", poly9, "WITHIN", "CLIP", "BOUNDS", 10, 10, 20, 20).Err("invalid argument 'CLIP'"),
@@ -186,6 +187,10 @@ func testcmd_INTERSECTS_CLIP_test(mc *mockServer) error {
 		Do("TEST", "OBJECT", poly8, "INTERSECTS", "CLIP", "BOUNDS", 37.733, -122.4408378, 37.7341129, -122.44).Str("[1 "+poly8+"]"),
 		Do("TEST", "OBJECT", multipoly5, "INTERSECTS", "CLIP", "BOUNDS", 37.73227823422744, -122.44120001792908, 37.73319038868677, -122.43955314159392).Str("[1 "+`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[-122.4408378,37.73319038868677],[-122.4408378,37.733],[-122.44,37.733],[-122.44,37.73319038868677],[-122.4408378,37.73319038868677]]]},"properties":{}},{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[-122.44091033935547,37.73227823422744],[-122.43994474411011,37.73227823422744],[-122.43994474411011,37.73254976045042],[-122.44091033935547,37.73254976045042],[-122.44091033935547,37.73227823422744]]]},"properties":{}}]}`+"]"),
 		Do("TEST", "OBJECT", poly101, "INTERSECTS", "CLIP", "BOUNDS", 37.73315644825698, -122.44054287672043, 37.73349585185455, -122.44008690118788).Str("0"),
+
+		Do("SET", "mykey", "tri", "OBJECT", `{"type":"Polygon","coordinates":[[[-122.4404,37.7331],[-122.4401,37.7331],[-122.4404,37.7334],[-122.4404,37.7331]]]}`).OK(),
+		Do("TEST", "OBJECT", poly9, "INTERSECTS", "CLIP", "GET", "mykey", "tri").Str(`[1 {"type":"Polygon","coordinates":[[[-122.44037926197052,37.73313523548048],[-122.44017541408539,37.73313523548048],[-122.44017541408539,37.73317541408539],[-122.44036857568777,37.73336857568778],[-122.44037926197052,37.73336857568778],[-122.44037926197052,37.73313523548048]]]}]`),
+		Do("TEST", "OBJECT", poly9, "INTERSECTS", "CLIP", "OBJECT", `{"type":"Polygon","coordinates":[[[-122.4404,37.7331],[-122.4401,37.7331],[-122.4404,37.7334],[-122.4404,37.7331]]]}`).JSON().Str(`{"ok":true,"result":true,"object":{"type":"Polygon","coordinates":[[[-122.44037926197052,37.73313523548048],[-122.44017541408539,37.73313523548048],[-122.44017541408539,37.73317541408539],[-122.44036857568777,37.73336857568778],[-122.44037926197052,37.73336857568778],[-122.44037926197052,37.73313523548048]]]}}`),
 	)
 }
 